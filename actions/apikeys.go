@@ -0,0 +1,193 @@
+package actions
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/utils"
+	"github.com/mailbadger/app/validator"
+)
+
+func GetAPIKeys(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := storage.GetAPIKeys(middleware.GetUser(c).ID)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get api keys: unable to fetch api keys")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch api keys. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// PostAPIKey creates a new api key with the given scopes, the key
+// is returned only in this response.
+func PostAPIKey(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := &params.APIKey{}
+		if !bindAPIKey(c, body) {
+			return
+		}
+
+		k := &entities.APIKey{
+			UserID: middleware.GetUser(c).ID,
+			Name:   body.Name,
+			Scopes: body.Scopes,
+			Active: true,
+		}
+
+		err := setNewAPIKey(k)
+		if err == nil {
+			err = storage.CreateAPIKey(k)
+		}
+		if err != nil {
+			logger.From(c).WithError(err).Error("post api key: unable to create api key")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to create api key, please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, k)
+	}
+}
+
+func PutAPIKey(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k, ok := getAPIKey(c, storage)
+		if !ok {
+			return
+		}
+
+		body := &params.APIKey{}
+		if !bindAPIKey(c, body) {
+			return
+		}
+
+		k.Name = body.Name
+		k.Scopes = body.Scopes
+
+		if err := storage.UpdateAPIKey(k); err != nil {
+			logger.From(c).WithError(err).WithField("api_key_id", k.ID).Error("put api key: unable to update api key")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to update api key.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, k)
+	}
+}
+
+// RotateAPIKey replaces the key with a new one, the old key stops working
+// immediately and the new key is returned only in this response.
+func RotateAPIKey(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k, ok := getAPIKey(c, storage)
+		if !ok {
+			return
+		}
+
+		err := setNewAPIKey(k)
+		if err == nil {
+			k.LastUsedAt = entities.NullTime{}
+			err = storage.UpdateAPIKey(k)
+		}
+		if err != nil {
+			logger.From(c).WithError(err).WithField("api_key_id", k.ID).Error("rotate api key: unable to update api key")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to rotate api key.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, k)
+	}
+}
+
+func DeleteAPIKey(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k, ok := getAPIKey(c, storage)
+		if !ok {
+			return
+		}
+
+		if err := storage.DeleteAPIKey(k.ID, k.UserID); err != nil {
+			logger.From(c).WithError(err).WithField("api_key_id", k.ID).Error("delete api key: unable to delete api key")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to delete api key.",
+			})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func getAPIKey(c *gin.Context, storage storage.Storage) (*entities.APIKey, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Id must be an integer.",
+		})
+		return nil, false
+	}
+
+	k, err := storage.GetAPIKeyByID(id, middleware.GetUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Api key not found.",
+		})
+		return nil, false
+	}
+
+	return k, true
+}
+
+func bindAPIKey(c *gin.Context, body *params.APIKey) bool {
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid parameters, please try again.",
+		})
+		return false
+	}
+
+	if err := validator.Validate(body); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return false
+	}
+
+	for _, s := range body.Scopes {
+		if !entities.IsValidScope(s) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": fmt.Sprintf("Invalid scope %s.", s),
+			})
+			return false
+		}
+	}
+
+	return true
+}
+
+func setNewAPIKey(k *entities.APIKey) error {
+	b, err := utils.GenerateRandomBytes(24)
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+
+	k.SetKey(entities.APIKeyPrefix + hex.EncodeToString(b))
+
+	return nil
+}
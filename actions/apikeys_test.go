@@ -0,0 +1,174 @@
+package actions_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	e.GET("/api/api-keys").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.POST("/api/api-keys").WithJSON(params.APIKey{Name: "ci"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ContainsKey("errors")
+
+	auth.POST("/api/api-keys").WithJSON(params.APIKey{Name: "ci", Scopes: []string{"api-keys:write"}}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Invalid scope api-keys:write.")
+
+	created := auth.POST("/api/api-keys").WithJSON(params.APIKey{Name: "ci", Scopes: []string{"segments:read"}}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	created.ValueEqual("name", "ci").
+		ValueEqual("scopes", []string{"segments:read"}).
+		ValueEqual("active", true).
+		NotContainsKey("secret_key")
+	key := created.Value("key").String().Raw()
+	id := int64(created.Value("id").Number().Raw())
+	assert.Equal(t, key[:11], created.Value("prefix").String().Raw())
+
+	stored, err := s.GetAPIKeyByID(id, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.HashAPIKey(key), stored.SecretKey)
+	assert.False(t, stored.LastUsedAt.Valid)
+
+	// the key is shown only once
+	keys := auth.GET("/api/api-keys").
+		Expect().
+		Status(http.StatusOK).JSON().Array()
+	keys.Length().Equal(1)
+	keys.First().Object().NotContainsKey("key")
+
+	e.GET("/api/segments").WithHeader(middleware.APIKeyAuth, key).
+		Expect().
+		Status(http.StatusOK)
+
+	stored, err = s.GetAPIKeyByID(id, u.ID)
+	assert.Nil(t, err)
+	assert.True(t, stored.LastUsedAt.Valid)
+
+	// the scopes are enforced by the policy
+	e.POST("/api/segments").WithHeader(middleware.APIKeyAuth, key).WithJSON(params.Segment{Name: "news"}).
+		Expect().
+		Status(http.StatusUnauthorized)
+	e.GET("/api/subscribers").WithHeader(middleware.APIKeyAuth, key).
+		Expect().
+		Status(http.StatusUnauthorized)
+	e.GET("/api/api-keys").WithHeader(middleware.APIKeyAuth, key).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.PUT("/api/api-keys/{id}", id).WithJSON(params.APIKey{Name: "ci", Scopes: []string{"segments:read", "segments:write"}}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("scopes", []string{"segments:read", "segments:write"})
+
+	e.POST("/api/segments").WithHeader(middleware.APIKeyAuth, key).WithJSON(params.Segment{Name: "news"}).
+		Expect().
+		Status(http.StatusCreated)
+
+	// the all scope grants access to every resource, except the api keys
+	auth.PUT("/api/api-keys/{id}", id).WithJSON(params.APIKey{Name: "ci", Scopes: []string{entities.APIKeyScopeAll}}).
+		Expect().
+		Status(http.StatusOK)
+	e.GET("/api/jobs").WithHeader(middleware.APIKeyAuth, key).
+		Expect().
+		Status(http.StatusOK)
+	e.GET("/api/metrics/subscribers").WithHeader(middleware.APIKeyAuth, key).
+		Expect().
+		Status(http.StatusOK)
+	e.GET("/api/api-keys").WithHeader(middleware.APIKeyAuth, key).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	rotated := auth.POST("/api/api-keys/{id}/rotate", id).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("key").String().NotEqual(key).Raw()
+
+	e.GET("/api/segments").WithHeader(middleware.APIKeyAuth, key).
+		Expect().
+		Status(http.StatusUnauthorized)
+	e.GET("/api/segments").WithHeader(middleware.APIKeyAuth, rotated).
+		Expect().
+		Status(http.StatusOK)
+
+	auth.DELETE("/api/api-keys/{id}", 999).
+		Expect().
+		Status(http.StatusNotFound)
+	auth.DELETE("/api/api-keys/{id}", id).
+		Expect().
+		Status(http.StatusNoContent)
+
+	e.GET("/api/segments").WithHeader(middleware.APIKeyAuth, rotated).
+		Expect().
+		Status(http.StatusUnauthorized)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/segmentio/ksuid"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"github.com/mailbadger/app/emails"
 	"github.com/mailbadger/app/entities"
@@ -69,6 +70,7 @@ func StartCampaign(
 			return
 		}
 
+		prevStatus := campaign.Status
 		campaign.Status = entities.StatusSending
 		campaign.SetEventID()
 
@@ -99,13 +101,32 @@ func StartCampaign(
 			return
 		}
 
-		sesKeys, err := storage.GetSesKeys(u.ID)
+		if campaign.SplitTest != nil && !validateVariantsData(c, storage, campaign.SplitTest, body.DefaultTemplateData) {
+			return
+		}
+
+		// the campaign is sent with the current version of the template, even if it is edited in the meantime.
+		campaign.TemplateVersion = template.Version
+
+		// SES keys are required only when the user doesn't deliver through SMTP.
+		sesKeys := &entities.SesKeys{}
+		usesSMTP, err := storage.HasSMTPSettings(u.ID)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"message": "Amazon Ses keys are not set.",
+			logger.From(c).WithError(err).Error("Unable to fetch smtp settings.")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to start the campaign, please try again.",
 			})
 			return
 		}
+		if !usesSMTP {
+			sesKeys, err = storage.GetSesKeys(u.ID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"message": "Amazon Ses keys are not set.",
+				})
+				return
+			}
+		}
 
 		lists, err := storage.GetSegmentsByIDs(u.ID, body.SegmentIDs)
 		if err != nil || len(lists) == 0 {
@@ -115,18 +136,37 @@ func StartCampaign(
 			return
 		}
 
-		sender, err := emails.NewSesSenderFromCreds(sesKeys.AccessKey, sesKeys.SecretKey, sesKeys.Region)
-		if err != nil {
-			logger.From(c).WithError(err).Error("send campaign: unable to create SES client")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": "SES keys are incorrect.",
+		configSetExists := false
+		if !usesSMTP {
+			sender, err := emails.NewSesSenderFromCreds(sesKeys.AccessKey, sesKeys.SecretKey, sesKeys.Region)
+			if err != nil {
+				logger.From(c).WithError(err).Error("send campaign: unable to create SES client")
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "SES keys are incorrect.",
+				})
+				return
+			}
+
+			_, err = sender.DescribeConfigurationSet(&ses.DescribeConfigurationSetInput{
+				ConfigurationSetName: aws.String(emails.ConfigurationSetName),
 			})
-			return
+			configSetExists = err == nil
 		}
 
-		_, err = sender.DescribeConfigurationSet(&ses.DescribeConfigurationSetInput{
-			ConfigurationSetName: aws.String(emails.ConfigurationSetName),
-		})
+		// the campaigner sends the variants to the test group first.
+		if campaign.SplitTest != nil {
+			campaign.SplitTest.Status = entities.SplitTestStatusTesting
+			campaign.SplitTest.WinnerVariantID = 0
+			campaign.SplitTest.TestCompletedAt = entities.NullTime{}
+			err = storage.UpdateCampaignSplitTest(campaign.SplitTest)
+			if err != nil {
+				logger.From(c).WithField("campaign_id", id).WithError(err).Error("send campaign: unable to update split test")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"message": "We're unable to start the campaign.",
+				})
+				return
+			}
+		}
 
 		msg, err := json.Marshal(entities.CampaignerTopicParams{
 			EventID:                *campaign.EventID, // this id is handled in campaigns SetEventID method
@@ -134,10 +174,11 @@ func StartCampaign(
 			SegmentIDs:             body.SegmentIDs,
 			Source:                 fmt.Sprintf("%s <%s>", body.FromName, body.Source),
 			TemplateData:           body.DefaultTemplateData,
+			TemplateVersion:        campaign.TemplateVersion,
 			UserID:                 u.ID,
 			UserUUID:               u.UUID,
 			SesKeys:                *sesKeys,
-			ConfigurationSetExists: err == nil,
+			ConfigurationSetExists: configSetExists,
 		})
 		if err != nil {
 			logger.From(c).WithFields(logrus.Fields{
@@ -151,13 +192,15 @@ func StartCampaign(
 			return
 		}
 
-		err = publisher.SendMessage(c, queueURL, msg)
+		// the status is saved before the campaign is published, the campaigner completes
+		// the campaign only while it's sending.
+		err = storage.UpdateCampaign(campaign)
 		if err != nil {
 			logger.From(c).WithFields(logrus.Fields{
 				"campaign_id": id,
 				"template_id": campaign.BaseTemplate.ID,
 				"segment_ids": body.SegmentIDs,
-			}).WithError(err).Error("send campaign: unable to queue campaign for sending")
+			}).WithError(err).Error("send campaign: unable to update campaign")
 
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"message": "We're unable to start the campaign.",
@@ -165,13 +208,18 @@ func StartCampaign(
 			return
 		}
 
-		err = storage.UpdateCampaign(campaign)
+		err = publisher.SendMessage(c, queueURL, msg)
 		if err != nil {
 			logger.From(c).WithFields(logrus.Fields{
 				"campaign_id": id,
 				"template_id": campaign.BaseTemplate.ID,
 				"segment_ids": body.SegmentIDs,
-			}).WithError(err).Error("send campaign: unable to update campaign")
+			}).WithError(err).Error("send campaign: unable to queue campaign for sending")
+
+			_, err = storage.SwapCampaignStatus(id, u.ID, entities.StatusSending, prevStatus)
+			if err != nil {
+				logger.From(c).WithField("campaign_id", id).WithError(err).Error("send campaign: unable to revert the campaign status")
+			}
 
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"message": "We're unable to start the campaign.",
@@ -185,6 +233,264 @@ func StartCampaign(
 	}
 }
 
+func PauseCampaign(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		campaign, err := storage.GetCampaign(id, middleware.GetUser(c).ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign not found",
+			})
+			return
+		}
+
+		if campaign.Status != entities.StatusSending {
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "Only campaigns which are sending can be paused.",
+			})
+			return
+		}
+
+		// the status is swapped so that a campaign which the campaigner has just completed isn't paused.
+		swapped, err := storage.SwapCampaignStatus(id, campaign.UserID, entities.StatusSending, entities.StatusPaused)
+		if err != nil {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Error("pause campaign: unable to update campaign")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "We're unable to pause the campaign.",
+			})
+			return
+		}
+		if !swapped {
+			c.JSON(http.StatusConflict, gin.H{
+				"message": "The campaign is no longer sending.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "The campaign has been paused.",
+		})
+	}
+}
+
+func ResumeCampaign(
+	storage storage.Storage,
+	publisher sqs.PublisherAPI,
+	queueURL sqs.CampaignerQueueURL,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		campaign, err := storage.GetCampaign(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign not found",
+			})
+			return
+		}
+
+		if campaign.Status != entities.StatusPaused {
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "Only paused campaigns can be resumed.",
+			})
+			return
+		}
+
+		// the status is swapped so that only one of the concurrent requests resumes the campaign,
+		// a campaigner which hasn't noticed the pause yet keeps sending once the status is changed.
+		swapped, err := storage.SwapCampaignStatus(id, u.ID, entities.StatusPaused, entities.StatusSending)
+		if err != nil {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Error("resume campaign: unable to update campaign")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "We're unable to resume the campaign.",
+			})
+			return
+		}
+		if !swapped {
+			c.JSON(http.StatusConflict, gin.H{
+				"message": "The campaign is no longer paused.",
+			})
+			return
+		}
+
+		// The campaigner sets paused_at on the checkpoint once it notices the pause and stops, a new campaigner
+		// message is published only when this request claims the checkpoint. The campaigner checks the status
+		// after saving the checkpoint and claims it back when the status was changed before the checkpoint
+		// was read here, so exactly one campaigner sends from the checkpoint.
+		checkpoint, err := storage.GetCampaignCheckpoint(id, u.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Error("resume campaign: unable to fetch checkpoint")
+			revertResume(c, storage, id, u.ID, nil)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "We're unable to resume the campaign.",
+			})
+			return
+		}
+
+		if err == nil && checkpoint.PausedAt.Valid {
+			claimed, err := storage.ClaimPausedCampaignCheckpoint(id, u.ID)
+			if err != nil {
+				logger.From(c).WithField("campaign_id", id).WithError(err).Error("resume campaign: unable to claim checkpoint")
+				revertResume(c, storage, id, u.ID, nil)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"message": "We're unable to resume the campaign.",
+				})
+				return
+			}
+
+			if claimed {
+				status, body := publishResumedCampaign(c, storage, publisher, queueURL, checkpoint, u.ID)
+				if status != http.StatusOK {
+					revertResume(c, storage, id, u.ID, checkpoint)
+					c.JSON(status, body)
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "The campaign has been resumed.",
+		})
+	}
+}
+
+// publishResumedCampaign publishes the campaigner message which resumes the campaign from the checkpoint,
+// it returns the status and the body of the response when the message couldn't be published.
+func publishResumedCampaign(
+	c *gin.Context,
+	storage storage.Storage,
+	publisher sqs.PublisherAPI,
+	queueURL sqs.CampaignerQueueURL,
+	checkpoint *entities.CampaignCheckpoint,
+	userID int64,
+) (int, gin.H) {
+	logEntry := logger.From(c).WithField("campaign_id", checkpoint.CampaignID)
+
+	p, err := checkpoint.GetParams()
+	if err != nil {
+		logEntry.WithError(err).Error("resume campaign: unable to unmarshal checkpoint params")
+		return http.StatusInternalServerError, gin.H{"message": "We're unable to resume the campaign."}
+	}
+
+	// SES keys are not stored in the checkpoint.
+	usesSMTP, err := storage.HasSMTPSettings(userID)
+	if err != nil {
+		logEntry.WithError(err).Error("Unable to fetch smtp settings.")
+		return http.StatusInternalServerError, gin.H{"message": "Unable to resume campaign, please try again."}
+	}
+	if !usesSMTP {
+		sesKeys, err := storage.GetSesKeys(userID)
+		if err != nil {
+			return http.StatusNotFound, gin.H{"message": "Amazon Ses keys are not set."}
+		}
+		p.SesKeys = *sesKeys
+	}
+
+	msg, err := json.Marshal(p)
+	if err != nil {
+		logEntry.WithError(err).Error("resume campaign: unable to marshal campaigner message body")
+		return http.StatusInternalServerError, gin.H{"message": "We're unable to resume the campaign."}
+	}
+
+	err = publisher.SendMessage(c, queueURL, msg)
+	if err != nil {
+		logEntry.WithError(err).Error("resume campaign: unable to queue campaign for sending")
+		return http.StatusInternalServerError, gin.H{"message": "We're unable to resume the campaign."}
+	}
+
+	return http.StatusOK, nil
+}
+
+// revertResume pauses the campaign again after it couldn't be resumed, the claimed
+// checkpoint is saved back with its paused_at so that the next resume publishes it.
+func revertResume(c *gin.Context, storage storage.Storage, id, userID int64, checkpoint *entities.CampaignCheckpoint) {
+	logEntry := logger.From(c).WithField("campaign_id", id)
+
+	if checkpoint != nil {
+		err := storage.SaveCampaignCheckpoint(checkpoint)
+		if err != nil {
+			logEntry.WithError(err).Error("resume campaign: unable to restore checkpoint")
+		}
+	}
+
+	_, err := storage.SwapCampaignStatus(id, userID, entities.StatusSending, entities.StatusPaused)
+	if err != nil {
+		logEntry.WithError(err).Error("resume campaign: unable to pause the campaign again")
+	}
+}
+
+func CancelCampaign(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		campaign, err := storage.GetCampaign(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign not found",
+			})
+			return
+		}
+
+		if campaign.Status != entities.StatusSending &&
+			campaign.Status != entities.StatusPaused &&
+			campaign.Status != entities.StatusScheduled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "Only sending, paused or scheduled campaigns can be cancelled.",
+			})
+			return
+		}
+
+		// the campaign is cancelled only if its status hasn't changed since it was read,
+		// e.g. the campaigner could have completed it in the meantime.
+		cancelled, err := storage.CompleteCampaign(id, u.ID, campaign.Status, entities.StatusCancelled, time.Now().UTC())
+		if err != nil {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Error("cancel campaign: unable to update campaign")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "We're unable to cancel the campaign.",
+			})
+			return
+		}
+		if !cancelled {
+			c.JSON(http.StatusConflict, gin.H{
+				"message": "The status of the campaign has changed, please try again.",
+			})
+			return
+		}
+
+		err = storage.DeleteCampaignCheckpoint(id, u.ID)
+		if err != nil {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Warn("cancel campaign: unable to delete checkpoint")
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "The campaign has been cancelled.",
+		})
+	}
+}
+
 func GetCampaigns(store storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		val, ok := c.Get("cursor")
@@ -314,9 +620,14 @@ func PostCampaign(
 			UserID:       user.ID,
 			BaseTemplate: template.GetBase(),
 			Status:       entities.StatusDraft,
+			TrackOpens:   body.TrackOpens,
+			TrackClicks:  body.TrackClicks,
 		}
 
-		err = storage.CreateCampaign(campaign)
+		err = campaign.SetExcludedLinks(body.ExcludedLinks)
+		if err == nil {
+			err = storage.CreateCampaign(campaign)
+		}
 		if err != nil {
 			logger.From(c).WithError(err).Error("unable to create campaign")
 			c.JSON(http.StatusUnprocessableEntity, gin.H{
@@ -379,9 +690,15 @@ func PutCampaign(storage storage.Storage) gin.HandlerFunc {
 		}
 
 		campaign.Name = body.Name
+		campaign.TemplateID = template.ID
 		campaign.BaseTemplate = template.GetBase()
+		campaign.TrackOpens = body.TrackOpens
+		campaign.TrackClicks = body.TrackClicks
 
-		err = storage.UpdateCampaign(campaign)
+		err = campaign.SetExcludedLinks(body.ExcludedLinks)
+		if err == nil {
+			err = storage.UpdateCampaignSettings(campaign)
+		}
 		if err != nil {
 			logger.From(c).
 				WithError(err).
@@ -526,11 +843,61 @@ func GetCampaignStats(storage storage.Storage) gin.HandlerFunc {
 			})
 			return
 		}
+		campaignStats.Variants, err = getVariantsStats(storage, id, user.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign stats not found.",
+			})
+			return
+		}
 
 		c.JSON(http.StatusOK, campaignStats)
 	}
 }
 
+// GetCampaignStatsTimeSeries returns the campaign events bucketed by the hour or day interval,
+// along with the open, click and bounce rates.
+func GetCampaignStatsTimeSeries(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		interval := c.DefaultQuery("interval", entities.StatsIntervalDay)
+		if interval != entities.StatsIntervalHour && interval != entities.StatsIntervalDay {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The interval must be hour or day.",
+			})
+			return
+		}
+
+		user := middleware.GetUser(c)
+
+		_, err = storage.GetCampaign(id, user.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign not found.",
+			})
+			return
+		}
+
+		ts, err := storage.GetCampaignStatsTimeSeries(id, user.ID, interval)
+		if err != nil {
+			logger.From(c).WithError(err).WithField("campaign_id", id).Error("get campaign stats time series: unable to fetch from store")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "We are unable to process the request at the moment, please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, ts)
+	}
+}
+
 func GetCampaignClicksStats(storage storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
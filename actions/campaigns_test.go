@@ -4,18 +4,21 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
 	"github.com/mailbadger/app/entities/params"
 	"github.com/mailbadger/app/opa"
 	"github.com/mailbadger/app/services/boundaries"
 	"github.com/mailbadger/app/services/exporters"
 	"github.com/mailbadger/app/services/reports"
-	"github.com/mailbadger/app/services/subscribers"
 	"github.com/mailbadger/app/services/templates"
 	"github.com/mailbadger/app/session"
 	"github.com/mailbadger/app/sqs"
@@ -43,7 +46,6 @@ func TestCampaigns(t *testing.T) {
 
 	templatesvc := templates.New(s, mockS3, "test_bucket")
 	boundarysvc := boundaries.New(s)
-	subscrsvc := subscribers.New(mockS3, s)
 	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
 
 	compiler, err := opa.NewCompiler()
@@ -60,7 +62,6 @@ func TestCampaigns(t *testing.T) {
 		mockSender,
 		templatesvc,
 		boundarysvc,
-		subscrsvc,
 		reportsvc,
 		compiler,
 		false, // enable signup
@@ -129,7 +130,23 @@ func TestCampaigns(t *testing.T) {
 		ValueEqual("name", "foo1").
 		ValueEqual("status", "draft")
 
-	auth.PUT("/api/campaigns/" + idStr).WithJSON(params.PutCampaign{Name: "TESTputtest", TemplateName: templateName}).
+	auth.PUT("/api/campaigns/" + idStr).WithJSON(params.PutCampaign{
+		Name:          "TESTputtest",
+		TemplateName:  templateName,
+		TrackOpens:    true,
+		TrackClicks:   true,
+		ExcludedLinks: []string{"not a link"},
+	}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	auth.PUT("/api/campaigns/" + idStr).WithJSON(params.PutCampaign{
+		Name:          "TESTputtest",
+		TemplateName:  templateName,
+		TrackOpens:    true,
+		TrackClicks:   true,
+		ExcludedLinks: []string{"https://example.com/private"},
+	}).
 		Expect().
 		Status(http.StatusOK)
 
@@ -138,7 +155,10 @@ func TestCampaigns(t *testing.T) {
 		Expect().
 		Status(http.StatusOK).JSON().Object().
 		ValueEqual("name", "TESTputtest").
-		ValueEqual("status", "draft")
+		ValueEqual("status", "draft").
+		ValueEqual("track_opens", true).
+		ValueEqual("track_clicks", true).
+		ValueEqual("excluded_links", []string{"https://example.com/private"})
 
 	// start campaign
 	auth.POST("/api/campaigns/"+idStr+"/start").WithJSON(params.StartCampaign{}).
@@ -197,9 +217,321 @@ func TestCampaigns(t *testing.T) {
 			"source":       "This field is required",
 		})
 
+	// pause campaign which is not sending
+	auth.POST("/api/campaigns/1/pause").
+		Expect().
+		Status(http.StatusForbidden).JSON().Object().
+		ValueEqual("message", "Only campaigns which are sending can be paused.")
+
+	// cancel scheduled campaign
+	auth.POST("/api/campaigns/1/cancel").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("message", "The campaign has been cancelled.")
+
+	auth.GET("/api/campaigns/1").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.StatusCancelled)
+
+	auth.POST("/api/campaigns/1/resume").
+		Expect().
+		Status(http.StatusForbidden).JSON().Object().
+		ValueEqual("message", "Only paused campaigns can be resumed.")
+
+	auth.POST("/api/campaigns/1/cancel").
+		Expect().
+		Status(http.StatusForbidden).JSON().Object().
+		ValueEqual("message", "Only sending, paused or scheduled campaigns can be cancelled.")
+
+	auth.POST("/api/campaigns/2223/pause").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Campaign not found")
+
+	// pause and resume a sending campaign
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+	sending, err := s.GetCampaignByName("foo2", u.ID)
+	assert.Nil(t, err)
+	sending.Status = entities.StatusSending
+	assert.Nil(t, s.UpdateCampaign(sending))
+	sendingIDStr := strconv.FormatInt(sending.ID, 10)
+
+	auth.POST("/api/campaigns/"+sendingIDStr+"/pause").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("message", "The campaign has been paused.")
+
+	auth.GET("/api/campaigns/"+sendingIDStr).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.StatusPaused)
+
+	// resume without checkpoint, the campaigner hasn't picked up the campaign yet
+	auth.POST("/api/campaigns/"+sendingIDStr+"/resume").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("message", "The campaign has been resumed.")
+
+	auth.GET("/api/campaigns/"+sendingIDStr).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.StatusSending)
+
+	auth.POST("/api/campaigns/" + sendingIDStr + "/pause").
+		Expect().
+		Status(http.StatusOK)
+
+	// resume before the campaigner has stopped, it keeps sending from its checkpoint
+	checkpoint := &entities.CampaignCheckpoint{
+		UserID:           u.ID,
+		CampaignID:       sending.ID,
+		EventID:          ksuid.New(),
+		LastSubscriberID: 1000,
+	}
+	assert.Nil(t, checkpoint.SetParams(entities.CampaignerTopicParams{CampaignID: sending.ID, UserID: u.ID}))
+	assert.Nil(t, s.SaveCampaignCheckpoint(checkpoint))
+
+	auth.POST("/api/campaigns/"+sendingIDStr+"/resume").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("message", "The campaign has been resumed.")
+	mockPub.AssertNumberOfCalls(t, "SendMessage", 0)
+
+	auth.POST("/api/campaigns/" + sendingIDStr + "/pause").
+		Expect().
+		Status(http.StatusOK)
+
+	// resume after the campaigner has stopped republishes the campaigner message
+	checkpoint.PausedAt.SetValid(time.Now())
+	assert.Nil(t, s.SaveCampaignCheckpoint(checkpoint))
+
+	auth.POST("/api/campaigns/"+sendingIDStr+"/resume").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Amazon Ses keys are not set.")
+
+	// the failed resume leaves the campaign paused and the checkpoint unclaimed.
+	auth.GET("/api/campaigns/"+sendingIDStr).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.StatusPaused)
+
+	assert.Nil(t, s.CreateSesKeys(&entities.SesKeys{UserID: u.ID, AccessKey: "abcd", SecretKey: "efgh", Region: "eu-west-1"}))
+	mockPub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Once().Return(nil)
+
+	auth.POST("/api/campaigns/"+sendingIDStr+"/resume").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("message", "The campaign has been resumed.")
+	mockPub.AssertNumberOfCalls(t, "SendMessage", 1)
+
+	checkpoint, err = s.GetCampaignCheckpoint(sending.ID, u.ID)
+	assert.Nil(t, err)
+	assert.False(t, checkpoint.PausedAt.Valid)
+
+	auth.POST("/api/campaigns/"+sendingIDStr+"/resume").
+		Expect().
+		Status(http.StatusForbidden).JSON().Object().
+		ValueEqual("message", "Only paused campaigns can be resumed.")
+
+	auth.POST("/api/campaigns/" + sendingIDStr + "/cancel").
+		Expect().
+		Status(http.StatusOK)
+
+	_, err = s.GetCampaignCheckpoint(sending.ID, u.ID)
+	assert.NotNil(t, err)
+
+	// split test
+	auth.POST("/api/templates").WithJSON(params.PostTemplate{Name: "test2", HTMLPart: "<html> variant </html>", TextPart: "txtpart", SubjectPart: "subpart"}).
+		Expect().
+		Status(http.StatusCreated)
+
+	splitTest := params.PutCampaignSplitTest{
+		TemplateNames:  []string{"test1", "test2"},
+		TestPercentage: 20,
+		WaitMinutes:    60,
+		WinnerCriteria: entities.WinnerCriteriaOpens,
+	}
+
+	auth.PUT("/api/campaigns/"+sendingIDStr+"/split-test").WithJSON(splitTest).
+		Expect().
+		Status(http.StatusForbidden).JSON().Object().
+		ValueEqual("message", "The split test can be changed only for draft or scheduled campaigns.")
+
+	draft := &entities.Campaign{Name: "split", UserID: u.ID, TemplateID: sending.TemplateID, Status: entities.StatusDraft}
+	assert.Nil(t, s.CreateCampaign(draft))
+	draftIDStr := strconv.FormatInt(draft.ID, 10)
+
+	auth.PUT("/api/campaigns/"+draftIDStr+"/split-test").WithJSON(params.PutCampaignSplitTest{TemplateNames: []string{"test1"}}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Invalid parameters, please try again")
+
+	auth.PUT("/api/campaigns/"+draftIDStr+"/split-test").WithJSON(params.PutCampaignSplitTest{
+		TemplateNames:  []string{"test1", "test1"},
+		TestPercentage: 20,
+		WaitMinutes:    60,
+		WinnerCriteria: entities.WinnerCriteriaOpens,
+	}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Each variant must use a different template.")
+
+	auth.PUT("/api/campaigns/"+draftIDStr+"/split-test").WithJSON(params.PutCampaignSplitTest{
+		TemplateNames:  []string{"test1", "missing"},
+		TestPercentage: 20,
+		WaitMinutes:    60,
+		WinnerCriteria: entities.WinnerCriteriaClicks,
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("message", "Template with the name missing does not exists.")
+
+	variants := auth.PUT("/api/campaigns/"+draftIDStr+"/split-test").WithJSON(splitTest).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.SplitTestStatusDraft).
+		ValueEqual("test_percentage", 20).
+		Value("variants").Array()
+	variants.Length().Equal(2)
+	variants.First().Object().ValueEqual("name", "A")
+	variants.Last().Object().ValueEqual("name", "B")
+	variantA := int64(variants.First().Object().Value("id").Number().Raw())
+
+	auth.GET("/api/campaigns/" + draftIDStr).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("split_test").Object().
+		Value("variants").Array().Length().Equal(2)
+
+	// the stats are broken down per variant
+	assert.Nil(t, s.CreateOpen(&entities.Open{UserID: u.ID, CampaignID: draft.ID, VariantID: variantA, Recipient: "foo@example.com"}))
+	stats := auth.GET("/api/campaigns/" + draftIDStr + "/stats").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	stats.Value("opens").Object().ValueEqual("unique", 1)
+	stats.Value("variants").Array().Length().Equal(2)
+	stats.Value("variants").Array().First().Object().
+		ValueEqual("name", "A").
+		Value("opens").Object().ValueEqual("unique", 1)
+	stats.Value("variants").Array().Last().Object().
+		Value("opens").Object().ValueEqual("unique", 0)
+
+	auth.DELETE("/api/campaigns/" + draftIDStr + "/split-test").
+		Expect().
+		Status(http.StatusNoContent)
+
+	auth.GET("/api/campaigns/"+draftIDStr).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("split_test", nil)
+
+	auth.GET("/api/campaigns/" + draftIDStr + "/stats").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		NotContainsKey("variants")
+
+	// the stats time series
+	series := auth.GET("/api/campaigns/"+draftIDStr+"/stats/timeseries").
+		WithQuery("interval", "hour").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	series.ValueEqual("interval", "hour")
+	series.Value("opens").Object().ValueEqual("unique", 1)
+	series.Value("buckets").Array().Length().Equal(1)
+	series.Value("buckets").Array().First().Object().
+		Value("opens").Object().ValueEqual("total", 1)
+
+	auth.GET("/api/campaigns/"+draftIDStr+"/stats/timeseries").
+		WithQuery("interval", "week").
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "The interval must be hour or day.")
+
+	auth.GET("/api/campaigns/2223/stats/timeseries").
+		Expect().
+		Status(http.StatusNotFound)
+
 	// delete campaign by id
 	auth.DELETE("/api/campaigns/" + idStr).
 		Expect().
 		Status(http.StatusNoContent)
 
 }
+
+// completingStore completes the campaign after it's read, as the campaigner does when it
+// finishes between the read and the update of the campaign.
+type completingStore struct {
+	storage.Storage
+}
+
+func (s *completingStore) GetCampaign(id, userID int64) (*entities.Campaign, error) {
+	campaign, err := s.Storage.GetCampaign(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.CompleteCampaign(id, userID, entities.StatusSending, entities.StatusSent, time.Now().UTC())
+	return campaign, err
+}
+
+func TestCampaignCompletedConcurrently(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := &completingStore{storage.From(db)}
+	sess := session.New(s, "jXn2r5u8x/A?D(G+KbPeSgVkYp3s6v9y", "jXn2r5u8x/A?D(G+KbPeSgVkYp3s6v9y", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templates.New(s, mockS3, "test_bucket"),
+		boundaries.New(s),
+		reports.New(exporters.NewSubscribersExporter(mockS3, s), s),
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	for _, action := range []string{"pause", "cancel"} {
+		campaign := &entities.Campaign{UserID: u.ID, Name: action, Status: entities.StatusSending}
+		assert.Nil(t, s.CreateCampaign(campaign))
+
+		auth.POST("/api/campaigns/{id}/"+action, campaign.ID).
+			Expect().
+			Status(http.StatusConflict)
+
+		// the completed campaign isn't overwritten.
+		status, err := s.GetCampaignStatus(campaign.ID, u.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.StatusSent, status)
+	}
+}
@@ -0,0 +1,638 @@
+package actions
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/segmentio/ksuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+func GetForms(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		val, ok := c.Get("cursor")
+		if !ok {
+			logger.From(c).Error("get forms: unable to fetch pagination cursor from context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch forms. Please try again.",
+			})
+			return
+		}
+
+		p, ok := val.(*storage.PaginationCursor)
+		if !ok {
+			logger.From(c).Error("get forms: unable to cast pagination cursor from context value")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch forms. Please try again.",
+			})
+			return
+		}
+
+		err := store.GetForms(middleware.GetUser(c).ID, p)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get forms: unable to fetch forms collection")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch forms. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+func GetForm(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		f, err := storage.GetForm(id, middleware.GetUser(c).ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Form not found.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, f)
+	}
+}
+
+func PostForm(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := &params.Form{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		_, err := storage.GetFormByName(body.Name, u.ID)
+		if err == nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Form with that name already exists.",
+			})
+			return
+		}
+
+		f := &entities.Form{
+			UserID: u.ID,
+			UUID:   uuid.NewString(),
+		}
+		if !setFormData(c, storage, f, body) {
+			return
+		}
+
+		if err := storage.CreateForm(f); err != nil {
+			logger.From(c).WithError(err).Error("post form: unable to create form")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to create form.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, f)
+	}
+}
+
+func PutForm(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		f, err := storage.GetForm(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Form not found.",
+			})
+			return
+		}
+
+		body := &params.Form{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		f2, err := storage.GetFormByName(body.Name, u.ID)
+		if err == nil && f2.ID != f.ID {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Form with that name already exists.",
+			})
+			return
+		}
+
+		if !setFormData(c, storage, f, body) {
+			return
+		}
+
+		if err := storage.UpdateForm(f); err != nil {
+			logger.From(c).WithError(err).WithField("form_id", id).Error("put form: unable to update form")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to update form.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, f)
+	}
+}
+
+func DeleteForm(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		_, err = storage.GetForm(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Form not found.",
+			})
+			return
+		}
+
+		err = storage.DeleteForm(id, u.ID)
+		if err != nil {
+			logger.From(c).WithError(err).WithField("form_id", id).Error("delete form: unable to delete form")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to delete form.",
+			})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// confirmationEmailInterval is the minimum time between two confirmation emails to the same subscriber.
+const confirmationEmailInterval = 10 * time.Minute
+
+// PostFormSubscribe subscribes a website visitor through the public form. When the form
+// has double opt-in enabled, the subscriber is pending (inactive) until the signed link
+// in the confirmation email is clicked.
+func PostFormSubscribe(
+	storage storage.Storage,
+	boundarysvc boundaries.Service,
+	templatesvc templates.Service,
+	webhooksvc webhooks.Service,
+	publisher sqs.PublisherAPI,
+	queueURL sqs.SendEmailQueueURL,
+	secret string,
+	appURL string,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, err := storage.GetFormByUUID(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Form not found.",
+			})
+			return
+		}
+
+		body := &params.FormSubscribe{}
+		if c.ContentType() == binding.MIMEJSON {
+			err = c.ShouldBindJSON(body)
+		} else {
+			err = c.ShouldBind(body)
+			body.Metadata = c.PostFormMap("metadata")
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		logEntry := logger.From(c).WithFields(logrus.Fields{
+			"form_id": f.ID,
+			"user_id": f.UserID,
+		})
+
+		meta, err := f.FilterMetadata(body.Metadata)
+		if err != nil {
+			logEntry.WithError(err).Error("form subscribe: unable to unmarshal metadata fields")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to subscribe, please try again.",
+			})
+			return
+		}
+
+		u, err := storage.GetUser(f.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Form not found.",
+			})
+			return
+		}
+
+		s, err := storage.GetSubscriberByEmail(body.Email, u.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logEntry.WithError(err).Error("form subscribe: unable to fetch subscriber")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to subscribe, please try again.",
+			})
+			return
+		}
+
+		sendConfirmation := f.DoubleOptIn
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			limitExceeded, _, err := boundarysvc.SubscribersLimitExceeded(u)
+			if err != nil {
+				logEntry.WithError(err).Error("form subscribe: unable to check subscribers limit for user")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"message": "Unable to subscribe, please try again.",
+				})
+				return
+			}
+			if limitExceeded {
+				logEntry.Info("form subscribe: user has exceeded his subscribers limit")
+				c.JSON(http.StatusForbidden, gin.H{
+					"message": "Unable to subscribe, the subscribers limit is exceeded.",
+				})
+				return
+			}
+
+			s = &entities.Subscriber{
+				UserID:   u.ID,
+				Name:     body.Name,
+				Email:    body.Email,
+				Active:   !f.DoubleOptIn,
+				Segments: f.Segments,
+			}
+			s.MetaJSON, err = json.Marshal(meta)
+			if err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"message": "Unable to subscribe, invalid metadata.",
+				})
+				return
+			}
+
+			if err := storage.CreateSubscriber(s); err != nil {
+				logEntry.WithError(err).Error("form subscribe: unable to create subscriber")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"message": "Unable to subscribe, please try again.",
+				})
+				return
+			}
+
+			dispatchWebhookEvent(c, webhooksvc, u.ID, entities.WebhookEventSubscriberCreated, s)
+		} else {
+			// existing subscribers are only added to the form segments, the rest of their data
+			// is not changed by an unauthenticated request. Subscribers who have unsubscribed
+			// are activated again only through the confirmation link.
+			sendConfirmation = f.DoubleOptIn && !s.Active && !s.Blacklisted
+			for _, seg := range f.Segments {
+				seg.Subscribers = []entities.Subscriber{*s}
+				if err := storage.AppendSubscribers(&seg); err != nil {
+					logEntry.WithError(err).Error("form subscribe: unable to append subscriber to segment")
+					c.JSON(http.StatusInternalServerError, gin.H{
+						"message": "Unable to subscribe, please try again.",
+					})
+					return
+				}
+			}
+		}
+
+		if sendConfirmation {
+			// the confirmation email is sent at most once per interval to the address, the response
+			// is the same either way so that the form can't be used to flood the inbox.
+			now := time.Now().UTC()
+			claimed, err := storage.ClaimConfirmationEmail(s.ID, u.ID, now, now.Add(-confirmationEmailInterval))
+			if err != nil {
+				logEntry.WithError(err).Error("form subscribe: unable to claim confirmation email")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"message": "Unable to send the confirmation email, please try again.",
+				})
+				return
+			}
+
+			if !claimed {
+				logEntry.WithField("subscriber_id", s.ID).Info("form subscribe: confirmation email was sent recently, skipping")
+			} else {
+				err = sendConfirmationEmail(c, storage, templatesvc, publisher, queueURL, f, s, u, secret, appURL)
+				if err != nil {
+					logEntry.WithError(err).Error("form subscribe: unable to send confirmation email")
+					if err := storage.ReleaseConfirmationEmail(s.ID, u.ID); err != nil {
+						logEntry.WithError(err).Error("form subscribe: unable to release confirmation email")
+					}
+					c.JSON(http.StatusInternalServerError, gin.H{
+						"message": "Unable to send the confirmation email, please try again.",
+					})
+					return
+				}
+			}
+		}
+
+		if f.RedirectURL != "" {
+			c.Redirect(http.StatusSeeOther, f.RedirectURL)
+			return
+		}
+
+		msg := "You have successfully subscribed."
+		if f.DoubleOptIn {
+			msg = "Please check your inbox to confirm the subscription."
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": msg,
+		})
+	}
+}
+
+// GetFormConfirm activates the pending subscriber when the signed token from the
+// confirmation email matches, and redirects to the confirmation page of the form.
+func GetFormConfirm(storage storage.Storage, secret, appURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Query("email")
+		token := c.Query("t")
+		failedURL := appURL + "/subscription-confirmed.html?failed=true"
+
+		logEntry := logger.From(c).WithFields(logrus.Fields{
+			"email": email,
+			"uuid":  c.Param("id"),
+		})
+
+		f, err := storage.GetFormByUUID(c.Param("id"))
+		if err != nil {
+			logEntry.WithError(err).Warn("confirm subscription: cannot find form by uuid")
+			c.Redirect(http.StatusTemporaryRedirect, failedURL)
+			return
+		}
+
+		s, err := storage.GetSubscriberByEmail(email, f.UserID)
+		if err != nil {
+			logEntry.WithError(err).Warn("confirm subscription: unable to fetch subscriber by email")
+			c.Redirect(http.StatusTemporaryRedirect, failedURL)
+			return
+		}
+
+		// the links expire, so that an old link doesn't activate a subscriber who has unsubscribed since.
+		unix, err := strconv.ParseInt(c.Query("e"), 10, 64)
+		if err != nil || time.Now().After(time.Unix(unix, 0)) {
+			logEntry.Warn("confirm subscription: the link has expired")
+			c.Redirect(http.StatusTemporaryRedirect, failedURL)
+			return
+		}
+
+		hash, err := s.GenerateConfirmToken(secret, time.Unix(unix, 0))
+		if err != nil {
+			logEntry.WithError(err).Error("confirm subscription: unable to generate hash")
+			c.Redirect(http.StatusTemporaryRedirect, failedURL)
+			return
+		}
+
+		if len(token) != len(hash) || subtle.ConstantTimeCompare([]byte(token), []byte(hash)) != 1 {
+			logEntry.Warn("confirm subscription: hashes don't match")
+			c.Redirect(http.StatusTemporaryRedirect, failedURL)
+			return
+		}
+
+		if s.Blacklisted {
+			logEntry.Info("confirm subscription: subscriber is blacklisted")
+			c.Redirect(http.StatusTemporaryRedirect, failedURL)
+			return
+		}
+
+		if !s.Active {
+			err = storage.ConfirmSubscriber(s.ID, f.UserID)
+			if err != nil {
+				logEntry.WithError(err).Error("confirm subscription: unable to activate subscriber")
+				c.Redirect(http.StatusTemporaryRedirect, failedURL)
+				return
+			}
+		}
+
+		if f.ConfirmRedirectURL != "" {
+			c.Redirect(http.StatusTemporaryRedirect, f.ConfirmRedirectURL)
+			return
+		}
+
+		c.Redirect(http.StatusTemporaryRedirect, appURL+"/subscription-confirmed.html")
+	}
+}
+
+// setFormData sets the request body data to the form, it writes the
+// error response and returns false when the data is invalid.
+func setFormData(c *gin.Context, storage storage.Storage, f *entities.Form, body *params.Form) bool {
+	segs := []entities.Segment{}
+	if len(body.SegmentIDs) > 0 {
+		var err error
+		segs, err = storage.GetSegmentsByIDs(f.UserID, body.SegmentIDs)
+		if err != nil || len(segs) != len(body.SegmentIDs) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Invalid data",
+				"errors": map[string]string{
+					"segments": "Unable to find the specified segments.",
+				},
+			})
+			return false
+		}
+	}
+
+	for _, seg := range segs {
+		if seg.IsDynamic() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": fmt.Sprintf("Segment %s is dynamic, only static segments can be used in forms.", seg.Name),
+			})
+			return false
+		}
+	}
+
+	f.ConfirmationTemplateID = 0
+	if body.DoubleOptIn {
+		template, err := storage.GetTemplateByName(body.ConfirmationTemplateName, f.UserID)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": fmt.Sprintf("Template with the name %s does not exists.", body.ConfirmationTemplateName),
+			})
+			return false
+		}
+		f.ConfirmationTemplateID = template.ID
+	}
+
+	if err := f.SetMetadataFields(body.MetadataFields); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message": "Invalid metadata fields.",
+		})
+		return false
+	}
+
+	f.Name = body.Name
+	f.Segments = segs
+	f.RedirectURL = body.RedirectURL
+	f.ConfirmRedirectURL = body.ConfirmRedirectURL
+	f.DoubleOptIn = body.DoubleOptIn
+	f.Source = body.Source
+	f.FromName = body.FromName
+
+	return true
+}
+
+// sendConfirmationEmail renders the confirmation template of the form and
+// publishes the email to the sender queue, through the user's SES account or SMTP relay.
+func sendConfirmationEmail(
+	ctx context.Context,
+	storage storage.Storage,
+	templatesvc templates.Service,
+	publisher sqs.PublisherAPI,
+	queueURL sqs.SendEmailQueueURL,
+	f *entities.Form,
+	s *entities.Subscriber,
+	u *entities.User,
+	secret string,
+	appURL string,
+) error {
+	tmpl, err := templatesvc.ParseTemplate(ctx, f.ConfirmationTemplateID, u.ID)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+
+	data, err := s.GetMetadata()
+	if err != nil {
+		return fmt.Errorf("get metadata: %w", err)
+	}
+	if s.Name != "" {
+		data[entities.TagName] = s.Name
+	}
+
+	data[entities.TagConfirmUrl], err = s.GetConfirmURL(f.UUID, secret, appURL, time.Now().Add(entities.ConfirmTokenTTL))
+	if err != nil {
+		return fmt.Errorf("get confirm url: %w", err)
+	}
+
+	html, subject, text, err := renderTemplate(tmpl, data)
+	if err != nil {
+		return err
+	}
+
+	// SES keys are required only when the user doesn't deliver through SMTP.
+	sesKeys := &entities.SesKeys{}
+	usesSMTP, err := storage.HasSMTPSettings(u.ID)
+	if err != nil {
+		return fmt.Errorf("get smtp settings: %w", err)
+	}
+	if !usesSMTP {
+		sesKeys, err = storage.GetSesKeys(u.ID)
+		if err != nil {
+			return fmt.Errorf("get ses keys: %w", err)
+		}
+	}
+
+	// the confirmation email is sent as a transactional message, so that it's
+	// not tracked as a campaign send and its status can be looked up.
+	m := &entities.TransactionalMessage{
+		UserID:     u.ID,
+		EventID:    ksuid.New(),
+		TemplateID: f.ConfirmationTemplateID,
+		Recipient:  s.Email,
+		Source:     f.Source,
+		Status:     entities.TransactionalStatusQueued,
+	}
+	if err := storage.CreateTransactionalMessage(m); err != nil {
+		return fmt.Errorf("create transactional message: %w", err)
+	}
+
+	msg, err := json.Marshal(entities.SenderTopicParams{
+		EventID:         m.EventID,
+		UserID:          u.ID,
+		UserUUID:        u.UUID,
+		SubscriberEmail: s.Email,
+		Source:          fmt.Sprintf("%s <%s>", f.FromName, f.Source),
+		HTMLPart:        []byte(html),
+		SubjectPart:     []byte(subject),
+		TextPart:        []byte(text),
+		SesKeys:         *sesKeys,
+		TransactionalID: m.ID,
+	})
+	if err == nil {
+		err = publisher.SendMessage(ctx, queueURL, msg)
+	}
+	if err != nil {
+		uerr := storage.UpdateTransactionalMessageStatus(m.ID, u.ID, entities.TransactionalStatusFailed, "Unable to queue the email.", nil)
+		if uerr != nil {
+			return fmt.Errorf("publish: %w, update transactional message: %v", err, uerr)
+		}
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// renderTemplate renders the html, subject and text parts of the parsed template with the data.
+func renderTemplate(tmpl *entities.CampaignTemplateData, data map[string]string) (html, subject, text string, err error) {
+	data = tmpl.Helpers.Data(data)
+
+	html, err = tmpl.HTMLPart.Render(data)
+	if err != nil {
+		return "", "", "", fmt.Errorf("render html: %w", err)
+	}
+	subject, err = tmpl.SubjectPart.Render(data)
+	if err != nil {
+		return "", "", "", fmt.Errorf("render subject: %w", err)
+	}
+	text, err = tmpl.TextPart.Render(data)
+	if err != nil {
+		return "", "", "", fmt.Errorf("render text: %w", err)
+	}
+
+	return html, subject, text, nil
+}
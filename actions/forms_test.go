@@ -0,0 +1,319 @@
+package actions_test
+
+import (
+	"encoding/json"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestForms(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(`<a href="{{confirm_url}}">Confirm</a>`)),
+	}, nil).Once()
+
+	var published entities.SenderTopicParams
+	mockPub := new(sqs.MockPublisher)
+	mockPub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		err := json.Unmarshal(args.Get(2).([]byte), &published)
+		assert.Nil(t, err)
+	}).Return(nil).Once()
+
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	static := &entities.Segment{Name: "newsletter", UserID: u.ID, Type: entities.SegmentTypeStatic}
+	assert.Nil(t, s.CreateSegment(static))
+	dynamic := &entities.Segment{Name: "pro", UserID: u.ID, Type: entities.SegmentTypeDynamic}
+	assert.Nil(t, dynamic.SetRules(entities.SegmentRule{Field: entities.RuleFieldName, Operator: entities.RuleOpEquals, Value: "pro"}))
+	assert.Nil(t, s.CreateSegment(dynamic))
+
+	tmpl := &entities.Template{BaseTemplate: entities.BaseTemplate{UserID: u.ID, Name: "confirm", SubjectPart: "Confirm {{name}}"}, TextPart: "{{confirm_url}}"}
+	assert.Nil(t, s.CreateTemplate(tmpl))
+	assert.Nil(t, s.CreateSesKeys(&entities.SesKeys{UserID: u.ID, AccessKey: "key", SecretKey: "secret", Region: "eu-west-1"}))
+
+	e.POST("/api/forms").WithJSON(params.Form{Name: "signup"}).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.POST("/api/forms").WithJSON(params.Form{Name: "signup", DoubleOptIn: true, RedirectURL: "javascript:alert(1)"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{
+			"confirmation_template_name": "This field is required",
+			"source":                     "This field is required",
+			"from_name":                  "This field is required",
+			"redirect_url":               "Must be a valid http or https URL",
+		})
+
+	auth.POST("/api/forms").WithJSON(params.Form{Name: "signup", SegmentIDs: []int64{dynamic.ID}}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Segment pro is dynamic, only static segments can be used in forms.")
+
+	auth.POST("/api/forms").WithJSON(params.Form{Name: "signup", SegmentIDs: []int64{static.ID, 999}}).
+		Expect().
+		Status(http.StatusUnprocessableEntity)
+
+	auth.POST("/api/forms").WithJSON(params.Form{
+		Name:                     "signup",
+		DoubleOptIn:              true,
+		ConfirmationTemplateName: "missing",
+		Source:                   "news@example.com",
+		FromName:                 "News",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("message", "Template with the name missing does not exists.")
+
+	single := auth.POST("/api/forms").WithJSON(params.Form{
+		Name:           "signup",
+		SegmentIDs:     []int64{static.ID},
+		MetadataFields: []string{"plan"},
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	single.ValueEqual("double_opt_in", false).
+		ValueEqual("metadata_fields", []string{"plan"}).
+		Value("segments").Array().Length().Equal(1)
+	singleUUID := single.Value("uuid").String().Raw()
+	singleID := int64(single.Value("id").Number().Raw())
+
+	auth.POST("/api/forms").WithJSON(params.Form{Name: "signup"}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("message", "Form with that name already exists.")
+
+	double := auth.POST("/api/forms").WithJSON(params.Form{
+		Name:                     "double",
+		SegmentIDs:               []int64{static.ID},
+		DoubleOptIn:              true,
+		ConfirmationTemplateName: "confirm",
+		Source:                   "news@example.com",
+		FromName:                 "News",
+		RedirectURL:              "https://example.org/thanks",
+		ConfirmRedirectURL:       "https://example.org/confirmed",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	double.ValueEqual("confirmation_template_id", tmpl.ID)
+	doubleUUID := double.Value("uuid").String().Raw()
+
+	auth.GET("/api/forms").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("collection").Array().Length().Equal(2)
+
+	auth.PUT("/api/forms/{id}", singleID).WithJSON(params.Form{
+		Name:           "signup",
+		SegmentIDs:     []int64{static.ID},
+		MetadataFields: []string{"plan", "city"},
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("metadata_fields", []string{"plan", "city"})
+
+	// single opt-in subscribe
+	e.POST("/api/forms/{uuid}/subscribe", "foobar").WithJSON(params.FormSubscribe{Email: "a@example.com"}).
+		Expect().
+		Status(http.StatusNotFound)
+
+	e.POST("/api/forms/{uuid}/subscribe", singleUUID).WithJSON(params.FormSubscribe{Email: "invalid"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{"email": "Invalid email format"})
+
+	e.POST("/api/forms/{uuid}/subscribe", singleUUID).WithJSON(params.FormSubscribe{
+		Email:    "single@example.com",
+		Name:     "Single",
+		Metadata: map[string]string{"plan": "pro", "admin": "true"},
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("message", "You have successfully subscribed.")
+
+	sub, err := s.GetSubscriberByEmail("single@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.True(t, sub.Active)
+	meta, err := sub.GetMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"plan": "pro"}, meta)
+
+	total, err := s.GetTotalSubscribersBySegment(static.ID, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// url encoded html form
+	e.POST("/api/forms/{uuid}/subscribe", singleUUID).WithFormField("email", "html@example.com").
+		WithFormField("metadata[city]", "Skopje").
+		Expect().
+		Status(http.StatusOK)
+
+	sub, err = s.GetSubscriberByEmail("html@example.com", u.ID)
+	assert.Nil(t, err)
+	meta, err = sub.GetMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"city": "Skopje"}, meta)
+
+	// double opt-in subscribe
+	e.POST("/api/forms/{uuid}/subscribe", doubleUUID).WithJSON(params.FormSubscribe{Email: "double@example.com", Name: "Double"}).
+		Expect().
+		Status(http.StatusSeeOther).
+		Header("Location").Equal("https://example.org/thanks")
+
+	pending, err := s.GetSubscriberByEmail("double@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.False(t, pending.Active)
+
+	assert.Equal(t, "double@example.com", published.SubscriberEmail)
+	assert.Equal(t, "News <news@example.com>", published.Source)
+	assert.Equal(t, "Confirm Double", string(published.SubjectPart))
+	assert.Zero(t, published.CampaignID)
+	assert.Zero(t, published.SubscriberID)
+	assert.Contains(t, string(published.HTMLPart), "http://example.com/api/forms/"+doubleUUID+"/confirm?")
+
+	// the confirmation email is sent as a transactional message
+	tm, err := s.GetTransactionalMessage(published.TransactionalID, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "double@example.com", tm.Recipient)
+	assert.Equal(t, entities.TransactionalStatusQueued, tm.Status)
+
+	confirmURL, err := url.Parse(html.UnescapeString(string(published.TextPart)))
+	assert.Nil(t, err)
+	query := confirmURL.Query()
+	expires, err := strconv.ParseInt(query.Get("e"), 10, 64)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(entities.ConfirmTokenTTL), time.Unix(expires, 0), time.Minute)
+
+	token, err := pending.GenerateConfirmToken("secretexmplkeythatis32characters", time.Unix(expires, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, token, query.Get("t"))
+	mockS3.AssertExpectations(t)
+	mockPub.AssertExpectations(t)
+
+	// the confirmation email is not sent again to the same address right away
+	e.POST("/api/forms/{uuid}/subscribe", doubleUUID).WithJSON(params.FormSubscribe{Email: "double@example.com"}).
+		Expect().
+		Status(http.StatusSeeOther).
+		Header("Location").Equal("https://example.org/thanks")
+	mockPub.AssertNumberOfCalls(t, "SendMessage", 1)
+
+	e.GET("/api/forms/{uuid}/confirm", doubleUUID).
+		WithQuery("email", "double@example.com").
+		WithQuery("t", "invalid").
+		Expect().
+		Status(http.StatusTemporaryRedirect).
+		Header("Location").Equal("http://example.com/subscription-confirmed.html?failed=true")
+
+	// the expired links are refused, even when the token is signed
+	expired := time.Now().Add(-time.Minute)
+	expiredToken, err := pending.GenerateConfirmToken("secretexmplkeythatis32characters", expired)
+	assert.Nil(t, err)
+	e.GET("/api/forms/{uuid}/confirm", doubleUUID).
+		WithQuery("email", "double@example.com").
+		WithQuery("e", expired.Unix()).
+		WithQuery("t", expiredToken).
+		Expect().
+		Status(http.StatusTemporaryRedirect).
+		Header("Location").Equal("http://example.com/subscription-confirmed.html?failed=true")
+
+	// the expiry time is signed
+	e.GET("/api/forms/{uuid}/confirm", doubleUUID).
+		WithQuery("email", "double@example.com").
+		WithQuery("e", expires+3600).
+		WithQuery("t", token).
+		Expect().
+		Status(http.StatusTemporaryRedirect).
+		Header("Location").Equal("http://example.com/subscription-confirmed.html?failed=true")
+
+	e.GET("/api/forms/{uuid}/confirm", doubleUUID).
+		WithQuery("email", "double@example.com").
+		WithQuery("e", expires).
+		WithQuery("t", token).
+		Expect().
+		Status(http.StatusTemporaryRedirect).
+		Header("Location").Equal("https://example.org/confirmed")
+
+	confirmed, err := s.GetSubscriberByEmail("double@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.True(t, confirmed.Active)
+
+	// the subscriber is active, the confirmation email is not sent again
+	e.POST("/api/forms/{uuid}/subscribe", doubleUUID).WithJSON(params.FormSubscribe{Email: "double@example.com"}).
+		Expect().
+		Status(http.StatusSeeOther)
+
+	auth.DELETE("/api/forms/{id}", singleID).
+		Expect().
+		Status(http.StatusNoContent)
+
+	auth.GET("/api/forms/{id}", singleID).
+		Expect().
+		Status(http.StatusNotFound)
+}
@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/gin-gonic/gin"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/jobs"
+	"github.com/mailbadger/app/storage"
+)
+
+// GetJobs returns the jobs of the user, the latest jobs are returned first.
+func GetJobs(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		val, ok := c.Get("cursor")
+		if !ok {
+			logger.From(c).Error("get jobs: unable to fetch pagination cursor from context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch jobs. Please try again.",
+			})
+			return
+		}
+
+		p, ok := val.(*storage.PaginationCursor)
+		if !ok {
+			logger.From(c).Error("get jobs: unable to cast pagination cursor from context value")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch jobs. Please try again.",
+			})
+			return
+		}
+
+		err := store.GetJobs(middleware.GetUser(c).ID, p)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get jobs: unable to fetch jobs collection")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch jobs. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+// GetJob returns the status and progress of the job.
+func GetJob(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := getJob(c, storage)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// PostJobRetry runs the failed job again.
+func PostJobRetry(storage storage.Storage, jobsvc jobs.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := getJob(c, storage)
+		if !ok {
+			return
+		}
+
+		err := jobsvc.Retry(c, job)
+		if err != nil {
+			if errors.Is(err, jobs.ErrNotFailed) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "Only the failed jobs can be retried.",
+				})
+				return
+			}
+			if errors.Is(err, jobs.ErrNotRetryable) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "The job can't be retried, please submit the request again.",
+				})
+				return
+			}
+
+			logger.From(c).WithError(err).WithField("job_id", job.ID).Error("retry job: unable to retry job")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to retry the job, please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// GetJobRejects responds with a temporary url of the file with the rejected rows of the import job.
+func GetJobRejects(storage storage.Storage, s3Client s3iface.S3API, bucket string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := getJob(c, storage)
+		if !ok {
+			return
+		}
+
+		res := new(entities.ImportSubscribersResult)
+		if job.Type == entities.JobTypeImportSubscribers && !job.Result.IsNull() {
+			err := json.Unmarshal(job.Result, res)
+			if err != nil {
+				logger.From(c).WithError(err).WithField("job_id", job.ID).Error("job rejects: unable to unmarshal result")
+			}
+		}
+
+		if res.RejectsFile == "" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "The job has no rejected rows.",
+			})
+			return
+		}
+
+		req, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(jobs.RejectsKey(job.UserID, res.RejectsFile)),
+		})
+
+		pUrl, err := req.Presign(15 * time.Minute)
+		if err != nil {
+			logger.From(c).WithError(err).Warn("Unable to sign s3 url.")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Unable to sign url.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"url": pUrl,
+		})
+	}
+}
+
+func getJob(c *gin.Context, storage storage.Storage) (*entities.Job, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Id must be an integer.",
+		})
+		return nil, false
+	}
+
+	job, err := storage.GetJob(id, middleware.GetUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Job not found.",
+		})
+		return nil, false
+	}
+
+	return job, true
+}
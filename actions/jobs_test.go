@@ -0,0 +1,528 @@
+package actions_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/jobs"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/subscribers"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestJobs(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.New(mockS3, s), s)
+
+	// the webhooks of the imported subscribers are published to their own queue.
+	webhookPub := new(sqs.MockPublisher)
+	var webhookMsgs []entities.WebhookTopicParams
+	webhookPub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		var msg entities.WebhookTopicParams
+		assert.Nil(t, json.Unmarshal(args.Get(2).([]byte), &msg))
+		webhookMsgs = append(webhookMsgs, msg)
+	}).Return(nil)
+	webhookQueueURL := "http://example.com/webhook-queue"
+	webhooksvc := webhooks.New(s, webhookPub, &webhookQueueURL)
+
+	var conf config.Config
+	conf.Storage.S3.FilesBucket = "files-bucket"
+	runner := jobs.NewRunner(s, jobs.NewHandlers(s, mockS3, subscribers.New(mockS3, s, webhooksvc), reportsvc, conf))
+	runJobs(t, s, mockPub, runner)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	file := func(key, body string) {
+		mockS3.On("GetObject", &s3.GetObjectInput{
+			Bucket: aws.String("files-bucket"),
+			Key:    aws.String(key),
+		}).Once().Return(&s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(body))}, nil)
+	}
+
+	e.GET("/api/jobs/1").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.GET("/api/jobs/abc").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	auth.GET("/api/jobs/999").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Job not found.")
+
+	for _, email := range []string{"jane@example.com", "jack@example.com"} {
+		assert.Nil(t, s.CreateSubscriber(&entities.Subscriber{UserID: u.ID, Email: email, Active: true}))
+	}
+
+	// the job is done and the subscriber is removed.
+	file(fmt.Sprintf("subscribers/remove/%d/remove.csv", u.ID), "email\njane@example.com\n")
+	job := auth.POST("/api/subscribers/bulk-remove").
+		WithJSON(params.BulkRemoveSubscribers{Filename: "remove.csv"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object()
+	job.ValueEqual("type", entities.JobTypeBulkRemoveSubscribers)
+	doneID := int64(job.Value("id").Number().Raw())
+
+	auth.GET("/api/jobs/{id}", doneID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.JobStatusDone).
+		ValueEqual("processed", 1).
+		ValueEqual("total", 1).
+		ValueEqual("attempts", 1).
+		ValueEqual("error", "")
+
+	_, err = s.GetSubscriberByEmail("jane@example.com", u.ID)
+	assert.NotNil(t, err)
+
+	auth.POST("/api/jobs/{id}/retry", doneID).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Only the failed jobs can be retried.")
+
+	// the missing file fails the job without retrying it.
+	mockS3.On("GetObject", &s3.GetObjectInput{
+		Bucket: aws.String("files-bucket"),
+		Key:    aws.String(fmt.Sprintf("subscribers/remove/%d/missing.csv", u.ID)),
+	}).Once().Return(nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil))
+	failedID := int64(auth.POST("/api/subscribers/bulk-remove").
+		WithJSON(params.BulkRemoveSubscribers{Filename: "missing.csv"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	failed := auth.GET("/api/jobs/{id}", failedID).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	failed.ValueEqual("status", entities.JobStatusFailed).
+		ValueEqual("attempts", 1)
+	failed.Value("error").String().Contains("file not found")
+
+	// the retried job runs from the start.
+	file(fmt.Sprintf("subscribers/remove/%d/missing.csv", u.ID), "email\njack@example.com\n")
+	auth.POST("/api/jobs/{id}/retry", failedID).
+		Expect().
+		Status(http.StatusAccepted)
+
+	auth.GET("/api/jobs/{id}", failedID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.JobStatusDone).
+		ValueEqual("processed", 1).
+		ValueEqual("error", "")
+
+	_, err = s.GetSubscriberByEmail("jack@example.com", u.ID)
+	assert.NotNil(t, err)
+
+	// the temporary errors are retried until the max attempts are reached.
+	mockS3.On("GetObject", &s3.GetObjectInput{
+		Bucket: aws.String("files-bucket"),
+		Key:    aws.String(fmt.Sprintf("subscribers/remove/%d/flaky.csv", u.ID)),
+	}).Times(entities.JobMaxAttempts).Return(nil, errors.New("connection reset"))
+	flakyID := int64(auth.POST("/api/subscribers/bulk-remove").
+		WithJSON(params.BulkRemoveSubscribers{Filename: "flaky.csv"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	auth.GET("/api/jobs/{id}", flakyID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.JobStatusPending).
+		ValueEqual("attempts", 1)
+
+	flaky, err := s.GetJob(flakyID, u.ID)
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(runner.Run(context.Background(), flaky), jobs.ErrRetry))
+	assert.Nil(t, runner.Run(context.Background(), flaky))
+	assert.Equal(t, entities.JobStatusFailed, flaky.Status)
+	assert.Equal(t, entities.JobMaxAttempts, flaky.Attempts)
+	assert.Contains(t, flaky.Error, "connection reset")
+
+	// the credentials are cleared from the payload of the finished ses keys job.
+	assert.Nil(t, s.CreateSesKeys(&entities.SesKeys{UserID: u.ID, AccessKey: "foo", SecretKey: "bar", Region: "eu-west-1"}))
+	sesJob := &entities.Job{
+		UserID:      u.ID,
+		Type:        entities.JobTypeSESKeys,
+		Status:      entities.JobStatusPending,
+		Payload:     entities.JSON(`{"access_key":"foo","secret_key":"bar","region":"eu-west-1"}`),
+		MaxAttempts: entities.JobMaxAttempts,
+	}
+	assert.Nil(t, s.CreateJob(sesJob))
+	assert.Nil(t, runner.Run(context.Background(), sesJob))
+
+	sesJob, err = s.GetJob(sesJob.ID, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.JobStatusFailed, sesJob.Status)
+	assert.Equal(t, entities.JSON("{}"), sesJob.Payload)
+
+	auth.POST("/api/jobs/{id}/retry", sesJob.ID).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "The job can't be retried, please submit the request again.")
+
+	// the import maps the columns, merges the existing subscribers and rejects the invalid rows.
+	old := &entities.Subscriber{
+		UserID:   u.ID,
+		Name:     "Old",
+		Email:    "old@example.com",
+		MetaJSON: []byte(`{"city":"Paris","plan":"free"}`),
+		Active:   true,
+	}
+	assert.Nil(t, s.CreateSubscriber(old))
+
+	hook := &entities.Webhook{
+		UserID: u.ID,
+		URL:    "https://example.com/hook",
+		Secret: "secret",
+		Events: entities.StringList{entities.WebhookEventSubscriberCreated},
+		Active: true,
+	}
+	assert.Nil(t, s.CreateWebhook(hook))
+
+	auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "import.csv", Mapping: map[string]string{"E-mail": "name"}}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{"mapping": "the email field must be mapped"})
+
+	auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "import.csv", Mapping: map[string]string{"E-mail": "phone"}}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{"mapping": "unknown field 'phone' for column 'E-mail'"})
+
+	auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "import.csv", Mode: "replace"}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	csvFile := "E-mail,Full name,City,Ignored\n" +
+		"new@example.com,New,Berlin,x\n" +
+		"old@example.com,Old Updated,Rome,x\n" +
+		"not-an-email,Bad,Oslo,x\n" +
+		"new@example.com,Dup,Berlin,x\n" +
+		",Missing,Oslo,x\n" +
+		"short@example.com,Short\n"
+	importKey := fmt.Sprintf("subscribers/import/%d/import.csv", u.ID)
+	// the file is fetched by the action and by the job.
+	file(importKey, csvFile)
+	file(importKey, csvFile)
+
+	var rejects string
+	mockS3.On("PutObject", mock.MatchedBy(func(in *s3.PutObjectInput) bool {
+		return strings.HasPrefix(*in.Key, fmt.Sprintf("subscribers/import/%d/rejects/", u.ID))
+	})).Once().Run(func(args mock.Arguments) {
+		b, _ := io.ReadAll(args.Get(0).(*s3.PutObjectInput).Body)
+		rejects = string(b)
+	}).Return(&s3.PutObjectOutput{}, nil)
+
+	importID := int64(auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{
+			Filename: "import.csv",
+			Mapping: map[string]string{
+				"E-mail":    "email",
+				"Full name": "name",
+				"City":      "metadata.city",
+			},
+			Mode: entities.ImportModeMerge,
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	imported := auth.GET("/api/jobs/{id}", importID).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	imported.ValueEqual("status", entities.JobStatusDone).
+		ValueEqual("processed", 6).
+		ValueEqual("total", 6)
+	imported.Value("result").Object().
+		ValueEqual("created", 1).
+		ValueEqual("updated", 1).
+		ValueEqual("skipped", 0).
+		ValueEqual("rejected", 4).
+		ValueEqual("rejects_file", fmt.Sprintf("import_%d_rejects.csv", importID))
+
+	assert.Equal(t, "line,E-mail,Full name,City,Ignored,error\n"+
+		"4,not-an-email,Bad,Oslo,x,invalid email address\n"+
+		"5,new@example.com,Dup,Berlin,x,duplicate email in the file\n"+
+		"6,,Missing,Oslo,x,missing email\n"+
+		"7,short@example.com,Short,\"expected 4 columns, got 2\"\n", rejects)
+
+	created, err := s.GetSubscriberByEmail("new@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "New", created.Name)
+	assert.JSONEq(t, `{"city":"Berlin"}`, string(created.MetaJSON))
+
+	updated, err := s.GetSubscriberByEmail("old@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Old Updated", updated.Name)
+	assert.JSONEq(t, `{"city":"Rome","plan":"free"}`, string(updated.MetaJSON))
+
+	// only the created subscribers are dispatched to the webhooks.
+	assert.Len(t, webhookMsgs, 1)
+	delivery, err := s.GetWebhookDelivery(webhookMsgs[0].DeliveryID, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, hook.ID, delivery.WebhookID)
+	assert.Equal(t, entities.WebhookEventSubscriberCreated, delivery.EventType)
+	assert.Contains(t, string(delivery.Payload), `"email":"new@example.com"`)
+
+	// the existing subscribers are skipped by default.
+	skipFile := "email,name\nold@example.com,Skipped\n"
+	file(fmt.Sprintf("subscribers/import/%d/skip.csv", u.ID), skipFile)
+	file(fmt.Sprintf("subscribers/import/%d/skip.csv", u.ID), skipFile)
+	skipID := int64(auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "skip.csv"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	auth.GET("/api/jobs/{id}", skipID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("result").Object().
+		ValueEqual("created", 0).
+		ValueEqual("skipped", 1).
+		NotContainsKey("rejects_file")
+
+	updated, err = s.GetSubscriberByEmail("old@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Old Updated", updated.Name)
+
+	auth.GET("/api/jobs/{id}/rejects", skipID).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "The job has no rejected rows.")
+
+	// the emails of the existing subscribers are matched case insensitively.
+	casedFile := "email,name\nOLD@Example.com,Old Cased\n"
+	file(fmt.Sprintf("subscribers/import/%d/cased.csv", u.ID), casedFile)
+	file(fmt.Sprintf("subscribers/import/%d/cased.csv", u.ID), casedFile)
+	casedID := int64(auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "cased.csv", Mode: entities.ImportModeMerge}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	auth.GET("/api/jobs/{id}", casedID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.JobStatusDone).
+		Value("result").Object().
+		ValueEqual("created", 0).
+		ValueEqual("updated", 1)
+
+	updated, err = s.GetSubscriberByEmail("old@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Old Cased", updated.Name)
+
+	_, err = s.GetSubscriberByEmail("OLD@Example.com", u.ID)
+	assert.NotNil(t, err)
+
+	// the json lines are imported with the nested keys as metadata.
+	jsonlFile := `{"email":"json@example.com","name":"Json","metadata":{"city":"Lisbon"},"profile":{"age":30}}` + "\n" +
+		"not json\n" +
+		`{"email":"json2@example.com","tags":["a","b"]}` + "\n"
+	file(fmt.Sprintf("subscribers/import/%d/people.jsonl", u.ID), jsonlFile)
+	file(fmt.Sprintf("subscribers/import/%d/people.jsonl", u.ID), jsonlFile)
+	mockS3.On("PutObject", mock.MatchedBy(func(in *s3.PutObjectInput) bool {
+		return strings.HasPrefix(*in.Key, fmt.Sprintf("subscribers/import/%d/rejects/", u.ID))
+	})).Once().Return(&s3.PutObjectOutput{}, nil)
+
+	jsonlID := int64(auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "people.jsonl"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	jsonl := auth.GET("/api/jobs/{id}", jsonlID).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	jsonl.ValueEqual("total", 3)
+	jsonl.Value("result").Object().
+		ValueEqual("created", 2).
+		ValueEqual("rejected", 1)
+
+	created, err = s.GetSubscriberByEmail("json@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Json", created.Name)
+	assert.JSONEq(t, `{"city":"Lisbon","profile.age":"30"}`, string(created.MetaJSON))
+
+	created, err = s.GetSubscriberByEmail("json2@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"tags":"[\"a\",\"b\"]"}`, string(created.MetaJSON))
+
+	// the xlsx file is detected by the content type.
+	xlsx := xlsxFile(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Subscribers" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="worksheet" Target="worksheets/subscribers.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Email</t></si><si><t>Name</t></si>` +
+			`<si><r><t>xlsx@</t></r><r><t>example.com</t></r></si></sst>`,
+		"xl/worksheets/subscribers.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Plan</t></is></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" t="inlineStr"><is><t>Xlsx</t></is></c><c r="C2" t="inlineStr"><is><t>pro</t></is></c></row>` +
+			`<row r="3"></row>` +
+			`<row r="4"><c r="A4" t="inlineStr"><is><t>xlsx2@example.com</t></is></c><c r="C4"><v>42</v></c></row>` +
+			`</sheetData></worksheet>`,
+	})
+	for i := 0; i < 2; i++ {
+		mockS3.On("GetObject", &s3.GetObjectInput{
+			Bucket: aws.String("files-bucket"),
+			Key:    aws.String(fmt.Sprintf("subscribers/import/%d/export", u.ID)),
+		}).Once().Return(&s3.GetObjectOutput{
+			Body:        io.NopCloser(strings.NewReader(xlsx)),
+			ContentType: aws.String("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"),
+		}, nil)
+	}
+
+	xlsxID := int64(auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "export"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	auth.GET("/api/jobs/{id}", xlsxID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.JobStatusDone).
+		ValueEqual("total", 2).
+		Value("result").Object().
+		ValueEqual("created", 2).
+		ValueEqual("rejected", 0)
+
+	created, err = s.GetSubscriberByEmail("xlsx@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Xlsx", created.Name)
+	assert.JSONEq(t, `{"Plan":"pro"}`, string(created.MetaJSON))
+
+	created, err = s.GetSubscriberByEmail("xlsx2@example.com", u.ID)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"Plan":"42"}`, string(created.MetaJSON))
+
+	file(fmt.Sprintf("subscribers/import/%d/list.pdf", u.ID), "%PDF-1.4")
+	auth.POST("/api/subscribers/import").
+		WithJSON(params.ImportSubscribers{Filename: "list.pdf"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Unsupported file format, the supported formats are csv, tsv, jsonl and xlsx.")
+
+	// the email column of the bulk remove file can be anywhere in the header.
+	file(fmt.Sprintf("subscribers/remove/%d/remove.tsv", u.ID), "name\temail\nJson\tjson@example.com\n")
+	tsvID := int64(auth.POST("/api/subscribers/bulk-remove").
+		WithJSON(params.BulkRemoveSubscribers{Filename: "remove.tsv"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("job").Object().
+		Value("id").Number().Raw())
+
+	auth.GET("/api/jobs/{id}", tsvID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("status", entities.JobStatusDone).
+		ValueEqual("processed", 1)
+
+	_, err = s.GetSubscriberByEmail("json@example.com", u.ID)
+	assert.NotNil(t, err)
+
+	auth.GET("/api/jobs").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("collection").Array().Length().Equal(10)
+}
+
+// xlsxFile returns the contents of the xlsx file with the given parts.
+func xlsxFile(t *testing.T, parts map[string]string) string {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+
+	return buf.String()
+}
@@ -0,0 +1,92 @@
+package actions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+const (
+	metricsDateLayout = "2006-01-02"
+	// metricsDefaultDays is the number of days of the date range when from is not set.
+	metricsDefaultDays = 30
+)
+
+// GetSubscriberMetrics returns the subscriber growth, churn and net change bucketed by the interval,
+// along with the engagement of the campaigns sent in the date range. The to date is inclusive.
+func GetSubscriberMetrics(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := &params.SubscriberMetrics{}
+		if err := c.ShouldBindQuery(query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+			})
+			return
+		}
+
+		if err := validator.Validate(query); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		interval := query.Interval
+		if interval == "" {
+			interval = entities.StatsIntervalDay
+		}
+
+		to := time.Now().UTC()
+		if query.To != "" {
+			to, _ = time.Parse(metricsDateLayout, query.To)
+		}
+		to = entities.TruncateToInterval(to, entities.StatsIntervalDay).AddDate(0, 0, 1)
+
+		from := to.AddDate(0, 0, -metricsDefaultDays)
+		if query.From != "" {
+			from, _ = time.Parse(metricsDateLayout, query.From)
+		}
+
+		if !from.Before(to) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The from date must be before the to date.",
+			})
+			return
+		}
+
+		if entities.CountIntervals(from, to, interval, entities.MaxMetricsBuckets) > entities.MaxMetricsBuckets {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The date range is too large for the interval.",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		metrics, err := storage.GetSubscriberMetrics(u.ID, from, to)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get subscriber metrics: unable to fetch metrics")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "We are unable to process the request at the moment, please try again.",
+			})
+			return
+		}
+
+		campaigns, err := storage.GetCampaignsEngagement(u.ID, from, to)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get subscriber metrics: unable to fetch campaigns engagement")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "We are unable to process the request at the moment, please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, entities.NewSubscriberMetricsReport(from, to, interval, metrics, campaigns))
+	}
+}
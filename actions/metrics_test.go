@@ -0,0 +1,125 @@
+package actions_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestSubscriberMetrics(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e.GET("/api/metrics/subscribers").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	assert.Nil(t, s.CreateSubscriber(&entities.Subscriber{UserID: u.ID, Name: "Jane", Email: "jane@example.com", Active: true}))
+	assert.Nil(t, s.CreateSubscriber(&entities.Subscriber{UserID: u.ID, Name: "John", Email: "john@example.com", Active: true}))
+
+	res := auth.GET("/api/metrics/subscribers").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	res.ValueEqual("interval", "day").
+		ValueEqual("created", 2).
+		ValueEqual("unsubscribed", 0).
+		ValueEqual("net", 2)
+	res.Value("buckets").Array().Length().Equal(30)
+	res.Value("buckets").Array().Last().Object().ValueEqual("created", 2)
+	res.Value("engagement").Array().Empty()
+	res.Value("top_campaigns").Array().Empty()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	auth.GET("/api/metrics/subscribers").
+		WithQuery("from", today).
+		WithQuery("to", today).
+		WithQuery("interval", "hour").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("net", 2).
+		Value("buckets").Array().Length().Equal(24)
+
+	auth.GET("/api/metrics/subscribers").
+		WithQuery("from", "2021-13-01").
+		WithQuery("interval", "year").
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{
+			"from":     "Must be of format: 2006-01-02",
+			"interval": "Must be one of: hour day week month",
+		})
+
+	auth.GET("/api/metrics/subscribers").
+		WithQuery("from", "2021-05-02").
+		WithQuery("to", "2021-05-01").
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "The from date must be before the to date.")
+
+	auth.GET("/api/metrics/subscribers").
+		WithQuery("from", "2020-01-01").
+		WithQuery("to", "2021-01-01").
+		WithQuery("interval", "hour").
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "The date range is too large for the interval.")
+}
@@ -0,0 +1,280 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+func GetPartials(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		val, ok := c.Get("cursor")
+		if !ok {
+			logger.From(c).Error("get partials: unable to fetch pagination cursor from context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch partials. Please try again.",
+			})
+			return
+		}
+
+		p, ok := val.(*storage.PaginationCursor)
+		if !ok {
+			logger.From(c).Error("get partials: unable to cast pagination cursor from context value")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch partials. Please try again.",
+			})
+			return
+		}
+
+		err := store.GetPartials(middleware.GetUser(c).ID, p, c.QueryMap("scopes"))
+		if err != nil {
+			logger.From(c).WithError(err).Error("get partials: unable to fetch partials collection")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch partials. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+func GetPartial(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		p, err := storage.GetPartial(id, middleware.GetUser(c).ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Partial not found.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+func PostPartial(storage storage.Storage, templatesvc templates.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := &params.Partial{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		_, err := storage.GetPartialByName(body.Name, u.ID)
+		if err == nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Partial with that name already exists.",
+			})
+			return
+		}
+
+		p := &entities.Partial{
+			UserID:  u.ID,
+			Name:    body.Name,
+			Kind:    body.Kind,
+			Content: body.Content,
+		}
+		if !validatePartial(c, templatesvc, p) {
+			return
+		}
+
+		if err := storage.CreatePartial(p); err != nil {
+			logger.From(c).WithError(err).Error("post partial: unable to create partial")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to create partial.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, p)
+	}
+}
+
+// PutPartial updates the partial, the kind of the partial can't be changed since the
+// layouts are attached to the templates while the partials are included by name.
+func PutPartial(storage storage.Storage, templatesvc templates.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		p, err := storage.GetPartial(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Partial not found.",
+			})
+			return
+		}
+
+		body := &params.Partial{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		if body.Kind != p.Kind {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "The kind of the partial can't be changed.",
+			})
+			return
+		}
+
+		p2, err := storage.GetPartialByName(body.Name, u.ID)
+		if err == nil && p2.ID != p.ID {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Partial with that name already exists.",
+			})
+			return
+		}
+
+		p.Name = body.Name
+		p.Content = body.Content
+		if !validatePartial(c, templatesvc, p) {
+			return
+		}
+
+		if err := storage.UpdatePartial(p); err != nil {
+			logger.From(c).WithError(err).WithField("partial_id", id).Error("put partial: unable to update partial")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to update partial.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+// DeletePartial deletes the partial, the layouts can't be deleted while they wrap
+// any version of the templates and the partials while any version includes them.
+func DeletePartial(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		p, err := storage.GetPartial(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Partial not found.",
+			})
+			return
+		}
+
+		if p.Kind == entities.PartialKindLayout {
+			n, err := storage.CountLayoutUsages(p.ID, u.ID)
+			if err != nil {
+				logger.From(c).WithError(err).WithField("partial_id", id).Error("delete partial: unable to count layout usages")
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"message": "Unable to delete partial.",
+				})
+				return
+			}
+			if n > 0 {
+				c.JSON(http.StatusConflict, gin.H{
+					"message": "The layout is used by templates and can't be deleted.",
+				})
+				return
+			}
+		} else {
+			n, err := storage.CountPartialUsages(p.Name, u.ID)
+			if err != nil {
+				logger.From(c).WithError(err).WithField("partial_id", id).Error("delete partial: unable to count partial usages")
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"message": "Unable to delete partial.",
+				})
+				return
+			}
+			if n > 0 {
+				c.JSON(http.StatusConflict, gin.H{
+					"message": "The partial is used by templates and can't be deleted.",
+				})
+				return
+			}
+		}
+
+		err = storage.DeletePartial(id, u.ID)
+		if err != nil {
+			logger.From(c).WithError(err).WithField("partial_id", id).Error("delete partial: unable to delete partial")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to delete partial.",
+			})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// validatePartial responds with the reason when the content of the partial is not valid.
+func validatePartial(c *gin.Context, templatesvc templates.Service, p *entities.Partial) bool {
+	err := templatesvc.ValidatePartial(c, p)
+	if err == nil {
+		return true
+	}
+
+	switch {
+	case errors.Is(err, templates.ErrParsePartial),
+		errors.Is(err, templates.ErrPartialNotFound),
+		errors.Is(err, templates.ErrPartialCycle),
+		errors.Is(err, templates.ErrLayoutContent):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("Invalid partial, %s.", err),
+		})
+	default:
+		logger.From(c).WithError(err).Error("validate partial: unable to validate partial")
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message": "Unable to validate partial, please try again.",
+		})
+	}
+
+	return false
+}
@@ -0,0 +1,223 @@
+package actions_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestPartials(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	html := `<p>Hi {{name | default: "friend"}}, joined {{joined | date: "02.01.2006"}}</p>`
+	mockS3 := new(s3mock.MockS3Client)
+	mockS3.On("PutObject", mock.AnythingOfType("*s3.PutObjectInput")).Return(&s3.PutObjectOutput{}, nil).Once()
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(html)),
+	}, nil).Once()
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(html)),
+	}, nil).Once()
+
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	header := params.Partial{Name: "header", Kind: entities.PartialKindPartial, Content: `<h1>{{title | default: "News" | upper}}</h1>`}
+
+	e.POST("/api/partials").WithJSON(header).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.POST("/api/partials").WithJSON(params.Partial{Name: "header", Kind: "snippet", Content: "x"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ContainsKey("errors")
+
+	headerID := auth.POST("/api/partials").WithJSON(header).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		ValueEqual("name", "header").
+		Value("id").Number().Raw()
+
+	auth.POST("/api/partials").WithJSON(header).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("message", "Partial with that name already exists.")
+
+	// the partials which are included must exist and must not include themselves.
+	auth.POST("/api/partials").WithJSON(params.Partial{Name: "footer", Kind: entities.PartialKindPartial, Content: "{{> missing}}"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Invalid partial, partial not found: missing.")
+
+	auth.POST("/api/partials").WithJSON(params.Partial{Name: "footer", Kind: entities.PartialKindPartial, Content: "{{> footer}}"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Invalid partial, the partial includes itself: footer > footer.")
+
+	auth.POST("/api/partials").WithJSON(params.Partial{Name: "footer", Kind: entities.PartialKindPartial, Content: "<p>{{name | lower}}</p>"}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	footerID := auth.POST("/api/partials").WithJSON(params.Partial{Name: "footer", Kind: entities.PartialKindPartial, Content: "<footer>{{> header}}</footer>"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").Number().Raw()
+
+	auth.PUT("/api/partials/{id}", headerID).WithJSON(params.Partial{Name: "header", Kind: entities.PartialKindPartial, Content: "{{> footer}}"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Invalid partial, the partial includes itself: header > footer > header.")
+
+	auth.PUT("/api/partials/{id}", headerID).WithJSON(params.Partial{Name: "header", Kind: entities.PartialKindLayout, Content: "{{> content}}"}).
+		Expect().
+		Status(http.StatusUnprocessableEntity)
+
+	// the layouts must include the html part of the template.
+	auth.POST("/api/partials").WithJSON(params.Partial{Name: "main", Kind: entities.PartialKindLayout, Content: "<body>{{> header}}</body>"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Invalid partial, the layout must include the content with {{> content}}.")
+
+	layoutID := auth.POST("/api/partials").WithJSON(params.Partial{Name: "main", Kind: entities.PartialKindLayout, Content: "<body>{{> header}}{{> content}}</body>"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").Number().Raw()
+
+	auth.GET("/api/partials").WithQuery("scopes[kind]", entities.PartialKindLayout).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("collection").Array().Length().Equal(1)
+
+	auth.GET("/api/partials/{id}", headerID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("content", header.Content)
+
+	tmpl := params.PostTemplate{
+		Name:        "welcome",
+		HTMLPart:    html,
+		TextPart:    "Hi {{name | default: \"friend\"}}",
+		SubjectPart: "Welcome {{> header}}",
+		Layout:      "nope",
+	}
+	auth.POST("/api/templates").WithJSON(tmpl).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("message", "Layout with the name nope does not exists.")
+
+	tmpl.Layout = "main"
+	tmpl.SubjectPart = "Welcome {{> missing}}"
+	auth.POST("/api/templates").WithJSON(tmpl).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Unable to create template, partial not found: missing")
+
+	tmpl.SubjectPart = "Welcome"
+	templateID := auth.POST("/api/templates").WithJSON(tmpl).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		ValueEqual("layout_id", layoutID).
+		Value("id").Number().Raw()
+
+	// the html part is wrapped by the layout and the helpers are applied.
+	auth.POST("/api/templates/{id}/preview", templateID).WithJSON(params.PreviewTemplate{
+		Data: map[string]string{"joined": "2021-03-04"},
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("subject_part", "Welcome").
+		ValueEqual("text_part", "Hi friend").
+		ValueEqual("html_part", "<body><h1>NEWS</h1><p>Hi friend, joined 04.03.2021</p></body>")
+
+	// the version is rendered with the partials which it was saved with.
+	auth.PUT("/api/partials/{id}", headerID).WithJSON(params.Partial{Name: "header", Kind: entities.PartialKindPartial, Content: "<h1>Changed</h1>"}).
+		Expect().
+		Status(http.StatusOK)
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+	tpl, err := templatesvc.ParseTemplateVersion(context.Background(), int64(templateID), 1, u.ID)
+	assert.Nil(t, err)
+	rendered, err := tpl.HTMLPart.Render(tpl.Helpers.Data(map[string]string{"joined": "2021-03-04"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "<body><h1>NEWS</h1><p>Hi friend, joined 04.03.2021</p></body>", rendered)
+
+	auth.DELETE("/api/partials/{id}", layoutID).
+		Expect().
+		Status(http.StatusConflict)
+
+	// the header is included by the layout of the template.
+	auth.DELETE("/api/partials/{id}", headerID).
+		Expect().
+		Status(http.StatusConflict).JSON().Object().
+		ValueEqual("message", "The partial is used by templates and can't be deleted.")
+
+	auth.DELETE("/api/partials/{id}", footerID).
+		Expect().
+		Status(http.StatusNoContent)
+
+	auth.GET("/api/partials/{id}", footerID).
+		Expect().
+		Status(http.StatusNotFound)
+
+	mockS3.AssertExpectations(t)
+}
@@ -0,0 +1,281 @@
+package actions
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/services/webhooks"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+// GetPreferences renders the preference center of the subscriber, the page is
+// accessible with the signed token from the unsubscribe url of the emails.
+func GetPreferences(storage storage.Storage, unsubscribeSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Query("email")
+		uuid := c.Query("uuid")
+		token := c.Query("t")
+
+		data := gin.H{
+			"email":  email,
+			"uuid":   uuid,
+			"t":      token,
+			"failed": c.Query("failed"),
+			"saved":  c.Query("saved"),
+		}
+
+		logEntry := logger.From(c).WithFields(logrus.Fields{
+			"email": email,
+			"uuid":  uuid,
+		})
+
+		u, s, err := getSubscriberByToken(storage, unsubscribeSecret, email, uuid, token)
+		if err != nil {
+			logEntry.WithError(err).Warn("preferences: unable to verify subscriber")
+			data["invalid"] = true
+			c.HTML(http.StatusOK, "preferences.html", data)
+			return
+		}
+
+		segs, err := storage.GetPublicSegments(u.ID)
+		if err != nil {
+			logEntry.WithError(err).Error("preferences: unable to fetch public segments")
+			data["invalid"] = true
+			c.HTML(http.StatusOK, "preferences.html", data)
+			return
+		}
+
+		meta, err := s.GetMetadata()
+		if err != nil {
+			logEntry.WithError(err).Error("preferences: unable to unmarshal metadata")
+			data["invalid"] = true
+			c.HTML(http.StatusOK, "preferences.html", data)
+			return
+		}
+
+		topics := make([]entities.Topic, len(segs))
+		for i, seg := range segs {
+			topics[i] = entities.Topic{
+				ID:         seg.ID,
+				Name:       seg.Name,
+				Subscribed: inSegments(seg.ID, s.Segments),
+			}
+		}
+
+		data["name"] = s.Name
+		data["metadata"] = meta
+		data["topics"] = topics
+		data["active"] = s.Active
+		if s.IsPaused(time.Now()) {
+			data["paused_until"] = s.PausedUntil.Format("January 2, 2006")
+		}
+
+		c.HTML(http.StatusOK, "preferences.html", data)
+	}
+}
+
+// PostPreferences saves the changes from the preference center. Subscribers can opt in or out of the
+// public segments, update their name and metadata, pause the emails for a number of days or unsubscribe.
+func PostPreferences(storage storage.Storage, webhooksvc webhooks.Service, unsubscribeSecret, appURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := &params.PostPreferences{}
+
+		var err error
+		if c.ContentType() == binding.MIMEJSON {
+			err = c.ShouldBindJSON(body)
+		} else {
+			err = c.ShouldBind(body)
+			body.Metadata = c.PostFormMap("metadata")
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		query := url.Values{}
+		query.Add("email", body.Email)
+		query.Add("uuid", body.UUID)
+		query.Add("t", body.Token)
+		pageURL := appURL + "/unsubscribe.html?" + query.Encode()
+
+		logEntry := logger.From(c).WithFields(logrus.Fields{
+			"email": body.Email,
+			"uuid":  body.UUID,
+		})
+
+		u, s, err := getSubscriberByToken(storage, unsubscribeSecret, body.Email, body.UUID, body.Token)
+		if err != nil {
+			logEntry.WithError(err).Warn("preferences: unable to verify subscriber")
+			c.Redirect(http.StatusSeeOther, pageURL+"&failed=true")
+			return
+		}
+
+		if body.Unsubscribe {
+			if s.Active {
+				err = storage.DeactivateSubscriber(u.ID, s.Email)
+				if err != nil {
+					logEntry.WithError(err).Error("preferences: unable to deactivate subscriber")
+					c.Redirect(http.StatusSeeOther, pageURL+"&failed=true")
+					return
+				}
+
+				s.Active = false
+				dispatchWebhookEvent(c, webhooksvc, u.ID, entities.WebhookEventSubscriberUnsubscribed, s)
+			}
+
+			c.Redirect(http.StatusSeeOther, appURL+"/unsubscribe-success.html")
+			return
+		}
+
+		changes, err := setPreferences(storage, u.ID, s, body)
+		if err != nil {
+			logEntry.WithError(err).Error("preferences: unable to set preferences")
+			c.Redirect(http.StatusSeeOther, pageURL+"&failed=true")
+			return
+		}
+
+		err = storage.UpdateSubscriberPreferences(s, changes)
+		if err != nil {
+			logEntry.WithError(err).Error("preferences: unable to update preferences")
+			c.Redirect(http.StatusSeeOther, pageURL+"&failed=true")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, pageURL+"&saved=true")
+	}
+}
+
+// getSubscriberByToken fetches the subscriber along with the segments and
+// verifies the signed token from the unsubscribe url.
+func getSubscriberByToken(
+	storage storage.Storage,
+	unsubscribeSecret string,
+	email, uuid, token string,
+) (*entities.User, *entities.Subscriber, error) {
+	u, err := storage.GetUserByUUID(uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s, err := storage.GetSubscriberByEmail(email, u.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash, err := s.GenerateUnsubscribeToken(unsubscribeSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(token) != len(hash) || subtle.ConstantTimeCompare([]byte(token), []byte(hash)) != 1 {
+		return nil, nil, errors.New("hashes don't match")
+	}
+
+	s, err = storage.GetSubscriber(s.ID, u.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, s, nil
+}
+
+// setPreferences sets the name, metadata and pause of the request body to the
+// subscriber and returns the changes between the current and new preferences.
+// Only the existing metadata keys can be updated, an empty value removes the key.
+func setPreferences(
+	storage storage.Storage,
+	userID int64,
+	s *entities.Subscriber,
+	body *params.PostPreferences,
+) (*entities.PreferenceChanges, error) {
+	changes := &entities.PreferenceChanges{}
+
+	if body.Name != s.Name {
+		s.Name = body.Name
+		changes.Updated = true
+	}
+
+	meta, err := s.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range body.Metadata {
+		old, ok := meta[k]
+		if !ok || old == v {
+			continue
+		}
+		if v == "" {
+			delete(meta, k)
+		} else {
+			meta[k] = v
+		}
+		changes.Updated = true
+	}
+	s.MetaJSON, err = json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if body.PauseDays > 0 {
+		until := now.AddDate(0, 0, body.PauseDays)
+		s.PausedUntil = &until
+		changes.Paused = true
+	} else if body.Resume && s.IsPaused(now) {
+		s.PausedUntil = nil
+		changes.Resumed = true
+	}
+
+	segs, err := storage.GetPublicSegments(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range segs {
+		subscribed := inSegments(seg.ID, s.Segments)
+		wanted := containsID(seg.ID, body.SegmentIDs)
+		if wanted && !subscribed {
+			changes.OptIn = append(changes.OptIn, seg)
+		} else if !wanted && subscribed {
+			changes.OptOut = append(changes.OptOut, seg)
+		}
+	}
+
+	return changes, nil
+}
+
+func inSegments(id int64, segs []entities.Segment) bool {
+	for _, seg := range segs {
+		if seg.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(id int64, ids []int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
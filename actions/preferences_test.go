@@ -0,0 +1,256 @@
+package actions_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestPreferences(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+	u.UUID = uuid.NewString()
+	assert.Nil(t, s.UpdateUser(u))
+
+	auth.POST("/api/segments").WithJSON(params.Segment{
+		Name:   "vip",
+		Type:   entities.SegmentTypeDynamic,
+		Rules:  &entities.SegmentRule{Field: entities.RuleFieldName, Operator: entities.RuleOpEquals, Value: "vip"},
+		Public: true,
+	}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Only static segments can be public.")
+
+	news := auth.POST("/api/segments").WithJSON(params.Segment{Name: "news", Public: true}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	news.ValueEqual("public", true)
+	newsID := int64(news.Value("id").Number().Raw())
+
+	offersID := int64(auth.POST("/api/segments").WithJSON(params.Segment{Name: "offers", Public: true}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").Number().Raw())
+
+	internal := &entities.Segment{Name: "internal", UserID: u.ID, Type: entities.SegmentTypeStatic}
+	assert.Nil(t, s.CreateSegment(internal))
+
+	sub := &entities.Subscriber{
+		UserID:   u.ID,
+		Name:     "Jane",
+		Email:    "jane@example.com",
+		MetaJSON: []byte(`{"city":"Skopje","plan":"pro"}`),
+		Active:   true,
+		Segments: []entities.Segment{{Model: entities.Model{ID: newsID}}, *internal},
+	}
+	assert.Nil(t, s.CreateSubscriber(sub))
+
+	token, err := sub.GenerateUnsubscribeToken("secretexmplkeythatis32characters")
+	assert.Nil(t, err)
+
+	page := e.GET("/unsubscribe.html").
+		WithQuery("email", sub.Email).
+		WithQuery("uuid", u.UUID).
+		WithQuery("t", token).
+		Expect().
+		Status(http.StatusOK).
+		Body()
+	page.Contains("Email preferences").
+		Contains(`name="metadata[city]"`).
+		Contains("offers").
+		NotContains("internal")
+
+	e.GET("/unsubscribe.html").
+		WithQuery("email", sub.Email).
+		WithQuery("uuid", u.UUID).
+		WithQuery("t", "invalid").
+		Expect().
+		Status(http.StatusOK).
+		Body().Contains("The link is invalid.").NotContains("<form")
+
+	e.POST("/api/preferences").WithJSON(params.PostPreferences{Email: sub.Email, UUID: u.UUID, Token: token, PauseDays: 400}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ContainsKey("errors")
+
+	e.POST("/api/preferences").WithJSON(params.PostPreferences{Email: sub.Email, UUID: u.UUID, Token: "invalid"}).
+		Expect().
+		Status(http.StatusSeeOther).
+		Header("Location").Contains("failed=true")
+
+	// the html form opts out of news, opts in to offers, updates the metadata and pauses the emails
+	e.POST("/api/preferences").
+		WithFormField("email", sub.Email).
+		WithFormField("uuid", u.UUID).
+		WithFormField("t", token).
+		WithFormField("name", "Jane Doe").
+		WithFormField("metadata[city]", "Ohrid").
+		WithFormField("metadata[plan]", "").
+		WithFormField("metadata[admin]", "true").
+		WithFormField("segments", offersID).
+		WithFormField("pause_days", 7).
+		Expect().
+		Status(http.StatusSeeOther).
+		Header("Location").Contains("saved=true")
+
+	updated, err := s.GetSubscriber(sub.ID, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jane Doe", updated.Name)
+	meta, err := updated.GetMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"city": "Ohrid"}, meta)
+	assert.True(t, updated.IsPaused(time.Now()))
+	assert.True(t, updated.Active)
+
+	segIDs := make([]int64, len(updated.Segments))
+	for i, seg := range updated.Segments {
+		segIDs[i] = seg.ID
+	}
+	assert.ElementsMatch(t, []int64{internal.ID, offersID}, segIDs)
+
+	var events []string
+	err = db.Model(&entities.SubscriberEvent{}).
+		Where("subscriber_id = ? AND event_type <> ?", sub.ID, entities.SubscriberEventTypeCreated).
+		Pluck("event_type", &events).Error
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"opted_in", "opted_out", "updated", "paused"}, events)
+
+	var metrics entities.SubscriberMetrics
+	err = db.Where("user_id = ?", u.ID).First(&metrics).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), metrics.OptedIn)
+	assert.Equal(t, int64(1), metrics.OptedOut)
+	assert.Equal(t, int64(1), metrics.Updated)
+	assert.Equal(t, int64(1), metrics.Paused)
+
+	e.GET("/unsubscribe.html").
+		WithQuery("email", sub.Email).
+		WithQuery("uuid", u.UUID).
+		WithQuery("t", token).
+		Expect().
+		Status(http.StatusOK).
+		Body().Contains("Emails are paused until")
+
+	e.POST("/api/preferences").WithJSON(params.PostPreferences{
+		Email:      sub.Email,
+		UUID:       u.UUID,
+		Token:      token,
+		Name:       "Jane Doe",
+		SegmentIDs: []int64{offersID},
+		Resume:     true,
+	}).
+		Expect().
+		Status(http.StatusSeeOther)
+
+	updated, err = s.GetSubscriber(sub.ID, u.ID)
+	assert.Nil(t, err)
+	assert.False(t, updated.IsPaused(time.Now()))
+
+	err = db.Where("user_id = ?", u.ID).First(&metrics).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), metrics.Paused)
+	assert.Equal(t, int64(1), metrics.Resumed)
+
+	e.POST("/api/preferences").WithJSON(params.PostPreferences{Email: sub.Email, UUID: u.UUID, Token: token, Unsubscribe: true}).
+		Expect().
+		Status(http.StatusSeeOther).
+		Header("Location").Equal("http://example.com/unsubscribe-success.html")
+
+	updated, err = s.GetSubscriber(sub.ID, u.ID)
+	assert.Nil(t, err)
+	assert.False(t, updated.Active)
+
+	// RFC 8058 one-click unsubscribe
+	other := &entities.Subscriber{UserID: u.ID, Email: "bob@example.com", Active: true}
+	assert.Nil(t, s.CreateSubscriber(other))
+
+	oneClickURL, err := other.GetOneClickUnsubscribeURL(u.UUID, "secretexmplkeythatis32characters", "http://example.com")
+	assert.Nil(t, err)
+	oneClick, err := url.Parse(oneClickURL)
+	assert.Nil(t, err)
+
+	e.POST(oneClick.Path).WithQueryString(oneClick.RawQuery).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	e.POST("/api/unsubscribe/one-click").
+		WithQuery("email", other.Email).
+		WithQuery("uuid", u.UUID).
+		WithQuery("t", "invalid").
+		WithFormField("List-Unsubscribe", "One-Click").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	e.POST(oneClick.Path).WithQueryString(oneClick.RawQuery).
+		WithFormField("List-Unsubscribe", "One-Click").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("message", "You have been unsubscribed.")
+
+	updated, err = s.GetSubscriber(other.ID, u.ID)
+	assert.Nil(t, err)
+	assert.False(t, updated.Active)
+}
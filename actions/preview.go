@@ -0,0 +1,321 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/ksuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+// PostTemplatePreview renders the template with the given data, or with the metadata of
+// the given subscriber along with its unsubscribe url.
+func PostTemplatePreview(
+	templatesvc templates.Service,
+	storage storage.Storage,
+	unsubscribeSecret string,
+	appURL string,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		body := &params.PreviewTemplate{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		data, _, err := previewData(storage, u, body.SubscriberID, body.Data, unsubscribeSecret, appURL)
+		if err != nil {
+			handlePreviewDataError(c, err, "preview template: unable to get preview data")
+			return
+		}
+
+		tmpl, err := templatesvc.ParseTemplate(c, id, u.ID)
+		if err != nil {
+			handleParseTemplateError(c, err, "preview template: unable to parse template")
+			return
+		}
+
+		html, subject, text, err := renderTemplate(tmpl, data)
+		if err != nil {
+			logger.From(c).WithFields(logrus.Fields{
+				"user_id":     u.ID,
+				"template_id": id,
+			}).WithError(err).Error("preview template: unable to render template")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Failed to render the template.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, entities.TemplatePreview{
+			SubjectPart: subject,
+			HTMLPart:    html,
+			TextPart:    text,
+		})
+	}
+}
+
+// PostCampaignTestSend sends the rendered campaign template to the seed addresses through the
+// sender queue. The test emails are neither logged nor tracked, so the status and the stats of
+// the campaign are left intact.
+func PostCampaignTestSend(
+	storage storage.Storage,
+	templatesvc templates.Service,
+	publisher sqs.PublisherAPI,
+	queueURL sqs.SendEmailQueueURL,
+	unsubscribeSecret string,
+	appURL string,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		body := &params.TestSendCampaign{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		campaign, err := storage.GetCampaign(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign not found",
+			})
+			return
+		}
+
+		templateID := campaign.TemplateID
+		if body.VariantID != 0 {
+			var (
+				v  *entities.CampaignVariant
+				ok bool
+			)
+			if campaign.SplitTest != nil {
+				v, ok = campaign.SplitTest.Variant(body.VariantID)
+			}
+			if !ok {
+				c.JSON(http.StatusNotFound, gin.H{
+					"message": "Split test variant not found.",
+				})
+				return
+			}
+			templateID = v.TemplateID
+		}
+
+		logEntry := logger.From(c).WithFields(logrus.Fields{
+			"user_id":     u.ID,
+			"campaign_id": id,
+			"template_id": templateID,
+		})
+
+		data, unsubscribeURL, err := previewData(storage, u, body.SubscriberID, body.DefaultTemplateData, unsubscribeSecret, appURL)
+		if err != nil {
+			handlePreviewDataError(c, err, "test send: unable to get template data")
+			return
+		}
+
+		tmpl, err := templatesvc.ParseTemplate(c, templateID, u.ID)
+		if err != nil {
+			handleParseTemplateError(c, err, "test send: unable to parse template")
+			return
+		}
+
+		html, subject, text, err := renderTemplate(tmpl, data)
+		if err != nil {
+			logEntry.WithError(err).Error("test send: unable to render template")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Failed to render template. Unable to send the test email.",
+			})
+			return
+		}
+
+		// SES keys are required only when the user doesn't deliver through SMTP.
+		sesKeys := &entities.SesKeys{}
+		usesSMTP, err := storage.HasSMTPSettings(u.ID)
+		if err != nil {
+			logger.From(c).WithError(err).Error("Unable to fetch smtp settings.")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to send test emails, please try again.",
+			})
+			return
+		}
+		if !usesSMTP {
+			sesKeys, err = storage.GetSesKeys(u.ID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"message": "Amazon Ses keys are not set.",
+				})
+				return
+			}
+		}
+
+		for _, email := range body.Emails {
+			// every test email has its own event id, the sender skips the duplicate events.
+			msg, err := json.Marshal(entities.SenderTopicParams{
+				EventID:         ksuid.New(),
+				UserID:          u.ID,
+				UserUUID:        u.UUID,
+				CampaignID:      campaign.ID,
+				SubscriberEmail: email,
+				Source:          fmt.Sprintf("%s <%s>", body.FromName, body.Source),
+				HTMLPart:        []byte(html),
+				SubjectPart:     []byte(subject),
+				TextPart:        []byte(text),
+				SesKeys:         *sesKeys,
+				UnsubscribeURL:  unsubscribeURL,
+				TestSend:        true,
+			})
+			if err == nil {
+				err = publisher.SendMessage(c, queueURL, msg)
+			}
+			if err != nil {
+				logEntry.WithError(err).Error("test send: unable to publish message")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"message": "Unable to send the test email, please try again.",
+				})
+				return
+			}
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "The test email has been queued.",
+		})
+	}
+}
+
+// previewSubscriber is the sample subscriber whose unsubscribe url is rendered when no subscriber is given,
+// the IDs of the subscribers are positive so its signed url can't change the preferences of a subscriber.
+var previewSubscriber = entities.Subscriber{Model: entities.Model{ID: -1}, Email: "subscriber@example.com"}
+
+// previewData returns the data which the template is rendered with. The unsubscribe url is signed like
+// in the campaigns, for a sample subscriber when none is given. When a subscriber is given its
+// metadata takes precedence over the data, and its one-click unsubscribe url is returned.
+func previewData(
+	storage storage.Storage,
+	u *entities.User,
+	subscriberID int64,
+	data map[string]string,
+	unsubscribeSecret string,
+	appURL string,
+) (map[string]string, string, error) {
+	res := make(map[string]string, len(data)+2)
+	for k, v := range data {
+		res[k] = v
+	}
+
+	if subscriberID == 0 {
+		var err error
+		res[entities.TagUnsubscribeUrl], err = previewSubscriber.GetUnsubscribeURL(u.UUID, unsubscribeSecret, appURL)
+		if err != nil {
+			return nil, "", fmt.Errorf("get sample unsubscribe url: %w", err)
+		}
+		return res, "", nil
+	}
+
+	s, err := storage.GetSubscriber(subscriberID, u.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get subscriber: %w", err)
+	}
+
+	meta, err := s.GetMetadata()
+	if err != nil {
+		return nil, "", fmt.Errorf("get metadata: %w", err)
+	}
+	for k, v := range meta {
+		res[k] = v
+	}
+	if s.Name != "" {
+		res[entities.TagName] = s.Name
+	}
+
+	res[entities.TagUnsubscribeUrl], err = s.GetUnsubscribeURL(u.UUID, unsubscribeSecret, appURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("get unsubscribe url: %w", err)
+	}
+
+	oneClickURL, err := s.GetOneClickUnsubscribeURL(u.UUID, unsubscribeSecret, appURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("get one-click unsubscribe url: %w", err)
+	}
+
+	return res, oneClickURL, nil
+}
+
+// handlePreviewDataError responds to the errors of the preview data.
+func handlePreviewDataError(c *gin.Context, err error, msg string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Subscriber not found.",
+		})
+		return
+	}
+
+	logger.From(c).WithError(err).Error(msg)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"message": "Unable to get the subscriber data, please try again.",
+	})
+}
+
+// handleParseTemplateError responds to the errors of the template parsing.
+func handleParseTemplateError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Template not found.",
+		})
+	case errors.Is(err, templates.ErrHTMLPartNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "HTML part not found.",
+		})
+	default:
+		logger.From(c).WithError(err).Error(msg)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message": "Failed to parse the template.",
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package actions_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestPreview(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	for i := 0; i < 4; i++ {
+		mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Return(&s3.GetObjectOutput{
+			Body: ioutil.NopCloser(strings.NewReader(`<p>{{city}}</p><a href="{{unsubscribe_url}}">unsubscribe</a>`)),
+		}, nil).Once()
+	}
+
+	var published []entities.SenderTopicParams
+	mockPub := new(sqs.MockPublisher)
+	mockPub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		var p entities.SenderTopicParams
+		err := json.Unmarshal(args.Get(2).([]byte), &p)
+		assert.Nil(t, err)
+		published = append(published, p)
+	}).Return(nil).Twice()
+
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	tmpl := &entities.Template{BaseTemplate: entities.BaseTemplate{UserID: u.ID, Name: "news", SubjectPart: "Hi {{name}}"}, TextPart: "News from {{city}}"}
+	assert.Nil(t, s.CreateTemplate(tmpl))
+
+	sub := &entities.Subscriber{UserID: u.ID, Email: "jane@example.com", Name: "Jane", MetaJSON: []byte(`{"city":"Paris"}`), Active: true}
+	assert.Nil(t, s.CreateSubscriber(sub))
+
+	campaign := &entities.Campaign{UserID: u.ID, Name: "news", TemplateID: tmpl.ID, Status: entities.StatusDraft}
+	assert.Nil(t, s.CreateCampaign(campaign))
+
+	e.POST("/api/templates/{id}/preview", tmpl.ID).WithJSON(params.PreviewTemplate{}).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.POST("/api/templates/{id}/preview", tmpl.ID).WithJSON(params.PreviewTemplate{SubscriberID: 999}).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Subscriber not found.")
+
+	auth.POST("/api/templates/{id}/preview", 999).WithJSON(params.PreviewTemplate{}).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Template not found.")
+
+	// the template is rendered with the supplied data and a sample unsubscribe url.
+	auth.POST("/api/templates/{id}/preview", tmpl.ID).WithJSON(params.PreviewTemplate{
+		Data: map[string]string{"city": "Berlin"},
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("subject_part", "Hi ").
+		ValueEqual("text_part", "News from Berlin").
+		Path("$.html_part").String().
+		Contains("<p>Berlin</p>").
+		Contains(`<a href="http://example.com/unsubscribe.html?email=subscriber%40example.com&amp;t=`).
+		Contains("&amp;uuid=" + u.UUID + `">unsubscribe</a>`)
+
+	// the metadata of the subscriber takes precedence over the supplied data.
+	preview := auth.POST("/api/templates/{id}/preview", tmpl.ID).WithJSON(params.PreviewTemplate{
+		SubscriberID: sub.ID,
+		Data:         map[string]string{"city": "Berlin"},
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	preview.ValueEqual("subject_part", "Hi Jane").
+		ValueEqual("text_part", "News from Paris")
+	preview.Value("html_part").String().
+		Contains("<p>Paris</p>").
+		Contains("http://example.com/unsubscribe.html?").
+		Contains("email=jane%40example.com")
+
+	body := params.TestSendCampaign{
+		Emails:              []string{"seed1@example.com", "seed2@example.com"},
+		Source:              "news@example.com",
+		FromName:            "News",
+		DefaultTemplateData: map[string]string{"city": "Berlin"},
+	}
+
+	auth.POST("/api/campaigns/{id}/test-send", campaign.ID).WithJSON(params.TestSendCampaign{}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ContainsKey("errors")
+
+	auth.POST("/api/campaigns/{id}/test-send", 999).WithJSON(body).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Campaign not found")
+
+	variant := body
+	variant.VariantID = 1
+	auth.POST("/api/campaigns/{id}/test-send", campaign.ID).WithJSON(variant).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Split test variant not found.")
+
+	auth.POST("/api/campaigns/{id}/test-send", campaign.ID).WithJSON(body).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Amazon Ses keys are not set.")
+
+	assert.Nil(t, s.CreateSMTPSettings(&entities.SMTPSettings{
+		UserID:     u.ID,
+		Host:       "smtp.example.com",
+		Port:       587,
+		Encryption: "starttls",
+		AuthMethod: "login",
+	}))
+
+	auth.POST("/api/campaigns/{id}/test-send", campaign.ID).WithJSON(body).
+		Expect().
+		Status(http.StatusAccepted)
+
+	assert.Len(t, published, 2)
+	for i, p := range published {
+		assert.True(t, p.TestSend)
+		assert.Equal(t, campaign.ID, p.CampaignID)
+		assert.Equal(t, body.Emails[i], p.SubscriberEmail)
+		assert.Equal(t, "News <news@example.com>", p.Source)
+		assert.Equal(t, "News from Berlin", string(p.TextPart))
+	}
+	assert.NotEqual(t, published[0].EventID, published[1].EventID)
+
+	// the campaign status is left intact.
+	c, err := s.GetCampaign(campaign.ID, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.StatusDraft, c.Status)
+
+	mockPub.AssertExpectations(t)
+}
@@ -0,0 +1,177 @@
+package actions
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/jobs"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+// PostReport starts the export of the resource in the requested format, the campaign results
+// are exported for a single campaign while the other resources can be filtered by campaign.
+func PostReport(storage storage.Storage, reportsvc reports.Service, jobsvc jobs.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := &params.PostReport{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		report := &entities.Report{
+			Resource: body.Resource,
+			Format:   body.Format,
+			Gzip:     body.Gzip,
+			Note:     "Started the export process.",
+		}
+
+		if body.CampaignID != 0 {
+			if body.Resource == entities.SubscribersResource {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "The subscribers can't be exported by campaign.",
+				})
+				return
+			}
+
+			_, err := storage.GetCampaign(body.CampaignID, middleware.GetUser(c).ID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"message": "Campaign not found.",
+				})
+				return
+			}
+			report.CampaignID = &body.CampaignID
+		}
+
+		startExport(c, storage, reportsvc, jobsvc, report)
+	}
+}
+
+// GetReportDownload returns a temporary url of the report file.
+func GetReportDownload(storage storage.Storage, s3Client s3iface.S3API, bucket string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		downloadReport(c, storage, s3Client, bucket)
+	}
+}
+
+// startExport creates the report and starts a job which generates it.
+func startExport(c *gin.Context, storage storage.Storage, reportsvc reports.Service, jobsvc jobs.Service, report *entities.Report) {
+	u := middleware.GetUser(c)
+
+	fields := logrus.Fields{
+		"user_id":  u.ID,
+		"resource": report.Resource,
+		"format":   report.Format,
+		"note":     report.Note,
+	}
+
+	report, err := reportsvc.CreateExportReport(c, u.ID, report, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, reports.ErrAnotherReportRunning):
+			logger.From(c).WithFields(fields).WithError(err).Info("There is a report already running for this user")
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "There is a report already running.",
+			})
+		case errors.Is(err, reports.ErrLimitReached):
+			logger.From(c).WithFields(fields).WithError(err).Info("This user reached the daily limit")
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "You reached the daily limit, unable to generate report.",
+			})
+		default:
+			logger.From(c).WithFields(fields).WithError(err).Error("Unable to create export report service")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to create export report.",
+			})
+		}
+		return
+	}
+
+	job, err := jobsvc.Enqueue(c, u.ID, entities.JobTypeExportReport, entities.ExportReportJob{
+		ReportID: report.ID,
+	})
+	if err != nil {
+		logger.From(c).WithFields(fields).WithError(err).Error("Unable to enqueue export job")
+
+		// the report is failed so it doesn't block the next exports.
+		report.Status = entities.StatusFailed
+		if err := storage.UpdateReport(report); err != nil {
+			logger.From(c).WithFields(fields).WithError(err).Error("Unable to update report")
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Unable to create export report.",
+		})
+		return
+	}
+	report.JobID = job.ID
+
+	c.JSON(http.StatusOK, report)
+}
+
+// downloadReport responds with a temporary url of the report file from the filename query param.
+func downloadReport(c *gin.Context, storage storage.Storage, s3Client s3iface.S3API, bucket string) {
+	u := middleware.GetUser(c)
+
+	report, err := storage.GetReportByFilename(c.Query("filename"), u.ID)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message": "Report not found.",
+		})
+		return
+	}
+
+	switch report.Status {
+	case entities.StatusFailed:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message": "Failed to generate report, please try again.",
+			"status":  report.Status,
+		})
+	case entities.StatusInProgress:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message":       "Generating report, please try again later.",
+			"status":        report.Status,
+			"progress":      report.Progress(),
+			"rows_exported": report.RowsExported,
+			"total_rows":    report.TotalRows,
+		})
+	case entities.StatusDone:
+		req, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(report.Key()),
+		})
+
+		pUrl, err := req.Presign(15 * time.Minute)
+		if err != nil {
+			logger.From(c).WithError(err).Warn("Unable to sign s3 url.")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Unable to sign url.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"url": pUrl,
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package actions_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/jobs"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/subscribers"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestReports(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	var (
+		mu           sync.Mutex
+		uploaded     = make(map[string]string)
+		contentTypes = make(map[string]string)
+	)
+	mockS3 := new(s3mock.MockS3Client)
+	mockS3.On("PutObject", mock.AnythingOfType("*s3.PutObjectInput")).
+		Run(func(args mock.Arguments) {
+			in := args.Get(0).(*s3.PutObjectInput)
+			b, _ := io.ReadAll(in.Body)
+
+			mu.Lock()
+			uploaded[*in.Key] = string(b)
+			contentTypes[*in.Key] = *in.ContentType
+			mu.Unlock()
+		}).
+		Return(&s3.PutObjectOutput{}, nil)
+
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.New(mockS3, s), s)
+
+	var conf config.Config
+	conf.Storage.S3.FilesBucket = "files-bucket"
+	webhookQueueURL := "http://example.com/webhook-queue"
+	webhooksvc := webhooks.New(s, mockPub, &webhookQueueURL)
+	runner := jobs.NewRunner(s, jobs.NewHandlers(s, mockS3, subscribers.New(mockS3, s, webhooksvc), reportsvc, conf))
+	runJobs(t, s, mockPub, runner)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e.POST("/api/reports").WithJSON(params.PostReport{Resource: entities.BouncesResource}).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.POST("/api/reports").WithJSON(params.PostReport{Resource: "users", Format: "xml"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{
+			"resource": "Must be one of: subscribers campaign_results bounces complaints send_logs",
+			"format":   "Must be one of: csv json",
+		})
+
+	auth.POST("/api/reports").WithJSON(params.PostReport{Resource: entities.CampaignResultsResource}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{"campaign_id": "This field is required"})
+
+	auth.POST("/api/reports").WithJSON(params.PostReport{Resource: entities.CampaignResultsResource, CampaignID: 999}).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Campaign not found.")
+
+	auth.POST("/api/reports").WithJSON(params.PostReport{Resource: entities.SubscribersResource, CampaignID: 1}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	campaign := &entities.Campaign{Name: "news", UserID: u.ID, Status: entities.StatusSent}
+	assert.Nil(t, s.CreateCampaign(campaign))
+
+	sub := &entities.Subscriber{UserID: u.ID, Name: "Jane", Email: "jane@example.com", Active: true}
+	assert.Nil(t, s.CreateSubscriber(sub))
+	assert.Nil(t, s.CreateSendLog(&entities.SendLog{
+		ID:           ksuid.New(),
+		EventID:      ksuid.New(),
+		UserID:       u.ID,
+		CampaignID:   campaign.ID,
+		SubscriberID: sub.ID,
+		Status:       entities.SendLogStatusSuccessful,
+	}))
+	assert.Nil(t, s.CreateDelivery(&entities.Delivery{UserID: u.ID, CampaignID: campaign.ID, Recipient: sub.Email}))
+	assert.Nil(t, s.CreateClick(&entities.Click{UserID: u.ID, CampaignID: campaign.ID, Recipient: sub.Email, Link: "https://example.com"}))
+
+	report := auth.POST("/api/reports").WithJSON(params.PostReport{
+		Resource:   entities.CampaignResultsResource,
+		Format:     entities.ReportFormatJSON,
+		CampaignID: campaign.ID,
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	report.ValueEqual("resource", entities.CampaignResultsResource).
+		ValueEqual("format", entities.ReportFormatJSON).
+		ValueEqual("campaign_id", campaign.ID).
+		ValueEqual("status", entities.StatusInProgress)
+	report.Value("file_name").String().Match(`^campaign_results_\d+_\d+\.ndjson$`)
+
+	filename := report.Value("file_name").String().Raw()
+	assert.Eventually(t, func() bool {
+		r, err := s.GetReportByFilename(filename, u.ID)
+		return err == nil && r.Status == entities.StatusDone
+	}, 5*time.Second, 10*time.Millisecond)
+
+	done, err := s.GetReportByFilename(filename, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), done.RowsExported)
+	assert.Equal(t, int64(1), done.TotalRows)
+	assert.Equal(t, int64(100), done.Progress())
+
+	mu.Lock()
+	body := uploaded[fmt.Sprintf("campaign_results/export/%d/%s", u.ID, filename)]
+	mu.Unlock()
+	assert.Contains(t, body, `{"email":"jane@example.com","subscriber_id":`)
+	assert.Contains(t, body, `"delivered":true,"opens":0,"clicked_links":["https://example.com"],"bounce_type":"","complained":false`)
+
+	report = auth.POST("/api/reports").WithJSON(params.PostReport{
+		Resource: entities.SubscribersResource,
+		Gzip:     true,
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	report.ValueEqual("gzip", true)
+	report.Value("file_name").String().Match(`^subscribers_\d+\.csv\.gz$`)
+
+	filename = report.Value("file_name").String().Raw()
+	assert.Eventually(t, func() bool {
+		r, err := s.GetReportByFilename(filename, u.ID)
+		return err == nil && r.Status == entities.StatusDone
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	key := fmt.Sprintf("subscribers/export/%d/%s", u.ID, filename)
+	body = uploaded[key]
+	contentType := contentTypes[key]
+	mu.Unlock()
+	assert.Equal(t, "application/gzip", contentType)
+
+	gz, err := gzip.NewReader(strings.NewReader(body))
+	assert.Nil(t, err)
+	csv, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Contains(t, string(csv), "Jane,jane@example.com,")
+
+	// the reports which are not done can't be downloaded.
+	auth.GET("/api/reports/download").
+		WithQuery("filename", "missing.csv").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("message", "Report not found.")
+
+	assert.Nil(t, s.CreateReport(&entities.Report{
+		UserID:   u.ID,
+		Resource: entities.BouncesResource,
+		Format:   entities.ReportFormatCSV,
+		FileName: "bounces_1.csv",
+		Type:     "export",
+		Status:   entities.StatusFailed,
+	}))
+	auth.GET("/api/reports/download").
+		WithQuery("filename", "bounces_1.csv").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("message", "Failed to generate report, please try again.")
+
+	assert.Nil(t, s.CreateReport(&entities.Report{
+		UserID:       u.ID,
+		Resource:     entities.SendLogsResource,
+		Format:       entities.ReportFormatCSV,
+		FileName:     "send_logs_1.csv",
+		Type:         "export",
+		Status:       entities.StatusInProgress,
+		RowsExported: 250,
+		TotalRows:    1000,
+	}))
+	auth.GET("/api/reports/download").
+		WithQuery("filename", "send_logs_1.csv").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		ValueEqual("status", entities.StatusInProgress).
+		ValueEqual("progress", 25).
+		ValueEqual("rows_exported", 250).
+		ValueEqual("total_rows", 1000)
+}
@@ -1,10 +1,13 @@
 package actions
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"github.com/mailbadger/app/entities"
 	"github.com/mailbadger/app/entities/params"
@@ -112,6 +115,25 @@ func PostSegment(storage storage.Storage) gin.HandlerFunc {
 		l := &entities.Segment{
 			Name:   body.Name,
 			UserID: middleware.GetUser(c).ID,
+			Type:   entities.SegmentTypeStatic,
+			Public: body.Public,
+		}
+
+		if body.Type == entities.SegmentTypeDynamic {
+			if body.Public {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "Only static segments can be public.",
+				})
+				return
+			}
+
+			l.Type = entities.SegmentTypeDynamic
+			if err := setSegmentRules(l, body.Rules); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": err.Error(),
+				})
+				return
+			}
 		}
 
 		_, err := storage.GetSegmentByName(body.Name, middleware.GetUser(c).ID)
@@ -171,7 +193,37 @@ func PutSegment(storage storage.Storage) gin.HandlerFunc {
 			return
 		}
 
+		if body.Type != "" && body.Type != l.Type {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The type of the segment cannot be changed.",
+			})
+			return
+		}
+
+		if body.Rules != nil {
+			if !l.IsDynamic() {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "Rules can be set only on dynamic segments.",
+				})
+				return
+			}
+			if err := setSegmentRules(l, body.Rules); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": err.Error(),
+				})
+				return
+			}
+		}
+
+		if body.Public && l.IsDynamic() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Only static segments can be public.",
+			})
+			return
+		}
+
 		l.Name = body.Name
+		l.Public = body.Public
 
 		if err = storage.UpdateSegment(l); err != nil {
 			c.JSON(http.StatusUnprocessableEntity, gin.H{
@@ -225,6 +277,13 @@ func PutSegmentSubscribers(storage storage.Storage) gin.HandlerFunc {
 			return
 		}
 
+		if l.IsDynamic() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The subscribers of a dynamic segment are defined by its rules.",
+			})
+			return
+		}
+
 		body := &params.SegmentSubs{}
 		if err := c.ShouldBindJSON(body); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -293,6 +352,12 @@ func GetSegmentsubscribers(store storage.Storage) gin.HandlerFunc {
 		}
 
 		err = store.GetSubscribersBySegmentID(id, middleware.GetUser(c).ID, p)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Segment not found.",
+			})
+			return
+		}
 		if err != nil {
 			logger.From(c).WithError(err).Error("get group subs: unable to fetch subscribers for segment collection")
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -323,6 +388,13 @@ func DetachSegmentSubscribers(storage storage.Storage) gin.HandlerFunc {
 			return
 		}
 
+		if l.IsDynamic() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The subscribers of a dynamic segment are defined by its rules.",
+			})
+			return
+		}
+
 		body := &params.SegmentSubs{}
 		if err := c.ShouldBindJSON(body); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -386,6 +458,13 @@ func DetachSubscriber(storage storage.Storage) gin.HandlerFunc {
 			return
 		}
 
+		if l.IsDynamic() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The subscribers of a dynamic segment are defined by its rules.",
+			})
+			return
+		}
+
 		s, err := storage.GetSubscriber(subID, user.ID)
 		if err != nil {
 			logger.From(c).WithFields(logrus.Fields{"subscriber_id": subID, "segment_id": id}).WithError(err).
@@ -410,3 +489,14 @@ func DetachSubscriber(storage storage.Storage) gin.HandlerFunc {
 		c.Status(http.StatusNoContent)
 	}
 }
+
+// setSegmentRules validates the rule tree and sets it on the dynamic segment.
+func setSegmentRules(l *entities.Segment, rules *entities.SegmentRule) error {
+	if rules == nil {
+		return fmt.Errorf("%w: rules are required for dynamic segments", entities.ErrInvalidSegmentRule)
+	}
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	return l.SetRules(*rules)
+}
@@ -1,19 +1,22 @@
 package actions_test
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
 	"github.com/mailbadger/app/entities/params"
 	"github.com/mailbadger/app/opa"
 	"github.com/mailbadger/app/services/boundaries"
 	"github.com/mailbadger/app/services/exporters"
 	"github.com/mailbadger/app/services/reports"
-	"github.com/mailbadger/app/services/subscribers"
 	"github.com/mailbadger/app/services/templates"
 	"github.com/mailbadger/app/session"
 	"github.com/mailbadger/app/sqs"
@@ -42,7 +45,6 @@ func TestSegments(t *testing.T) {
 
 	templatesvc := templates.New(s, mockS3, "test_bucket")
 	boundarysvc := boundaries.New(s)
-	subscrsvc := subscribers.New(mockS3, s)
 	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
 
 	compiler, err := opa.NewCompiler()
@@ -59,7 +61,6 @@ func TestSegments(t *testing.T) {
 		mockSender,
 		templatesvc,
 		boundarysvc,
-		subscrsvc,
 		reportsvc,
 		compiler,
 		false, // enable signup
@@ -99,6 +100,94 @@ func TestSegments(t *testing.T) {
 		Expect().
 		Status(http.StatusOK)
 
+	// dynamic segments
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+	for i, plan := range []string{"pro", "free", "pro"} {
+		err = s.CreateSubscriber(&entities.Subscriber{
+			UserID:   u.ID,
+			Email:    fmt.Sprintf("dynamic%d@example.com", i),
+			MetaJSON: []byte(fmt.Sprintf(`{"plan":"%s"}`, plan)),
+			Active:   true,
+		})
+		assert.Nil(t, err)
+	}
+
+	auth.POST("/api/segments").WithJSON(params.Segment{Name: "pro", Type: entities.SegmentTypeDynamic}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("errors", map[string]string{"rules": "This field is required"})
+
+	auth.POST("/api/segments").WithJSON(params.Segment{
+		Name:  "pro",
+		Type:  entities.SegmentTypeDynamic,
+		Rules: &entities.SegmentRule{Field: "foo", Operator: entities.RuleOpEquals, Value: "bar"},
+	}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "invalid segment rule: unknown field 'foo'")
+
+	dynamicID := auth.POST("/api/segments").WithJSON(params.Segment{
+		Name: "pro",
+		Type: entities.SegmentTypeDynamic,
+		Rules: &entities.SegmentRule{
+			Match: entities.RuleMatchAll,
+			Rules: []entities.SegmentRule{
+				{Field: entities.RuleFieldMetadata, Key: "plan", Operator: entities.RuleOpEquals, Value: "pro"},
+				{Field: entities.RuleFieldActive, Operator: entities.RuleOpEquals, Value: "true"},
+			},
+		},
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		ValueEqual("type", entities.SegmentTypeDynamic).
+		Value("id").Number().Raw()
+	dynamicPath := "/api/segments/" + strconv.FormatFloat(dynamicID, 'f', 0, 64)
+
+	auth.GET(dynamicPath).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("subscribers_in_segment", 2).
+		ValueEqual("total_subscribers", 3)
+
+	auth.GET(dynamicPath+"/subscribers").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("total", 2)
+
+	auth.GET("/api/segments").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("collection").Array().First().Object().
+		ValueEqual("subscribers_in_segment", 2)
+
+	auth.PUT(dynamicPath+"/subscribers").WithJSON(params.SegmentSubs{Ids: []int64{1}}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "The subscribers of a dynamic segment are defined by its rules.")
+
+	auth.PUT(dynamicPath).WithJSON(params.Segment{Name: "pro", Type: entities.SegmentTypeStatic}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "The type of the segment cannot be changed.")
+
+	auth.PUT(dynamicPath).WithJSON(params.Segment{
+		Name:  "free",
+		Rules: &entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "plan", Operator: entities.RuleOpEquals, Value: "free"},
+	}).
+		Expect().
+		Status(http.StatusOK)
+
+	auth.GET(dynamicPath).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("name", "free").
+		ValueEqual("subscribers_in_segment", 1)
+
+	auth.GET("/api/segments/2223/subscribers").
+		Expect().
+		Status(http.StatusNotFound)
+
 	// delete segment by id
 	auth.DELETE("/api/segments/1").
 		Expect().
@@ -1,9 +1,7 @@
 package actions
 
 import (
-	"fmt"
 	"net/http"
-	"net/url"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ses"
@@ -16,6 +14,7 @@ import (
 	"github.com/mailbadger/app/events"
 	"github.com/mailbadger/app/logger"
 	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/jobs"
 	"github.com/mailbadger/app/storage"
 	"github.com/mailbadger/app/validator"
 )
@@ -38,7 +37,8 @@ func GetSESKeys(storage storage.Storage) gin.HandlerFunc {
 	}
 }
 
-func PostSESKeys(store storage.Storage, appURL string) gin.HandlerFunc {
+// PostSESKeys validates the SES keys and starts a job which creates the AWS resources for the keys.
+func PostSESKeys(store storage.Storage, jobsvc jobs.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		u := middleware.GetUser(c)
 
@@ -65,14 +65,7 @@ func PostSESKeys(store storage.Storage, appURL string) gin.HandlerFunc {
 			return
 		}
 
-		keys := &entities.SesKeys{
-			AccessKey: body.AccessKey,
-			SecretKey: body.SecretKey,
-			Region:    body.Region,
-			UserID:    u.ID,
-		}
-
-		sender, err := emails.NewSesSenderFromCreds(keys.AccessKey, keys.SecretKey, keys.Region)
+		_, err = emails.NewSesSenderFromCreds(body.AccessKey, body.SecretKey, body.Region)
 		if err != nil {
 			logger.From(c).WithError(err).Warn("Unable to create SES sender.")
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -81,7 +74,7 @@ func PostSESKeys(store storage.Storage, appURL string) gin.HandlerFunc {
 			return
 		}
 
-		snsClient, err := events.NewEventsClient(keys.AccessKey, keys.SecretKey, keys.Region)
+		_, err = events.NewEventsClient(body.AccessKey, body.SecretKey, body.Region)
 		if err != nil {
 			logger.From(c).WithError(err).Warn("Unable to create SNS client.")
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -90,121 +83,26 @@ func PostSESKeys(store storage.Storage, appURL string) gin.HandlerFunc {
 			return
 		}
 
-		//TODO: Move this to work queue to be more robust.
-		//createAWSResources is a slow process and could fail periodically.
-		go func(
-			c *gin.Context,
-			sender emails.Sender,
-			snsClient events.EventsClient,
-			store storage.Storage,
-			keys *entities.SesKeys,
-			uuid string,
-			appURL string,
-		) {
-			err := createAWSResources(sender, snsClient, uuid, appURL)
-			if err != nil {
-				logger.From(c).WithError(err).Warn("Unable to create AWS resources.")
-				return
-			}
-
-			err = store.CreateSesKeys(keys)
-			if err != nil {
-				logger.From(c).WithError(err).Error("Unable to create SES keys.")
-			}
-		}(c.Copy(), sender, snsClient, store, keys, u.UUID, appURL)
-
-		c.JSON(http.StatusOK, gin.H{
-			"message": "We are currently processing the request.",
-		})
-	}
-}
-
-func createAWSResources(
-	sender emails.Sender,
-	snsClient events.EventsClient,
-	uuid string,
-	appURL string,
-) error {
-	hookURLStr := fmt.Sprintf("%s/api/hooks/%s", appURL, uuid)
-	hookURL, err := url.Parse(hookURLStr)
-	if err != nil {
-		return fmt.Errorf("ses keys: unable to parse hook URL: %w", err)
-	}
-	snsRes, err := snsClient.CreateTopic(&sns.CreateTopicInput{
-		Name: aws.String(events.SNSTopicName),
-	})
-	if err != nil {
-		return fmt.Errorf("ses keys: unable to create SNS topic: %w", err)
-	}
-
-	topicArn := *snsRes.TopicArn
-
-	_, err = snsClient.Subscribe(&sns.SubscribeInput{
-		Protocol: aws.String(hookURL.Scheme),
-		Endpoint: aws.String(hookURLStr),
-		TopicArn: aws.String(topicArn),
-	})
-	if err != nil {
-		return fmt.Errorf("ses keys: unable to subscribe to topic: %w", err)
-	}
-
-	// Check if the configuration set is already created
-	cs, err := sender.DescribeConfigurationSet(&ses.DescribeConfigurationSetInput{
-		ConfigurationSetName: aws.String(emails.ConfigurationSetName),
-		ConfigurationSetAttributeNames: []*string{
-			aws.String("eventDestinations"),
-		},
-	})
-
-	if err != nil {
-		_, err = sender.CreateConfigurationSet(&ses.CreateConfigurationSetInput{
-			ConfigurationSet: &ses.ConfigurationSet{
-				Name: aws.String(emails.ConfigurationSetName),
-			},
+		// creating the AWS resources is a slow process and could fail periodically,
+		// the keys are stored by the job once the resources are created.
+		job, err := jobsvc.Enqueue(c, u.ID, entities.JobTypeSESKeys, entities.SESKeysJob{
+			AccessKey: body.AccessKey,
+			SecretKey: body.SecretKey,
+			Region:    body.Region,
 		})
 		if err != nil {
-			return fmt.Errorf("ses keys: unable to create configuration set: %w", err)
-		}
-	}
-
-	// Check if the event destination is set
-	eventFound := false
-	for _, e := range cs.EventDestinations {
-		if e.Name != nil && *e.Name == events.SNSTopicName {
-			eventFound = true
+			logger.From(c).WithError(err).Error("Unable to enqueue SES keys job.")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to process the request, please try again.",
+			})
+			return
 		}
-	}
-
-	if eventFound {
-		return nil
-	}
 
-	_, err = sender.CreateConfigurationSetEventDestination(&ses.CreateConfigurationSetEventDestinationInput{
-		ConfigurationSetName: aws.String(emails.ConfigurationSetName),
-		EventDestination: &ses.EventDestination{
-			Name:    aws.String(events.SNSTopicName),
-			Enabled: aws.Bool(true),
-			MatchingEventTypes: []*string{
-				aws.String("send"),
-				aws.String("open"),
-				aws.String("click"),
-				aws.String("bounce"),
-				aws.String("reject"),
-				aws.String("delivery"),
-				aws.String("complaint"),
-				aws.String("renderingFailure"),
-			},
-			SNSDestination: &ses.SNSDestination{
-				TopicARN: aws.String(topicArn),
-			},
-		},
-	})
-
-	if err != nil {
-		return fmt.Errorf("ses keys: unable to set event destination: %w", err)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "We are currently processing the request.",
+			"job":     job,
+		})
 	}
-
-	return nil
 }
 
 func DeleteSESKeys(storage storage.Storage) gin.HandlerFunc {
@@ -1,7 +1,9 @@
 package actions_test
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"net/http"
 	"testing"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/gavv/httpexpect/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/emails"
@@ -18,9 +21,10 @@ import (
 	"github.com/mailbadger/app/mode"
 	"github.com/mailbadger/app/routes"
 	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/jobs"
 	"github.com/mailbadger/app/services/reports"
-	"github.com/mailbadger/app/services/subscribers"
 	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
 	"github.com/mailbadger/app/session"
 	"github.com/mailbadger/app/sqs"
 	"github.com/mailbadger/app/storage"
@@ -40,7 +44,6 @@ func setup(
 	emailSender emails.Sender,
 	templatesvc templates.Service,
 	boundarysvc boundaries.Service,
-	subscrsvc subscribers.Service,
 	reportsvc reports.Service,
 	compiler *ast.Compiler,
 	enableSignup bool,
@@ -49,6 +52,10 @@ func setup(
 	mode.SetMode("test")
 
 	queueURL := "http://example.com/campaigns-queue"
+	sendEmailQueueURL := "http://example.com/send-email-queue"
+	webhookQueueURL := "http://example.com/webhook-queue"
+	eventsQueueURL := "http://example.com/events-queue"
+	jobsQueueURL := "http://example.com/jobs-queue"
 	api := routes.New(
 		sess,
 		s,
@@ -58,9 +65,12 @@ func setup(
 		emailSender,
 		templatesvc,
 		boundarysvc,
-		subscrsvc,
 		reportsvc,
+		webhooks.New(s, pub, &webhookQueueURL),
+		jobs.New(s, pub, &jobsQueueURL),
 		&queueURL,
+		&sendEmailQueueURL,
+		&eventsQueueURL,
 		"/var/www/app",       // app dir
 		"http://example.com", // app url
 		"files-bucket",
@@ -68,6 +78,7 @@ func setup(
 		verifyEmail,
 		"",                                 // recaptcha secret
 		"secretexmplkeythatis32characters", // unsubscribe token secret
+		"secretexmplkeythatis32characters", // encryption key
 		"test@example.com",                 // system email
 		config.Social{},
 	)
@@ -79,6 +90,10 @@ func setup(
 		Client: &http.Client{
 			Transport: httpexpect.NewBinder(handler),
 			Jar:       httpexpect.NewJar(),
+			// redirects are asserted in the tests instead of followed.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
 		},
 		Reporter: httpexpect.NewAssertReporter(t),
 		Printers: []httpexpect.Printer{
@@ -133,3 +148,23 @@ func createAuthenticatedExpect(e *httpexpect.Expect, s storage.Storage) (*httpex
 		req.WithHeader("X-CSRF-Token", token)
 	}), nil
 }
+
+// runJobs runs the published jobs synchronously with the given runner.
+func runJobs(t *testing.T, s storage.Storage, pub *sqs.MockPublisher, runner *jobs.Runner) {
+	pub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		msg := new(entities.JobTopicParams)
+		err := json.Unmarshal(args.Get(2).([]byte), msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		job, err := s.GetJob(msg.JobID, msg.UserID)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		_ = runner.Run(context.Background(), job)
+	}).Return(nil)
+}
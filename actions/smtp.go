@@ -0,0 +1,112 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/utils"
+	"github.com/mailbadger/app/validator"
+)
+
+func GetSMTPSettings(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := middleware.GetUser(c)
+
+		settings, err := storage.GetSMTPSettings(u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "SMTP settings not set.",
+			})
+			return
+		}
+
+		settings.Password = "" //do not return the password
+
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+func PostSMTPSettings(storage storage.Storage, encryptionKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := middleware.GetUser(c)
+
+		_, err := storage.GetSMTPSettings(u.ID)
+		if err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "SMTP settings are already set.",
+			})
+			return
+		}
+
+		body := &params.PostSMTPSettings{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			logger.From(c).WithError(err).Error("Unable to bind smtp settings params.")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			logger.From(c).WithError(err).Error("Invalid smtp settings params.")
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		// the password is needed in plain text to authenticate, so it's encrypted instead of hashed.
+		password, err := utils.Encrypt(body.Password, encryptionKey)
+		if err != nil {
+			logger.From(c).WithError(err).Error("Unable to encrypt the SMTP password.")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to create SMTP settings.",
+			})
+			return
+		}
+
+		settings := &entities.SMTPSettings{
+			UserID:     u.ID,
+			Host:       body.Host,
+			Port:       body.Port,
+			Username:   body.Username,
+			Password:   password,
+			Encryption: body.Encryption,
+			AuthMethod: body.AuthMethod,
+		}
+
+		err = storage.CreateSMTPSettings(settings)
+		if err != nil {
+			logger.From(c).WithError(err).Error("Unable to create SMTP settings.")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to create SMTP settings.",
+			})
+			return
+		}
+
+		settings.Password = ""
+
+		c.JSON(http.StatusCreated, settings)
+	}
+}
+
+func DeleteSMTPSettings(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := middleware.GetUser(c)
+
+		err := storage.DeleteSMTPSettings(u.ID)
+		if err != nil {
+			logger.From(c).WithError(err).Error("Unable to delete SMTP settings.")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Unable to delete SMTP settings.",
+			})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,218 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+func PutCampaignSplitTest(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		campaign, err := storage.GetCampaign(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign not found",
+			})
+			return
+		}
+
+		if campaign.Status != entities.StatusDraft && campaign.Status != entities.StatusScheduled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "The split test can be changed only for draft or scheduled campaigns.",
+			})
+			return
+		}
+
+		body := &params.PutCampaignSplitTest{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		st := &entities.CampaignSplitTest{
+			UserID:         u.ID,
+			CampaignID:     campaign.ID,
+			TestPercentage: body.TestPercentage,
+			WaitMinutes:    body.WaitMinutes,
+			WinnerCriteria: body.WinnerCriteria,
+			Status:         entities.SplitTestStatusDraft,
+		}
+
+		seen := make(map[string]bool, len(body.TemplateNames))
+		for i, name := range body.TemplateNames {
+			if seen[name] {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "Each variant must use a different template.",
+				})
+				return
+			}
+			seen[name] = true
+
+			template, err := storage.GetTemplateByName(name, u.ID)
+			if err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"message": fmt.Sprintf("Template with the name %s does not exists.", name),
+				})
+				return
+			}
+
+			st.Variants = append(st.Variants, entities.CampaignVariant{
+				UserID:     u.ID,
+				CampaignID: campaign.ID,
+				TemplateID: template.ID,
+				Name:       entities.VariantName(i),
+			})
+		}
+
+		err = storage.SaveCampaignSplitTest(st)
+		if err != nil {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Error("put split test: unable to save split test")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to save the split test, please try again.",
+			})
+			return
+		}
+
+		st, err = storage.GetCampaignSplitTest(campaign.ID, u.ID)
+		if err != nil {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Error("put split test: unable to fetch split test")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to save the split test, please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, st)
+	}
+}
+
+func DeleteCampaignSplitTest(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		campaign, err := storage.GetCampaign(id, u.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Campaign not found",
+			})
+			return
+		}
+
+		if campaign.Status != entities.StatusDraft && campaign.Status != entities.StatusScheduled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "The split test can be changed only for draft or scheduled campaigns.",
+			})
+			return
+		}
+
+		err = storage.DeleteCampaignSplitTest(campaign.ID, u.ID)
+		if err != nil {
+			logger.From(c).WithField("campaign_id", id).WithError(err).Error("delete split test: unable to delete split test")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to delete the split test, please try again.",
+			})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// validateVariantsData validates the default template data against the templates of
+// every split test variant, and pins the current versions of the templates to the variants.
+func validateVariantsData(c *gin.Context, storage storage.Storage, st *entities.CampaignSplitTest, data map[string]string) bool {
+	for i := range st.Variants {
+		v := &st.Variants[i]
+		template, err := storage.GetTemplate(v.TemplateID, st.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": fmt.Sprintf("Template of variant %s not found. Unable to send campaign.", v.Name),
+			})
+			return false
+		}
+
+		err = template.ValidateData(data)
+		if err != nil {
+			if errors.Is(err, entities.ErrMissingDefaultData) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": fmt.Sprintf("Incomplete default template data for variant %s. Unable to send the campaign.", v.Name),
+				})
+				return false
+			}
+			logger.From(c).WithFields(logrus.Fields{
+				"campaign_id": st.CampaignID,
+				"template_id": v.TemplateID,
+			}).WithError(err).Error("send campaign: unable to parse variant template")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": fmt.Sprintf("Failed to parse the template of variant %s. Unable to send the campaign.", v.Name),
+			})
+			return false
+		}
+
+		v.TemplateVersion = template.Version
+	}
+
+	return true
+}
+
+// getVariantsStats returns the stats of each split test variant of the campaign,
+// nil is returned when the campaign has no split test.
+func getVariantsStats(storage storage.Storage, campaignID, userID int64) ([]entities.VariantStats, error) {
+	st, err := storage.GetCampaignSplitTest(campaignID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	stats := make([]entities.VariantStats, 0, len(st.Variants))
+	for _, v := range st.Variants {
+		s, err := storage.GetCampaignVariantStats(campaignID, v.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		s.Name = v.Name
+		s.Winner = st.WinnerVariantID == v.ID
+		stats = append(stats, *s)
+	}
+
+	return stats, nil
+}
@@ -1,17 +1,13 @@
 package actions
 
 import (
-	"bytes"
-	"context"
 	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -24,10 +20,11 @@ import (
 	"github.com/mailbadger/app/logger"
 	"github.com/mailbadger/app/routes/middleware"
 	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/jobs"
 	"github.com/mailbadger/app/services/reports"
 	"github.com/mailbadger/app/services/subscribers"
+	"github.com/mailbadger/app/services/webhooks"
 	"github.com/mailbadger/app/storage"
-	"github.com/mailbadger/app/utils"
 	"github.com/mailbadger/app/validator"
 )
 
@@ -86,7 +83,7 @@ func GetSubscriber(storage storage.Storage) gin.HandlerFunc {
 	}
 }
 
-func PostSubscriber(boundarysvc boundaries.Service, storage storage.Storage) gin.HandlerFunc {
+func PostSubscriber(boundarysvc boundaries.Service, storage storage.Storage, webhooksvc webhooks.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var err error
 		body := &params.PostSubscriber{}
@@ -168,6 +165,8 @@ func PostSubscriber(boundarysvc boundaries.Service, storage storage.Storage) gin
 			return
 		}
 
+		dispatchWebhookEvent(c, webhooksvc, s.UserID, entities.WebhookEventSubscriberCreated, s)
+
 		c.JSON(http.StatusCreated, s)
 	}
 }
@@ -266,6 +265,7 @@ func DeleteSubscriber(storage storage.Storage) gin.HandlerFunc {
 
 func PostUnsubscribe(
 	storage storage.Storage,
+	webhooksvc webhooks.Service,
 	unsubscribeSecret string,
 	appURL string,
 ) gin.HandlerFunc {
@@ -357,12 +357,65 @@ func PostUnsubscribe(
 			return
 		}
 
+		if sub.Active {
+			sub.Active = false
+			dispatchWebhookEvent(c, webhooksvc, u.ID, entities.WebhookEventSubscriberUnsubscribed, sub)
+		}
+
 		c.Redirect(http.StatusTemporaryRedirect, appURL+"/unsubscribe-success.html")
 	}
 }
 
+// PostOneClickUnsubscribe unsubscribes the subscriber with a single POST request from the mailbox provider,
+// as described in RFC 8058. The email, uuid and signed token are the query parameters of the
+// List-Unsubscribe url and the body of the request must contain List-Unsubscribe=One-Click.
+func PostOneClickUnsubscribe(storage storage.Storage, webhooksvc webhooks.Service, unsubscribeSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.PostForm("List-Unsubscribe") != "One-Click" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		logEntry := logger.From(c).WithFields(logrus.Fields{
+			"email": c.Query("email"),
+			"uuid":  c.Query("uuid"),
+		})
+
+		u, s, err := getSubscriberByToken(storage, unsubscribeSecret, c.Query("email"), c.Query("uuid"), c.Query("t"))
+		if err != nil {
+			logEntry.WithError(err).Warn("one-click unsubscribe: unable to verify subscriber")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Unable to unsubscribe, the link is invalid.",
+			})
+			return
+		}
+
+		if s.Active {
+			err = storage.DeactivateSubscriber(u.ID, s.Email)
+			if err != nil {
+				logEntry.WithError(err).Error("one-click unsubscribe: unable to deactivate subscriber")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"message": "Unable to unsubscribe, please try again.",
+				})
+				return
+			}
+
+			s.Active = false
+			dispatchWebhookEvent(c, webhooksvc, u.ID, entities.WebhookEventSubscriberUnsubscribed, s)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "You have been unsubscribed.",
+		})
+	}
+}
+
+// ImportSubscribers checks the subscribers limit of the user against the uploaded file
+// and starts a job which imports the subscribers from the file.
 func ImportSubscribers(
-	subscrsvc subscribers.Service,
+	jobsvc jobs.Service,
 	boundarysvc boundaries.Service,
 	storage storage.Storage,
 	s3Client s3iface.S3API,
@@ -385,9 +438,19 @@ func ImportSubscribers(
 			return
 		}
 
-		var segs []entities.Segment
+		var merr *subscribers.MappingError
+		if err := subscribers.ValidateMapping(reqParams.Mapping); errors.As(err, &merr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+				"errors": map[string]string{
+					"mapping": merr.Reason,
+				},
+			})
+			return
+		}
+
 		if len(reqParams.SegmentIDs) > 0 {
-			segs, err = storage.GetSegmentsByIDs(u.ID, reqParams.SegmentIDs)
+			_, err = storage.GetSegmentsByIDs(u.ID, reqParams.SegmentIDs)
 			if err != nil {
 				c.JSON(http.StatusUnprocessableEntity, gin.H{
 					"message": "Invalid data",
@@ -434,50 +497,62 @@ func ImportSubscribers(
 			}
 		}()
 
-		// duplicate stream read.
-		var buf bytes.Buffer
-		tee := io.TeeReader(res.Body, &buf)
+		format, err := subscribers.DetectFormat(reqParams.Filename, aws.StringValue(res.ContentType))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Unsupported file format, the supported formats are csv, tsv, jsonl and xlsx.",
+			})
+			return
+		}
 
-		csvCount, err := utils.CountLines(tee)
+		rows, err := subscribers.CountRows(res.Body, format)
 		if err != nil {
-			logger.From(c).WithError(err).Error("import subscribers: unable to count lines")
+			if errors.Is(err, subscribers.ErrInvalidFormat) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "Unable to read the file, please check its format.",
+				})
+				return
+			}
+			logger.From(c).WithError(err).Error("import subscribers: unable to count rows")
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 				"message": "Unable to import subscribers. Please try again.",
 			})
 			return
 		}
 
-		if count+int64(csvCount) > u.Boundaries.SubscribersLimit {
+		limit := u.Boundaries.SubscribersLimit
+		if limit > 0 && count+rows > limit {
 			c.JSON(http.StatusForbidden, gin.H{
 				"message": "With this import you will exceed the limit of your subscribers, update your plan or contact the support team.",
 				"total":   count,
-				"count":   csvCount,
+				"count":   rows,
 			})
 			return
 		}
 
-		go func(
-			ctx context.Context,
-			svc subscribers.Service,
-			userID int64,
-			segs []entities.Segment,
-			r io.Reader,
-		) {
-			err := svc.ImportSubscribersFromFile(ctx, u.ID, segs, r)
-			if err != nil {
-				logger.From(ctx).WithFields(logrus.Fields{
-					"segments": segs,
-				}).WithError(err).Error("import subscribers: unable to import subscribers from file")
-			}
-		}(c.Copy(), subscrsvc, u.ID, segs, &buf)
+		job, err := jobsvc.Enqueue(c, u.ID, entities.JobTypeImportSubscribers, entities.ImportSubscribersJob{
+			Filename:   reqParams.Filename,
+			SegmentIDs: reqParams.SegmentIDs,
+			Mapping:    reqParams.Mapping,
+			Mode:       reqParams.Mode,
+		})
+		if err != nil {
+			logger.From(c).WithError(err).Error("import subscribers: unable to enqueue import job")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to import subscribers. Please try again.",
+			})
+			return
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message": "We will begin processing the file shortly. As we import the subscribers, you will see them in the dashboard.",
+			"job":     job,
 		})
 	}
 }
 
-func BulkRemoveSubscribers(svc subscribers.Service, s3Client s3iface.S3API, bucket string) gin.HandlerFunc {
+// BulkRemoveSubscribers starts a job which removes the subscribers listed in the uploaded file.
+func BulkRemoveSubscribers(jobsvc jobs.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		u := middleware.GetUser(c)
 
@@ -495,136 +570,36 @@ func BulkRemoveSubscribers(svc subscribers.Service, s3Client s3iface.S3API, buck
 			return
 		}
 
-		res, err := s3Client.GetObject(&s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(fmt.Sprintf("subscribers/remove/%d/%s", u.ID, body.Filename)),
+		job, err := jobsvc.Enqueue(c, u.ID, entities.JobTypeBulkRemoveSubscribers, entities.BulkRemoveSubscribersJob{
+			Filename: body.Filename,
 		})
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			logger.From(c).WithError(err).Error("delete subs: unable to enqueue bulk remove job")
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"message": "Unable to remove subscribers. Please try again.",
 			})
 			return
 		}
 
-		go func(ctx context.Context, svc subscribers.Service, filename string, userID int64, r io.ReadCloser) {
-			err := svc.RemoveSubscribersFromFile(ctx, filename, userID, r)
-			if err != nil {
-				logger.From(ctx).WithFields(logrus.Fields{
-					"filename": filename,
-				}).WithError(err).Error("delete subs: wnable to remove subscribers")
-			}
-		}(c, svc, body.Filename, u.ID, res.Body)
-
 		c.JSON(http.StatusOK, gin.H{
 			"message": "We will begin processing the file shortly.",
+			"job":     job,
 		})
 	}
 }
 
-func ExportSubscribers(reportsvc reports.Service, bucket string) gin.HandlerFunc {
+func ExportSubscribers(storage storage.Storage, reportsvc reports.Service, jobsvc jobs.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		const (
-			resource = "subscribers"
-			note     = "Started the export process."
-		)
-
-		u := middleware.GetUser(c)
-
-		report, err := reportsvc.CreateExportReport(c, u.ID, resource, note, time.Now())
-		if err != nil {
-			switch {
-			case errors.Is(err, reports.ErrAnotherReportRunning):
-				logger.From(c).WithFields(logrus.Fields{
-					"user_id":  u.ID,
-					"resource": resource,
-					"note":     note,
-				}).WithError(err).Info("There is a report already running for this user")
-				c.JSON(http.StatusForbidden, gin.H{
-					"message": "There is a report already running.",
-				})
-			case errors.Is(err, reports.ErrLimitReached):
-				logger.From(c).WithFields(logrus.Fields{
-					"user_id":  u.ID,
-					"resource": resource,
-					"note":     note,
-				}).WithError(err).Info("This user reached the daily limit")
-				c.JSON(http.StatusForbidden, gin.H{
-					"message": "You reached the daily limit, unable to generate report.",
-				})
-			default:
-				logger.From(c).WithFields(logrus.Fields{
-					"user_id":  u.ID,
-					"resource": resource,
-					"note":     note,
-				}).WithError(err).Error("Unable to create export report service")
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"message": "Unable to create export report.",
-				})
-			}
-			return
-		}
-
-		go func(c context.Context, report *entities.Report) {
-			report, err = reportsvc.GenerateExportReport(c, u.ID, report, bucket)
-			if err != nil {
-				logger.From(c).WithFields(logrus.Fields{
-					"report": report,
-				}).WithError(err).Error("Export failed")
-			}
-		}(c.Copy(), report)
-
-		c.JSON(http.StatusOK, report)
+		startExport(c, storage, reportsvc, jobsvc, &entities.Report{
+			Resource: entities.SubscribersResource,
+			Format:   entities.ReportFormatCSV,
+			Note:     "Started the export process.",
+		})
 	}
 }
 
 func DownloadSubscribersReport(storage storage.Storage, s3Client s3iface.S3API, bucket string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		u := middleware.GetUser(c)
-
-		fileName := c.Query("filename")
-
-		report, err := storage.GetReportByFilename(fileName, u.ID)
-		if err != nil {
-			c.JSON(http.StatusUnprocessableEntity, gin.H{
-				"message": "Report not found.",
-			})
-			return
-		}
-
-		if report.Status == entities.StatusFailed {
-			c.JSON(http.StatusUnprocessableEntity, gin.H{
-				"message": "Failed to generate report, please try again.",
-				"status":  report.Status,
-			})
-			return
-		}
-
-		if report.Status == entities.StatusInProgress {
-			c.JSON(http.StatusUnprocessableEntity, gin.H{
-				"message": "Generating report, please try again later.",
-				"status":  report.Status,
-			})
-			return
-		}
-
-		if report.Status == entities.StatusDone {
-			req, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{
-				Bucket: aws.String(bucket),
-				Key:    aws.String(fmt.Sprintf("subscribers/export/%d/%s", u.ID, fileName)),
-			})
-
-			pUrl, err := req.Presign(15 * time.Minute)
-			if err != nil {
-				logger.From(c).WithError(err).Warn("Unable to sign s3 url.")
-				c.JSON(http.StatusBadRequest, gin.H{
-					"message": "Unable to sign url.",
-				})
-				return
-			}
-
-			c.JSON(http.StatusOK, gin.H{
-				"url": pUrl,
-			})
-		}
+		downloadReport(c, storage, s3Client, bucket)
 	}
 }
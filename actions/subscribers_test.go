@@ -12,7 +12,6 @@ import (
 	"github.com/mailbadger/app/services/boundaries"
 	"github.com/mailbadger/app/services/exporters"
 	"github.com/mailbadger/app/services/reports"
-	"github.com/mailbadger/app/services/subscribers"
 	"github.com/mailbadger/app/services/templates"
 	"github.com/mailbadger/app/session"
 	"github.com/mailbadger/app/sqs"
@@ -41,7 +40,6 @@ func TestSubscribers(t *testing.T) {
 
 	templatesvc := templates.New(s, mockS3, "test_bucket")
 	boundarysvc := boundaries.New(s)
-	subscrsvc := subscribers.New(mockS3, s)
 	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
 
 	compiler, err := opa.NewCompiler()
@@ -58,7 +56,6 @@ func TestSubscribers(t *testing.T) {
 		mockSender,
 		templatesvc,
 		boundarysvc,
-		subscrsvc,
 		reportsvc,
 		compiler,
 		false, // enable signup
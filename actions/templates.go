@@ -2,6 +2,7 @@ package actions
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -128,9 +129,10 @@ func PostTemplate(
 				UserID:      u.ID,
 				Name:        body.Name,
 				SubjectPart: body.SubjectPart,
+				InlineCSS:   body.InlineCSS,
 			},
-			HTMLPart: body.HTMLPart,
-			TextPart: body.TextPart,
+			HTMLSource: body.HTMLPart,
+			TextSource: body.TextPart,
 		}
 
 		_, err := storage.GetTemplateByName(template.Name, u.ID)
@@ -141,9 +143,18 @@ func PostTemplate(
 			return
 		}
 
-		err = svc.AddTemplate(c, template)
+		if !setTemplateLayout(c, storage, template, body.Layout) {
+			return
+		}
+
+		err = svc.AddTemplate(c, template, body.Strict)
 		if err != nil {
 			switch {
+			case errors.Is(err, templates.ErrLintWarnings):
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message":  "Unable to create template, the template has lint warnings",
+					"warnings": template.Warnings,
+				})
 			case errors.Is(err, templates.ErrParseHTMLPart):
 				c.JSON(http.StatusBadRequest, gin.H{
 					"message": "Unable to create template, failed to parse html_part",
@@ -156,6 +167,13 @@ func PostTemplate(
 				c.JSON(http.StatusBadRequest, gin.H{
 					"message": "Unable to create template, failed to parse subject_part",
 				})
+			case errors.Is(err, templates.ErrLayoutNotFound),
+				errors.Is(err, templates.ErrPartialNotFound),
+				errors.Is(err, templates.ErrPartialCycle),
+				errors.Is(err, templates.ErrParsePartial):
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": fmt.Sprintf("Unable to create template, %s", err),
+				})
 			default:
 				logger.From(c).WithFields(logrus.Fields{
 					"template": template,
@@ -216,13 +234,26 @@ func PutTemplate(
 			return
 		}
 		template.Name = body.Name
-		template.HTMLPart = body.HTMLPart
-		template.TextPart = body.TextPart
+		template.HTMLSource = body.HTMLPart
+		template.TextSource = body.TextPart
 		template.SubjectPart = body.SubjectPart
+		template.InlineCSS = body.InlineCSS
+		if !setTemplateLayout(c, storage, template, body.Layout) {
+			return
+		}
 
-		err = svc.UpdateTemplate(c, template)
+		err = svc.UpdateTemplate(c, template, body.Strict)
 		if err != nil {
 			switch {
+			case errors.Is(err, templates.ErrLintWarnings):
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message":  "Unable to update template, the template has lint warnings",
+					"warnings": template.Warnings,
+				})
+			case errors.Is(err, templates.ErrVersionConflict):
+				c.JSON(http.StatusConflict, gin.H{
+					"message": "The template has been modified in the meantime, please reload it and try again.",
+				})
 			case errors.Is(err, templates.ErrParseHTMLPart):
 				c.JSON(http.StatusBadRequest, gin.H{
 					"message": "Unable to update template, failed to parse html_part",
@@ -235,6 +266,13 @@ func PutTemplate(
 				c.JSON(http.StatusBadRequest, gin.H{
 					"message": "Unable to update template, failed to parse subject_part",
 				})
+			case errors.Is(err, templates.ErrLayoutNotFound),
+				errors.Is(err, templates.ErrPartialNotFound),
+				errors.Is(err, templates.ErrPartialCycle),
+				errors.Is(err, templates.ErrParsePartial):
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": fmt.Sprintf("Unable to update template, %s", err),
+				})
 			default:
 				logger.From(c).WithFields(logrus.Fields{
 					"template": template,
@@ -251,6 +289,25 @@ func PutTemplate(
 	}
 }
 
+// setTemplateLayout sets the layout of the template by its name, the template has no layout when the name is empty.
+func setTemplateLayout(c *gin.Context, storage storage.Storage, template *entities.Template, name string) bool {
+	template.LayoutID = 0
+	if name == "" {
+		return true
+	}
+
+	layout, err := storage.GetPartialByName(name, template.UserID)
+	if err != nil || layout.Kind != entities.PartialKindLayout {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message": fmt.Sprintf("Layout with the name %s does not exists.", name),
+		})
+		return false
+	}
+	template.LayoutID = layout.ID
+
+	return true
+}
+
 func DeleteTemplate(svc templates.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -279,3 +336,209 @@ func DeleteTemplate(svc templates.Service) gin.HandlerFunc {
 		c.Status(http.StatusNoContent)
 	}
 }
+
+func GetTemplateVersions(svc templates.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		val, ok := c.Get("cursor")
+		if !ok {
+			logger.From(c).Error("get template versions: unable to fetch pagination cursor from context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch template versions. Please try again.",
+			})
+			return
+		}
+
+		p, ok := val.(*storage.PaginationCursor)
+		if !ok {
+			logger.From(c).Error("get template versions: unable to cast pagination cursor from context value")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch template versions. Please try again.",
+			})
+			return
+		}
+
+		err = svc.GetTemplateVersions(c, id, u.ID, p)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"message": "Template not found.",
+				})
+				return
+			}
+
+			logger.From(c).WithFields(logrus.Fields{
+				"user_id":     u.ID,
+				"template_id": id,
+			}).WithError(err).Error("get template versions: unable to list template versions")
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch template versions. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+func GetTemplateVersion(svc templates.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Version must be an integer",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		template, err := svc.GetTemplateVersion(c, id, version, u.ID)
+		if err != nil {
+			if !handleTemplateVersionError(c, err) {
+				logger.From(c).WithFields(logrus.Fields{
+					"user_id":     u.ID,
+					"template_id": id,
+					"version":     version,
+				}).WithError(err).Error("get template version: unable to get template version")
+
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"message": "Unable to get template version",
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, template)
+	}
+}
+
+func GetTemplateVersionsDiff(svc templates.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		query := &params.TemplateVersionsDiff{}
+		if err := c.ShouldBindQuery(query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again",
+			})
+			return
+		}
+
+		if err := validator.Validate(query); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		diff, err := svc.DiffTemplateVersions(c, id, *query.From, *query.To, u.ID)
+		if err != nil {
+			if !handleTemplateVersionError(c, err) {
+				logger.From(c).WithFields(logrus.Fields{
+					"user_id":     u.ID,
+					"template_id": id,
+					"from":        *query.From,
+					"to":          *query.To,
+				}).WithError(err).Error("diff template versions: unable to diff template versions")
+
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"message": "Unable to compare the template versions.",
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
+func PostTemplateVersionRestore(svc templates.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer",
+			})
+			return
+		}
+
+		version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Version must be an integer",
+			})
+			return
+		}
+
+		u := middleware.GetUser(c)
+
+		template, err := svc.RestoreTemplateVersion(c, id, version, u.ID)
+		if err != nil {
+			if !handleTemplateVersionError(c, err) {
+				logger.From(c).WithFields(logrus.Fields{
+					"user_id":     u.ID,
+					"template_id": id,
+					"version":     version,
+				}).WithError(err).Error("restore template version: unable to restore template version")
+
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"message": "Unable to restore the template version, please try again.",
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, template)
+	}
+}
+
+// handleTemplateVersionError responds to the known errors of the template versions,
+// false is returned when the error is unknown.
+func handleTemplateVersionError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Template version not found.",
+		})
+	case errors.Is(err, templates.ErrHTMLPartNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "HTML part not found.",
+		})
+	case errors.Is(err, templates.ErrHTMLPartInvalidState):
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "The state of the HTML part is invalid.",
+		})
+	case errors.Is(err, templates.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "The template has been modified in the meantime, please reload it and try again.",
+		})
+	default:
+		return false
+	}
+	return true
+}
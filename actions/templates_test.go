@@ -10,16 +10,17 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
 	"github.com/mailbadger/app/entities/params"
 	"github.com/mailbadger/app/opa"
 	"github.com/mailbadger/app/services/boundaries"
 	"github.com/mailbadger/app/services/exporters"
 	"github.com/mailbadger/app/services/reports"
-	"github.com/mailbadger/app/services/subscribers"
 	"github.com/mailbadger/app/services/templates"
 	"github.com/mailbadger/app/session"
 	"github.com/mailbadger/app/sqs"
@@ -44,21 +45,27 @@ func TestTemplates(t *testing.T) {
 	readCloser := ioutil.NopCloser(strings.NewReader("hello world"))
 
 	mockS3.On("PutObject", mock.AnythingOfType("*s3.PutObjectInput")).Once().Return(nil, errors.New("error"))
-	mockS3.On("PutObject", mock.AnythingOfType("*s3.PutObjectInput")).Twice().Return(&s3.PutObjectAclOutput{}, nil)
+	mockS3.On("PutObject", mock.AnythingOfType("*s3.PutObjectInput")).Times(3).Return(&s3.PutObjectAclOutput{}, nil)
 	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Once().Return(nil, awserr.New(s3.ErrCodeNoSuchKey, "no such ky", errors.New("key not found")))
 	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Once().Return(nil, awserr.New(s3.ErrCodeInvalidObjectState, "invalid object state", errors.New("invalid object state")))
 	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Once().Return(nil, errors.New("some error"))
 	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Once().Return(&s3.GetObjectOutput{
 		Body: readCloser,
 	}, nil)
-	mockS3.On("DeleteObject", mock.AnythingOfType("*s3.DeleteObjectInput")).Twice().Return(&s3.DeleteObjectOutput{}, nil)
+	// the html parts of the versions which are compared and restored
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Once().Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader("<span>test 2 template updated<span>")),
+	}, nil)
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Once().Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader("<span>test 2 template<span>")),
+	}, nil)
+	mockS3.On("DeleteObjects", mock.AnythingOfType("*s3.DeleteObjectsInput")).Once().Return(&s3.DeleteObjectsOutput{}, nil)
 
 	mockPub := new(sqs.MockPublisher)
 	mockSender := new(emails.MockSender)
 
 	templatesvc := templates.New(s, mockS3, "test_bucket")
 	boundarysvc := boundaries.New(s)
-	subscrsvc := subscribers.New(mockS3, s)
 	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
 
 	compiler, err := opa.NewCompiler()
@@ -75,7 +82,6 @@ func TestTemplates(t *testing.T) {
 		mockSender,
 		templatesvc,
 		boundarysvc,
-		subscrsvc,
 		reportsvc,
 		compiler,
 		false, // enable signup
@@ -110,7 +116,6 @@ func TestTemplates(t *testing.T) {
 			"html_part":    "This field is required",
 			"name":         "This field is required",
 			"subject_part": "This field is required",
-			"text_part":    "This field is required",
 		})
 
 	// TODO fix test for html validation
@@ -208,7 +213,6 @@ func TestTemplates(t *testing.T) {
 			"html_part":    "This field is required",
 			"name":         "This field is required",
 			"subject_part": "This field is required",
-			"text_part":    "This field is required",
 		})
 
 	// test put template for non existing template
@@ -372,6 +376,89 @@ func TestTemplates(t *testing.T) {
 	collection.Value("links").Object().ContainsKey("previous").ContainsKey("next")
 	collection.Value("collection").Array().Empty().Length().Equal(0)
 
+	// test list template versions, a version is created on every save
+	collection = auth.GET("/api/templates/"+idStr+"/versions").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ValueEqual("total", 2)
+
+	collection.Value("collection").Array().Length().Equal(2)
+	collection.Value("collection").Array().Element(0).Object().
+		ValueEqual("version", 2).
+		ValueEqual("name", "template 2 updated")
+
+	// test list versions of non existing template
+	auth.GET("/api/templates/94829342/versions").
+		Expect().
+		Status(http.StatusNotFound).
+		JSON().Object().
+		ValueEqual("message", "Template not found.")
+
+	// test diff template versions without params
+	auth.GET("/api/templates/"+idStr+"/versions/diff").
+		WithQuery("from", 1).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().
+		ValueEqual("errors", map[string]string{
+			"to": "This field is required",
+		})
+
+	// test diff template versions with non existing version
+	auth.GET("/api/templates/"+idStr+"/versions/diff").
+		WithQuery("from", 5).
+		WithQuery("to", 1).
+		Expect().
+		Status(http.StatusNotFound).
+		JSON().Object().
+		ValueEqual("message", "Template version not found.")
+
+	// test diff template versions, the first GetObject returns the html part of version 1
+	diff := auth.GET("/api/templates/"+idStr+"/versions/diff").
+		WithQuery("from", 1).
+		WithQuery("to", 2).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ValueEqual("from", 1).
+		ValueEqual("to", 2).
+		ValueEqual("subject_part", "")
+
+	diff.Value("name").String().Contains("-template 2\n").Contains("+template 2 updated\n")
+	diff.Value("html_part").String().Contains("-hello world").Contains("+<span>test 2 template updated<span>")
+	diff.Value("text_part").String().Contains("--- v1\n+++ v2\n")
+
+	// test restore template version with non integer version
+	auth.POST("/api/templates/"+idStr+"/versions/v1/restore").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().
+		ValueEqual("message", "Version must be an integer")
+
+	// test restore non existing template version
+	auth.POST("/api/templates/"+idStr+"/versions/5/restore").
+		Expect().
+		Status(http.StatusNotFound).
+		JSON().Object().
+		ValueEqual("message", "Template version not found.")
+
+	// test restore template version, the content is saved as a new version and the name is kept
+	auth.POST("/api/templates/"+idStr+"/versions/1/restore").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ValueEqual("version", 3).
+		ValueEqual("name", "template 2 updated").
+		ValueEqual("html_part", "<span>test 2 template<span>").
+		ValueEqual("text_part", "template {{.number}} 223")
+
+	auth.GET("/api/templates/"+idStr+"/versions").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ValueEqual("total", 3)
+
 	// test get template with id not integer
 	auth.DELETE("/api/templates/2.2").
 		Expect().
@@ -389,3 +476,225 @@ func TestTemplates(t *testing.T) {
 		Expect().
 		Status(http.StatusNoContent)
 }
+
+func TestTemplateSources(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	source := `<html><head><style>p { color: red } a:hover { color: blue }</style></head>` +
+		`<body><h1>News</h1><p style="margin: 0">Hi {{name}}, read <a href="https://example.com">more</a>.</p>` +
+		`<ul><li>One</li><li>Two</li></ul></body></html>`
+	inlined := `<html><head><style>
+a:hover { color: blue }
+</style></head><body><h1>News</h1><p style="color: red; margin: 0">Hi {{name}}, read <a href="https://example.com">more</a>.</p>` +
+		`<ul><li>One</li><li>Two</li></ul></body></html>`
+	text := "News\n====\n\nHi {{name}}, read more [1].\n\n- One\n- Two\n\nLinks:\n[1] https://example.com"
+
+	isSource := func(in *s3.GetObjectInput) bool { return strings.HasSuffix(*in.Key, "/source") }
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockS3.On("PutObject", mock.AnythingOfType("*s3.PutObjectInput")).Times(3).Return(&s3.PutObjectOutput{}, nil)
+	mockS3.On("GetObject", mock.MatchedBy(isSource)).Once().Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(source)),
+	}, nil)
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Once().Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(inlined)),
+	}, nil)
+
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test post template with inlined css and generated text part
+	obj := auth.POST("/api/templates").WithJSON(params.PostTemplate{
+		Name:        "newsletter",
+		HTMLPart:    source,
+		SubjectPart: "News",
+		InlineCSS:   true,
+	}).Expect().
+		Status(http.StatusCreated).
+		JSON().Object().
+		ValueEqual("html_part", inlined).
+		ValueEqual("html_source", source).
+		ValueEqual("text_part", text).
+		ValueEqual("text_source", "").
+		ValueEqual("inline_css", true)
+
+	idStr := strconv.FormatFloat(obj.Value("id").Number().Raw(), 'f', 0, 64)
+
+	// test get template with the html source
+	auth.GET("/api/templates/"+idStr).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ValueEqual("html_part", inlined).
+		ValueEqual("html_source", source).
+		ValueEqual("text_part", text)
+
+	// test put template with the css kept and the text part written by the author
+	auth.PUT("/api/templates/"+idStr).WithJSON(params.PutTemplate{
+		Name:        "newsletter",
+		HTMLPart:    source,
+		TextPart:    "Hi {{name}}",
+		SubjectPart: "News",
+	}).Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ValueEqual("html_part", source).
+		ValueEqual("html_source", source).
+		ValueEqual("text_part", "Hi {{name}}").
+		ValueEqual("text_source", "Hi {{name}}").
+		ValueEqual("inline_css", false)
+
+	mockS3.AssertExpectations(t)
+}
+
+func TestTemplateLint(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockS3.On("PutObject", mock.AnythingOfType("*s3.PutObjectInput")).Times(2).Return(&s3.PutObjectOutput{}, nil)
+
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+	assert.Nil(t, s.CreateSubscriber(&entities.Subscriber{
+		UserID:   u.ID,
+		Email:    "jane@example.com",
+		MetaJSON: []byte(`{"city":"Skopje"}`),
+		Active:   true,
+	}))
+
+	lint := params.PostTemplate{
+		Name:        "offer",
+		HTMLPart:    `<div><img src="logo.png"><a href="javascript:alert(1)">Offer</a><p>Hi {{cty}}</div></span>`,
+		TextPart:    "Hi",
+		SubjectPart: "BUY NOW!!!",
+	}
+	codes := []string{
+		entities.LintMissingUnsubscribe,
+		entities.LintMissingUnsubscribe,
+		entities.LintRelativeURL,
+		entities.LintMissingAlt,
+		entities.LintInvalidURL,
+		entities.LintUnbalancedTag,
+		entities.LintSpamSubject,
+		entities.LintSpamSubject,
+		entities.LintSpamSubject,
+		entities.LintUnknownTag,
+	}
+
+	// test post template with warnings in strict mode
+	lint.Strict = true
+	warnings := auth.POST("/api/templates").WithJSON(lint).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().
+		ValueEqual("message", "Unable to create template, the template has lint warnings").
+		Value("warnings").Array()
+
+	warnings.Length().Equal(len(codes))
+	for i, code := range codes {
+		warnings.Element(i).Object().ValueEqual("code", code)
+	}
+	warnings.Element(9).Object().
+		ValueEqual("part", "html_part").
+		ValueEqual("message", "the tag {{cty}} is not a metadata key of the subscribers")
+
+	// test post template with warnings
+	lint.Strict = false
+	auth.POST("/api/templates").WithJSON(lint).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object().
+		Value("warnings").Array().Length().Equal(len(codes))
+
+	// test post template without warnings in strict mode
+	auth.POST("/api/templates").WithJSON(params.PostTemplate{
+		Name:        "welcome",
+		HTMLPart:    `<p>Hi {{name}} from {{city}}</p><a href="{{unsubscribe_url}}">Unsubscribe</a>`,
+		SubjectPart: "Welcome {{name | default: \"friend\"}}",
+		Strict:      true,
+	}).Expect().
+		Status(http.StatusCreated).
+		JSON().Object().
+		NotContainsKey("warnings")
+
+	mockS3.AssertExpectations(t)
+}
@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/services/webhooks"
+	"github.com/mailbadger/app/storage"
+)
+
+// pixel is a transparent 1x1 gif image.
+var pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// GetTrackOpen records the open of the campaign email and responds with the tracking pixel,
+// the pixel is returned even when the open can't be recorded so the email is displayed properly.
+func GetTrackOpen(storage storage.Storage, webhooksvc webhooks.Service, secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+
+		p, _, err := entities.ParseTrackingParams(c.Request.URL.Query(), secret)
+		if err != nil {
+			logger.From(c).WithError(err).Warn("track open: invalid tracking params")
+			c.Data(http.StatusOK, "image/gif", pixel)
+			return
+		}
+
+		s, ok := getTrackedSubscriber(c, storage, p)
+		if ok {
+			o := &entities.Open{
+				UserID:     p.UserID,
+				CampaignID: p.CampaignID,
+				VariantID:  p.VariantID,
+				Recipient:  s.Email,
+				UserAgent:  c.Request.UserAgent(),
+				IPAddress:  c.ClientIP(),
+				CreatedAt:  time.Now().UTC(),
+			}
+
+			err = storage.CreateOpen(o)
+			if err != nil {
+				logger.From(c).WithError(err).WithFields(trackingFields(p)).Error("track open: unable to create open record")
+			} else {
+				dispatchWebhookEvent(c, webhooksvc, p.UserID, entities.WebhookEventEmailOpened, o)
+			}
+		}
+
+		c.Data(http.StatusOK, "image/gif", pixel)
+	}
+}
+
+// GetTrackClick records the click of the link in the campaign email and redirects to the link.
+func GetTrackClick(storage storage.Storage, webhooksvc webhooks.Service, secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, link, err := entities.ParseTrackingParams(c.Request.URL.Query(), secret)
+		if err != nil || link == "" {
+			logger.From(c).WithError(err).Warn("track click: invalid tracking params")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The link is invalid.",
+			})
+			return
+		}
+
+		s, ok := getTrackedSubscriber(c, storage, p)
+		if ok {
+			click := &entities.Click{
+				UserID:     p.UserID,
+				CampaignID: p.CampaignID,
+				VariantID:  p.VariantID,
+				Recipient:  s.Email,
+				Link:       link,
+				UserAgent:  c.Request.UserAgent(),
+				IPAddress:  c.ClientIP(),
+				CreatedAt:  time.Now().UTC(),
+			}
+
+			err = storage.CreateClick(click)
+			if err != nil {
+				logger.From(c).WithError(err).WithFields(trackingFields(p)).Error("track click: unable to create click record")
+			} else {
+				dispatchWebhookEvent(c, webhooksvc, p.UserID, entities.WebhookEventEmailClicked, click)
+			}
+		}
+
+		c.Redirect(http.StatusFound, link)
+	}
+}
+
+func getTrackedSubscriber(c *gin.Context, storage storage.Storage, p entities.TrackingParams) (*entities.Subscriber, bool) {
+	s, err := storage.GetSubscriber(p.SubscriberID, p.UserID)
+	if err != nil {
+		logger.From(c).WithError(err).WithFields(trackingFields(p)).Warn("tracking: unable to fetch subscriber")
+		return nil, false
+	}
+
+	return s, true
+}
+
+func trackingFields(p entities.TrackingParams) logrus.Fields {
+	return logrus.Fields{
+		"user_id":       p.UserID,
+		"campaign_id":   p.CampaignID,
+		"variant_id":    p.VariantID,
+		"subscriber_id": p.SubscriberID,
+	}
+}
@@ -0,0 +1,135 @@
+package actions_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestTracking(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockPub := new(sqs.MockPublisher)
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	_, err = createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	sub := &entities.Subscriber{
+		UserID: u.ID,
+		Name:   "Jane",
+		Email:  "jane@example.com",
+		Active: true,
+	}
+	assert.Nil(t, s.CreateSubscriber(sub))
+
+	secret := "secretexmplkeythatis32characters"
+	p := entities.TrackingParams{UserID: u.ID, CampaignID: 7, SubscriberID: sub.ID}
+
+	openURL, err := p.OpenURL(secret, "http://example.com")
+	assert.Nil(t, err)
+	open, err := url.Parse(openURL)
+	assert.Nil(t, err)
+
+	e.GET(open.Path).
+		WithQueryString(open.RawQuery).
+		WithHeader("User-Agent", "test-agent").
+		Expect().
+		Status(http.StatusOK).
+		ContentType("image/gif")
+
+	// the pixel is returned for the invalid urls without recording the open.
+	e.GET(open.Path).
+		WithQuery("u", u.ID).
+		WithQuery("c", 7).
+		WithQuery("s", sub.ID).
+		WithQuery("sig", "invalid").
+		Expect().
+		Status(http.StatusOK).
+		ContentType("image/gif")
+
+	opens, err := s.GetOpensStats(7, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), opens.Total)
+	assert.Equal(t, int64(1), opens.Unique)
+
+	clickURL, err := p.ClickURL(secret, "http://example.com", "https://example.com/offers?id=1")
+	assert.Nil(t, err)
+	click, err := url.Parse(clickURL)
+	assert.Nil(t, err)
+
+	e.GET(click.Path).
+		WithQueryString(click.RawQuery).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").Equal("https://example.com/offers?id=1")
+
+	// the signed link can't be replaced, so the endpoint is not an open redirect.
+	q := click.Query()
+	q.Set("url", "https://evil.example.com")
+	e.GET(click.Path).
+		WithQueryString(q.Encode()).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().
+		ValueEqual("message", "The link is invalid.")
+
+	clicks, err := s.GetClicksStats(7, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), clicks.TotalClicks)
+	assert.Equal(t, int64(1), clicks.UniqueClicks)
+}
@@ -0,0 +1,264 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/ksuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/validator"
+)
+
+func GetTransactionalMessages(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		val, ok := c.Get("cursor")
+		if !ok {
+			logger.From(c).Error("get transactional messages: unable to fetch pagination cursor from context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch transactional messages. Please try again.",
+			})
+			return
+		}
+
+		p, ok := val.(*storage.PaginationCursor)
+		if !ok {
+			logger.From(c).Error("get transactional messages: unable to cast pagination cursor from context value")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch transactional messages. Please try again.",
+			})
+			return
+		}
+
+		err := store.GetTransactionalMessages(middleware.GetUser(c).ID, p)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get transactional messages: unable to fetch transactional messages collection")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch transactional messages. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+func GetTransactionalMessage(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		m, err := storage.GetTransactionalMessage(id, middleware.GetUser(c).ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Transactional message not found.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, m)
+	}
+}
+
+// PostTransactionalSend sends a single email to the recipient, rendered from the
+// template and the per-message data. The email is delivered through the sender queue,
+// the returned message record is updated with the send result and the SES events.
+func PostTransactionalSend(
+	storage storage.Storage,
+	templatesvc templates.Service,
+	publisher sqs.PublisherAPI,
+	queueURL sqs.SendEmailQueueURL,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := middleware.GetUser(c)
+
+		body := &params.SendTransactional{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid parameters, please try again.",
+			})
+			return
+		}
+
+		if err := validator.Validate(body); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		var (
+			template *entities.Template
+			err      error
+		)
+		if body.TemplateID != 0 {
+			template, err = storage.GetTemplate(body.TemplateID, u.ID)
+		} else {
+			template, err = storage.GetTemplateByName(body.TemplateName, u.ID)
+		}
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Template not found.",
+			})
+			return
+		}
+
+		logEntry := logger.From(c).WithFields(logrus.Fields{
+			"user_id":     u.ID,
+			"template_id": template.ID,
+		})
+
+		data := make(map[string]string, len(body.Data)+1)
+		for k, v := range body.Data {
+			data[k] = v
+		}
+		if body.Name != "" {
+			data[entities.TagName] = body.Name
+		}
+
+		err = template.ValidateData(data)
+		if err != nil {
+			if errors.Is(err, entities.ErrMissingDefaultData) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "Incomplete template data. Unable to send the email.",
+				})
+				return
+			}
+			logEntry.WithError(err).Error("transactional send: unable to parse template")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Failed to parse template. Unable to send the email.",
+			})
+			return
+		}
+
+		// SES keys are required only when the user doesn't deliver through SMTP.
+		sesKeys := &entities.SesKeys{}
+		usesSMTP, err := storage.HasSMTPSettings(u.ID)
+		if err != nil {
+			logger.From(c).WithError(err).Error("Unable to fetch smtp settings.")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to send the email, please try again.",
+			})
+			return
+		}
+		if !usesSMTP {
+			sesKeys, err = storage.GetSesKeys(u.ID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"message": "Amazon Ses keys are not set.",
+				})
+				return
+			}
+		}
+
+		configSetExists := false
+		if !usesSMTP {
+			sender, err := emails.NewSesSenderFromCreds(sesKeys.AccessKey, sesKeys.SecretKey, sesKeys.Region)
+			if err != nil {
+				logEntry.WithError(err).Error("transactional send: unable to create SES client")
+				c.JSON(http.StatusBadRequest, gin.H{
+					"message": "SES keys are incorrect.",
+				})
+				return
+			}
+
+			_, err = sender.DescribeConfigurationSet(&ses.DescribeConfigurationSetInput{
+				ConfigurationSetName: aws.String(emails.ConfigurationSetName),
+			})
+			configSetExists = err == nil
+		}
+
+		tmpl, err := templatesvc.ParseTemplate(c, template.ID, u.ID)
+		if err != nil {
+			logEntry.WithError(err).Error("transactional send: unable to parse template")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Failed to parse template. Unable to send the email.",
+			})
+			return
+		}
+
+		html, subject, text, err := renderTemplate(tmpl, data)
+		if err != nil {
+			logEntry.WithError(err).Error("transactional send: unable to render template")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Failed to render template. Unable to send the email.",
+			})
+			return
+		}
+
+		m := &entities.TransactionalMessage{
+			UserID:     u.ID,
+			EventID:    ksuid.New(),
+			TemplateID: template.ID,
+			Recipient:  body.To,
+			Source:     body.Source,
+			Status:     entities.TransactionalStatusQueued,
+		}
+		if err := m.SetTags(body.Tags); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to send the email, invalid tags.",
+			})
+			return
+		}
+
+		if err := storage.CreateTransactionalMessage(m); err != nil {
+			logEntry.WithError(err).Error("transactional send: unable to create transactional message")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to send the email, please try again.",
+			})
+			return
+		}
+
+		msg, err := json.Marshal(entities.SenderTopicParams{
+			EventID:                m.EventID,
+			UserID:                 u.ID,
+			UserUUID:               u.UUID,
+			SubscriberEmail:        body.To,
+			Source:                 fmt.Sprintf("%s <%s>", body.FromName, body.Source),
+			ConfigurationSetExists: configSetExists,
+			HTMLPart:               []byte(html),
+			SubjectPart:            []byte(subject),
+			TextPart:               []byte(text),
+			SesKeys:                *sesKeys,
+			TransactionalID:        m.ID,
+			Tags:                   body.Tags,
+		})
+		if err == nil {
+			err = publisher.SendMessage(c, queueURL, msg)
+		}
+		if err != nil {
+			logEntry.WithError(err).Error("transactional send: unable to publish message")
+
+			m.Status = entities.TransactionalStatusFailed
+			m.Description = "Unable to queue the email."
+			uerr := storage.UpdateTransactionalMessageStatus(m.ID, u.ID, m.Status, m.Description, nil)
+			if uerr != nil {
+				logEntry.WithError(uerr).Error("transactional send: unable to update transactional message")
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to send the email, please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, m)
+	}
+}
@@ -0,0 +1,185 @@
+package actions_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestTransactional(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(`<p>Your code is {{code}}</p>`)),
+	}, nil).Once()
+	mockS3.On("GetObject", mock.AnythingOfType("*s3.GetObjectInput")).Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(`<p>Your code is {{code}}</p>`)),
+	}, nil).Once()
+
+	var published entities.SenderTopicParams
+	mockPub := new(sqs.MockPublisher)
+	mockPub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		err := json.Unmarshal(args.Get(2).([]byte), &published)
+		assert.Nil(t, err)
+	}).Return(nil).Once()
+	mockPub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("queue is down")).Once()
+
+	mockSender := new(emails.MockSender)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	tmpl := &entities.Template{BaseTemplate: entities.BaseTemplate{UserID: u.ID, Name: "otp", SubjectPart: "Hi {{name}}"}, TextPart: "Your code is {{code}}"}
+	assert.Nil(t, s.CreateTemplate(tmpl))
+
+	body := params.SendTransactional{
+		TemplateName: "otp",
+		To:           "jane@example.com",
+		Name:         "Jane",
+		Source:       "auth@example.com",
+		FromName:     "Auth",
+		Data:         map[string]string{"code": "123456"},
+		Tags:         map[string]string{"flow": "login"},
+	}
+
+	e.POST("/api/transactional/send").WithJSON(body).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.POST("/api/transactional/send").WithJSON(params.SendTransactional{
+		To:   "jane",
+		Tags: map[string]string{"campaign_id": "1"},
+	}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ContainsKey("errors")
+
+	missing := body
+	missing.TemplateName = "missing"
+	auth.POST("/api/transactional/send").WithJSON(missing).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Template not found.")
+
+	incomplete := body
+	incomplete.Data = nil
+	auth.POST("/api/transactional/send").WithJSON(incomplete).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "Incomplete template data. Unable to send the email.")
+
+	auth.POST("/api/transactional/send").WithJSON(body).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object().
+		ValueEqual("message", "Amazon Ses keys are not set.")
+
+	assert.Nil(t, s.CreateSMTPSettings(&entities.SMTPSettings{
+		UserID:     u.ID,
+		Host:       "smtp.example.com",
+		Port:       587,
+		Encryption: "starttls",
+		AuthMethod: "login",
+	}))
+
+	sent := auth.POST("/api/transactional/send").WithJSON(body).
+		Expect().
+		Status(http.StatusAccepted).JSON().Object()
+	sent.ValueEqual("status", entities.TransactionalStatusQueued).
+		ValueEqual("recipient", "jane@example.com").
+		ValueEqual("template_id", tmpl.ID).
+		ValueEqual("tags", map[string]string{"flow": "login"})
+	id := int64(sent.Value("id").Number().Raw())
+
+	assert.Equal(t, id, published.TransactionalID)
+	assert.Equal(t, "jane@example.com", published.SubscriberEmail)
+	assert.Equal(t, "Auth <auth@example.com>", published.Source)
+	assert.Equal(t, "Hi Jane", string(published.SubjectPart))
+	assert.Equal(t, "<p>Your code is 123456</p>", string(published.HTMLPart))
+	assert.Equal(t, map[string]string{"flow": "login"}, published.Tags)
+
+	// the message is marked as failed when it can't be queued.
+	byID := body
+	byID.TemplateName = ""
+	byID.TemplateID = tmpl.ID
+	auth.POST("/api/transactional/send").WithJSON(byID).
+		Expect().
+		Status(http.StatusInternalServerError)
+
+	auth.GET("/api/transactional").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("collection").Array().Length().Equal(2)
+
+	auth.GET("/api/transactional/{id}", id).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("id", id)
+
+	auth.GET("/api/transactional/{id}", 999).
+		Expect().
+		Status(http.StatusNotFound)
+
+	failed, err := s.GetTransactionalMessage(id+1, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionalStatusFailed, failed.Status)
+}
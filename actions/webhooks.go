@@ -0,0 +1,276 @@
+package actions
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/logger"
+	"github.com/mailbadger/app/routes/middleware"
+	"github.com/mailbadger/app/services/webhooks"
+	"github.com/mailbadger/app/storage"
+	"github.com/mailbadger/app/utils"
+	"github.com/mailbadger/app/validator"
+)
+
+func GetWebhooks(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		val, ok := c.Get("cursor")
+		if !ok {
+			logger.From(c).Error("get webhooks: unable to fetch pagination cursor from context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch webhooks. Please try again.",
+			})
+			return
+		}
+
+		p, ok := val.(*storage.PaginationCursor)
+		if !ok {
+			logger.From(c).Error("get webhooks: unable to cast pagination cursor from context value")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch webhooks. Please try again.",
+			})
+			return
+		}
+
+		err := store.GetWebhooks(middleware.GetUser(c).ID, p)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get webhooks: unable to fetch webhooks collection")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch webhooks. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+func GetWebhook(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w, ok := getWebhook(c, storage)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, w)
+	}
+}
+
+// PostWebhook registers a new webhook endpoint, the payloads sent to the endpoint
+// are signed with the generated secret of the webhook.
+func PostWebhook(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := &params.Webhook{}
+		if !bindWebhook(c, body) {
+			return
+		}
+
+		secret, err := utils.GenerateRandomString(32)
+		if err != nil {
+			logger.From(c).WithError(err).Error("post webhook: unable to generate secret")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to create webhook, please try again.",
+			})
+			return
+		}
+
+		w := &entities.Webhook{
+			UserID: middleware.GetUser(c).ID,
+			URL:    body.URL,
+			Secret: secret,
+			Events: body.Events,
+			Active: body.Active == nil || *body.Active,
+		}
+
+		if err := storage.CreateWebhook(w); err != nil {
+			logger.From(c).WithError(err).Error("post webhook: unable to create webhook")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to create webhook.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, w)
+	}
+}
+
+func PutWebhook(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w, ok := getWebhook(c, storage)
+		if !ok {
+			return
+		}
+
+		body := &params.Webhook{}
+		if !bindWebhook(c, body) {
+			return
+		}
+
+		w.URL = body.URL
+		w.Events = body.Events
+		if body.Active != nil {
+			w.Active = *body.Active
+		}
+
+		if err := storage.UpdateWebhook(w); err != nil {
+			logger.From(c).WithError(err).WithField("webhook_id", w.ID).Error("put webhook: unable to update webhook")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to update webhook.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, w)
+	}
+}
+
+func DeleteWebhook(storage storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w, ok := getWebhook(c, storage)
+		if !ok {
+			return
+		}
+
+		if err := storage.DeleteWebhook(w.ID, w.UserID); err != nil {
+			logger.From(c).WithError(err).WithField("webhook_id", w.ID).Error("delete webhook: unable to delete webhook")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"message": "Unable to delete webhook.",
+			})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetWebhookDeliveries returns the delivery log of the webhook.
+func GetWebhookDeliveries(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w, ok := getWebhook(c, store)
+		if !ok {
+			return
+		}
+
+		val, ok := c.Get("cursor")
+		if !ok {
+			logger.From(c).Error("get webhook deliveries: unable to fetch pagination cursor from context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch webhook deliveries. Please try again.",
+			})
+			return
+		}
+
+		p, ok := val.(*storage.PaginationCursor)
+		if !ok {
+			logger.From(c).Error("get webhook deliveries: unable to cast pagination cursor from context value")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch webhook deliveries. Please try again.",
+			})
+			return
+		}
+
+		err := store.GetWebhookDeliveries(w.ID, w.UserID, p)
+		if err != nil {
+			logger.From(c).WithError(err).Error("get webhook deliveries: unable to fetch webhook deliveries collection")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to fetch webhook deliveries. Please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+// PostWebhookRedeliver sends the event of the delivery to the webhook again.
+func PostWebhookRedeliver(storage storage.Storage, webhooksvc webhooks.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w, ok := getWebhook(c, storage)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Id must be an integer.",
+			})
+			return
+		}
+
+		d, err := storage.GetWebhookDelivery(id, w.UserID)
+		if err != nil || d.WebhookID != w.ID {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Webhook delivery not found.",
+			})
+			return
+		}
+
+		if !w.Active {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "The webhook is disabled.",
+			})
+			return
+		}
+
+		redelivery, err := webhooksvc.Redeliver(c, d)
+		if err != nil {
+			logger.From(c).WithError(err).WithField("delivery_id", d.ID).Error("redeliver webhook: unable to redeliver event")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Unable to redeliver the event, please try again.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, redelivery)
+	}
+}
+
+func getWebhook(c *gin.Context, storage storage.Storage) (*entities.Webhook, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Id must be an integer.",
+		})
+		return nil, false
+	}
+
+	w, err := storage.GetWebhook(id, middleware.GetUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Webhook not found.",
+		})
+		return nil, false
+	}
+
+	return w, true
+}
+
+func bindWebhook(c *gin.Context, body *params.Webhook) bool {
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid parameters, please try again.",
+		})
+		return false
+	}
+
+	if err := validator.Validate(body); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return false
+	}
+
+	return true
+}
+
+// dispatchWebhookEvent sends the event to the user's webhooks, the request
+// doesn't fail when the event can't be dispatched.
+func dispatchWebhookEvent(c *gin.Context, webhooksvc webhooks.Service, userID int64, eventType string, data interface{}) {
+	err := webhooksvc.Dispatch(c, userID, eventType, data)
+	if err != nil {
+		logger.From(c).WithError(err).WithField("event_type", eventType).Error("unable to dispatch webhook event")
+	}
+}
@@ -0,0 +1,203 @@
+package actions_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/entities/params"
+	"github.com/mailbadger/app/opa"
+	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/session"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+	s3mock "github.com/mailbadger/app/storage/s3"
+)
+
+func TestWebhooks(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+	sess := session.New(s, "foo", "secretexmplkeythatis32characters", true)
+
+	mockS3 := new(s3mock.MockS3Client)
+	mockSender := new(emails.MockSender)
+
+	var published []entities.WebhookTopicParams
+	mockPub := new(sqs.MockPublisher)
+	mockPub.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		var p entities.WebhookTopicParams
+		err := json.Unmarshal(args.Get(2).([]byte), &p)
+		assert.Nil(t, err)
+		published = append(published, p)
+	}).Return(nil)
+
+	templatesvc := templates.New(s, mockS3, "test_bucket")
+	boundarysvc := boundaries.New(s)
+	reportsvc := reports.New(exporters.NewSubscribersExporter(mockS3, s), s)
+
+	compiler, err := opa.NewCompiler()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	e := setup(
+		t, s,
+		sess,
+		mockS3,
+		mockPub,
+		mockSender,
+		templatesvc,
+		boundarysvc,
+		reportsvc,
+		compiler,
+		false, // enable signup
+		false, // verify email
+	)
+	auth, err := createAuthenticatedExpect(e, s)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	u, err := s.GetUserByUsername("john")
+	assert.Nil(t, err)
+
+	e.GET("/api/webhooks").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	auth.POST("/api/webhooks").WithJSON(params.Webhook{URL: "ftp://example.com", Events: []string{"subscriber.created"}}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	auth.POST("/api/webhooks").WithJSON(params.Webhook{URL: "https://example.com/hooks", Events: []string{"foo.bar"}}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	auth.POST("/api/webhooks").WithJSON(params.Webhook{URL: "https://example.com/hooks"}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	obj := auth.POST("/api/webhooks").
+		WithJSON(params.Webhook{
+			URL:    "https://example.com/hooks",
+			Events: []string{entities.WebhookEventSubscriberCreated},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	obj.ValueEqual("url", "https://example.com/hooks")
+	obj.ValueEqual("active", true)
+	obj.Value("secret").String().NotEmpty()
+	obj.Value("events").Array().Equal([]string{entities.WebhookEventSubscriberCreated})
+
+	id := int64(obj.Value("id").Number().Raw())
+
+	auth.GET("/api/webhooks").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("collection").Array().Length().Equal(1)
+
+	auth.GET("/api/webhooks/foo").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	auth.GET("/api/webhooks/999").
+		Expect().
+		Status(http.StatusNotFound)
+
+	auth.GET(fmt.Sprintf("/api/webhooks/%d", id)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("url", "https://example.com/hooks")
+
+	// creating a subscriber dispatches the event to the webhook.
+	auth.POST("/api/subscribers").
+		WithJSON(params.PostSubscriber{Name: "Jane", Email: "jane@example.com"}).
+		Expect().
+		Status(http.StatusCreated)
+
+	assert.Len(t, published, 1)
+	assert.Equal(t, u.ID, published[0].UserID)
+
+	d, err := s.GetWebhookDelivery(published[0].DeliveryID, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, id, d.WebhookID)
+	assert.Equal(t, entities.WebhookEventSubscriberCreated, d.EventType)
+	assert.Equal(t, entities.WebhookDeliveryStatusPending, d.Status)
+
+	var event entities.WebhookEvent
+	err = json.Unmarshal(d.Payload, &event)
+	assert.Nil(t, err)
+	assert.Equal(t, d.EventID, event.ID)
+	assert.Equal(t, "jane@example.com", event.Data.(map[string]interface{})["email"])
+
+	auth.GET(fmt.Sprintf("/api/webhooks/%d/deliveries", id)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("collection").Array().Length().Equal(1)
+
+	auth.POST(fmt.Sprintf("/api/webhooks/%d/deliveries/999/redeliver", id)).
+		Expect().
+		Status(http.StatusNotFound)
+
+	auth.POST(fmt.Sprintf("/api/webhooks/%d/deliveries/%d/redeliver", id, d.ID)).
+		Expect().
+		Status(http.StatusAccepted).JSON().Object().
+		ValueEqual("event_id", d.EventID.String()).
+		ValueEqual("status", entities.WebhookDeliveryStatusPending)
+
+	assert.Len(t, published, 2)
+	assert.NotEqual(t, d.ID, published[1].DeliveryID)
+
+	// the webhook isn't subscribed to the unsubscribed event.
+	active := false
+	auth.PUT(fmt.Sprintf("/api/webhooks/%d", id)).
+		WithJSON(params.Webhook{
+			URL:    "https://example.com/updated",
+			Events: []string{entities.WebhookEventSubscriberUnsubscribed},
+			Active: &active,
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("url", "https://example.com/updated").
+		ValueEqual("active", false)
+
+	auth.POST(fmt.Sprintf("/api/webhooks/%d/deliveries/%d/redeliver", id, d.ID)).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object().
+		ValueEqual("message", "The webhook is disabled.")
+
+	auth.POST("/api/subscribers").
+		WithJSON(params.PostSubscriber{Name: "Jim", Email: "jim@example.com"}).
+		Expect().
+		Status(http.StatusCreated)
+
+	assert.Len(t, published, 2)
+
+	auth.DELETE(fmt.Sprintf("/api/webhooks/%d", id)).
+		Expect().
+		Status(http.StatusNoContent)
+
+	auth.GET(fmt.Sprintf("/api/webhooks/%d", id)).
+		Expect().
+		Status(http.StatusNotFound)
+}
@@ -14,9 +14,10 @@ import (
 	boundarysvc "github.com/mailbadger/app/services/boundaries"
 	"github.com/mailbadger/app/services/campaigns/scheduler"
 	"github.com/mailbadger/app/services/exporters"
+	jobsvc "github.com/mailbadger/app/services/jobs"
 	reportsvc "github.com/mailbadger/app/services/reports"
-	subscrsvc "github.com/mailbadger/app/services/subscribers"
 	templatesvc "github.com/mailbadger/app/services/templates"
+	webhooksvc "github.com/mailbadger/app/services/webhooks"
 	"github.com/mailbadger/app/session"
 	awssqs "github.com/mailbadger/app/sqs"
 	awss3 "github.com/mailbadger/app/storage/s3"
@@ -31,16 +32,21 @@ var svcSet = wire.NewSet(
 	emails.NewSesSender,
 	wire.Bind(new(s3iface.S3API), new(*s3.S3)),
 	awssqs.GetCampaignerQueueURL,
+	awssqs.GetSendEmailQueueURL,
+	awssqs.GetWebhookQueueURL,
+	awssqs.GetEventsQueueURL,
+	awssqs.GetJobsQueueURL,
 	wire.Bind(new(awssqs.SendReceiveMessageAPI), new(*sqs.Client)),
 	awssqs.NewPublisher,
 	wire.Bind(new(awssqs.PublisherAPI), new(awssqs.Publisher)),
 	scheduler.New,
 	templatesvc.From,
 	boundarysvc.New,
-	subscrsvc.New,
-	exporters.NewSubscribersExporter,
-	wire.Bind(new(exporters.Exporter), new(*exporters.SubscribersExporter)),
+	exporters.New,
+	wire.Bind(new(exporters.Exporter), new(exporters.Exporters)),
 	reportsvc.New,
+	webhooksvc.New,
+	jobsvc.New,
 )
 
 func initAwsConfig(ctx context.Context) (aws.Config, error) {
@@ -16,9 +16,10 @@ import (
 	"github.com/mailbadger/app/services/boundaries"
 	"github.com/mailbadger/app/services/campaigns/scheduler"
 	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/jobs"
 	"github.com/mailbadger/app/services/reports"
-	"github.com/mailbadger/app/services/subscribers"
 	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
 	"github.com/mailbadger/app/session"
 	"github.com/mailbadger/app/sqs"
 	"github.com/mailbadger/app/storage"
@@ -51,14 +52,31 @@ func initApp(ctx context.Context, conf config.Config) (app, error) {
 	}
 	service := templates.From(storageStorage, s3S3, conf)
 	boundariesService := boundaries.New(storageStorage)
-	subscribersService := subscribers.New(s3S3, storageStorage)
-	subscribersExporter := exporters.NewSubscribersExporter(s3S3, storageStorage)
-	reportsService := reports.New(subscribersExporter, storageStorage)
+	exportersExporters := exporters.New(s3S3, storageStorage)
+	reportsService := reports.New(exportersExporters, storageStorage)
+	webhookQueueURL, err := sqs.GetWebhookQueueURL(ctx, client)
+	if err != nil {
+		return app{}, err
+	}
+	webhooksService := webhooks.New(storageStorage, publisher, webhookQueueURL)
+	jobsQueueURL, err := sqs.GetJobsQueueURL(ctx, client)
+	if err != nil {
+		return app{}, err
+	}
+	jobsService := jobs.New(storageStorage, publisher, jobsQueueURL)
 	campaignerQueueURL, err := sqs.GetCampaignerQueueURL(ctx, client)
 	if err != nil {
 		return app{}, err
 	}
-	api := routes.From(sessionSession, storageStorage, compiler, publisher, s3S3, sender, service, boundariesService, subscribersService, reportsService, campaignerQueueURL, conf)
+	sendEmailQueueURL, err := sqs.GetSendEmailQueueURL(ctx, client)
+	if err != nil {
+		return app{}, err
+	}
+	eventsQueueURL, err := sqs.GetEventsQueueURL(ctx, client)
+	if err != nil {
+		return app{}, err
+	}
+	api := routes.From(sessionSession, storageStorage, compiler, publisher, s3S3, sender, service, boundariesService, reportsService, webhooksService, jobsService, campaignerQueueURL, sendEmailQueueURL, eventsQueueURL, conf)
 	serverServer := server.From(api, conf)
 	schedulerScheduler := scheduler.New(storageStorage, publisher, campaignerQueueURL)
 	mainApp := newApp(serverServer, schedulerScheduler)
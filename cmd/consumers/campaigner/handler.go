@@ -16,6 +16,7 @@ import (
 	"github.com/mailbadger/app/entities"
 	"github.com/mailbadger/app/services/campaigns"
 	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
 	awssqs "github.com/mailbadger/app/sqs"
 	"github.com/mailbadger/app/storage"
 )
@@ -24,6 +25,7 @@ type handler struct {
 	store             storage.Storage
 	campaignsvc       campaigns.Service
 	templatesvc       templates.Service
+	webhooksvc        webhooks.Service
 	sqsclient         *sqs.Client
 	queueURL          awssqs.CampaignerQueueURL
 	sendEmailQueueURL awssqs.SendEmailQueueURL
@@ -33,6 +35,7 @@ func newHandler(
 	store storage.Storage,
 	campaignsvc campaigns.Service,
 	templatesvc templates.Service,
+	webhooksvc webhooks.Service,
 	sqsclient *sqs.Client,
 	queueURL awssqs.CampaignerQueueURL,
 	sendEmailQueueURL awssqs.SendEmailQueueURL,
@@ -41,6 +44,7 @@ func newHandler(
 		store:             store,
 		campaignsvc:       campaignsvc,
 		templatesvc:       templatesvc,
+		webhooksvc:        webhooksvc,
 		sqsclient:         sqsclient,
 		queueURL:          queueURL,
 		sendEmailQueueURL: sendEmailQueueURL,
@@ -71,7 +75,7 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 
 	logEntry.Info("Received a message, processing..")
 
-	campaign, err := h.store.GetCampaign(msg.UserID, msg.CampaignID)
+	campaign, err := h.store.GetCampaign(msg.CampaignID, msg.UserID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logEntry.WithError(err).Warn("campaign does not exist")
@@ -81,9 +85,19 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 		return err
 	}
 
-	logEntry.WithField("template_id", campaign.TemplateID)
+	if campaign.Status == entities.StatusCancelled {
+		logEntry.Info("campaign is cancelled, skipping")
+		return nil
+	}
+
+	if campaign.SplitTest != nil && campaign.SplitTest.Status == entities.SplitTestStatusWaiting {
+		logEntry.Info("split test is waiting for a winner, skipping")
+		return nil
+	}
+
+	logEntry = logEntry.WithField("template_id", campaign.TemplateID)
 
-	parsedTemplate, err := h.templatesvc.ParseTemplate(ctx, msg.UserID, campaign.TemplateID)
+	pick, err := h.templatePicker(ctx, campaign, msg)
 	if err != nil {
 		logEntry.WithError(err).Error("unable to prepare campaign template data")
 
@@ -95,7 +109,7 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 		return nil
 	}
 
-	err = h.processSubscribers(ctx, msg, campaign, parsedTemplate, logEntry, m.ReceiptHandle)
+	err = h.processSubscribers(ctx, msg, campaign, pick, logEntry, m.ReceiptHandle)
 	if err != nil {
 		// TODO return wrapped errors and do the logging here instead of inside processSubscribers
 		err = h.logFailedCampaign(ctx, campaign, "failed to process subscribers")
@@ -114,15 +128,28 @@ func (h *handler) processSubscribers(
 	ctx context.Context,
 	msg *entities.CampaignerTopicParams,
 	campaign *entities.Campaign,
-	parsedTemplate *entities.CampaignTemplateData,
+	pick templatePicker,
 	logEntry *logrus.Entry,
 	receiptHandle *string,
 ) error {
+	checkpoint, err := h.getCheckpoint(msg)
+	if err != nil {
+		logEntry.WithError(err).Error("unable to fetch campaign checkpoint")
+		return err
+	}
+
 	var (
 		timestamp time.Time
-		nextID    int64
+		nextID          = checkpoint.LastSubscriberID
 		limit     int64 = 1000
+		done      bool
+		// the variants are sent to the split test group before the winner is chosen.
+		testPhase = campaign.SplitTest != nil && campaign.SplitTest.Status != entities.SplitTestStatusCompleted
 	)
+	if checkpoint.LastCreatedAt.Valid {
+		timestamp = checkpoint.LastCreatedAt.Time
+		logEntry.WithField("next_id", nextID).Info("resuming campaign from checkpoint")
+	}
 
 	id := ksuid.New() // this id will be only used for saving failed send logs
 
@@ -131,6 +158,85 @@ func (h *handler) processSubscribers(
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
+			// the campaign could have been paused or cancelled while the previous batch was processed.
+			status, err := h.store.GetCampaignStatus(campaign.ID, msg.UserID)
+			if err != nil {
+				logEntry.WithError(err).Error("unable to fetch campaign status")
+				return err
+			}
+
+			switch status {
+			case entities.StatusPaused:
+				logEntry.WithField("next_id", nextID).Info("campaign is paused, saving checkpoint")
+				checkpoint.PausedAt.SetValid(time.Now().UTC())
+				err := h.store.SaveCampaignCheckpoint(checkpoint)
+				if err != nil {
+					logEntry.WithError(err).Error("unable to save campaign checkpoint")
+					return err
+				}
+
+				// the campaign could have been resumed before the checkpoint was saved, the resume request
+				// then publishes no message and the campaigner keeps sending if it claims the checkpoint back.
+				resumed, err := h.claimResumedCheckpoint(campaign.ID, msg.UserID)
+				if err != nil {
+					logEntry.WithError(err).Error("unable to claim campaign checkpoint")
+					return err
+				}
+				if !resumed {
+					return nil
+				}
+
+				logEntry.WithField("next_id", nextID).Info("campaign was resumed, continuing")
+				checkpoint.PausedAt = entities.NullTime{}
+				continue
+			case entities.StatusCancelled:
+				logEntry.Info("campaign is cancelled, stop processing subscribers")
+				return h.store.DeleteCampaignCheckpoint(campaign.ID, msg.UserID)
+			}
+
+			if done && testPhase {
+				err := h.completeTestPhase(campaign.SplitTest, msg)
+				if err != nil {
+					logEntry.WithError(err).Errorf("unable to set split test status to '%s'", entities.SplitTestStatusWaiting)
+					return err
+				}
+
+				err = h.store.DeleteCampaignCheckpoint(campaign.ID, msg.UserID)
+				if err != nil {
+					logEntry.WithError(err).Warn("unable to delete campaign checkpoint")
+				}
+				return nil
+			}
+
+			if done {
+				sent, err := h.setStatusSent(ctx, campaign)
+				if err != nil {
+					logEntry.WithError(err).Errorf("unable to set campaign status to '%s'", entities.StatusSent)
+					return err
+				}
+				if !sent {
+					// the campaign was paused or cancelled after its status was checked, which
+					// is handled on the next iteration, any other status completed the campaign.
+					status, err := h.store.GetCampaignStatus(campaign.ID, msg.UserID)
+					if err != nil {
+						logEntry.WithError(err).Error("unable to fetch campaign status")
+						return err
+					}
+					if status == entities.StatusPaused || status == entities.StatusCancelled {
+						continue
+					}
+
+					logEntry.WithField("status", status).Warn("campaign is no longer sending, stop processing subscribers")
+					return nil
+				}
+
+				err = h.store.DeleteCampaignCheckpoint(campaign.ID, msg.UserID)
+				if err != nil {
+					logEntry.WithError(err).Warn("unable to delete campaign checkpoint")
+				}
+				return nil
+			}
+
 			subs, err := h.store.GetDistinctSubscribersBySegmentIDs(
 				msg.SegmentIDs,
 				msg.UserID,
@@ -154,15 +260,25 @@ func (h *handler) processSubscribers(
 				logrus.WithError(err).Error("unable to extend the message visibility timeout")
 			}
 
+			pickTemplate, err := pick(subs)
+			if err != nil {
+				logEntry.WithError(err).Error("unable to pick the subscriber templates")
+				return err
+			}
+
 			for _, s := range subs {
+				parsedTemplate, variantID, ok := pickTemplate(s)
+				if !ok {
+					continue
+				}
+
 				id = id.Next()
 				params, err := h.campaignsvc.PrepareSubscriberEmailData(
 					s,
 					*msg,
-					campaign.ID,
-					parsedTemplate.HTMLPart,
-					parsedTemplate.SubjectPart,
-					parsedTemplate.TextPart,
+					campaign,
+					variantID,
+					parsedTemplate,
 				)
 				if err != nil {
 					logEntry.WithField("subscriber_id", s.ID).WithError(err).Error("unable to prepare subscriber email data")
@@ -210,34 +326,209 @@ func (h *handler) processSubscribers(
 				}
 			}
 
-			if len(subs) < 1000 {
-				err := h.setStatusSent(ctx, campaign)
-				if err != nil {
-					logEntry.WithError(err).Errorf("unable to set campaign status to '%s'", entities.StatusSent)
-					return err
-				}
-				return nil
+			// the campaign is marked as sent on the next iteration, after
+			// checking that it wasn't paused or cancelled in the meantime.
+			done = int64(len(subs)) < limit
+			if len(subs) == 0 {
+				continue
 			}
 
 			// set  vars for next batches
 			lastSub := subs[len(subs)-1]
 			nextID = lastSub.ID
 			timestamp = lastSub.CreatedAt
+
+			checkpoint.LastSubscriberID = nextID
+			checkpoint.LastCreatedAt.SetValid(timestamp)
+			err = h.store.SaveCampaignCheckpoint(checkpoint)
+			if err != nil {
+				logEntry.WithError(err).Error("unable to save campaign checkpoint")
+				return err
+			}
+		}
+	}
+}
+
+// claimResumedCheckpoint claims the checkpoint of the paused campaign if the campaign
+// has been resumed in the meantime, it reports whether the checkpoint was claimed.
+func (h *handler) claimResumedCheckpoint(campaignID, userID int64) (bool, error) {
+	status, err := h.store.GetCampaignStatus(campaignID, userID)
+	if err != nil {
+		return false, fmt.Errorf("get campaign status: %w", err)
+	}
+	if status != entities.StatusSending {
+		return false, nil
+	}
+
+	return h.store.ClaimPausedCampaignCheckpoint(campaignID, userID)
+}
+
+// getCheckpoint returns the checkpoint of the campaign's current event, a new checkpoint
+// which starts from the beginning of the subscribers list is created when there is none.
+func (h *handler) getCheckpoint(msg *entities.CampaignerTopicParams) (*entities.CampaignCheckpoint, error) {
+	checkpoint, err := h.store.GetCampaignCheckpoint(msg.CampaignID, msg.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err == nil && checkpoint.EventID == msg.EventID {
+		return checkpoint, nil
+	}
+
+	// the checkpoint belongs to a previous send of the campaign, start over.
+	checkpoint.UserID = msg.UserID
+	checkpoint.CampaignID = msg.CampaignID
+	checkpoint.EventID = msg.EventID
+	checkpoint.LastSubscriberID = 0
+	checkpoint.LastCreatedAt = entities.NullTime{}
+	checkpoint.PausedAt = entities.NullTime{}
+	err = checkpoint.SetParams(*msg)
+	if err != nil {
+		return nil, fmt.Errorf("set checkpoint params: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+// templatePick returns the parsed template and the split test variant id which the
+// subscriber receives, or false when the subscriber should be skipped.
+type templatePick func(s entities.Subscriber) (*entities.CampaignTemplateData, int64, bool)
+
+// templatePicker returns the template pick for a batch of subscribers.
+type templatePicker func(subs []entities.Subscriber) (templatePick, error)
+
+// templatePicker parses the templates of the campaign at the versions which were pinned
+// when the campaign was started. Campaigns without a split test send their template to
+// every subscriber. During the test phase each subscriber of the test group receives one
+// of the variants, after that the winner is sent to the subscribers who weren't sent a variant.
+func (h *handler) templatePicker(ctx context.Context, campaign *entities.Campaign, msg *entities.CampaignerTopicParams) (templatePicker, error) {
+	userID := msg.UserID
+	st := campaign.SplitTest
+	if st == nil {
+		tpl, err := h.templatesvc.ParseTemplateVersion(ctx, campaign.TemplateID, msg.TemplateVersion, userID)
+		if err != nil {
+			return nil, err
+		}
+		pick := func(entities.Subscriber) (*entities.CampaignTemplateData, int64, bool) {
+			return tpl, 0, true
+		}
+		return func([]entities.Subscriber) (templatePick, error) {
+			return pick, nil
+		}, nil
+	}
+
+	if st.Status == entities.SplitTestStatusCompleted {
+		winner, ok := st.Variant(st.WinnerVariantID)
+		if !ok {
+			return nil, fmt.Errorf("split test winner %d not found", st.WinnerVariantID)
+		}
+		tpl, err := h.templatesvc.ParseTemplateVersion(ctx, winner.TemplateID, winner.TemplateVersion, userID)
+		if err != nil {
+			return nil, err
+		}
+		return func(subs []entities.Subscriber) (templatePick, error) {
+			// only the subscribers who were sent a variant are skipped, the ones who
+			// joined the segments after the test phase receive the winner even if
+			// they hash into the test group.
+			var ids []int64
+			for _, s := range subs {
+				if _, inTest := st.Assign(s.ID); inTest {
+					ids = append(ids, s.ID)
+				}
+			}
+
+			sent := make(map[int64]bool)
+			if len(ids) > 0 {
+				sentIDs, err := h.store.GetSentSubscriberIDs(campaign.ID, userID, ids)
+				if err != nil {
+					return nil, fmt.Errorf("get sent subscriber ids: %w", err)
+				}
+				for _, id := range sentIDs {
+					sent[id] = true
+				}
+			}
+
+			return func(s entities.Subscriber) (*entities.CampaignTemplateData, int64, bool) {
+				if sent[s.ID] {
+					return nil, 0, false
+				}
+				return tpl, winner.ID, true
+			}, nil
+		}, nil
+	}
+
+	tpls := make(map[int64]*entities.CampaignTemplateData, len(st.Variants))
+	for _, v := range st.Variants {
+		tpl, err := h.templatesvc.ParseTemplateVersion(ctx, v.TemplateID, v.TemplateVersion, userID)
+		if err != nil {
+			return nil, err
 		}
+		tpls[v.ID] = tpl
 	}
+
+	pick := func(s entities.Subscriber) (*entities.CampaignTemplateData, int64, bool) {
+		v, inTest := st.Assign(s.ID)
+		if !inTest {
+			return nil, 0, false
+		}
+		return tpls[v.ID], v.ID, true
+	}
+	return func([]entities.Subscriber) (templatePick, error) {
+		return pick, nil
+	}, nil
+}
+
+// completeTestPhase marks the split test as waiting, the scheduler picks the
+// winner and publishes the campaign again once the wait period elapses.
+func (h *handler) completeTestPhase(st *entities.CampaignSplitTest, msg *entities.CampaignerTopicParams) error {
+	st.Status = entities.SplitTestStatusWaiting
+	st.TestCompletedAt.SetValid(time.Now().UTC())
+	err := st.SetParams(*msg)
+	if err != nil {
+		return fmt.Errorf("set split test params: %w", err)
+	}
+	return h.store.UpdateCampaignSplitTest(st)
 }
 
 // logFailedCampaign updates campaign status to failed & inserts campaign  failed log.
 func (h *handler) logFailedCampaign(ctx context.Context, campaign *entities.Campaign, description string) error {
 	campaign.Status = entities.StatusFailed
 	campaign.CompletedAt.SetValid(time.Now().UTC())
-	return h.store.LogFailedCampaign(campaign, description)
+	err := h.store.LogFailedCampaign(campaign, description)
+	if err != nil {
+		return err
+	}
+
+	h.dispatchWebhookEvent(ctx, campaign, entities.WebhookEventCampaignFailed)
+	return nil
 }
 
-func (h *handler) setStatusSent(ctx context.Context, campaign *entities.Campaign) error {
+// setStatusSent completes the campaign unless it was paused or cancelled during the last batch,
+// it reports whether the campaign was completed.
+func (h *handler) setStatusSent(ctx context.Context, campaign *entities.Campaign) (bool, error) {
+	now := time.Now().UTC()
+	sent, err := h.store.CompleteCampaign(campaign.ID, campaign.UserID, entities.StatusSending, entities.StatusSent, now)
+	if err != nil || !sent {
+		return false, err
+	}
+
 	campaign.Status = entities.StatusSent
-	campaign.CompletedAt.SetValid(time.Now().UTC())
-	return h.store.UpdateCampaign(campaign)
+	campaign.CompletedAt.SetValid(now)
+	h.dispatchWebhookEvent(ctx, campaign, entities.WebhookEventCampaignSent)
+	return true, nil
+}
+
+// dispatchWebhookEvent notifies the user's webhooks about the campaign status, the
+// campaign status is already updated so a failed dispatch is only logged.
+func (h *handler) dispatchWebhookEvent(ctx context.Context, campaign *entities.Campaign, eventType string) {
+	err := h.webhooksvc.Dispatch(ctx, campaign.UserID, eventType, campaign)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"campaign_id": campaign.ID,
+			"user_id":     campaign.UserID,
+			"event_type":  eventType,
+		}).WithError(err).Error("unable to dispatch webhook event")
+	}
 }
 
 func (h *handler) DeleteMessage(ctx context.Context, m types.Message) error {
@@ -11,6 +11,7 @@ import (
 	"github.com/google/wire"
 	"github.com/mailbadger/app/services/campaigns"
 	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
 	awssqs "github.com/mailbadger/app/sqs"
 	awss3 "github.com/mailbadger/app/storage/s3"
 )
@@ -24,11 +25,14 @@ var svcSet = wire.NewSet(
 	wire.Bind(new(s3iface.S3API), new(*s3.S3)),
 	awssqs.GetCampaignerQueueURL,
 	awssqs.GetSendEmailQueueURL,
+	awssqs.GetWebhookQueueURL,
 	newQueueURL,
 	awssqs.NewPublisher,
+	wire.Bind(new(awssqs.PublisherAPI), new(awssqs.Publisher)),
 	awssqs.NewConsumerFrom,
 	templates.From,
 	campaigns.From,
+	webhooks.New,
 )
 
 func initAwsConfig(ctx context.Context) (aws.Config, error) {
@@ -11,6 +11,7 @@ import (
 	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/services/campaigns"
 	"github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
 	"github.com/mailbadger/app/sqs"
 	"github.com/mailbadger/app/storage"
 	"github.com/mailbadger/app/storage/s3"
@@ -32,6 +33,12 @@ func initApp(ctx context.Context, conf config.Config) (app, error) {
 		return app{}, err
 	}
 	templatesService := templates.From(storageStorage, s3S3, conf)
+	publisher := sqs.NewPublisher(client)
+	webhookQueueURL, err := sqs.GetWebhookQueueURL(ctx, client)
+	if err != nil {
+		return app{}, err
+	}
+	webhooksService := webhooks.New(storageStorage, publisher, webhookQueueURL)
 	campaignerQueueURL, err := sqs.GetCampaignerQueueURL(ctx, client)
 	if err != nil {
 		return app{}, err
@@ -40,7 +47,7 @@ func initApp(ctx context.Context, conf config.Config) (app, error) {
 	if err != nil {
 		return app{}, err
 	}
-	mainHandler := newHandler(storageStorage, service, templatesService, client, campaignerQueueURL, sendEmailQueueURL)
+	mainHandler := newHandler(storageStorage, service, templatesService, webhooksService, client, campaignerQueueURL, sendEmailQueueURL)
 	queueURL := newQueueURL(campaignerQueueURL)
 	consumer := sqs.NewConsumerFrom(conf, queueURL, client)
 	mainApp := newApp(mainHandler, consumer)
@@ -0,0 +1,581 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/services/webhooks"
+	awssqs "github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+)
+
+const (
+	// maxDeleteBatchSize is the max number of messages deleted with a single SQS request.
+	maxDeleteBatchSize = 10
+
+	// maxReceiveCount is the number of times a message whose event can't be stored is received,
+	// after that the message is logged and deleted so it isn't received again forever.
+	maxReceiveCount = 10
+
+	// processedEventsRetention is how long the keys of the processed events are kept,
+	// SQS keeps the messages for 14 days at most so they can't be received after that.
+	processedEventsRetention = 14 * 24 * time.Hour
+)
+
+type handler struct {
+	store      storage.Storage
+	webhooksvc webhooks.Service
+	sqsclient  *sqs.Client
+	queueURL   awssqs.EventsQueueURL
+}
+
+// event is an SES notification received from the queue.
+type event struct {
+	key     string
+	user    *entities.User
+	msg     entities.SesMessage
+	message types.Message
+}
+
+// recipient is a permanently bounced email address of the user.
+type recipient struct {
+	userID int64
+	email  string
+}
+
+// notification is a webhook event dispatched after the events are stored.
+type notification struct {
+	userID    int64
+	eventType string
+	data      interface{}
+}
+
+// batch holds the records of the events along with the
+// actions which are taken once the records are stored.
+type batch struct {
+	entities.EventsBatch
+	deactivate    []recipient
+	notifications []notification
+}
+
+func newHandler(
+	store storage.Storage,
+	webhooksvc webhooks.Service,
+	sqsclient *sqs.Client,
+	queueURL awssqs.EventsQueueURL,
+) *handler {
+	return &handler{
+		store:      store,
+		webhooksvc: webhooksvc,
+		sqsclient:  sqsclient,
+		queueURL:   queueURL,
+	}
+}
+
+// HandleMessages stores the SES events of the messages in a single transaction and returns the
+// handled messages, which can be deleted. The events which are already stored are skipped, so a
+// notification delivered more than once by SNS is counted once. When the transaction fails the
+// events are stored one by one, so a single bad event doesn't fail the other messages, the messages
+// of the failed events are received again. An error is returned when all of the messages should be
+// received again.
+func (h *handler) HandleMessages(ctx context.Context, msgs []types.Message) ([]types.Message, error) {
+	events, err := h.parseMessages(msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return msgs, nil
+	}
+
+	keys := make([]string, len(events))
+	for i, e := range events {
+		keys[i] = e.key
+	}
+
+	processed, err := h.store.GetProcessedEventKeys(keys)
+	if err != nil {
+		return nil, fmt.Errorf("get processed event keys: %w", err)
+	}
+
+	skip := make(map[string]bool, len(events))
+	for _, k := range processed {
+		skip[k] = true
+	}
+
+	b := new(batch)
+	pending := make([]event, 0, len(events))
+	for _, e := range events {
+		if skip[e.key] {
+			logrus.WithField("event_key", e.key).Info("event is already processed, skipping")
+			continue
+		}
+		skip[e.key] = true
+
+		h.addProcessedEvent(b, e)
+		pending = append(pending, e)
+	}
+
+	err = h.storeBatch(ctx, b)
+	if err == nil {
+		return msgs, nil
+	}
+	logrus.WithError(err).Warn("unable to store the events batch, storing the events one by one")
+
+	failed := make(map[string]bool)
+	for _, e := range pending {
+		eb := new(batch)
+		h.addProcessedEvent(eb, e)
+
+		err = h.storeBatch(ctx, eb)
+		if err == nil {
+			continue
+		}
+
+		logEntry := logrus.WithFields(logrus.Fields{
+			"user_id":    e.user.ID,
+			"message_id": e.msg.Mail.MessageID,
+			"event_type": e.msg.NotificationType,
+			"event_key":  e.key,
+		}).WithError(err)
+
+		if receiveCount(e.message) >= maxReceiveCount {
+			logEntry.WithField("body", aws.ToString(e.message.Body)).Error("unable to store the event, dropping the message")
+			continue
+		}
+
+		logEntry.Error("unable to store the event, the message will be received again")
+		failed[aws.ToString(e.message.ReceiptHandle)] = true
+	}
+
+	handled := make([]types.Message, 0, len(msgs))
+	for _, m := range msgs {
+		if !failed[aws.ToString(m.ReceiptHandle)] {
+			handled = append(handled, m)
+		}
+	}
+
+	return handled, nil
+}
+
+// addProcessedEvent adds the records of the event to the batch along with the key of the event.
+func (h *handler) addProcessedEvent(b *batch, e event) {
+	if h.addEvent(b, e) {
+		b.Processed = append(b.Processed, entities.ProcessedEvent{Key: e.key})
+	}
+}
+
+// storeBatch stores the records of the batch, then it deactivates the permanently
+// bounced recipients and dispatches the webhook events.
+func (h *handler) storeBatch(ctx context.Context, b *batch) error {
+	err := h.store.CreateEvents(&b.EventsBatch)
+	if err != nil {
+		return fmt.Errorf("create events: %w", err)
+	}
+
+	for _, r := range b.deactivate {
+		err = h.store.DeactivateSubscriber(r.userID, r.email)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"user_id":   r.userID,
+				"recipient": r.email,
+			}).WithError(err).Error("Unable to blacklist bounced recipient")
+		}
+	}
+
+	h.dispatchWebhookEvents(ctx, b)
+
+	return nil
+}
+
+// receiveCount returns the number of times the message was received from the queue.
+func receiveCount(m types.Message) int {
+	n, err := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// PruneProcessedEvents deletes the keys of the events which were processed before the retention period.
+func (h *handler) PruneProcessedEvents() {
+	n, err := h.store.DeleteProcessedEventsBefore(time.Now().UTC().Add(-processedEventsRetention))
+	if err != nil {
+		logrus.WithError(err).Error("unable to delete the processed events")
+		return
+	}
+
+	logrus.WithField("deleted", n).Info("deleted the expired processed events")
+}
+
+// parseMessages decodes the SES notifications of the messages, the invalid
+// messages and the notifications of unknown users are skipped.
+func (h *handler) parseMessages(msgs []types.Message) ([]event, error) {
+	var (
+		events = make([]event, 0, len(msgs))
+		users  = make(map[string]*entities.User)
+	)
+
+	for _, m := range msgs {
+		if m.Body == nil || len(*m.Body) == 0 {
+			logrus.Error("Empty message, unable to proceed.")
+			continue
+		}
+
+		params := new(entities.EventsTopicParams)
+		err := json.Unmarshal([]byte(*m.Body), params)
+		if err != nil {
+			logrus.WithError(err).Error("Unable to unmarshal message")
+			continue
+		}
+
+		var msg entities.SesMessage
+		err = json.Unmarshal([]byte(params.Message), &msg)
+		if err != nil {
+			logrus.WithError(err).Error("Unable to unmarshal SES message")
+			continue
+		}
+
+		logEntry := logrus.WithFields(logrus.Fields{
+			"uuid":       params.UserUUID,
+			"message_id": msg.Mail.MessageID,
+			"event_type": msg.NotificationType,
+		})
+
+		key, ok := eventKey(msg)
+		if !ok {
+			if msg.NotificationType == emails.RenderingFailureType && msg.RenderingFailure != nil {
+				logEntry.WithFields(logrus.Fields{
+					"tags":          msg.Mail.Tags,
+					"error":         msg.RenderingFailure.ErrorMessage,
+					"template_name": msg.RenderingFailure.TemplateName,
+				}).Warn("Rendering html template failure.")
+				continue
+			}
+
+			logEntry.Error("Unknown AWS SES message.")
+			continue
+		}
+
+		u, ok := users[params.UserUUID]
+		if !ok {
+			u, err = h.store.GetUserByUUID(params.UserUUID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					logEntry.WithError(err).Warn("user does not exist")
+					continue
+				}
+				return nil, fmt.Errorf("get user by uuid: %w", err)
+			}
+			users[params.UserUUID] = u
+		}
+
+		events = append(events, event{
+			key:     key,
+			user:    u,
+			msg:     msg,
+			message: m,
+		})
+	}
+
+	return events, nil
+}
+
+// eventKey returns the idempotency key of the SES event, false is
+// returned when the event isn't stored.
+func eventKey(msg entities.SesMessage) (string, bool) {
+	var t time.Time
+	switch {
+	case msg.NotificationType == emails.SendType:
+		t = msg.Mail.Timestamp
+	case msg.NotificationType == emails.BounceType && msg.Bounce != nil:
+		t = msg.Bounce.Timestamp
+	case msg.NotificationType == emails.ComplaintType && msg.Complaint != nil:
+		t = msg.Complaint.Timestamp
+	case msg.NotificationType == emails.DeliveryType && msg.Delivery != nil:
+		t = msg.Delivery.Timestamp
+	case msg.NotificationType == emails.ClickType && msg.Click != nil:
+		t = msg.Click.Timestamp
+	case msg.NotificationType == emails.OpenType && msg.Open != nil:
+		t = msg.Open.Timestamp
+	default:
+		return "", false
+	}
+
+	return entities.EventKey(msg.Mail.MessageID, msg.NotificationType, t), true
+}
+
+// addEvent adds the records of the event to the batch, it returns false when the event has no records.
+func (h *handler) addEvent(b *batch, e event) bool {
+	msg := e.msg
+	logEntry := logrus.WithFields(logrus.Fields{
+		"user_id":    e.user.ID,
+		"message_id": msg.Mail.MessageID,
+		"event_type": msg.NotificationType,
+	})
+
+	// transactional messages are tracked on their own record, they don't belong to a campaign.
+	if tidTag, ok := msg.Mail.Tags[entities.TagTransactionalID]; ok && len(tidTag) > 0 {
+		tid, err := strconv.ParseInt(tidTag[0], 10, 64)
+		if err != nil {
+			logEntry.WithError(err).Error("unable to parse transactional id")
+			return false
+		}
+
+		return addTransactionalEvent(b, e.user.ID, tid, msg)
+	}
+
+	// fetch the campaign id from tags
+	cidTag, ok := msg.Mail.Tags["campaign_id"]
+	if !ok || len(cidTag) == 0 {
+		logEntry.WithFields(logrus.Fields{
+			"source": msg.Mail.Source,
+			"tags":   msg.Mail.Tags,
+		}).Error("campaign id not found in mail tags")
+		return false
+	}
+
+	cid, err := strconv.ParseInt(cidTag[0], 10, 64)
+	if err != nil {
+		logEntry.WithError(err).Error("unable to parse campaign id")
+		return false
+	}
+
+	// the variant id is tagged only on split test campaigns.
+	var vid int64
+	if vidTag, ok := msg.Mail.Tags["variant_id"]; ok && len(vidTag) > 0 {
+		vid, err = strconv.ParseInt(vidTag[0], 10, 64)
+		if err != nil {
+			logEntry.WithError(err).Error("unable to parse variant id")
+			return false
+		}
+	}
+
+	// the opens and clicks tracked by the app are already stored.
+	if (msg.NotificationType == emails.OpenType && hasTag(msg, entities.TagTrackedOpens)) ||
+		(msg.NotificationType == emails.ClickType && hasTag(msg, entities.TagTrackedClicks)) {
+		return false
+	}
+
+	uid := e.user.ID
+	switch msg.NotificationType {
+	case emails.BounceType:
+		for _, r := range msg.Bounce.BouncedRecipients {
+			b.Bounces = append(b.Bounces, entities.Bounce{
+				UserID:         uid,
+				CampaignID:     cid,
+				VariantID:      vid,
+				Recipient:      r.EmailAddress,
+				Action:         r.Action,
+				Status:         r.Status,
+				DiagnosticCode: r.DiagnosticCode,
+				Type:           msg.Bounce.BounceType,
+				SubType:        msg.Bounce.BounceSubType,
+				FeedbackID:     msg.Bounce.FeedbackID,
+				CreatedAt:      msg.Bounce.Timestamp,
+			})
+
+			if msg.Bounce.BounceType == "Permanent" {
+				b.deactivate = append(b.deactivate, recipient{uid, r.EmailAddress})
+			}
+		}
+	case emails.ComplaintType:
+		for _, r := range msg.Complaint.ComplainedRecipients {
+			b.Complaints = append(b.Complaints, entities.Complaint{
+				UserID:     uid,
+				CampaignID: cid,
+				VariantID:  vid,
+				Recipient:  r.EmailAddress,
+				Type:       msg.Complaint.ComplaintFeedbackType,
+				FeedbackID: msg.Complaint.FeedbackID,
+				CreatedAt:  msg.Complaint.Timestamp,
+			})
+		}
+	case emails.DeliveryType:
+		for _, r := range msg.Delivery.Recipients {
+			b.Deliveries = append(b.Deliveries, entities.Delivery{
+				UserID:               uid,
+				CampaignID:           cid,
+				VariantID:            vid,
+				Recipient:            r,
+				ProcessingTimeMillis: msg.Delivery.ProcessingTimeMillis,
+				ReportingMTA:         msg.Delivery.ReportingMTA,
+				RemoteMtaIP:          msg.Delivery.RemoteMtaIP,
+				SMTPResponse:         msg.Delivery.SMTPResponse,
+				CreatedAt:            msg.Delivery.Timestamp,
+			})
+		}
+	case emails.SendType:
+		for _, d := range msg.Mail.Destination {
+			b.Sends = append(b.Sends, entities.Send{
+				UserID:           uid,
+				CampaignID:       cid,
+				VariantID:        vid,
+				MessageID:        msg.Mail.MessageID,
+				Source:           msg.Mail.Source,
+				SendingAccountID: msg.Mail.SendingAccountID,
+				Destination:      d,
+				CreatedAt:        msg.Mail.Timestamp,
+			})
+		}
+	case emails.ClickType:
+		for _, d := range msg.Mail.Destination {
+			b.Clicks = append(b.Clicks, entities.Click{
+				UserID:     uid,
+				CampaignID: cid,
+				VariantID:  vid,
+				Recipient:  d,
+				Link:       msg.Click.Link,
+				UserAgent:  msg.Click.UserAgent,
+				IPAddress:  msg.Click.IPAddress,
+				CreatedAt:  msg.Click.Timestamp,
+			})
+		}
+	case emails.OpenType:
+		for _, d := range msg.Mail.Destination {
+			b.Opens = append(b.Opens, entities.Open{
+				UserID:     uid,
+				CampaignID: cid,
+				VariantID:  vid,
+				Recipient:  d,
+				UserAgent:  msg.Open.UserAgent,
+				IPAddress:  msg.Open.IPAddress,
+				CreatedAt:  msg.Open.Timestamp,
+			})
+		}
+	}
+
+	return true
+}
+
+func hasTag(msg entities.SesMessage, tag string) bool {
+	t, ok := msg.Mail.Tags[tag]
+	return ok && len(t) > 0 && t[0] == "true"
+}
+
+// addTransactionalEvent adds the event of the transactional message to the batch,
+// permanently bounced recipients are deactivated the same as with the campaigns.
+func addTransactionalEvent(b *batch, userID, tid int64, msg entities.SesMessage) bool {
+	var event, webhookEvent string
+	switch msg.NotificationType {
+	case emails.DeliveryType:
+		event = entities.TransactionalStatusDelivered
+	case emails.BounceType:
+		event = entities.TransactionalStatusBounced
+		webhookEvent = entities.WebhookEventEmailBounced
+		if msg.Bounce.BounceType == "Permanent" {
+			for _, r := range msg.Bounce.BouncedRecipients {
+				b.deactivate = append(b.deactivate, recipient{userID, r.EmailAddress})
+			}
+		}
+	case emails.ComplaintType:
+		event = entities.TransactionalStatusComplained
+		webhookEvent = entities.WebhookEventEmailComplained
+	case emails.OpenType:
+		event = entities.TransactionalEventOpen
+		webhookEvent = entities.WebhookEventEmailOpened
+	case emails.ClickType:
+		event = entities.TransactionalEventClick
+		webhookEvent = entities.WebhookEventEmailClicked
+	default:
+		// sends are already reflected by the sender result.
+		return false
+	}
+
+	b.Transactional = append(b.Transactional, entities.TransactionalEvent{
+		ID:     tid,
+		UserID: userID,
+		Event:  event,
+	})
+
+	if webhookEvent != "" {
+		b.notifications = append(b.notifications, notification{
+			userID:    userID,
+			eventType: webhookEvent,
+			data: map[string]interface{}{
+				"transactional_id": tid,
+				"message_id":       msg.Mail.MessageID,
+				"recipients":       msg.Mail.Destination,
+			},
+		})
+	}
+
+	return true
+}
+
+// dispatchWebhookEvents notifies the users' webhooks about the stored events,
+// the events are already stored so a failed dispatch is only logged.
+func (h *handler) dispatchWebhookEvents(ctx context.Context, b *batch) {
+	for i := range b.Bounces {
+		h.dispatch(ctx, b.Bounces[i].UserID, entities.WebhookEventEmailBounced, &b.Bounces[i])
+	}
+	for i := range b.Complaints {
+		h.dispatch(ctx, b.Complaints[i].UserID, entities.WebhookEventEmailComplained, &b.Complaints[i])
+	}
+	for i := range b.Clicks {
+		h.dispatch(ctx, b.Clicks[i].UserID, entities.WebhookEventEmailClicked, &b.Clicks[i])
+	}
+	for i := range b.Opens {
+		h.dispatch(ctx, b.Opens[i].UserID, entities.WebhookEventEmailOpened, &b.Opens[i])
+	}
+	for _, n := range b.notifications {
+		h.dispatch(ctx, n.userID, n.eventType, n.data)
+	}
+}
+
+func (h *handler) dispatch(ctx context.Context, userID int64, eventType string, data interface{}) {
+	err := h.webhooksvc.Dispatch(ctx, userID, eventType, data)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"event_type": eventType,
+		}).WithError(err).Error("unable to dispatch webhook event")
+	}
+}
+
+// DeleteMessages deletes the processed messages from the queue, the messages
+// which are not deleted are received again and skipped as already processed.
+func (h *handler) DeleteMessages(ctx context.Context, msgs []types.Message) {
+	for start := 0; start < len(msgs); start += maxDeleteBatchSize {
+		end := start + maxDeleteBatchSize
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+
+		entries := make([]types.DeleteMessageBatchRequestEntry, 0, end-start)
+		for i, m := range msgs[start:end] {
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: m.ReceiptHandle,
+			})
+		}
+
+		out, err := h.sqsclient.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: h.queueURL,
+			Entries:  entries,
+		})
+		if err != nil {
+			logrus.WithError(err).Error("Unable to delete the messages")
+			continue
+		}
+
+		for _, f := range out.Failed {
+			logrus.WithFields(logrus.Fields{
+				"code":    aws.ToString(f.Code),
+				"message": aws.ToString(f.Message),
+			}).Error("Unable to delete message")
+		}
+	}
+}
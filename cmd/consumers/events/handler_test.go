@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage"
+)
+
+// failingStore fails to store the batches which contain the event of the message.
+type failingStore struct {
+	storage.Storage
+	messageID string
+}
+
+func (s *failingStore) CreateEvents(b *entities.EventsBatch) error {
+	for _, p := range b.Processed {
+		if p.Key == entities.EventKey(s.messageID, emails.DeliveryType, eventTime) {
+			return errors.New("invalid event")
+		}
+	}
+	return s.Storage.CreateEvents(b)
+}
+
+// fakeWebhooks records the dispatched event types.
+type fakeWebhooks struct {
+	dispatched []string
+}
+
+func (f *fakeWebhooks) Dispatch(_ context.Context, _ int64, eventType string, _ interface{}) error {
+	f.dispatched = append(f.dispatched, eventType)
+	return nil
+}
+
+func (f *fakeWebhooks) DispatchAll(_ context.Context, _ int64, eventType string, data []interface{}) error {
+	for range data {
+		f.dispatched = append(f.dispatched, eventType)
+	}
+	return nil
+}
+
+func (f *fakeWebhooks) Redeliver(_ context.Context, d *entities.WebhookDelivery) (*entities.WebhookDelivery, error) {
+	return d, nil
+}
+
+func (f *fakeWebhooks) Deliver(_ context.Context, _ *entities.WebhookDelivery) error {
+	return nil
+}
+
+var eventTime = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func sesMessage(t *testing.T, uuid, messageID string, msg entities.SesMessage, received int) types.Message {
+	msg.Mail.MessageID = messageID
+	msg.Mail.Timestamp = eventTime
+	msg.Mail.Tags = map[string][]string{"campaign_id": {"1"}}
+
+	b, err := json.Marshal(msg)
+	assert.Nil(t, err)
+
+	body, err := json.Marshal(entities.EventsTopicParams{UserUUID: uuid, Message: string(b)})
+	assert.Nil(t, err)
+
+	return types.Message{
+		Body:          aws.String(string(body)),
+		ReceiptHandle: aws.String(messageID),
+		Attributes: map[string]string{
+			string(types.MessageSystemAttributeNameApproximateReceiveCount): strconv.Itoa(received),
+		},
+	}
+}
+
+func delivery(t *testing.T, uuid, messageID string, received int) types.Message {
+	return sesMessage(t, uuid, messageID, entities.SesMessage{
+		NotificationType: emails.DeliveryType,
+		Delivery: &entities.DeliveryMsg{
+			Timestamp:  eventTime,
+			Recipients: []string{"jane@example.com"},
+		},
+	}, received)
+}
+
+func TestHandleMessages(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+
+	u, err := s.GetUserByUsername("admin")
+	assert.Nil(t, err)
+
+	hooks := new(fakeWebhooks)
+	h := newHandler(&failingStore{Storage: s, messageID: "msg-bad"}, hooks, nil, nil)
+
+	bounce := sesMessage(t, u.UUID, "msg-1", entities.SesMessage{
+		NotificationType: emails.BounceType,
+		Bounce: &entities.BounceMsg{
+			BouncedRecipients: []*entities.BouncedRecipient{{EmailAddress: "john@example.com"}},
+			BounceType:        "Transient",
+			Timestamp:         eventTime,
+		},
+	}, 1)
+	invalid := types.Message{Body: aws.String("{"), ReceiptHandle: aws.String("invalid")}
+	unknownUser := delivery(t, "unknown", "msg-2", 1)
+
+	msgs := []types.Message{bounce, invalid, unknownUser, delivery(t, u.UUID, "msg-3", 1)}
+	handled, err := h.HandleMessages(context.Background(), msgs)
+	assert.Nil(t, err)
+	assert.Equal(t, msgs, handled)
+	assert.Equal(t, []string{entities.WebhookEventEmailBounced}, hooks.dispatched)
+
+	total, err := s.GetTotalBounces(1, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// the events which are already stored are skipped.
+	handled, err = h.HandleMessages(context.Background(), msgs)
+	assert.Nil(t, err)
+	assert.Equal(t, msgs, handled)
+	assert.Len(t, hooks.dispatched, 1)
+
+	total, err = s.GetTotalBounces(1, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// a bad event doesn't fail the other messages of the batch, its message is received again.
+	bad := delivery(t, u.UUID, "msg-bad", 1)
+	msgs = []types.Message{delivery(t, u.UUID, "msg-4", 1), bad, delivery(t, u.UUID, "msg-5", 1)}
+	handled, err = h.HandleMessages(context.Background(), msgs)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Message{msgs[0], msgs[2]}, handled)
+
+	total, err = s.GetTotalDelivered(1, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), total)
+
+	// the message is dropped after it's received too many times.
+	bad = delivery(t, u.UUID, "msg-bad", maxReceiveCount)
+	handled, err = h.HandleMessages(context.Background(), []types.Message{bad})
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Message{bad}, handled)
+
+	total, err = s.GetTotalDelivered(1, u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestPruneProcessedEvents(t *testing.T) {
+	db := storage.New(config.Config{
+		Storage: config.Storage{
+			DB: config.DB{
+				Driver:        "sqlite3",
+				Sqlite3Source: ":memory:",
+			},
+		},
+	})
+	s := storage.From(db)
+
+	now := time.Now().UTC()
+	expired := entities.EventKey("msg-1", emails.DeliveryType, now)
+	recent := entities.EventKey("msg-2", emails.DeliveryType, now)
+
+	err := db.Create(&entities.ProcessedEvent{Key: expired, CreatedAt: now.Add(-processedEventsRetention - time.Hour)}).Error
+	assert.Nil(t, err)
+	err = db.Create(&entities.ProcessedEvent{Key: recent, CreatedAt: now}).Error
+	assert.Nil(t, err)
+
+	newHandler(s, new(fakeWebhooks), nil, nil).PruneProcessedEvents()
+
+	keys, err := s.GetProcessedEventKeys([]string{expired, recent})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{recent}, keys)
+}
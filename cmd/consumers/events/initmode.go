@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/mailbadger/app/mode"
+)
+
+//nolint
+func initMode(m string) {
+	mode.SetMode(m)
+}
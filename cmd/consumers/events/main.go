@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/config"
+)
+
+// The received messages are processed in batches, a batch is processed when
+// it is full or when the flush interval passes. The expired keys of the processed
+// events are deleted on each prune interval.
+const (
+	batchSize     = 100
+	flushInterval = time.Second
+	pruneInterval = time.Hour
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	conf, err := config.FromEnv()
+	if err != nil {
+		logrus.WithError(err).Fatalln("unable to read config from env")
+	}
+
+	initMode(conf.Mode)
+	initLogger(conf.Logging)
+
+	app, err := initApp(ctx, conf)
+	if err != nil {
+		logrus.WithError(err).Fatalln("unable to initialize app")
+	}
+
+	batch := make([]types.Message, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		// the messages which are not deleted are received again after the visibility timeout.
+		handled, err := app.handler.HandleMessages(ctx, batch)
+		if err != nil {
+			logrus.WithError(err).Error("received an error when handling the messages")
+		}
+		app.handler.DeleteMessages(ctx, handled)
+
+		batch = make([]types.Message, 0, batchSize)
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+
+	msgs := app.consumer.PollSQS(ctx)
+	for {
+		select {
+		case m, ok := <-msgs:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, m)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-pruneTicker.C:
+			app.handler.PruneProcessedEvents()
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/services/jobs"
+	awssqs "github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+)
+
+const (
+	// maxVisibilityTimeout is the max number of seconds a message can be hidden from the queue.
+	maxVisibilityTimeout = 43200
+	// The message of the running job is kept hidden from the queue by extending its visibility
+	// timeout in intervals, so the job isn't received again while it is running.
+	heartbeatInterval = 30 * time.Second
+	heartbeatTimeout  = 60
+)
+
+type handler struct {
+	store     storage.Storage
+	runner    *jobs.Runner
+	sqsclient *sqs.Client
+	queueURL  awssqs.JobsQueueURL
+}
+
+func newHandler(
+	store storage.Storage,
+	runner *jobs.Runner,
+	sqsclient *sqs.Client,
+	queueURL awssqs.JobsQueueURL,
+) *handler {
+	return &handler{
+		store:     store,
+		runner:    runner,
+		sqsclient: sqsclient,
+		queueURL:  queueURL,
+	}
+}
+
+// HandleMessage runs the job of the message. When the job fails the message
+// is kept in the queue and hidden until the next attempt.
+func (h *handler) HandleMessage(ctx context.Context, m types.Message) error {
+	if m.Body == nil || len(*m.Body) == 0 {
+		logrus.Error("Empty message, unable to proceed.")
+		return nil
+	}
+
+	msg := new(entities.JobTopicParams)
+	err := json.Unmarshal([]byte(*m.Body), msg)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to unmarshal message")
+		return nil
+	}
+
+	logEntry := logrus.WithFields(logrus.Fields{
+		"job_id":  msg.JobID,
+		"user_id": msg.UserID,
+	})
+
+	job, err := h.store.GetJob(msg.JobID, msg.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logEntry.WithError(err).Warn("job does not exist")
+			return nil
+		}
+		logEntry.WithError(err).Error("unable to fetch job")
+		return err
+	}
+
+	// the running jobs are received again only when the previous run was interrupted.
+	if job.IsFinished() {
+		logEntry.WithField("status", job.Status).Info("job is already processed, skipping")
+		return nil
+	}
+
+	logEntry = logEntry.WithField("type", job.Type)
+
+	stop := h.heartbeat(ctx, m, logEntry)
+	err = h.runner.Run(ctx, job)
+	stop()
+
+	if err != nil {
+		if errors.Is(err, jobs.ErrRetry) {
+			logEntry.WithFields(logrus.Fields{
+				"attempts": job.Attempts,
+				"error":    job.Error,
+			}).Warn("job failed, retrying")
+			h.deferMessage(ctx, m, int32(jobs.Backoff(job.Attempts).Seconds()), logEntry)
+			return err
+		}
+		logEntry.WithError(err).Error("unable to run job")
+		return err
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"status": job.Status,
+		"error":  job.Error,
+	}).Info("job processed")
+
+	return nil
+}
+
+func (h *handler) DeleteMessage(ctx context.Context, m types.Message) error {
+	_, err := h.sqsclient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      h.queueURL,
+		ReceiptHandle: m.ReceiptHandle,
+	})
+	return err
+}
+
+// heartbeat extends the visibility timeout of the message until the returned func is called.
+func (h *handler) heartbeat(ctx context.Context, m types.Message, logEntry *logrus.Entry) func() {
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.deferMessage(ctx, m, heartbeatTimeout, logEntry)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// deferMessage hides the message from the queue for the given number of seconds.
+func (h *handler) deferMessage(ctx context.Context, m types.Message, seconds int32, logEntry *logrus.Entry) {
+	if seconds > maxVisibilityTimeout {
+		seconds = maxVisibilityTimeout
+	}
+
+	_, err := h.sqsclient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          h.queueURL,
+		ReceiptHandle:     m.ReceiptHandle,
+		VisibilityTimeout: seconds,
+	})
+	if err != nil {
+		logEntry.WithError(err).Error("Unable to change the message visibility timeout")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/google/wire"
+	"github.com/mailbadger/app/services/exporters"
+	"github.com/mailbadger/app/services/jobs"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/subscribers"
+	"github.com/mailbadger/app/services/webhooks"
+	awssqs "github.com/mailbadger/app/sqs"
+	awss3 "github.com/mailbadger/app/storage/s3"
+)
+
+//nolint
+var svcSet = wire.NewSet(
+	initAwsConfig,
+	awssqs.NewClient,
+	awss3.NewClient,
+	wire.Bind(new(s3iface.S3API), new(*s3.S3)),
+	wire.Bind(new(awssqs.SendReceiveMessageAPI), new(*sqs.Client)),
+	awssqs.GetJobsQueueURL,
+	awssqs.GetWebhookQueueURL,
+	newQueueURL,
+	awssqs.NewPublisher,
+	wire.Bind(new(awssqs.PublisherAPI), new(awssqs.Publisher)),
+	awssqs.NewConsumerFrom,
+	webhooks.New,
+	subscribers.New,
+	exporters.New,
+	wire.Bind(new(exporters.Exporter), new(exporters.Exporters)),
+	reports.New,
+	jobs.NewHandlers,
+	jobs.NewRunner,
+)
+
+func initAwsConfig(ctx context.Context) (aws.Config, error) {
+	return config.LoadDefaultConfig(ctx)
+}
+
+func newQueueURL(url awssqs.JobsQueueURL) awssqs.QueueURL {
+	return awssqs.QueueURL(url)
+}
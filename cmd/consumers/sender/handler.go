@@ -7,28 +7,33 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/textproto"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ses"
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/segmentio/ksuid"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
+	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/emails"
 	"github.com/mailbadger/app/entities"
 	awssqs "github.com/mailbadger/app/sqs"
 	"github.com/mailbadger/app/storage"
 	"github.com/mailbadger/app/storage/redis"
+	"github.com/mailbadger/app/utils"
 )
 
 // Sender errors
 var (
 	ErrInvalidSesKeys = errors.New("invalid ses keys")
+	ErrCampaignPaused = errors.New("campaign is paused")
 )
 
 // Cache prefix and duration parameters
@@ -37,25 +42,47 @@ const (
 	cacheDuration = 7 * 24 * time.Hour // & days cache duration
 )
 
-// CharSet is used for the SES message body charset
-const CharSet = "UTF-8"
+// pausedVisibilityTimeout is the number of seconds a message of a paused
+// campaign is hidden from the queue before it is checked again.
+const pausedVisibilityTimeout = 300
 
 type handler struct {
 	storage   storage.Storage
 	cache     redis.Store
 	sqsclient *sqs.Client
 	queueURL  awssqs.SendEmailQueueURL
+	throttler *throttler
+
+	// encryptionKey decrypts the passwords of the SMTP settings.
+	encryptionKey string
+
+	mu      sync.Mutex
+	mailers map[int64]cachedMailer
+}
+
+// cachedMailer is a user's SMTP mailer along with the version
+// of the settings it was created from.
+type cachedMailer struct {
+	mailer     emails.Mailer
+	settingsID int64
+	updatedAt  time.Time
 }
 
 func newHandler(
 	storage storage.Storage,
 	cache redis.Store,
+	sqsclient *sqs.Client,
 	queueURL awssqs.SendEmailQueueURL,
+	conf config.Config,
 ) *handler {
 	return &handler{
-		storage:  storage,
-		cache:    cache,
-		queueURL: queueURL,
+		storage:       storage,
+		cache:         cache,
+		sqsclient:     sqsclient,
+		queueURL:      queueURL,
+		throttler:     newThrottler(cache, fetchSesQuota),
+		encryptionKey: conf.Server.EncryptionKey,
+		mailers:       make(map[int64]cachedMailer),
 	}
 }
 
@@ -98,6 +125,59 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 		return nil
 	}
 
+	// transactional messages don't belong to a campaign, and the test emails
+	// are sent regardless of the campaign status.
+	var status string
+	if msg.TransactionalID == 0 && !msg.TestSend {
+		status, err = h.storage.GetCampaignStatus(msg.CampaignID, msg.UserID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logEntry.WithError(err).Error("Unable to fetch campaign status")
+			return err
+		}
+	}
+
+	switch status {
+	case entities.StatusPaused:
+		// keep the message in the queue until the campaign is resumed or cancelled.
+		logEntry.Info("Campaign is paused, deferring message")
+		h.deferMessage(ctx, m, pausedVisibilityTimeout, logEntry)
+		return ErrCampaignPaused
+	case entities.StatusCancelled:
+		logEntry.Info("Campaign is cancelled, skipping message")
+		err = h.storage.CreateSendLog(&entities.SendLog{
+			ID:           ksuid.New(),
+			EventID:      msg.EventID,
+			UserID:       msg.UserID,
+			CampaignID:   msg.CampaignID,
+			SubscriberID: msg.SubscriberID,
+			Status:       entities.SendLogStatusFailed,
+			Description:  entities.SendLogDescriptionOnCampaignCancelled,
+		})
+		if err != nil {
+			logEntry.WithError(err).Error("Unable to add log for cancelled campaign.")
+		}
+		return nil
+	}
+
+	settings, err := h.storage.GetSMTPSettings(msg.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logEntry.WithError(err).Error("Unable to fetch smtp settings")
+		return err
+	}
+
+	// SMTP relays don't expose a send quota, only SES sends are throttled.
+	if settings == nil {
+		allowed, wait, err := h.throttler.Take(ctx, msg.UserID, msg.SesKeys)
+		if err != nil {
+			// SES throttling errors are still retried when the quota is unknown.
+			logEntry.WithError(err).Warn("Unable to check the send rate")
+		} else if !allowed {
+			logEntry.WithField("wait", wait.String()).Info("Send rate exceeded, deferring message")
+			h.deferMessage(ctx, m, visibilityTimeout(wait), logEntry)
+			return ErrThrottled
+		}
+	}
+
 	if err := h.cache.Set(ctx, cacheKey, []byte("1"), cacheDuration); err != nil {
 		logEntry.WithError(err).Error("Unable to write to cache")
 		return err
@@ -114,7 +194,24 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 	}
 
 	defer func() {
-		if err == nil {
+		if err == nil && msg.TransactionalID != 0 {
+			// transactional messages keep the send result on their own record.
+			status := entities.TransactionalStatusSent
+			if sendLog.Status != entities.SendLogStatusSuccessful {
+				status = entities.TransactionalStatusFailed
+			}
+			err = h.storage.UpdateTransactionalMessageStatus(
+				msg.TransactionalID,
+				msg.UserID,
+				status,
+				sendLog.Description,
+				sendLog.MessageID,
+			)
+			if err != nil {
+				logEntry.WithField("transactional_id", msg.TransactionalID).
+					WithError(err).Error("Unable to update transactional message for sent email result.")
+			}
+		} else if err == nil && !msg.TestSend {
 			err = h.storage.CreateSendLog(sendLog)
 			if err != nil {
 				logrus.WithFields(logrus.Fields{
@@ -128,24 +225,42 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 		}
 	}()
 
-	client, err := newSesClient(msg.SesKeys)
-	if err != nil {
-		logEntry.WithError(err).Error("Unable to create ses sender")
+	var mailer emails.Mailer
+	if settings != nil {
+		mailer, err = h.smtpMailer(settings)
+		if err != nil {
+			logEntry.WithError(err).Error("Unable to create smtp sender")
 
-		sendLog.Status = entities.StatusFailed
-		sendLog.Description = entities.SendLogDescriptionOnSesClientError
+			sendLog.Status = entities.StatusFailed
+			sendLog.Description = entities.SendLogDescriptionOnSMTPClientError
 
-		return nil
+			return nil
+		}
+	} else {
+		mailer, err = newSesMailer(msg.SesKeys, msg.ConfigurationSetExists)
+		if err != nil {
+			logEntry.WithError(err).Error("Unable to create ses sender")
+
+			sendLog.Status = entities.StatusFailed
+			sendLog.Description = entities.SendLogDescriptionOnSesClientError
+
+			return nil
+		}
 	}
 
-	resp, err := sendEmail(client, *msg)
+	messageID, err := mailer.Send(ctx, newMessage(*msg))
 	if err != nil {
 		sendLog.Status = entities.StatusFailed
 		sendLog.Description = entities.SendLogDescriptionOnSendEmailError
 
 		// First check errors for retrying (returning) they don't need to be inserted in send logs
 		// also if the error is retryable delete it from cache
-		if aerr, ok := err.(awserr.Error); ok {
+		var (
+			aerr awserr.Error
+			serr *textproto.Error
+		)
+		switch {
+		case errors.As(err, &aerr):
 			switch aerr.Code() {
 			case ses.ErrCodeMessageRejected:
 				sendLog.Description = "Unable to send email, message rejected."
@@ -178,7 +293,11 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 				}
 				return err
 			}
-		} else {
+		case errors.As(err, &serr) && serr.Code >= 500:
+			// 5xx replies are permanent failures, retrying won't help.
+			sendLog.Description = "Unable to send email, message rejected by the SMTP server."
+			logEntry.WithError(serr).Error("Unable to send email. Message rejected by the SMTP server.")
+		default:
 			logEntry.WithError(err).Error("Unable to send templated email.")
 			rerr := h.cache.Delete(ctx, cacheKey)
 			if rerr != nil {
@@ -188,8 +307,8 @@ func (h *handler) HandleMessage(ctx context.Context, m types.Message) (err error
 		}
 	}
 
-	if resp != nil {
-		sendLog.MessageID = resp.MessageId
+	if messageID != "" {
+		sendLog.MessageID = &messageID
 	}
 
 	return nil
@@ -203,6 +322,67 @@ func (h *handler) DeleteMessage(ctx context.Context, m types.Message) error {
 	return err
 }
 
+// Close closes the pooled SMTP connections.
+func (h *handler) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, c := range h.mailers {
+		if err := c.mailer.Close(); err != nil {
+			logrus.WithField("user_id", id).WithError(err).Warn("Unable to close smtp mailer")
+		}
+		delete(h.mailers, id)
+	}
+}
+
+// smtpMailer returns the pooled SMTP mailer for the user, a new one is
+// created when the user's settings have changed since it was cached.
+func (h *handler) smtpMailer(s *entities.SMTPSettings) (emails.Mailer, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c, ok := h.mailers[s.UserID]; ok {
+		if c.settingsID == s.ID && c.updatedAt.Equal(s.UpdatedAt) {
+			return c.mailer, nil
+		}
+		_ = c.mailer.Close()
+		delete(h.mailers, s.UserID)
+	}
+
+	password, err := utils.Decrypt(s.Password, h.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt smtp password: %w", err)
+	}
+
+	m := emails.NewSMTPMailer(emails.SMTPConfig{
+		Host:       s.Host,
+		Port:       s.Port,
+		Username:   s.Username,
+		Password:   password,
+		Encryption: s.Encryption,
+		AuthMethod: s.AuthMethod,
+	})
+	h.mailers[s.UserID] = cachedMailer{
+		mailer:     m,
+		settingsID: s.ID,
+		updatedAt:  s.UpdatedAt,
+	}
+
+	return m, nil
+}
+
+// deferMessage hides the message from the queue for the given number of seconds.
+func (h *handler) deferMessage(ctx context.Context, m types.Message, timeout int32, logEntry *logrus.Entry) {
+	_, err := h.sqsclient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          h.queueURL,
+		ReceiptHandle:     m.ReceiptHandle,
+		VisibilityTimeout: timeout,
+	})
+	if err != nil {
+		logEntry.WithError(err).Error("Unable to change the message visibility timeout")
+	}
+}
+
 func newSesClient(keys entities.SesKeys) (emails.Sender, error) {
 	if keys.AccessKey == "" || keys.SecretKey == "" || keys.Region == "" {
 		return nil, ErrInvalidSesKeys
@@ -216,45 +396,52 @@ func newSesClient(keys entities.SesKeys) (emails.Sender, error) {
 	return client, nil
 }
 
-func sendEmail(client emails.Sender, msg entities.SenderTopicParams) (*ses.SendEmailOutput, error) {
-	input := &ses.SendEmailInput{
-		Destination: &ses.Destination{
-			ToAddresses: []*string{aws.String(msg.SubscriberEmail)},
-		},
-		Message: &ses.Message{
-			Body: &ses.Body{
-				Html: &ses.Content{
-					Charset: aws.String(CharSet),
-					Data:    aws.String(string(msg.HTMLPart)),
-				},
-				Text: &ses.Content{
-					Charset: aws.String(CharSet),
-					Data:    aws.String(string(msg.TextPart)),
-				},
-			},
-			Subject: &ses.Content{
-				Charset: aws.String(CharSet),
-				Data:    aws.String(string(msg.SubjectPart)),
-			},
-		},
-		Source: aws.String(msg.Source),
-		Tags: []*ses.MessageTag{
-			{
-				Name:  aws.String("campaign_id"),
-				Value: aws.String(strconv.FormatInt(msg.CampaignID, 10)),
-			},
-			{
-				Name:  aws.String("user_id"),
-				Value: aws.String(msg.UserUUID),
-			},
-		},
+func newSesMailer(keys entities.SesKeys, configurationSetExists bool) (emails.Mailer, error) {
+	client, err := newSesClient(keys)
+	if err != nil {
+		return nil, err
 	}
 
-	if msg.ConfigurationSetExists {
-		input.ConfigurationSetName = aws.String(emails.ConfigurationSetName)
+	var configurationSet string
+	if configurationSetExists {
+		configurationSet = emails.ConfigurationSetName
 	}
 
-	return client.SendEmail(input)
+	return emails.NewSesMailer(client, configurationSet), nil
+}
+
+func newMessage(msg entities.SenderTopicParams) *emails.Message {
+	m := &emails.Message{
+		From:    msg.Source,
+		To:      []string{msg.SubscriberEmail},
+		Subject: string(msg.SubjectPart),
+		HTML:    string(msg.HTMLPart),
+		Text:    string(msg.TextPart),
+		Tags: map[string]string{
+			"campaign_id": strconv.FormatInt(msg.CampaignID, 10),
+			"user_id":     msg.UserUUID,
+		},
+	}
+	// transactional messages are tracked by their own id along with the custom tags.
+	if msg.TransactionalID != 0 {
+		for k, v := range msg.Tags {
+			m.Tags[k] = v
+		}
+		delete(m.Tags, "campaign_id")
+		m.Tags[entities.TagTransactionalID] = strconv.FormatInt(msg.TransactionalID, 10)
+	}
+	// the variant is used to break down the split test stats.
+	if msg.VariantID != 0 {
+		m.Tags["variant_id"] = strconv.FormatInt(msg.VariantID, 10)
+	}
+	if msg.TrackOpens {
+		m.Tags[entities.TagTrackedOpens] = "true"
+	}
+	if msg.TrackClicks {
+		m.Tags[entities.TagTrackedClicks] = "true"
+	}
+	m.SetListUnsubscribe(msg.UnsubscribeURL, msg.UnsubscribeMailto)
+	return m
 }
 
 func genCacheKey(prefix string, key string) string {
@@ -47,4 +47,6 @@ func main() {
 	if err := g.Wait(); err != nil {
 		logrus.WithError(err).Error("received an error when handling a message")
 	}
+
+	app.handler.Close()
 }
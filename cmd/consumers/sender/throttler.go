@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ses"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage/redis"
+)
+
+// Throttling cache prefixes and durations
+const (
+	throttlePrefix      = "sender:throttle:"
+	quotaPrefix         = "sender:quota:"
+	quotaCacheDuration  = time.Hour
+	dailyWindowDuration = 24 * time.Hour
+)
+
+// maxVisibilityTimeout is the max visibility timeout (12 hours) allowed by SQS.
+const maxVisibilityTimeout = 12 * time.Hour
+
+// ErrThrottled is returned when the message exceeds the user's send rate or daily cap
+// and it has been deferred.
+var ErrThrottled = errors.New("send rate exceeded")
+
+// tokenBucketScript takes a token from the user's bucket refilled at the max send rate,
+// and counts the sends in the current 24 hour window. The counter is raised to the number
+// of emails SES has sent in the last 24 hours, so the sends before a restart are counted too.
+//
+// KEYS[1] - bucket key, KEYS[2] - daily counter key
+// ARGV[1] - rate (tokens per second), ARGV[2] - bucket capacity, ARGV[3] - now in ms,
+// ARGV[4] - daily cap (0 for unlimited), ARGV[5] - daily window in seconds,
+// ARGV[6] - sent in the last 24 hours
+//
+// Returns {allowed, wait in ms}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local cap = tonumber(ARGV[4])
+local sentLast24Hours = tonumber(ARGV[6])
+
+if cap > 0 then
+	local sent = tonumber(redis.call("GET", KEYS[2]) or "0")
+	if sent < sentLast24Hours then
+		sent = sentLast24Hours
+		local ttl = redis.call("PTTL", KEYS[2])
+		if ttl > 0 then
+			redis.call("SET", KEYS[2], sent, "PX", ttl)
+		else
+			redis.call("SET", KEYS[2], sent, "EX", ARGV[5])
+		end
+	end
+	if sent >= cap then
+		local ttl = redis.call("PTTL", KEYS[2])
+		if ttl < 0 then
+			ttl = tonumber(ARGV[5]) * 1000
+		end
+		return {0, ttl}
+	end
+end
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+	if redis.call("INCR", KEYS[2]) == 1 then
+		redis.call("EXPIRE", KEYS[2], ARGV[5])
+	end
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity * 1000 / rate) + 1000)
+
+return {allowed, wait}
+`)
+
+// quotaFetcher returns the SES send quota of the user.
+type quotaFetcher func(keys entities.SesKeys) (*entities.SendQuota, error)
+
+// throttler is a redis backed token bucket, keyed per user, which limits
+// the sends to the user's SES max send rate and 24 hour cap.
+type throttler struct {
+	cache      redis.Store
+	fetchQuota quotaFetcher
+	now        func() time.Time
+}
+
+func newThrottler(cache redis.Store, fetchQuota quotaFetcher) *throttler {
+	return &throttler{
+		cache:      cache,
+		fetchQuota: fetchQuota,
+		now:        time.Now,
+	}
+}
+
+// Take takes a token from the user's bucket. When the limit is exceeded it returns false
+// and the duration after which the message should be retried.
+func (t *throttler) Take(ctx context.Context, userID int64, keys entities.SesKeys) (bool, time.Duration, error) {
+	quota, err := t.quota(ctx, userID, keys)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if quota.MaxSendRate <= 0 {
+		// unknown rate, nothing to throttle against.
+		return true, 0, nil
+	}
+
+	capacity := math.Max(1, quota.MaxSendRate)
+	res, err := t.cache.Eval(
+		ctx,
+		tokenBucketScript,
+		[]string{
+			fmt.Sprintf("%sbucket:%d", throttlePrefix, userID),
+			fmt.Sprintf("%sdaily:%d", throttlePrefix, userID),
+		},
+		quota.MaxSendRate,
+		capacity,
+		t.now().UnixNano()/int64(time.Millisecond),
+		int64(math.Max(0, quota.Max24HourSend)),
+		int64(dailyWindowDuration/time.Second),
+		int64(math.Max(0, quota.SentLast24Hours)),
+	)
+	if err != nil {
+		return false, 0, fmt.Errorf("throttler: eval token bucket: %w", err)
+	}
+
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return false, 0, fmt.Errorf("throttler: unexpected token bucket result %v", res)
+	}
+	allowed, _ := vals[0].(int64)
+	wait, _ := vals[1].(int64)
+
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// quota returns the cached send quota of the user, the quota is fetched
+// from SES when the cached value is missing or expired.
+func (t *throttler) quota(ctx context.Context, userID int64, keys entities.SesKeys) (*entities.SendQuota, error) {
+	key := fmt.Sprintf("%s%d", quotaPrefix, userID)
+
+	quota := new(entities.SendQuota)
+	b, err := t.cache.Get(ctx, key)
+	if err == nil && json.Unmarshal(b, quota) == nil {
+		return quota, nil
+	}
+
+	quota, err = t.fetchQuota(keys)
+	if err != nil {
+		return nil, fmt.Errorf("throttler: fetch quota: %w", err)
+	}
+
+	b, err = json.Marshal(quota)
+	if err != nil {
+		return nil, fmt.Errorf("throttler: marshal quota: %w", err)
+	}
+
+	err = t.cache.Set(ctx, key, b, quotaCacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("throttler: cache quota: %w", err)
+	}
+
+	return quota, nil
+}
+
+// fetchSesQuota fetches the send quota of the SES account.
+func fetchSesQuota(keys entities.SesKeys) (*entities.SendQuota, error) {
+	client, err := newSesClient(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.GetSendQuota(&ses.GetSendQuotaInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	quota := new(entities.SendQuota)
+	if res.MaxSendRate != nil {
+		quota.MaxSendRate = *res.MaxSendRate
+	}
+	if res.Max24HourSend != nil {
+		quota.Max24HourSend = *res.Max24HourSend
+	}
+	if res.SentLast24Hours != nil {
+		quota.SentLast24Hours = *res.SentLast24Hours
+	}
+
+	return quota, nil
+}
+
+// visibilityTimeout converts the wait duration into a visibility timeout in seconds,
+// rounded up and bounded by the SQS limits.
+func visibilityTimeout(wait time.Duration) int32 {
+	if wait > maxVisibilityTimeout {
+		wait = maxVisibilityTimeout
+	}
+
+	secs := int32(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+
+	return secs
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage/redis"
+)
+
+var errNotFound = errors.New("not found")
+
+// fakeStore is an in-memory redis.Store, the results of the scripts are set by the tests.
+type fakeStore struct {
+	data map[string][]byte
+
+	evalRes  interface{}
+	evalErr  error
+	evalKeys []string
+	evalArgs []interface{}
+	evals    int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{data: make(map[string][]byte)}
+}
+
+func (f *fakeStore) Get(_ context.Context, key string) ([]byte, error) {
+	b, ok := f.data[key]
+	if !ok {
+		return nil, errNotFound
+	}
+	return b, nil
+}
+
+func (f *fakeStore) Set(_ context.Context, key string, content []byte, _ time.Duration) error {
+	f.data[key] = content
+	return nil
+}
+
+func (f *fakeStore) Delete(_ context.Context, key string) error {
+	delete(f.data, key)
+	return nil
+}
+
+func (f *fakeStore) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := f.data[key]
+	return ok, nil
+}
+
+func (f *fakeStore) Expire(_ context.Context, _ string, _ time.Duration) error {
+	return nil
+}
+
+func (f *fakeStore) Eval(_ context.Context, _ *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	f.evals++
+	f.evalKeys = keys
+	f.evalArgs = args
+	return f.evalRes, f.evalErr
+}
+
+func TestThrottlerTake(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	quota := &entities.SendQuota{
+		MaxSendRate:     14,
+		Max24HourSend:   50000,
+		SentLast24Hours: 1200,
+	}
+
+	tests := []struct {
+		name     string
+		quota    *entities.SendQuota
+		quotaErr error
+		evalRes  interface{}
+		evalErr  error
+		allowed  bool
+		wait     time.Duration
+		evals    int
+		err      bool
+	}{
+		{
+			name:    "allowed",
+			quota:   quota,
+			evalRes: []interface{}{int64(1), int64(0)},
+			allowed: true,
+			evals:   1,
+		},
+		{
+			name:    "throttled",
+			quota:   quota,
+			evalRes: []interface{}{int64(0), int64(1500)},
+			wait:    1500 * time.Millisecond,
+			evals:   1,
+		},
+		{
+			name:    "unknown rate",
+			quota:   &entities.SendQuota{},
+			allowed: true,
+		},
+		{
+			name:     "quota error",
+			quotaErr: errors.New("invalid keys"),
+			err:      true,
+		},
+		{
+			name:    "eval error",
+			quota:   quota,
+			evalErr: errors.New("connection refused"),
+			evals:   1,
+			err:     true,
+		},
+		{
+			name:    "unexpected result",
+			quota:   quota,
+			evalRes: int64(1),
+			evals:   1,
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newFakeStore()
+			cache.evalRes = tt.evalRes
+			cache.evalErr = tt.evalErr
+
+			th := newThrottler(cache, func(keys entities.SesKeys) (*entities.SendQuota, error) {
+				return tt.quota, tt.quotaErr
+			})
+			th.now = func() time.Time { return now }
+
+			allowed, wait, err := th.Take(context.Background(), 1, entities.SesKeys{})
+			assert.Equal(t, tt.err, err != nil)
+			assert.Equal(t, tt.allowed, allowed)
+			assert.Equal(t, tt.wait, wait)
+			assert.Equal(t, tt.evals, cache.evals)
+
+			if tt.evals > 0 {
+				assert.Equal(t, []string{"sender:throttle:bucket:1", "sender:throttle:daily:1"}, cache.evalKeys)
+				assert.Equal(t, []interface{}{
+					float64(14),
+					float64(14),
+					now.UnixNano() / int64(time.Millisecond),
+					int64(50000),
+					int64(86400),
+					int64(1200), // the daily counter is seeded with the sends of the last 24 hours.
+				}, cache.evalArgs)
+			}
+		})
+	}
+}
+
+func TestThrottlerQuota(t *testing.T) {
+	cache := newFakeStore()
+	fetched := 0
+	th := newThrottler(cache, func(keys entities.SesKeys) (*entities.SendQuota, error) {
+		fetched++
+		return &entities.SendQuota{MaxSendRate: 14, Max24HourSend: 50000, SentLast24Hours: 10}, nil
+	})
+
+	quota, err := th.quota(context.Background(), 1, entities.SesKeys{})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(14), quota.MaxSendRate)
+	assert.Equal(t, 1, fetched)
+
+	// the fetched quota is cached.
+	quota, err = th.quota(context.Background(), 1, entities.SesKeys{})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(10), quota.SentLast24Hours)
+	assert.Equal(t, 1, fetched)
+
+	// the quota of each user is cached separately.
+	b, err := json.Marshal(entities.SendQuota{MaxSendRate: 1})
+	assert.Nil(t, err)
+	cache.data["sender:quota:2"] = b
+
+	quota, err = th.quota(context.Background(), 2, entities.SesKeys{})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1), quota.MaxSendRate)
+	assert.Equal(t, 1, fetched)
+
+	// an invalid cached value is fetched again.
+	cache.data["sender:quota:3"] = []byte("{")
+	_, err = th.quota(context.Background(), 3, entities.SesKeys{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, fetched)
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	tests := []struct {
+		wait     time.Duration
+		expected int32
+	}{
+		{0, 1},
+		{200 * time.Millisecond, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+		{13 * time.Hour, 43200},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, visibilityTimeout(tt.wait), tt.wait.String())
+	}
+}
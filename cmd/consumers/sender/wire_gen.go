@@ -32,7 +32,7 @@ func initApp(ctx context.Context, conf config.Config) (app, error) {
 	if err != nil {
 		return app{}, err
 	}
-	mainHandler := newHandler(storageStorage, redisStore, sendEmailQueueURL)
+	mainHandler := newHandler(storageStorage, redisStore, client, sendEmailQueueURL, conf)
 	queueURL := newQueueURL(sendEmailQueueURL)
 	consumer := sqs.NewConsumerFrom(conf, queueURL, client)
 	mainApp := newApp(mainHandler, consumer)
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/services/webhooks"
+	awssqs "github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+)
+
+// maxVisibilityTimeout is the max number of seconds a message can be hidden from the queue.
+const maxVisibilityTimeout = 43200
+
+type handler struct {
+	store      storage.Storage
+	webhooksvc webhooks.Service
+	sqsclient  *sqs.Client
+	queueURL   awssqs.WebhookQueueURL
+}
+
+func newHandler(
+	store storage.Storage,
+	webhooksvc webhooks.Service,
+	sqsclient *sqs.Client,
+	queueURL awssqs.WebhookQueueURL,
+) *handler {
+	return &handler{
+		store:      store,
+		webhooksvc: webhooksvc,
+		sqsclient:  sqsclient,
+		queueURL:   queueURL,
+	}
+}
+
+// HandleMessage delivers the webhook event. When the delivery fails the message
+// is kept in the queue and hidden until the next attempt.
+func (h *handler) HandleMessage(ctx context.Context, m types.Message) error {
+	if m.Body == nil || len(*m.Body) == 0 {
+		logrus.Error("Empty message, unable to proceed.")
+		return nil
+	}
+
+	msg := new(entities.WebhookTopicParams)
+	err := json.Unmarshal([]byte(*m.Body), msg)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to unmarshal message")
+		return nil
+	}
+
+	logEntry := logrus.WithFields(logrus.Fields{
+		"delivery_id": msg.DeliveryID,
+		"user_id":     msg.UserID,
+	})
+
+	d, err := h.store.GetWebhookDelivery(msg.DeliveryID, msg.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logEntry.WithError(err).Warn("webhook delivery does not exist")
+			return nil
+		}
+		logEntry.WithError(err).Error("unable to fetch webhook delivery")
+		return err
+	}
+
+	if d.Status != entities.WebhookDeliveryStatusPending {
+		logEntry.WithField("status", d.Status).Info("webhook delivery is already processed, skipping")
+		return nil
+	}
+
+	logEntry = logEntry.WithFields(logrus.Fields{
+		"webhook_id": d.WebhookID,
+		"event_type": d.EventType,
+	})
+
+	err = h.webhooksvc.Deliver(ctx, d)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrRetry) {
+			logEntry.WithFields(logrus.Fields{
+				"attempts":        d.Attempts,
+				"response_status": d.ResponseStatus,
+			}).Warn("webhook delivery failed, retrying")
+			h.deferMessage(ctx, m, webhooks.Backoff(d.Attempts).Seconds(), logEntry)
+			return err
+		}
+		logEntry.WithError(err).Error("unable to deliver webhook event")
+		return err
+	}
+
+	logEntry.WithField("status", d.Status).Info("webhook delivery processed")
+
+	return nil
+}
+
+func (h *handler) DeleteMessage(ctx context.Context, m types.Message) error {
+	_, err := h.sqsclient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      h.queueURL,
+		ReceiptHandle: m.ReceiptHandle,
+	})
+	return err
+}
+
+// deferMessage hides the message from the queue for the given number of seconds.
+func (h *handler) deferMessage(ctx context.Context, m types.Message, seconds float64, logEntry *logrus.Entry) {
+	timeout := int32(seconds)
+	if timeout > maxVisibilityTimeout {
+		timeout = maxVisibilityTimeout
+	}
+
+	_, err := h.sqsclient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          h.queueURL,
+		ReceiptHandle:     m.ReceiptHandle,
+		VisibilityTimeout: timeout,
+	})
+	if err != nil {
+		logEntry.WithError(err).Error("Unable to change the message visibility timeout")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/google/wire"
+	"github.com/mailbadger/app/services/webhooks"
+	awssqs "github.com/mailbadger/app/sqs"
+)
+
+//nolint
+var svcSet = wire.NewSet(
+	initAwsConfig,
+	awssqs.NewClient,
+	wire.Bind(new(awssqs.SendReceiveMessageAPI), new(*sqs.Client)),
+	awssqs.GetWebhookQueueURL,
+	newQueueURL,
+	awssqs.NewPublisher,
+	wire.Bind(new(awssqs.PublisherAPI), new(awssqs.Publisher)),
+	awssqs.NewConsumerFrom,
+	webhooks.New,
+)
+
+func initAwsConfig(ctx context.Context) (aws.Config, error) {
+	return config.LoadDefaultConfig(ctx)
+}
+
+func newQueueURL(url awssqs.WebhookQueueURL) awssqs.QueueURL {
+	return awssqs.QueueURL(url)
+}
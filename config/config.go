@@ -53,10 +53,12 @@ type Server struct {
 	AppDir              string `envconfig:"MB_APP_DIR"`
 	AppURL              string `envconfig:"MB_APP_URL"`
 	UnsubscribeSecret   string `envconfig:"MB_APP_UNSUBSCRIBE_SECRET"`
+	UnsubscribeMailto   string `envconfig:"MB_APP_UNSUBSCRIBE_MAILTO"`
 	SystemEmailSource   string `envconfig:"MB_APP_SYSTEM_EMAIL_SOURCE"`
 	EnableSignup        bool   `envconfig:"MB_APP_ENABLE_SIGNUP"`
 	VerifyEmailOnSignup bool   `envconfig:"MB_APP_VERIFY_EMAIL_ON_SIGNUP"`
 	RecaptchaSecret     string `envconfig:"MB_APP_RECAPTCHA_SECRET"`
+	EncryptionKey       string `envconfig:"MB_APP_ENCRYPTION_KEY"`
 }
 
 type Logging struct {
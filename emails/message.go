@@ -0,0 +1,187 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CharSet is the charset used for every part of an outgoing message.
+const CharSet = "UTF-8"
+
+// Mailer delivers provider-neutral messages through a concrete
+// backend such as SES or an SMTP relay.
+type Mailer interface {
+	// Send delivers the message and returns the message id assigned to it.
+	Send(ctx context.Context, m *Message) (string, error)
+	// Close releases any resources (connections) held by the mailer.
+	Close() error
+}
+
+// Message is a provider-neutral email message.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+	// Headers holds additional MIME headers, e.g. List-Unsubscribe.
+	Headers map[string]string
+	// Tags are key/value pairs attached to the message, used for
+	// event attribution (e.g. campaign_id, user_id).
+	Tags map[string]string
+}
+
+// Bytes encodes the message as an RFC 5322 message with a
+// multipart/alternative body when both html and text parts are set.
+func (m *Message) Bytes() ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("emails: message has no recipients")
+	}
+
+	h := make(textproto.MIMEHeader)
+	for k, v := range m.Headers {
+		h.Set(k, sanitizeHeader(v))
+	}
+	h.Set("From", encodeAddress(m.From))
+	to := make([]string, len(m.To))
+	for i, addr := range m.To {
+		to[i] = encodeAddress(addr)
+	}
+	h.Set("To", strings.Join(to, ", "))
+	h.Set("Subject", mime.QEncoding.Encode(CharSet, sanitizeHeader(m.Subject)))
+	h.Set("MIME-Version", "1.0")
+	if h.Get("Date") == "" {
+		h.Set("Date", time.Now().Format(time.RFC1123Z))
+	}
+
+	var body bytes.Buffer
+	switch {
+	case m.HTML != "" && m.Text != "":
+		w := multipart.NewWriter(&body)
+		h.Set("Content-Type", "multipart/alternative; boundary="+w.Boundary())
+		if err := writePart(w, "text/plain", m.Text); err != nil {
+			return nil, err
+		}
+		if err := writePart(w, "text/html", m.HTML); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("emails: close multipart writer: %w", err)
+		}
+	case m.HTML != "":
+		h.Set("Content-Type", contentType("text/html"))
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		if err := writeQuotedPrintable(&body, m.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		h.Set("Content-Type", contentType("text/plain"))
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		if err := writeQuotedPrintable(&body, m.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, h)
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// SetListUnsubscribe sets the RFC 2369 List-Unsubscribe header with the one-click
+// unsubscribe url and the optional mailto address. When the url is set, the RFC 8058
+// List-Unsubscribe-Post header is set as well, so mailbox providers can unsubscribe
+// the recipient with a single POST request.
+func (m *Message) SetListUnsubscribe(url, mailto string) {
+	var uris []string
+	if url != "" {
+		uris = append(uris, "<"+url+">")
+	}
+	if mailto != "" {
+		uris = append(uris, "<mailto:"+mailto+"?subject=unsubscribe>")
+	}
+	if len(uris) == 0 {
+		return
+	}
+
+	if m.Headers == nil {
+		m.Headers = make(map[string]string)
+	}
+	m.Headers["List-Unsubscribe"] = strings.Join(uris, ", ")
+	if url != "" {
+		m.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+}
+
+// sortedTags returns the tag keys in a deterministic order.
+func (m *Message) sortedTags() []string {
+	keys := make([]string, 0, len(m.Tags))
+	for k := range m.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writePart(w *multipart.Writer, mediaType, content string) error {
+	ph := make(textproto.MIMEHeader)
+	ph.Set("Content-Type", contentType(mediaType))
+	ph.Set("Content-Transfer-Encoding", "quoted-printable")
+	pw, err := w.CreatePart(ph)
+	if err != nil {
+		return fmt.Errorf("emails: create %s part: %w", mediaType, err)
+	}
+	return writeQuotedPrintable(pw, content)
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(content)); err != nil {
+		return fmt.Errorf("emails: encode body: %w", err)
+	}
+	return qw.Close()
+}
+
+func writeHeader(buf *bytes.Buffer, h textproto.MIMEHeader) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
+	}
+}
+
+func contentType(mediaType string) string {
+	return mediaType + "; charset=" + CharSet
+}
+
+// encodeAddress formats the address so that non-ascii display names
+// are encoded, falling back to the raw value when it cannot be parsed.
+func encodeAddress(addr string) string {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return sanitizeHeader(addr)
+	}
+	return a.String()
+}
+
+// sanitizeHeader strips line breaks to prevent header injection.
+func sanitizeHeader(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
@@ -15,6 +15,11 @@ func (m *MockSender) SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput,
 	return nil, args.Error(1)
 }
 
+func (m *MockSender) SendRawEmail(input *ses.SendRawEmailInput) (*ses.SendRawEmailOutput, error) {
+	args := m.Called(input)
+	return nil, args.Error(1)
+}
+
 func (m *MockSender) CreateConfigurationSet(input *ses.CreateConfigurationSetInput) (*ses.CreateConfigurationSetOutput, error) {
 	args := m.Called(input)
 	return nil, args.Error(1)
@@ -9,6 +9,7 @@ import (
 
 type Sender interface {
 	SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput, error)
+	SendRawEmail(input *ses.SendRawEmailInput) (*ses.SendRawEmailOutput, error)
 	CreateConfigurationSet(input *ses.CreateConfigurationSetInput) (*ses.CreateConfigurationSetOutput, error)
 	DescribeConfigurationSet(input *ses.DescribeConfigurationSetInput) (*ses.DescribeConfigurationSetOutput, error)
 	CreateConfigurationSetEventDestination(input *ses.CreateConfigurationSetEventDestinationInput) (*ses.CreateConfigurationSetEventDestinationOutput, error)
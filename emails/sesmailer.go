@@ -0,0 +1,102 @@
+package emails
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+)
+
+type sesMailer struct {
+	client           Sender
+	configurationSet string
+}
+
+// NewSesMailer creates a Mailer which delivers messages using the SES client.
+// When the configuration set name is not empty it is attached to every
+// message so that SES publishes the sending events.
+func NewSesMailer(client Sender, configurationSet string) Mailer {
+	return &sesMailer{
+		client:           client,
+		configurationSet: configurationSet,
+	}
+}
+
+// Send sends the message with SendEmail, or with SendRawEmail when the
+// message has custom headers which SendEmail does not support.
+func (m *sesMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	var tags []*ses.MessageTag
+	for _, k := range msg.sortedTags() {
+		tags = append(tags, &ses.MessageTag{
+			Name:  aws.String(k),
+			Value: aws.String(msg.Tags[k]),
+		})
+	}
+
+	var configSet *string
+	if m.configurationSet != "" {
+		configSet = aws.String(m.configurationSet)
+	}
+
+	if len(msg.Headers) > 0 {
+		raw, err := msg.Bytes()
+		if err != nil {
+			return "", err
+		}
+
+		res, err := m.client.SendRawEmail(&ses.SendRawEmailInput{
+			Destinations:         aws.StringSlice(msg.To),
+			Source:               aws.String(msg.From),
+			RawMessage:           &ses.RawMessage{Data: raw},
+			Tags:                 tags,
+			ConfigurationSetName: configSet,
+		})
+		if err != nil || res == nil {
+			return "", err
+		}
+
+		return messageID(res.MessageId), nil
+	}
+
+	res, err := m.client.SendEmail(&ses.SendEmailInput{
+		Destination: &ses.Destination{
+			ToAddresses: aws.StringSlice(msg.To),
+		},
+		Message: &ses.Message{
+			Body: &ses.Body{
+				Html: &ses.Content{
+					Charset: aws.String(CharSet),
+					Data:    aws.String(msg.HTML),
+				},
+				Text: &ses.Content{
+					Charset: aws.String(CharSet),
+					Data:    aws.String(msg.Text),
+				},
+			},
+			Subject: &ses.Content{
+				Charset: aws.String(CharSet),
+				Data:    aws.String(msg.Subject),
+			},
+		},
+		Source:               aws.String(msg.From),
+		Tags:                 tags,
+		ConfigurationSetName: configSet,
+	})
+	if err != nil || res == nil {
+		return "", err
+	}
+
+	return messageID(res.MessageId), nil
+}
+
+// Close is a no-op, the SES client holds no long-lived connections.
+func (m *sesMailer) Close() error {
+	return nil
+}
+
+func messageID(id *string) string {
+	if id == nil {
+		return ""
+	}
+	return *id
+}
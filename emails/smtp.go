@@ -0,0 +1,319 @@
+package emails
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// SMTP encryption modes.
+const (
+	SMTPEncryptionNone     = "none"
+	SMTPEncryptionSTARTTLS = "starttls"
+	SMTPEncryptionTLS      = "tls"
+)
+
+// SMTP authentication mechanisms.
+const (
+	SMTPAuthPlain = "plain"
+	SMTPAuthLogin = "login"
+)
+
+// Default SMTP connection parameters.
+const (
+	DefaultSMTPPoolSize = 5
+	DefaultSMTPTimeout  = 30 * time.Second
+)
+
+// SMTP errors
+var (
+	ErrSTARTTLSNotSupported = errors.New("smtp: server does not support STARTTLS")
+	ErrUnencryptedAuth      = errors.New("smtp: refusing to authenticate over an unencrypted connection")
+	ErrUnknownAuthMethod    = errors.New("smtp: unknown auth method")
+)
+
+// SMTPConfig holds the connection parameters of an SMTP relay.
+type SMTPConfig struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Encryption string
+	AuthMethod string
+	// PoolSize is the max number of idle connections kept open.
+	PoolSize int
+	// Timeout is applied when dialing and on every delivery.
+	Timeout time.Duration
+}
+
+type smtpConn struct {
+	conn   net.Conn
+	client *smtp.Client
+}
+
+type smtpMailer struct {
+	conf SMTPConfig
+	pool chan *smtpConn
+}
+
+// NewSMTPMailer creates a Mailer which delivers messages through the SMTP relay
+// described by the config. Connections are reused across messages, up to
+// conf.PoolSize idle connections are kept open.
+func NewSMTPMailer(conf SMTPConfig) Mailer {
+	if conf.PoolSize <= 0 {
+		conf.PoolSize = DefaultSMTPPoolSize
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultSMTPTimeout
+	}
+	if conf.Encryption == "" {
+		conf.Encryption = SMTPEncryptionSTARTTLS
+	}
+
+	return &smtpMailer{
+		conf: conf,
+		pool: make(chan *smtpConn, conf.PoolSize),
+	}
+}
+
+// Send delivers the message and returns the generated Message-Id.
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return "", fmt.Errorf("smtp: parse from address: %w", err)
+	}
+
+	rcpts := make([]string, len(msg.To))
+	for i, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return "", fmt.Errorf("smtp: parse recipient address: %w", err)
+		}
+		rcpts[i] = addr.Address
+	}
+
+	id := fmt.Sprintf("<%s@%s>", ksuid.New().String(), domain(from.Address))
+
+	headers := make(map[string]string, len(msg.Headers)+1)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers["Message-Id"] = id
+	if len(msg.Tags) > 0 {
+		tags := make([]string, 0, len(msg.Tags))
+		for _, k := range msg.sortedTags() {
+			tags = append(tags, k+"="+msg.Tags[k])
+		}
+		headers["X-Mailbadger-Tags"] = strings.Join(tags, "; ")
+	}
+
+	withHeaders := *msg
+	withHeaders.Headers = headers
+	raw, err := withHeaders.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	c, err := m.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	err = m.deliver(c, from.Address, rcpts, raw)
+	if err != nil {
+		// the connection state is unknown, don't return it to the pool
+		_ = c.client.Close()
+		return "", err
+	}
+
+	m.put(c)
+
+	return id, nil
+}
+
+// Close closes all idle connections in the pool.
+func (m *smtpMailer) Close() error {
+	for {
+		select {
+		case c := <-m.pool:
+			_ = c.client.Quit()
+		default:
+			return nil
+		}
+	}
+}
+
+func (m *smtpMailer) deliver(c *smtpConn, from string, rcpts []string, raw []byte) error {
+	if err := c.conn.SetDeadline(time.Now().Add(m.conf.Timeout)); err != nil {
+		return fmt.Errorf("smtp: set deadline: %w", err)
+	}
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range rcpts {
+		if err := c.client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// get returns an idle connection from the pool, or dials a new one when the
+// pool is empty or the idle connection has been closed by the server.
+func (m *smtpMailer) get(ctx context.Context) (*smtpConn, error) {
+	for {
+		select {
+		case c := <-m.pool:
+			_ = c.conn.SetDeadline(time.Now().Add(m.conf.Timeout))
+			if err := c.client.Reset(); err == nil {
+				return c, nil
+			}
+			_ = c.client.Close()
+		default:
+			return m.dial(ctx)
+		}
+	}
+}
+
+func (m *smtpMailer) put(c *smtpConn) {
+	_ = c.conn.SetDeadline(time.Time{})
+	select {
+	case m.pool <- c:
+	default:
+		_ = c.client.Quit()
+	}
+}
+
+func (m *smtpMailer) dial(ctx context.Context) (*smtpConn, error) {
+	addr := net.JoinHostPort(m.conf.Host, strconv.Itoa(m.conf.Port))
+	dialer := &net.Dialer{Timeout: m.conf.Timeout}
+	tlsConf := &tls.Config{
+		ServerName: m.conf.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if m.conf.Encryption == SMTPEncryptionTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConf}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smtp: dial %s: %w", addr, err)
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(m.conf.Timeout)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp: set deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, m.conf.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp: new client: %w", err)
+	}
+
+	if m.conf.Encryption == SMTPEncryptionSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, ErrSTARTTLSNotSupported
+		}
+		if err := client.StartTLS(tlsConf); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("smtp: starttls: %w", err)
+		}
+	}
+
+	if m.conf.Username != "" {
+		auth, err := m.auth()
+		if err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+		if err := client.Auth(auth); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("smtp: auth: %w", err)
+		}
+	}
+
+	return &smtpConn{conn: conn, client: client}, nil
+}
+
+func (m *smtpMailer) auth() (smtp.Auth, error) {
+	switch m.conf.AuthMethod {
+	case SMTPAuthPlain, "":
+		return smtp.PlainAuth("", m.conf.Username, m.conf.Password, m.conf.Host), nil
+	case SMTPAuthLogin:
+		return &loginAuth{
+			username: m.conf.Username,
+			password: m.conf.Password,
+			host:     m.conf.Host,
+		}, nil
+	default:
+		return nil, ErrUnknownAuthMethod
+	}
+}
+
+// loginAuth implements the AUTH LOGIN mechanism which net/smtp lacks.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Same restriction as smtp.PlainAuth, credentials are sent in clear text.
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, ErrUnencryptedAuth
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("smtp: wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("smtp: unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+func domain(addr string) string {
+	i := strings.LastIndex(addr, "@")
+	if i < 0 {
+		return "localhost"
+	}
+	return addr[i+1:]
+}
@@ -0,0 +1,178 @@
+package emails
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer accepts plain text SMTP sessions and records the
+// received messages and the number of accepted connections.
+type fakeSMTPServer struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns int
+	msgs  []string
+	rcpts []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{ln: ln}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			_ = tp.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if strings.Contains(line, "reject@") {
+				_ = tp.PrintfLine("550 mailbox unavailable")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, line[len("RCPT TO:"):])
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "DATA"):
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.msgs = append(s.msgs, string(data))
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 OK queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+	defer srv.ln.Close()
+
+	m := NewSMTPMailer(SMTPConfig{
+		Host:       "localhost",
+		Port:       srv.port(),
+		Encryption: SMTPEncryptionNone,
+		PoolSize:   1,
+	})
+	defer m.Close()
+
+	msg := &Message{
+		From:    "Foo <foo@example.com>",
+		To:      []string{"bar@example.com"},
+		Subject: "Hello",
+		HTML:    "<p>hello</p>",
+		Text:    "hello",
+		Tags:    map[string]string{"campaign_id": "1"},
+	}
+
+	for i := 0; i < 3; i++ {
+		id, err := m.Send(context.Background(), msg)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasSuffix(id, "@example.com>"))
+	}
+
+	srv.mu.Lock()
+	assert.Equal(t, 1, srv.conns, "connection should be reused")
+	assert.Len(t, srv.msgs, 3)
+	assert.Equal(t, "<bar@example.com>", srv.rcpts[0])
+	assert.Contains(t, srv.msgs[0], "X-Mailbadger-Tags: campaign_id=1")
+	srv.mu.Unlock()
+
+	msg.To = []string{"reject@example.com"}
+	_, err := m.Send(context.Background(), msg)
+	var serr *textproto.Error
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, 550, serr.Code)
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg := &Message{
+		From:    "Foo <foo@example.com>",
+		To:      []string{"bar@example.com"},
+		Subject: "Hello\r\nBcc: evil@example.com",
+		HTML:    "<p>hello</p>",
+		Text:    "hello",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+
+	raw, err := msg.Bytes()
+	assert.Nil(t, err)
+
+	tp := textproto.NewReader(bufio.NewReader(strings.NewReader(string(raw))))
+	h, err := tp.ReadMIMEHeader()
+	assert.Nil(t, err)
+	assert.Equal(t, `"Foo" <foo@example.com>`, h.Get("From"))
+	assert.Equal(t, "<bar@example.com>", h.Get("To"))
+	assert.Equal(t, "HelloBcc: evil@example.com", h.Get("Subject"))
+	assert.Empty(t, h.Get("Bcc"))
+	assert.Equal(t, "<https://example.com/unsubscribe>", h.Get("List-Unsubscribe"))
+	assert.True(t, strings.HasPrefix(h.Get("Content-Type"), "multipart/alternative; boundary="))
+
+	msg.To = nil
+	_, err = msg.Bytes()
+	assert.NotNil(t, err)
+}
+
+func TestSetListUnsubscribe(t *testing.T) {
+	msg := &Message{}
+	msg.SetListUnsubscribe("", "")
+	assert.Nil(t, msg.Headers)
+
+	msg.SetListUnsubscribe("", "unsubscribe@example.com")
+	assert.Equal(t, map[string]string{
+		"List-Unsubscribe": "<mailto:unsubscribe@example.com?subject=unsubscribe>",
+	}, msg.Headers)
+
+	msg.SetListUnsubscribe("https://example.com/api/unsubscribe/one-click?t=foo", "unsubscribe@example.com")
+	assert.Equal(t, map[string]string{
+		"List-Unsubscribe":      "<https://example.com/api/unsubscribe/one-click?t=foo>, <mailto:unsubscribe@example.com?subject=unsubscribe>",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}, msg.Headers)
+}
@@ -1,15 +1,115 @@
 package entities
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix is prepended to the generated api keys, so they are easier to recognize.
+const APIKeyPrefix = "mb_"
+
+// APIKeyPrefixLength is the number of leading characters of the key that are
+// stored as the prefix of the key, the "mb_" prefix followed by 8 random characters.
+const APIKeyPrefixLength = len(APIKeyPrefix) + 8
+
+// APIKeyScopeAll grants read and write access to all of the api key resources,
+// including the ones that are added later on.
+const APIKeyScopeAll = "*"
+
+// APIKeyResources are the resources of the API which can be accessed with api keys,
+// each resource is granted with a read (GET requests) or write scope, e.g. subscribers:write.
+var APIKeyResources = []string{
+	"campaigns",
+	"forms",
+	"jobs",
+	"metrics",
+	"partials",
+	"reports",
+	"s3",
+	"segments",
+	"ses",
+	"smtp",
+	"subscribers",
+	"templates",
+	"transactional",
+	"users",
+	"webhooks",
+}
 
 // APIKey represents the user key used to authenticate requests
-// with the API.
+// with the API. Only the hash of the key is stored, the key is shown
+// once when it is created or rotated.
 type APIKey struct {
-	ID        int64     `json:"id" gorm:"column:id; primary_key:yes"`
-	UserID    int64     `json:"-"`
-	User      User      `json:"-"`
-	SecretKey string    `json:"secret_key"`
-	Active    bool      `json:"active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         int64      `json:"id" gorm:"column:id; primary_key:yes"`
+	UserID     int64      `json:"-"`
+	User       User       `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"column:key_prefix"`
+	SecretKey  string     `json:"-"`
+	Key        string     `json:"key,omitempty" gorm:"-"`
+	Scopes     StringList `json:"scopes"`
+	Active     bool       `json:"active"`
+	LastUsedAt NullTime   `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// SetKey sets the plaintext key which is returned in the response,
+// along with the prefix and the hash which are stored.
+func (k *APIKey) SetKey(key string) {
+	k.Key = key
+	k.Prefix = key[:APIKeyPrefixLength]
+	k.SecretKey = HashAPIKey(key)
+}
+
+// HashAPIKey returns the hex encoded sha256 hash of the key. The keys are
+// random and long enough that a fast hash can be used to look them up.
+func HashAPIKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// GrantedScopes returns the scopes of the key, with the all scope expanded
+// to the read and write scopes of every resource.
+func (k *APIKey) GrantedScopes() []string {
+	for _, s := range k.Scopes {
+		if s != APIKeyScopeAll {
+			continue
+		}
+
+		scopes := make([]string, 0, len(APIKeyResources)*2)
+		for _, r := range APIKeyResources {
+			scopes = append(scopes, r+":read", r+":write")
+		}
+		return scopes
+	}
+
+	return []string(k.Scopes)
+}
+
+// IsValidScope checks if the scope is the all scope, or grants read or write access to a known resource.
+func IsValidScope(scope string) bool {
+	if scope == APIKeyScopeAll {
+		return true
+	}
+
+	i := strings.LastIndex(scope, ":")
+	if i == -1 {
+		return false
+	}
+
+	access := scope[i+1:]
+	if access != "read" && access != "write" {
+		return false
+	}
+
+	for _, r := range APIKeyResources {
+		if r == scope[:i] {
+			return true
+		}
+	}
+
+	return false
 }
@@ -7,6 +7,7 @@ type Bounce struct {
 	ID             int64     `json:"id" gorm:"column:id; primary_key:yes"`
 	UserID         int64     `json:"-"`
 	CampaignID     int64     `json:"campaign_id"`
+	VariantID      int64     `json:"variant_id"`
 	Recipient      string    `json:"recipient"`
 	Type           string    `json:"type"`
 	SubType        string    `json:"sub_type"`
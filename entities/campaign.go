@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/cbroglie/mustache"
@@ -16,21 +17,31 @@ const (
 	StatusSent = "sent"
 	// StatusScheduled indicates a scheduled campaign status.
 	StatusScheduled = "scheduled"
+	// StatusPaused indicates that the sending process of the campaign has been halted
+	// and can be resumed from where it stopped.
+	StatusPaused = "paused"
+	// StatusCancelled indicates that the sending process of the campaign has been stopped for good.
+	StatusCancelled = "cancelled"
 )
 
 // Campaign represents the campaign entity
 type Campaign struct {
 	Model
-	UserID       int64             `json:"-" gorm:"column:user_id; index"`
-	EventID      *ksuid.KSUID      `json:"-"`
-	Name         string            `json:"name" gorm:"not null"`
-	TemplateID   int64             `json:"-"`
-	BaseTemplate *BaseTemplate     `json:"template" gorm:"foreignKey:template_id"`
-	Schedule     *CampaignSchedule `json:"schedule" gorm:"foreignKey:campaign_id"`
-	Status       string            `json:"status"`
-	CompletedAt  NullTime          `json:"completed_at" gorm:"column:completed_at"`
-	DeletedAt    NullTime          `json:"-" gorm:"column:deleted_at"`
-	StartedAt    NullTime          `json:"started_at" gorm:"column:started_at"`
+	UserID          int64              `json:"-" gorm:"column:user_id; index"`
+	EventID         *ksuid.KSUID       `json:"-"`
+	Name            string             `json:"name" gorm:"not null"`
+	TemplateID      int64              `json:"-"`
+	TemplateVersion int64              `json:"template_version"`
+	BaseTemplate    *BaseTemplate      `json:"template" gorm:"foreignKey:template_id"`
+	Schedule        *CampaignSchedule  `json:"schedule" gorm:"foreignKey:campaign_id"`
+	SplitTest       *CampaignSplitTest `json:"split_test" gorm:"foreignKey:campaign_id"`
+	Status          string             `json:"status"`
+	TrackOpens      bool               `json:"track_opens"`
+	TrackClicks     bool               `json:"track_clicks"`
+	ExcludedLinks   JSON               `json:"excluded_links"`
+	CompletedAt     NullTime           `json:"completed_at" gorm:"column:completed_at"`
+	DeletedAt       NullTime           `json:"-" gorm:"column:deleted_at"`
+	StartedAt       NullTime           `json:"started_at" gorm:"column:started_at"`
 }
 
 // CampaignerTopicParams represent the request params used
@@ -40,6 +51,7 @@ type CampaignerTopicParams struct {
 	CampaignID             int64             `json:"campaign_id"`
 	SegmentIDs             []int64           `json:"segment_ids"`
 	TemplateData           map[string]string `json:"template_data"`
+	TemplateVersion        int64             `json:"template_version"`
 	Source                 string            `json:"source"`
 	UserID                 int64             `json:"user_id"`
 	UserUUID               string            `json:"user_uuid"`
@@ -54,6 +66,7 @@ type SenderTopicParams struct {
 	UserID                 int64       `json:"user_id"`
 	UserUUID               string      `json:"user_uuid"`
 	CampaignID             int64       `json:"campaign_id"`
+	VariantID              int64       `json:"variant_id,omitempty"`
 	SubscriberID           int64       `json:"subscriber_id"`
 	SubscriberEmail        string      `json:"subscriber_email"`
 	Source                 string      `json:"source"`
@@ -62,6 +75,17 @@ type SenderTopicParams struct {
 	SubjectPart            []byte      `json:"subject_part"`
 	TextPart               []byte      `json:"text_part"`
 	SesKeys                SesKeys     `json:"ses_keys"`
+	// TransactionalID and Tags are set only on emails sent through the transactional API.
+	TransactionalID int64             `json:"transactional_id,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	// UnsubscribeURL and UnsubscribeMailto are set as List-Unsubscribe headers.
+	UnsubscribeURL    string `json:"unsubscribe_url,omitempty"`
+	UnsubscribeMailto string `json:"unsubscribe_mailto,omitempty"`
+	// TrackOpens and TrackClicks are set when the opens and clicks are tracked by the app.
+	TrackOpens  bool `json:"track_opens,omitempty"`
+	TrackClicks bool `json:"track_clicks,omitempty"`
+	// TestSend is set on the test emails of a campaign, they are neither logged nor tracked.
+	TestSend bool `json:"test_send,omitempty"`
 }
 
 type CampaignTemplateData struct {
@@ -69,6 +93,8 @@ type CampaignTemplateData struct {
 	HTMLPart    *mustache.Template
 	SubjectPart *mustache.Template
 	TextPart    *mustache.Template
+	// Helpers evaluate the helper tags of the parts, the parts are rendered with Helpers.Data.
+	Helpers TemplateHelpers
 }
 
 // CampaignClicksStats represents clicks stats by campaign, total number of links and stats for each link
@@ -89,6 +115,38 @@ func (c Campaign) GetUpdatedAt() time.Time {
 	return c.Model.UpdatedAt
 }
 
+// GetExcludedLinks returns the links which are not tracked, the links
+// starting with any of the excluded links are left as they are.
+func (c *Campaign) GetExcludedLinks() ([]string, error) {
+	var links []string
+	if c.ExcludedLinks.IsNull() {
+		return links, nil
+	}
+
+	err := json.Unmarshal(c.ExcludedLinks, &links)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// SetExcludedLinks stores the links which are not tracked.
+func (c *Campaign) SetExcludedLinks(links []string) error {
+	if len(links) == 0 {
+		c.ExcludedLinks = nil
+		return nil
+	}
+
+	b, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+	c.ExcludedLinks = b
+
+	return nil
+}
+
 // SetCampaignEventID if the campaign is scheduled then sets the id to the scheduled campaign's id else generates new id
 func (c *Campaign) SetEventID() {
 	if c.Schedule != nil {
@@ -109,10 +167,11 @@ type OpensStats struct {
 }
 
 type CampaignStats struct {
-	TotalSent  int64        `json:"total_sent"`
-	Delivered  int64        `json:"delivered"`
-	Opens      *OpensStats  `json:"opens"`
-	Clicks     *ClicksStats `json:"clicks"`
-	Bounces    int64        `json:"bounces"`
-	Complaints int64        `json:"complaints"`
+	TotalSent  int64          `json:"total_sent"`
+	Delivered  int64          `json:"delivered"`
+	Opens      *OpensStats    `json:"opens"`
+	Clicks     *ClicksStats   `json:"clicks"`
+	Bounces    int64          `json:"bounces"`
+	Complaints int64          `json:"complaints"`
+	Variants   []VariantStats `json:"variants,omitempty"`
 }
@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// CampaignCheckpoint holds the position of the campaigner in the subscribers
+// list of a campaign, used to resume a paused campaign from where it stopped.
+// PausedAt is set by the campaigner once it has noticed the pause and stopped.
+type CampaignCheckpoint struct {
+	ID               int64       `json:"-" gorm:"column:id; primary_key:yes"`
+	UserID           int64       `json:"-" gorm:"column:user_id; index"`
+	CampaignID       int64       `json:"campaign_id" gorm:"column:campaign_id"`
+	EventID          ksuid.KSUID `json:"event_id"`
+	ParamsJSON       JSON        `json:"-" gorm:"column:params; type:json"`
+	LastSubscriberID int64       `json:"last_subscriber_id"`
+	LastCreatedAt    NullTime    `json:"last_created_at"`
+	PausedAt         NullTime    `json:"paused_at"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// GetParams returns the campaigner params the campaign was started with.
+func (c *CampaignCheckpoint) GetParams() (*CampaignerTopicParams, error) {
+	p := new(CampaignerTopicParams)
+	if c.ParamsJSON.IsNull() {
+		return p, nil
+	}
+
+	err := json.Unmarshal(c.ParamsJSON, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SetParams stores the campaigner params without the SES keys,
+// they are fetched again when the campaign is resumed.
+func (c *CampaignCheckpoint) SetParams(p CampaignerTopicParams) error {
+	p.SesKeys = SesKeys{}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	c.ParamsJSON = b
+
+	return nil
+}
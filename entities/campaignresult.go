@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// CampaignRecipientResult holds the results of the campaign email sent to a single recipient.
+type CampaignRecipientResult struct {
+	SendLogID    string    `json:"send_log_id"`
+	SubscriberID int64     `json:"subscriber_id"`
+	Email        string    `json:"email"`
+	Status       string    `json:"status"`
+	Description  string    `json:"description"`
+	Delivered    bool      `json:"delivered"`
+	Opens        int64     `json:"opens"`
+	ClickedLinks []string  `json:"clicked_links"`
+	BounceType   string    `json:"bounce_type"`
+	Complained   bool      `json:"complained"`
+	SentAt       time.Time `json:"sent_at"`
+}
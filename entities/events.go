@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"strconv"
+	"time"
+)
+
+// EventsTopicParams represent the SES notification received by the hook,
+// which is processed by the events consumer.
+type EventsTopicParams struct {
+	UserUUID string `json:"user_uuid"`
+	Message  string `json:"message"`
+}
+
+// ProcessedEvent holds the key of an SES event which is already stored,
+// SNS may deliver the same notification more than once.
+type ProcessedEvent struct {
+	ID        int64  `gorm:"column:id; primary_key:yes"`
+	Key       string `gorm:"column:event_key"`
+	CreatedAt time.Time
+}
+
+// EventKey returns the idempotency key of an SES event, made of the
+// SES message id, the event type and the timestamp of the event.
+func EventKey(messageID, eventType string, t time.Time) string {
+	return messageID + ":" + eventType + ":" + strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// TransactionalEvent is an SES event of a transactional message.
+type TransactionalEvent struct {
+	ID     int64
+	UserID int64
+	Event  string
+}
+
+// EventsBatch holds the records of the SES events which are stored together.
+type EventsBatch struct {
+	Processed     []ProcessedEvent
+	Bounces       []Bounce
+	Complaints    []Complaint
+	Deliveries    []Delivery
+	Sends         []Send
+	Clicks        []Click
+	Opens         []Open
+	Transactional []TransactionalEvent
+}
+
+// IsEmpty checks if the batch has no events.
+func (b *EventsBatch) IsEmpty() bool {
+	return len(b.Processed) == 0
+}
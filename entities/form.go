@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Form represents a public signup form, through which the visitors of
+// the user's website subscribe to the form segments.
+type Form struct {
+	Model
+	UserID                 int64     `json:"-" gorm:"column:user_id; index"`
+	UUID                   string    `json:"uuid" gorm:"column:uuid; not null"`
+	Name                   string    `json:"name" gorm:"not null"`
+	MetadataFieldsJSON     JSON      `json:"metadata_fields" gorm:"column:metadata_fields; type:json"`
+	RedirectURL            string    `json:"redirect_url"`
+	ConfirmRedirectURL     string    `json:"confirm_redirect_url"`
+	DoubleOptIn            bool      `json:"double_opt_in"`
+	ConfirmationTemplateID int64     `json:"confirmation_template_id,omitempty"`
+	Source                 string    `json:"source,omitempty"`
+	FromName               string    `json:"from_name,omitempty"`
+	Segments               []Segment `json:"segments" gorm:"many2many:forms_segments;"`
+}
+
+// GetMetadataFields returns the metadata fields which the form accepts.
+func (f Form) GetMetadataFields() ([]string, error) {
+	var fields []string
+	if f.MetadataFieldsJSON.IsNull() {
+		return fields, nil
+	}
+
+	err := json.Unmarshal(f.MetadataFieldsJSON, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// SetMetadataFields stores the metadata fields which the form accepts.
+func (f *Form) SetMetadataFields(fields []string) error {
+	if fields == nil {
+		fields = []string{}
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	f.MetadataFieldsJSON = b
+
+	return nil
+}
+
+// FilterMetadata returns only the metadata fields allowed by the form,
+// the rest of the submitted fields are dropped.
+func (f Form) FilterMetadata(m map[string]string) (map[string]string, error) {
+	fields, err := f.GetMetadataFields()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make(map[string]string)
+	for _, field := range fields {
+		if v, ok := m[field]; ok && v != "" {
+			filtered[field] = v
+		}
+	}
+
+	return filtered, nil
+}
+
+func (f Form) GetID() int64 {
+	return f.Model.ID
+}
+
+func (f Form) GetCreatedAt() time.Time {
+	return f.Model.CreatedAt
+}
+
+func (f Form) GetUpdatedAt() time.Time {
+	return f.Model.UpdatedAt
+}
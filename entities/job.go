@@ -0,0 +1,115 @@
+package entities
+
+import (
+	"time"
+)
+
+// Job statuses
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// Job types
+const (
+	JobTypeImportSubscribers     = "import_subscribers"
+	JobTypeBulkRemoveSubscribers = "bulk_remove_subscribers"
+	JobTypeExportReport          = "export_report"
+	JobTypeSESKeys               = "ses_keys"
+)
+
+// JobMaxAttempts is the default number of times a job is attempted before it fails.
+const JobMaxAttempts = 3
+
+// Job represents a long running task of the user which is processed in the background
+// by the jobs consumer. The payload holds the parameters of the job type, it is not
+// exposed since it can contain credentials.
+type Job struct {
+	Model
+	UserID      int64    `json:"-" gorm:"column:user_id; index"`
+	Type        string   `json:"type"`
+	Status      string   `json:"status"`
+	Payload     JSON     `json:"-"`
+	Processed   int64    `json:"processed"`
+	Total       int64    `json:"total"`
+	Attempts    int      `json:"attempts"`
+	MaxAttempts int      `json:"max_attempts"`
+	Error       string   `json:"error"`
+	Result      JSON     `json:"result,omitempty"`
+	StartedAt   NullTime `json:"started_at"`
+	CompletedAt NullTime `json:"completed_at"`
+}
+
+func (j Job) GetID() int64 {
+	return j.Model.ID
+}
+
+func (j Job) GetCreatedAt() time.Time {
+	return j.Model.CreatedAt
+}
+
+func (j Job) GetUpdatedAt() time.Time {
+	return j.Model.UpdatedAt
+}
+
+// IsFinished checks if the job is done or failed, the finished jobs are not processed again.
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusDone || j.Status == JobStatusFailed
+}
+
+// Import modes, they define how the existing subscribers are handled by the import.
+const (
+	// ImportModeSkip leaves the existing subscribers unchanged.
+	ImportModeSkip = "skip"
+	// ImportModeUpdate replaces the name and metadata of the existing subscribers.
+	ImportModeUpdate = "update"
+	// ImportModeMerge updates the name and merges the imported metadata into
+	// the metadata of the existing subscribers.
+	ImportModeMerge = "merge"
+)
+
+// ImportSubscribersJob is the payload of the import subscribers job. The mapping
+// maps the columns of the file to the subscriber fields.
+type ImportSubscribersJob struct {
+	Filename   string            `json:"filename"`
+	SegmentIDs []int64           `json:"segment_ids"`
+	Mapping    map[string]string `json:"mapping,omitempty"`
+	Mode       string            `json:"mode,omitempty"`
+}
+
+// ImportSubscribersResult is the result of the import subscribers job, the rows
+// which can't be imported are saved with the reasons in the rejects file.
+type ImportSubscribersResult struct {
+	Created     int64  `json:"created"`
+	Updated     int64  `json:"updated"`
+	Skipped     int64  `json:"skipped"`
+	Rejected    int64  `json:"rejected"`
+	RejectsFile string `json:"rejects_file,omitempty"`
+}
+
+// BulkRemoveSubscribersJob is the payload of the bulk remove subscribers job.
+type BulkRemoveSubscribersJob struct {
+	Filename string `json:"filename"`
+}
+
+// ExportReportJob is the payload of the export report job.
+type ExportReportJob struct {
+	ReportID int64 `json:"report_id"`
+}
+
+// SESKeysJob is the payload of the job which creates the AWS resources for the SES keys
+// of the user, the keys are stored once the resources are created.
+type SESKeysJob struct {
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	Region    string `json:"region"`
+}
+
+// JobTopicParams represent the request params used
+// by the jobs consumer.
+type JobTopicParams struct {
+	JobID  int64 `json:"job_id"`
+	UserID int64 `json:"user_id"`
+}
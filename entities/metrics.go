@@ -0,0 +1,161 @@
+package entities
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxMetricsBuckets is the max number of buckets of the metrics time series.
+const MaxMetricsBuckets = 1000
+
+// topCampaignsLimit is the number of campaigns listed in the top campaigns of the metrics.
+const topCampaignsLimit = 5
+
+// SubscriberMetricsBucket holds the subscriber changes which happened in a single interval.
+type SubscriberMetricsBucket struct {
+	Time         time.Time `json:"time"`
+	Created      int64     `json:"created"`
+	Unsubscribed int64     `json:"unsubscribed"`
+	Net          int64     `json:"net"`
+	Updated      int64     `json:"updated"`
+	OptedIn      int64     `json:"opted_in"`
+	OptedOut     int64     `json:"opted_out"`
+	Paused       int64     `json:"paused"`
+	Resumed      int64     `json:"resumed"`
+}
+
+// CampaignEngagement holds the engagement of a sent campaign.
+type CampaignEngagement struct {
+	CampaignID   int64     `json:"campaign_id"`
+	Name         string    `json:"name"`
+	CompletedAt  time.Time `json:"completed_at"`
+	TotalSent    int64     `json:"total_sent"`
+	Delivered    int64     `json:"delivered"`
+	UniqueOpens  int64     `json:"unique_opens"`
+	UniqueClicks int64     `json:"unique_clicks"`
+	OpenRate     float64   `json:"open_rate"`
+	ClickRate    float64   `json:"click_rate"`
+}
+
+// MonthlyEngagement holds the average rates of the campaigns sent in the month.
+type MonthlyEngagement struct {
+	Month        time.Time `json:"month"`
+	Campaigns    int64     `json:"campaigns"`
+	AvgOpenRate  float64   `json:"avg_open_rate"`
+	AvgClickRate float64   `json:"avg_click_rate"`
+}
+
+// SubscriberMetricsReport represents the account analytics: the subscriber growth and churn
+// bucketed by the interval and the engagement of the campaigns sent in the date range.
+type SubscriberMetricsReport struct {
+	From         time.Time                 `json:"from"`
+	To           time.Time                 `json:"to"`
+	Interval     string                    `json:"interval"`
+	Created      int64                     `json:"created"`
+	Unsubscribed int64                     `json:"unsubscribed"`
+	Net          int64                     `json:"net"`
+	Buckets      []SubscriberMetricsBucket `json:"buckets"`
+	Engagement   []MonthlyEngagement       `json:"engagement"`
+	TopCampaigns []CampaignEngagement      `json:"top_campaigns"`
+}
+
+// CountIntervals returns the number of intervals between from and to, counting
+// stops once the limit is exceeded.
+func CountIntervals(from, to time.Time, interval string, limit int) int {
+	n := 0
+	for t := TruncateToInterval(from, interval); t.Before(to) && n <= limit; t = NextInterval(t, interval) {
+		n++
+	}
+
+	return n
+}
+
+// NewSubscriberMetricsReport buckets the hourly subscriber metrics by the interval and
+// aggregates the engagement of the campaigns. The range is from inclusive to exclusive.
+func NewSubscriberMetricsReport(
+	from, to time.Time,
+	interval string,
+	metrics []SubscriberMetrics,
+	campaigns []CampaignEngagement,
+) *SubscriberMetricsReport {
+	r := &SubscriberMetricsReport{
+		From:         from,
+		To:           to,
+		Interval:     interval,
+		Buckets:      []SubscriberMetricsBucket{},
+		Engagement:   []MonthlyEngagement{},
+		TopCampaigns: []CampaignEngagement{},
+	}
+
+	idx := make(map[time.Time]int)
+	for t := TruncateToInterval(from, interval); t.Before(to); t = NextInterval(t, interval) {
+		idx[t] = len(r.Buckets)
+		r.Buckets = append(r.Buckets, SubscriberMetricsBucket{Time: t})
+	}
+
+	for _, m := range metrics {
+		i, ok := idx[TruncateToInterval(m.Datetime, interval)]
+		if !ok {
+			continue
+		}
+
+		b := &r.Buckets[i]
+		b.Created += m.Created
+		b.Unsubscribed += m.Unsubscribed
+		b.Net += m.Created - m.Unsubscribed
+		b.Updated += m.Updated
+		b.OptedIn += m.OptedIn
+		b.OptedOut += m.OptedOut
+		b.Paused += m.Paused
+		b.Resumed += m.Resumed
+
+		r.Created += m.Created
+		r.Unsubscribed += m.Unsubscribed
+	}
+	r.Net = r.Created - r.Unsubscribed
+
+	r.setEngagement(campaigns)
+
+	return r
+}
+
+func (r *SubscriberMetricsReport) setEngagement(campaigns []CampaignEngagement) {
+	months := make(map[time.Time]*MonthlyEngagement)
+	for i := range campaigns {
+		c := &campaigns[i]
+		c.OpenRate = rate(c.UniqueOpens, c.Delivered)
+		c.ClickRate = rate(c.UniqueClicks, c.Delivered)
+
+		month := TruncateToInterval(c.CompletedAt, StatsIntervalMonth)
+		m, ok := months[month]
+		if !ok {
+			m = &MonthlyEngagement{Month: month}
+			months[month] = m
+		}
+		m.Campaigns++
+		m.AvgOpenRate += c.OpenRate
+		m.AvgClickRate += c.ClickRate
+	}
+
+	for _, m := range months {
+		m.AvgOpenRate = roundRate(m.AvgOpenRate / float64(m.Campaigns))
+		m.AvgClickRate = roundRate(m.AvgClickRate / float64(m.Campaigns))
+		r.Engagement = append(r.Engagement, *m)
+	}
+	sort.Slice(r.Engagement, func(i, j int) bool {
+		return r.Engagement[i].Month.Before(r.Engagement[j].Month)
+	})
+
+	top := make([]CampaignEngagement, len(campaigns))
+	copy(top, campaigns)
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].OpenRate != top[j].OpenRate {
+			return top[i].OpenRate > top[j].OpenRate
+		}
+		return top[i].ClickRate > top[j].ClickRate
+	})
+	if len(top) > topCampaignsLimit {
+		top = top[:topCampaignsLimit]
+	}
+	r.TopCampaigns = append(r.TopCampaigns, top...)
+}
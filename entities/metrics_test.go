@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateToInterval(t *testing.T) {
+	// wednesday
+	tm := time.Date(2021, 9, 15, 13, 45, 10, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2021, 9, 15, 13, 0, 0, 0, time.UTC), TruncateToInterval(tm, StatsIntervalHour))
+	assert.Equal(t, time.Date(2021, 9, 15, 0, 0, 0, 0, time.UTC), TruncateToInterval(tm, StatsIntervalDay))
+	assert.Equal(t, time.Date(2021, 9, 13, 0, 0, 0, 0, time.UTC), TruncateToInterval(tm, StatsIntervalWeek))
+	assert.Equal(t, time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC), TruncateToInterval(tm, StatsIntervalMonth))
+
+	// the weeks start on monday, sunday belongs to the previous week.
+	sunday := time.Date(2021, 9, 19, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2021, 9, 13, 0, 0, 0, 0, time.UTC), TruncateToInterval(sunday, StatsIntervalWeek))
+
+	assert.Equal(t, time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), NextInterval(time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC), StatsIntervalMonth))
+	assert.Equal(t, 5, CountIntervals(time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), StatsIntervalWeek, 100))
+	assert.Equal(t, 11, CountIntervals(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), StatsIntervalDay, 10))
+}
+
+func TestNewSubscriberMetricsReport(t *testing.T) {
+	from := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	metrics := []SubscriberMetrics{
+		{Created: 5, Unsubscribed: 1, Datetime: from.Add(3 * time.Hour)},
+		{Created: 2, Unsubscribed: 4, OptedOut: 1, Resumed: 1, Datetime: from.AddDate(0, 0, 20)},
+		{Created: 1, Datetime: from.AddDate(0, 1, 5)},
+	}
+	campaigns := []CampaignEngagement{
+		{CampaignID: 1, CompletedAt: from.AddDate(0, 0, 2), Delivered: 10, UniqueOpens: 5, UniqueClicks: 1},
+		{CampaignID: 2, CompletedAt: from.AddDate(0, 0, 9), Delivered: 10, UniqueOpens: 3, UniqueClicks: 2},
+		{CampaignID: 3, CompletedAt: from.AddDate(0, 1, 1), Delivered: 4, UniqueOpens: 3},
+		{CampaignID: 4, CompletedAt: from.AddDate(0, 1, 2)},
+	}
+
+	r := NewSubscriberMetricsReport(from, to, StatsIntervalMonth, metrics, campaigns)
+	assert.Equal(t, int64(8), r.Created)
+	assert.Equal(t, int64(5), r.Unsubscribed)
+	assert.Equal(t, int64(3), r.Net)
+
+	assert.Len(t, r.Buckets, 2)
+	assert.Equal(t, from, r.Buckets[0].Time)
+	assert.Equal(t, int64(7), r.Buckets[0].Created)
+	assert.Equal(t, int64(2), r.Buckets[0].Net)
+	assert.Equal(t, int64(1), r.Buckets[0].OptedOut)
+	assert.Equal(t, int64(1), r.Buckets[0].Resumed)
+	assert.Equal(t, int64(1), r.Buckets[1].Net)
+
+	assert.Len(t, r.Engagement, 2)
+	assert.Equal(t, MonthlyEngagement{Month: from, Campaigns: 2, AvgOpenRate: 0.4, AvgClickRate: 0.15}, r.Engagement[0])
+	assert.Equal(t, int64(2), r.Engagement[1].Campaigns)
+	assert.Equal(t, 0.375, r.Engagement[1].AvgOpenRate)
+
+	assert.Len(t, r.TopCampaigns, 4)
+	assert.Equal(t, int64(3), r.TopCampaigns[0].CampaignID)
+	assert.Equal(t, 0.75, r.TopCampaigns[0].OpenRate)
+	assert.Equal(t, int64(1), r.TopCampaigns[1].CampaignID)
+	assert.Equal(t, int64(4), r.TopCampaigns[3].CampaignID)
+
+	// the empty days are included in the buckets.
+	r = NewSubscriberMetricsReport(from, from.AddDate(0, 0, 7), StatsIntervalDay, metrics, nil)
+	assert.Len(t, r.Buckets, 7)
+	assert.Equal(t, int64(5), r.Buckets[0].Created)
+	assert.Equal(t, int64(0), r.Buckets[6].Created)
+	assert.Empty(t, r.TopCampaigns)
+	assert.NotNil(t, r.Engagement)
+}
@@ -0,0 +1,16 @@
+package params
+
+import "strings"
+
+// APIKey represents request body for POST /api/api-keys & PUT /api/api-keys/{id}
+type APIKey struct {
+	Name   string   `json:"name" validate:"required,max=191"`
+	Scopes []string `json:"scopes" validate:"required,min=1,max=50,dive,required,max=50"`
+}
+
+func (p *APIKey) TrimSpaces() {
+	p.Name = strings.TrimSpace(p.Name)
+	for i := range p.Scopes {
+		p.Scopes[i] = strings.TrimSpace(p.Scopes[i])
+	}
+}
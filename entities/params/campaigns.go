@@ -6,24 +6,39 @@ import (
 
 // PostCampaign represents request body for POST /api/campaigns
 type PostCampaign struct {
-	Name         string `json:"name" validate:"required,max=191"`
-	TemplateName string `json:"template_name" validate:"required,max=191"`
+	Name          string   `json:"name" validate:"required,max=191"`
+	TemplateName  string   `json:"template_name" validate:"required,max=191"`
+	TrackOpens    bool     `json:"track_opens"`
+	TrackClicks   bool     `json:"track_clicks"`
+	ExcludedLinks []string `json:"excluded_links" validate:"max=100,dive,required,url,max=2000"`
 }
 
 func (p *PostCampaign) TrimSpaces() {
 	p.Name = strings.TrimSpace(p.Name)
 	p.TemplateName = strings.TrimSpace(p.TemplateName)
+	trimLinks(p.ExcludedLinks)
 }
 
 // PutCampaign represents request body for PUT /api/campaigns/{id}
 type PutCampaign struct {
-	Name         string `json:"name" validate:"required,max=191"`
-	TemplateName string `json:"template_name" validate:"required,max=191"`
+	Name          string   `json:"name" validate:"required,max=191"`
+	TemplateName  string   `json:"template_name" validate:"required,max=191"`
+	TrackOpens    bool     `json:"track_opens"`
+	TrackClicks   bool     `json:"track_clicks"`
+	ExcludedLinks []string `json:"excluded_links" validate:"max=100,dive,required,url,max=2000"`
 }
 
 func (p *PutCampaign) TrimSpaces() {
 	p.Name = strings.TrimSpace(p.Name)
 	p.TemplateName = strings.TrimSpace(p.TemplateName)
+	trimLinks(p.ExcludedLinks)
+}
+
+// trimLinks trims the spaces of the excluded links of the campaign.
+func trimLinks(links []string) {
+	for i := range links {
+		links[i] = strings.TrimSpace(links[i])
+	}
 }
 
 // StartCampaign represents request body for POST /api/campaigns/id/start
@@ -38,6 +53,24 @@ func (p *StartCampaign) TrimSpaces() {
 	p.FromName = strings.TrimSpace(p.FromName)
 }
 
+// TestSendCampaign represents request body for POST /api/campaigns/{id}/test-send
+type TestSendCampaign struct {
+	Emails              []string          `json:"emails" validate:"required,min=1,max=10,dive,required,email,max=191"`
+	Source              string            `json:"source" validate:"required,email,max=191"`
+	FromName            string            `json:"from_name" validate:"required,max=191"`
+	VariantID           int64             `json:"variant_id" validate:"omitempty,min=1"`
+	SubscriberID        int64             `json:"subscriber_id" validate:"omitempty,min=1"`
+	DefaultTemplateData map[string]string `json:"default_template_data" validate:"omitempty,dive,keys,required,alphanumhyphen,endkeys"`
+}
+
+func (p *TestSendCampaign) TrimSpaces() {
+	p.FromName = strings.TrimSpace(p.FromName)
+	p.Source = strings.TrimSpace(p.Source)
+	for i, e := range p.Emails {
+		p.Emails[i] = strings.TrimSpace(e)
+	}
+}
+
 type CampaignSchedule struct {
 	ScheduledAt         string            `json:"scheduled_at" validate:"required,datetime=2006-01-02 15:04:05,max=191"`
 	FromName            string            `json:"from_name" validate:"required,max=191"`
@@ -48,3 +81,18 @@ type CampaignSchedule struct {
 
 func (p *CampaignSchedule) TrimSpaces() {
 }
+
+// PutCampaignSplitTest represents request body for PUT /api/campaigns/{id}/split-test
+type PutCampaignSplitTest struct {
+	TemplateNames  []string `json:"template_names" validate:"required,min=2,max=4,dive,required,max=191"`
+	TestPercentage int      `json:"test_percentage" validate:"required,min=1,max=99"`
+	WaitMinutes    int64    `json:"wait_minutes" validate:"required,min=1,max=10080"`
+	WinnerCriteria string   `json:"winner_criteria" validate:"required,oneof=opens clicks"`
+}
+
+func (p *PutCampaignSplitTest) TrimSpaces() {
+	for i := range p.TemplateNames {
+		p.TemplateNames[i] = strings.TrimSpace(p.TemplateNames[i])
+	}
+	p.WinnerCriteria = strings.TrimSpace(p.WinnerCriteria)
+}
@@ -0,0 +1,44 @@
+package params
+
+import "strings"
+
+// Form represents request body for POST /api/forms & PUT /api/forms/{id}
+type Form struct {
+	Name                     string   `json:"name" validate:"required,max=191"`
+	SegmentIDs               []int64  `json:"segments" validate:"omitempty"`
+	MetadataFields           []string `json:"metadata_fields" validate:"omitempty,max=50,dive,required,alphanumhyphen,max=191"`
+	RedirectURL              string   `json:"redirect_url" validate:"omitempty,url,startswith=http,max=191"`
+	ConfirmRedirectURL       string   `json:"confirm_redirect_url" validate:"omitempty,url,startswith=http,max=191"`
+	DoubleOptIn              bool     `json:"double_opt_in"`
+	ConfirmationTemplateName string   `json:"confirmation_template_name" validate:"required_if=DoubleOptIn true,max=191"`
+	Source                   string   `json:"source" validate:"required_if=DoubleOptIn true,omitempty,email,max=191"`
+	FromName                 string   `json:"from_name" validate:"required_if=DoubleOptIn true,max=191"`
+}
+
+func (p *Form) TrimSpaces() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.RedirectURL = strings.TrimSpace(p.RedirectURL)
+	p.ConfirmRedirectURL = strings.TrimSpace(p.ConfirmRedirectURL)
+	p.ConfirmationTemplateName = strings.TrimSpace(p.ConfirmationTemplateName)
+	p.Source = strings.TrimSpace(p.Source)
+	p.FromName = strings.TrimSpace(p.FromName)
+	for i := range p.MetadataFields {
+		p.MetadataFields[i] = strings.TrimSpace(p.MetadataFields[i])
+	}
+}
+
+// FormSubscribe represents request body for POST /api/forms/{uuid}/subscribe,
+// the body is either JSON or an url encoded HTML form.
+type FormSubscribe struct {
+	Email    string            `json:"email" form:"email" validate:"required,email,max=191"`
+	Name     string            `json:"name" form:"name" validate:"omitempty,max=191"`
+	Metadata map[string]string `json:"metadata" form:"-" validate:"omitempty,dive,keys,required,alphanumhyphen,endkeys,max=191"`
+}
+
+func (p *FormSubscribe) TrimSpaces() {
+	p.Email = strings.TrimSpace(p.Email)
+	p.Name = strings.TrimSpace(p.Name)
+	for k, v := range p.Metadata {
+		p.Metadata[k] = strings.TrimSpace(v)
+	}
+}
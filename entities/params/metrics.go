@@ -0,0 +1,16 @@
+package params
+
+import "strings"
+
+// SubscriberMetrics represents the query params of GET /api/metrics/subscribers
+type SubscriberMetrics struct {
+	From     string `json:"from" form:"from" validate:"omitempty,datetime=2006-01-02"`
+	To       string `json:"to" form:"to" validate:"omitempty,datetime=2006-01-02"`
+	Interval string `json:"interval" form:"interval" validate:"omitempty,oneof=hour day week month"`
+}
+
+func (p *SubscriberMetrics) TrimSpaces() {
+	p.From = strings.TrimSpace(p.From)
+	p.To = strings.TrimSpace(p.To)
+	p.Interval = strings.TrimSpace(p.Interval)
+}
@@ -0,0 +1,16 @@
+package params
+
+import "strings"
+
+// PostReport represents request body for POST /api/reports
+type PostReport struct {
+	Resource   string `json:"resource" validate:"required,oneof=subscribers campaign_results bounces complaints send_logs"`
+	Format     string `json:"format" validate:"omitempty,oneof=csv json"`
+	CampaignID int64  `json:"campaign_id" validate:"required_if=Resource campaign_results,min=0"`
+	Gzip       bool   `json:"gzip"`
+}
+
+func (p *PostReport) TrimSpaces() {
+	p.Resource = strings.TrimSpace(p.Resource)
+	p.Format = strings.TrimSpace(p.Format)
+}
@@ -2,15 +2,21 @@ package params
 
 import (
 	"strings"
+
+	"github.com/mailbadger/app/entities"
 )
 
 // Segment represents request body for POST /api/segments & PUT /api/segments/{id}
 type Segment struct {
-	Name string `json:"name" validate:"required,max=191"`
+	Name   string                `json:"name" validate:"required,max=191"`
+	Type   string                `json:"type" validate:"omitempty,oneof=static dynamic"`
+	Rules  *entities.SegmentRule `json:"rules" validate:"required_if=Type dynamic"`
+	Public bool                  `json:"public"`
 }
 
 func (p *Segment) TrimSpaces() {
 	p.Name = strings.TrimSpace(p.Name)
+	p.Type = strings.TrimSpace(p.Type)
 }
 
 // SegmentSubs represents request body for PUT /api/segments/{id}/subscribers
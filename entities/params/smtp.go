@@ -0,0 +1,22 @@
+package params
+
+import (
+	"strings"
+)
+
+// PostSMTPSettings represents request body for POST /api/smtp/settings
+type PostSMTPSettings struct {
+	Host       string `json:"host" validate:"required,max=191"`
+	Port       int    `json:"port" validate:"required,min=1,max=65535"`
+	Username   string `json:"username" validate:"max=191"`
+	Password   string `json:"password" validate:"max=191"`
+	Encryption string `json:"encryption" validate:"required,oneof=none starttls tls"`
+	AuthMethod string `json:"auth_method" validate:"omitempty,oneof=plain login"`
+}
+
+func (p *PostSMTPSettings) TrimSpaces() {
+	p.Host = strings.TrimSpace(p.Host)
+	p.Username = strings.TrimSpace(p.Username)
+	p.Encryption = strings.TrimSpace(p.Encryption)
+	p.AuthMethod = strings.TrimSpace(p.AuthMethod)
+}
@@ -40,12 +40,18 @@ func (p *PostUnsubscribe) TrimSpaces() {
 
 // ImportSubscribers represents request body for POST /api/subscribers/import
 type ImportSubscribers struct {
-	Filename   string  `json:"filename" validate:"required"`
-	SegmentIDs []int64 `json:"segments" validate:"omitempty"`
+	Filename   string            `json:"filename" validate:"required"`
+	SegmentIDs []int64           `json:"segments" validate:"omitempty"`
+	Mapping    map[string]string `json:"mapping" validate:"omitempty"`
+	Mode       string            `json:"mode" validate:"omitempty,oneof=skip update merge"`
 }
 
 func (p *ImportSubscribers) TrimSpaces() {
 	p.Filename = strings.TrimSpace(p.Filename)
+	p.Mode = strings.TrimSpace(p.Mode)
+	for column, field := range p.Mapping {
+		p.Mapping[column] = strings.TrimSpace(field)
+	}
 }
 
 // BulkRemoveSubscribers represents request body for POST /api/subscribers/bulk-remove
@@ -56,3 +62,28 @@ type BulkRemoveSubscribers struct {
 func (p *BulkRemoveSubscribers) TrimSpaces() {
 	p.Filename = strings.TrimSpace(p.Filename)
 }
+
+// PostPreferences represents request body for POST /api/preferences, the body is
+// either JSON or an url encoded HTML form. The segments hold all public segments
+// the subscriber wants to receive emails from, the rest of them are opted out.
+type PostPreferences struct {
+	Email       string            `json:"email" form:"email" validate:"required,email"`
+	UUID        string            `json:"uuid" form:"uuid" validate:"required,uuid"`
+	Token       string            `json:"t" form:"t" validate:"required"`
+	Name        string            `json:"name" form:"name" validate:"omitempty,max=191"`
+	Metadata    map[string]string `json:"metadata" form:"-" validate:"omitempty,dive,keys,required,alphanumhyphen,endkeys,max=191"`
+	SegmentIDs  []int64           `json:"segments" form:"segments"`
+	PauseDays   int               `json:"pause_days" form:"pause_days" validate:"min=0,max=365"`
+	Resume      bool              `json:"resume" form:"resume"`
+	Unsubscribe bool              `json:"unsubscribe" form:"unsubscribe"`
+}
+
+func (p *PostPreferences) TrimSpaces() {
+	p.Email = strings.TrimSpace(p.Email)
+	p.UUID = strings.TrimSpace(p.UUID)
+	p.Token = strings.TrimSpace(p.Token)
+	p.Name = strings.TrimSpace(p.Name)
+	for k, v := range p.Metadata {
+		p.Metadata[k] = strings.TrimSpace(v)
+	}
+}
@@ -6,24 +6,66 @@ import "strings"
 type PostTemplate struct {
 	Name        string `json:"name" validate:"required,max=191"`
 	HTMLPart    string `json:"html_part" validate:"required,html"`
-	TextPart    string `json:"text_part" validate:"required"`
+	TextPart    string `json:"text_part"`
 	SubjectPart string `json:"subject_part" validate:"required,max=191"`
+	// Layout is the name of the layout which wraps the html part.
+	Layout string `json:"layout" validate:"omitempty,max=191"`
+	// InlineCSS inlines the styles of the html part, the text part is generated when it's empty.
+	InlineCSS bool `json:"inline_css"`
+	// Strict rejects the template when the linter reports any warnings.
+	Strict bool `json:"strict"`
 }
 
 func (p *PostTemplate) TrimSpaces() {
 	p.Name = strings.TrimSpace(p.Name)
 	p.SubjectPart = strings.TrimSpace(p.SubjectPart)
+	p.Layout = strings.TrimSpace(p.Layout)
 }
 
 // PutTemplate represents request body for PUT /api/templates
 type PutTemplate struct {
 	HTMLPart    string `json:"html_part" validate:"required,html"`
-	TextPart    string `json:"text_part" validate:"required"`
+	TextPart    string `json:"text_part"`
 	SubjectPart string `json:"subject_part" validate:"required,max=191"`
 	Name        string `json:"name" validate:"required,max=191"`
+	// Layout is the name of the layout which wraps the html part.
+	Layout string `json:"layout" validate:"omitempty,max=191"`
+	// InlineCSS inlines the styles of the html part, the text part is generated when it's empty.
+	InlineCSS bool `json:"inline_css"`
+	// Strict rejects the template when the linter reports any warnings.
+	Strict bool `json:"strict"`
 }
 
 func (p *PutTemplate) TrimSpaces() {
 	p.Name = strings.TrimSpace(p.Name)
 	p.SubjectPart = strings.TrimSpace(p.SubjectPart)
+	p.Layout = strings.TrimSpace(p.Layout)
 }
+
+// Partial represents request body for POST /api/partials & PUT /api/partials/{id}
+type Partial struct {
+	Name    string `json:"name" validate:"required,alphanumhyphen,max=191"`
+	Kind    string `json:"kind" validate:"required,oneof=partial layout"`
+	Content string `json:"content" validate:"required"`
+}
+
+func (p *Partial) TrimSpaces() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Kind = strings.TrimSpace(p.Kind)
+}
+
+// TemplateVersionsDiff represents the query params of GET /api/templates/{id}/versions/diff
+type TemplateVersionsDiff struct {
+	From *int64 `json:"from" form:"from" validate:"required,min=0"`
+	To   *int64 `json:"to" form:"to" validate:"required,min=0"`
+}
+
+func (p *TemplateVersionsDiff) TrimSpaces() {}
+
+// PreviewTemplate represents request body for POST /api/templates/{id}/preview
+type PreviewTemplate struct {
+	SubscriberID int64             `json:"subscriber_id" validate:"omitempty,min=1"`
+	Data         map[string]string `json:"data" validate:"omitempty,dive,keys,required,alphanumhyphen,endkeys"`
+}
+
+func (p *PreviewTemplate) TrimSpaces() {}
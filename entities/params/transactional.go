@@ -0,0 +1,26 @@
+package params
+
+import "strings"
+
+// SendTransactional represents request body for POST /api/transactional/send
+type SendTransactional struct {
+	TemplateID   int64             `json:"template_id" validate:"required_without=TemplateName"`
+	TemplateName string            `json:"template_name" validate:"required_without=TemplateID,max=191"`
+	To           string            `json:"to" validate:"required,email,max=191"`
+	Name         string            `json:"name" validate:"omitempty,max=191"`
+	Source       string            `json:"source" validate:"required,email,max=191"`
+	FromName     string            `json:"from_name" validate:"required,max=191"`
+	Data         map[string]string `json:"data" validate:"omitempty,dive,keys,required,alphanumhyphen,endkeys"`
+	Tags         map[string]string `json:"tags" validate:"omitempty,max=10,dive,keys,required,alphanumhyphen,max=191,ne=campaign_id,ne=user_id,ne=variant_id,ne=transactional_id,endkeys,required,alphanumhyphen,max=191"`
+}
+
+func (p *SendTransactional) TrimSpaces() {
+	p.TemplateName = strings.TrimSpace(p.TemplateName)
+	p.To = strings.TrimSpace(p.To)
+	p.Name = strings.TrimSpace(p.Name)
+	p.Source = strings.TrimSpace(p.Source)
+	p.FromName = strings.TrimSpace(p.FromName)
+	for k, v := range p.Tags {
+		p.Tags[k] = strings.TrimSpace(v)
+	}
+}
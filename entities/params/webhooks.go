@@ -0,0 +1,17 @@
+package params
+
+import "strings"
+
+// Webhook represents request body for POST /api/webhooks & PUT /api/webhooks/{id}
+type Webhook struct {
+	URL    string   `json:"url" validate:"required,url,startswith=http,max=191"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=subscriber.created subscriber.unsubscribed email.bounced email.complained email.opened email.clicked campaign.sent campaign.failed"`
+	Active *bool    `json:"active"`
+}
+
+func (p *Webhook) TrimSpaces() {
+	p.URL = strings.TrimSpace(p.URL)
+	for i := range p.Events {
+		p.Events[i] = strings.TrimSpace(p.Events[i])
+	}
+}
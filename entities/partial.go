@@ -0,0 +1,32 @@
+package entities
+
+const (
+	// PartialKindPartial indicates a snippet which the templates include with the {{> name}} tag.
+	PartialKindPartial = "partial"
+	// PartialKindLayout indicates a layout which wraps the html part of the templates.
+	PartialKindLayout = "layout"
+
+	// PartialContent is the name of the partial through which the layout includes the html part of the template.
+	PartialContent = "content"
+)
+
+// Partial represents a reusable part of the user's templates, e.g. the header and the footer.
+// The partials are included by name with the mustache partial tag, the layouts are attached to the
+// templates and include their html part with the {{> content}} tag.
+type Partial struct {
+	Model
+	UserID  int64  `json:"-" gorm:"column:user_id; index"`
+	Name    string `json:"name" gorm:"not null"`
+	Kind    string `json:"kind" gorm:"not null"`
+	Content string `json:"content" gorm:"not null"`
+}
+
+// TableName overrides the table name used by Partial to `template_partials`
+func (Partial) TableName() string {
+	return "template_partials"
+}
+
+// GetID returns the id of the partial
+func (p Partial) GetID() int64 {
+	return p.Model.ID
+}
@@ -0,0 +1,23 @@
+package entities
+
+// PreferenceChanges holds the changes a subscriber made in the preference center,
+// the name, metadata and paused until date are already set on the subscriber.
+type PreferenceChanges struct {
+	Updated bool
+	OptIn   []Segment
+	OptOut  []Segment
+	Paused  bool
+	Resumed bool
+}
+
+// IsEmpty reports whether the subscriber changed anything.
+func (p PreferenceChanges) IsEmpty() bool {
+	return !p.Updated && len(p.OptIn) == 0 && len(p.OptOut) == 0 && !p.Paused && !p.Resumed
+}
+
+// Topic represents a public segment shown in the preference center.
+type Topic struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Subscribed bool   `json:"subscribed"`
+}
@@ -1,20 +1,83 @@
 package entities
 
+import "fmt"
+
 const (
 	StatusFailed     = "failed"
 	StatusDone       = "done"
 	StatusInProgress = "in_progress"
 
-	SubscribersResource = "subscribers"
+	SubscribersResource     = "subscribers"
+	CampaignResultsResource = "campaign_results"
+	BouncesResource         = "bounces"
+	ComplaintsResource      = "complaints"
+	SendLogsResource        = "send_logs"
+
+	// ReportFormatCSV and ReportFormatJSON are the formats of the export reports,
+	// the json reports are written as newline-delimited json.
+	ReportFormatCSV  = "csv"
+	ReportFormatJSON = "json"
 )
 
 // Report represents the Report entity
 type Report struct {
 	Model
-	UserID   int64  `json:"-" gorm:"column:user_id; index"`
-	Resource string `json:"resource" gorm:"not null"`
-	FileName string `json:"file_name" gorm:"not null"`
-	Type     string `json:"type" gorm:"not null"`
-	Status   string `json:"status" gorm:"not null"`
-	Note     string `json:"note"`
+	UserID       int64  `json:"-" gorm:"column:user_id; index"`
+	Resource     string `json:"resource" gorm:"not null"`
+	Format       string `json:"format" gorm:"not null"`
+	Gzip         bool   `json:"gzip"`
+	CampaignID   *int64 `json:"campaign_id,omitempty"`
+	FileName     string `json:"file_name" gorm:"not null"`
+	Type         string `json:"type" gorm:"not null"`
+	Status       string `json:"status" gorm:"not null"`
+	Note         string `json:"note"`
+	RowsExported int64  `json:"rows_exported"`
+	TotalRows    int64  `json:"total_rows"`
+	// JobID is the id of the job which generates the report, it is set only when the report is created.
+	JobID int64 `json:"job_id,omitempty" gorm:"-"`
+}
+
+// FileExtension returns the extension of the report file by its format and compression.
+func (r *Report) FileExtension() string {
+	ext := "csv"
+	if r.Format == ReportFormatJSON {
+		ext = "ndjson"
+	}
+	if r.Gzip {
+		ext += ".gz"
+	}
+
+	return ext
+}
+
+// ContentType returns the content type of the report file.
+func (r *Report) ContentType() string {
+	switch {
+	case r.Gzip:
+		return "application/gzip"
+	case r.Format == ReportFormatJSON:
+		return "application/x-ndjson"
+	}
+
+	return "text/csv"
+}
+
+// Progress returns the percentage of the exported rows, it is 100 when there is nothing to export.
+func (r *Report) Progress() int64 {
+	if r.TotalRows <= 0 {
+		if r.Status == StatusDone {
+			return 100
+		}
+		return 0
+	}
+	if r.RowsExported >= r.TotalRows {
+		return 100
+	}
+
+	return r.RowsExported * 100 / r.TotalRows
+}
+
+// Key returns the key of the report file in the files bucket.
+func (r *Report) Key() string {
+	return fmt.Sprintf("%s/export/%d/%s", r.Resource, r.UserID, r.FileName)
 }
@@ -1,14 +1,25 @@
 package entities
 
 import (
+	"encoding/json"
 	"time"
 )
 
+const (
+	// SegmentTypeStatic indicates a segment whose subscribers are added and removed manually.
+	SegmentTypeStatic = "static"
+	// SegmentTypeDynamic indicates a segment whose subscribers match its rules at query time.
+	SegmentTypeDynamic = "dynamic"
+)
+
 // Segment represents the group of subscribers entity
 type Segment struct {
 	Model
 	Name        string       `json:"name" gorm:"not null" valid:"required,stringlength(1|191)"`
 	UserID      int64        `json:"-" gorm:"column:user_id; index"`
+	Type        string       `json:"type" gorm:"default:static"`
+	Public      bool         `json:"public"`
+	RulesJSON   JSON         `json:"rules,omitempty" gorm:"column:rules; type:json"`
 	Subscribers []Subscriber `json:"-" gorm:"many2many:subscribers_segments;"`
 }
 
@@ -25,6 +36,37 @@ type SegmentWithTotalSubs struct {
 	TotalSubscribers *int64 `json:"total_subscribers,omitempty" sql:"-" gorm:"-"`
 }
 
+// IsDynamic reports whether the subscribers of the segment are defined by rules.
+func (s Segment) IsDynamic() bool {
+	return s.Type == SegmentTypeDynamic
+}
+
+// GetRules returns the rule tree of the dynamic segment.
+func (s Segment) GetRules() (*SegmentRule, error) {
+	r := new(SegmentRule)
+	if s.RulesJSON.IsNull() {
+		return r, nil
+	}
+
+	err := json.Unmarshal(s.RulesJSON, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SetRules stores the rule tree of the dynamic segment.
+func (s *Segment) SetRules(r SegmentRule) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.RulesJSON = b
+
+	return nil
+}
+
 func (s Segment) GetID() int64 {
 	return s.Model.ID
 }
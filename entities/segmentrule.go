@@ -0,0 +1,179 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Segment rule group matches.
+const (
+	RuleMatchAll = "all"
+	RuleMatchAny = "any"
+)
+
+// Segment rule fields.
+const (
+	RuleFieldEmail          = "email"
+	RuleFieldName           = "name"
+	RuleFieldMetadata       = "metadata"
+	RuleFieldCreatedAt      = "created_at"
+	RuleFieldActive         = "active"
+	RuleFieldBlacklisted    = "blacklisted"
+	RuleFieldOpenedCampaign = "opened_campaign"
+	RuleFieldClickedLink    = "clicked_link"
+)
+
+// Segment rule operators.
+const (
+	RuleOpEquals    = "equals"
+	RuleOpNotEquals = "not_equals"
+	RuleOpContains  = "contains"
+	RuleOpExists    = "exists"
+	RuleOpNotExists = "not_exists"
+	RuleOpBefore    = "before"
+	RuleOpAfter     = "after"
+	RuleOpBetween   = "between"
+)
+
+// Segment rule tree limits.
+const (
+	MaxSegmentRuleDepth = 5
+	MaxSegmentRules     = 50
+)
+
+// ErrInvalidSegmentRule is returned when the segment rule tree is malformed.
+var ErrInvalidSegmentRule = errors.New("invalid segment rule")
+
+var metadataKeyRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// ruleOperators lists the operators allowed for each field.
+var ruleOperators = map[string][]string{
+	RuleFieldEmail:          {RuleOpEquals, RuleOpNotEquals, RuleOpContains},
+	RuleFieldName:           {RuleOpEquals, RuleOpNotEquals, RuleOpContains},
+	RuleFieldMetadata:       {RuleOpEquals, RuleOpNotEquals, RuleOpContains, RuleOpExists, RuleOpNotExists},
+	RuleFieldCreatedAt:      {RuleOpBefore, RuleOpAfter, RuleOpBetween},
+	RuleFieldActive:         {RuleOpEquals},
+	RuleFieldBlacklisted:    {RuleOpEquals},
+	RuleFieldOpenedCampaign: {RuleOpExists, RuleOpNotExists},
+	RuleFieldClickedLink:    {RuleOpExists, RuleOpNotExists},
+}
+
+// SegmentRule is a node in the rule tree of a dynamic segment. A node is either a group,
+// which matches all or any of its child rules, or a condition on a subscriber field.
+//
+// Conditions:
+//   - email, name: equals, not_equals, contains the value.
+//   - metadata: the value of the metadata key equals, not_equals or contains the value,
+//     or the key exists / not_exists.
+//   - created_at: before, after the value, or between the value and to (dates in
+//     2006-01-02 or RFC 3339 format).
+//   - active, blacklisted: equals the value (true or false).
+//   - opened_campaign: the subscriber opened (exists) or not (not_exists) the campaign id in value.
+//   - clicked_link: the subscriber clicked (exists) or not (not_exists) the link in value.
+type SegmentRule struct {
+	Match    string        `json:"match,omitempty"`
+	Rules    []SegmentRule `json:"rules,omitempty"`
+	Field    string        `json:"field,omitempty"`
+	Operator string        `json:"operator,omitempty"`
+	Key      string        `json:"key,omitempty"`
+	Value    string        `json:"value,omitempty"`
+	To       string        `json:"to,omitempty"`
+}
+
+// IsGroup reports whether the rule groups other rules.
+func (r SegmentRule) IsGroup() bool {
+	return r.Match != ""
+}
+
+// Validate checks that the rule tree is well formed.
+func (r SegmentRule) Validate() error {
+	count := 0
+	return r.validate(1, &count)
+}
+
+func (r SegmentRule) validate(depth int, count *int) error {
+	*count++
+	if *count > MaxSegmentRules {
+		return fmt.Errorf("%w: max %d rules allowed", ErrInvalidSegmentRule, MaxSegmentRules)
+	}
+
+	if r.IsGroup() {
+		if depth > MaxSegmentRuleDepth {
+			return fmt.Errorf("%w: max %d nested groups allowed", ErrInvalidSegmentRule, MaxSegmentRuleDepth)
+		}
+		if r.Match != RuleMatchAll && r.Match != RuleMatchAny {
+			return fmt.Errorf("%w: match must be one of: %s %s", ErrInvalidSegmentRule, RuleMatchAll, RuleMatchAny)
+		}
+		if len(r.Rules) == 0 {
+			return fmt.Errorf("%w: group must contain at least one rule", ErrInvalidSegmentRule)
+		}
+		for _, child := range r.Rules {
+			if err := child.validate(depth+1, count); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ops, ok := ruleOperators[r.Field]
+	if !ok {
+		return fmt.Errorf("%w: unknown field '%s'", ErrInvalidSegmentRule, r.Field)
+	}
+	if !contains(ops, r.Operator) {
+		return fmt.Errorf("%w: operator '%s' is not supported for field '%s'", ErrInvalidSegmentRule, r.Operator, r.Field)
+	}
+
+	switch r.Field {
+	case RuleFieldMetadata:
+		if !metadataKeyRegex.MatchString(r.Key) {
+			return fmt.Errorf("%w: metadata key must consist only of alphanumeric and hyphen characters", ErrInvalidSegmentRule)
+		}
+		if r.Operator != RuleOpExists && r.Operator != RuleOpNotExists && r.Value == "" {
+			return fmt.Errorf("%w: value is required for field '%s'", ErrInvalidSegmentRule, r.Field)
+		}
+	case RuleFieldEmail, RuleFieldName, RuleFieldClickedLink:
+		if r.Value == "" {
+			return fmt.Errorf("%w: value is required for field '%s'", ErrInvalidSegmentRule, r.Field)
+		}
+	case RuleFieldCreatedAt:
+		if _, err := ParseRuleTime(r.Value); err != nil {
+			return fmt.Errorf("%w: invalid date '%s'", ErrInvalidSegmentRule, r.Value)
+		}
+		if r.Operator == RuleOpBetween {
+			if _, err := ParseRuleTime(r.To); err != nil {
+				return fmt.Errorf("%w: invalid date '%s'", ErrInvalidSegmentRule, r.To)
+			}
+		}
+	case RuleFieldActive, RuleFieldBlacklisted:
+		if _, err := strconv.ParseBool(r.Value); err != nil {
+			return fmt.Errorf("%w: value of field '%s' must be true or false", ErrInvalidSegmentRule, r.Field)
+		}
+	case RuleFieldOpenedCampaign:
+		if _, err := strconv.ParseInt(r.Value, 10, 64); err != nil {
+			return fmt.Errorf("%w: value of field '%s' must be a campaign id", ErrInvalidSegmentRule, r.Field)
+		}
+	}
+
+	return nil
+}
+
+// ParseRuleTime parses the date of a created_at rule, in 2006-01-02 or RFC 3339 format.
+func ParseRuleTime(v string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, v)
+	if err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+func contains(vals []string, v string) bool {
+	for _, val := range vals {
+		if val == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentRuleValidate(t *testing.T) {
+	invalid := []SegmentRule{
+		{},
+		{Match: "none", Rules: []SegmentRule{{Field: RuleFieldName, Operator: RuleOpEquals, Value: "a"}}},
+		{Match: RuleMatchAll},
+		{Field: RuleFieldName, Operator: RuleOpExists},
+		{Field: RuleFieldName, Operator: RuleOpEquals},
+		{Field: RuleFieldMetadata, Key: "a b", Operator: RuleOpExists},
+		{Field: RuleFieldCreatedAt, Operator: RuleOpBetween, Value: "2021-01-01", To: "tomorrow"},
+		{Field: RuleFieldActive, Operator: RuleOpEquals, Value: "yes"},
+		{Field: RuleFieldOpenedCampaign, Operator: RuleOpExists, Value: "abc"},
+	}
+	for _, r := range invalid {
+		assert.ErrorIs(t, r.Validate(), ErrInvalidSegmentRule, "%+v", r)
+	}
+
+	deep := SegmentRule{Field: RuleFieldName, Operator: RuleOpEquals, Value: "a"}
+	for i := 0; i < MaxSegmentRuleDepth; i++ {
+		deep = SegmentRule{Match: RuleMatchAll, Rules: []SegmentRule{deep}}
+	}
+	assert.Nil(t, deep.Validate())
+	deep = SegmentRule{Match: RuleMatchAll, Rules: []SegmentRule{deep}}
+	assert.ErrorIs(t, deep.Validate(), ErrInvalidSegmentRule)
+}
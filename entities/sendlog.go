@@ -16,8 +16,12 @@ const (
 	SendLogDescriptionOnSuccessful = "Email sent successfully"
 	// SendLogDescriptionOnSesClientError description used when sender failed to create ses client
 	SendLogDescriptionOnSesClientError = "Unable to create ses client"
+	// SendLogDescriptionOnSMTPClientError description used when sender failed to create the smtp client
+	SendLogDescriptionOnSMTPClientError = "Unable to create smtp client"
 	// SendLogDescriptionOnSendEmailError description used when ses client fails to send the email
 	SendLogDescriptionOnSendEmailError = "Unable to send email"
+	// SendLogDescriptionOnCampaignCancelled description used when the campaign was cancelled before the mail was sent
+	SendLogDescriptionOnCampaignCancelled = "Campaign cancelled, email not sent"
 )
 
 type SendLog struct {
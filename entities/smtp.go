@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"time"
+)
+
+// SMTPSettings entity holds the SMTP relay which the client's
+// emails are delivered through instead of SES.
+type SMTPSettings struct {
+	ID         int64     `json:"id" gorm:"column:id; primary_key:yes"`
+	UserID     int64     `json:"-" gorm:"column:user_id; index"`
+	Host       string    `json:"host" gorm:"not null"`
+	Port       int       `json:"port" gorm:"not null"`
+	Username   string    `json:"username"`
+	Password   string    `json:"password,omitempty"`
+	Encryption string    `json:"encryption" gorm:"not null"`
+	AuthMethod string    `json:"auth_method"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name used by SMTPSettings to `smtp_settings`
+func (SMTPSettings) TableName() string {
+	return "smtp_settings"
+}
@@ -0,0 +1,150 @@
+package entities
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+const (
+	// SplitTestStatusDraft indicates a split test which hasn't started yet.
+	SplitTestStatusDraft = "draft"
+	// SplitTestStatusTesting indicates that the variants are being sent to the test group.
+	SplitTestStatusTesting = "testing"
+	// SplitTestStatusWaiting indicates that the test group has been sent and
+	// the results are collected until the wait period elapses.
+	SplitTestStatusWaiting = "waiting"
+	// SplitTestStatusCompleted indicates that the winner has been chosen and
+	// it is sent to the remainder of the subscribers.
+	SplitTestStatusCompleted = "completed"
+)
+
+// Split test winner criteria.
+const (
+	WinnerCriteriaOpens  = "opens"
+	WinnerCriteriaClicks = "clicks"
+)
+
+// Split test variant limits.
+const (
+	MinSplitTestVariants = 2
+	MaxSplitTestVariants = 4
+)
+
+// CampaignSplitTest holds the A/B test configuration of a campaign. The variants are sent
+// to a percentage of the subscribers, and after the wait period the variant with the most
+// unique opens or clicks is sent to the rest of them.
+type CampaignSplitTest struct {
+	ID              int64             `json:"-" gorm:"column:id; primary_key:yes"`
+	UserID          int64             `json:"-" gorm:"column:user_id; index"`
+	CampaignID      int64             `json:"-" gorm:"column:campaign_id"`
+	TestPercentage  int               `json:"test_percentage"`
+	WaitMinutes     int64             `json:"wait_minutes"`
+	WinnerCriteria  string            `json:"winner_criteria"`
+	Status          string            `json:"status"`
+	WinnerVariantID int64             `json:"winner_variant_id"`
+	ParamsJSON      JSON              `json:"-" gorm:"column:params; type:json"`
+	TestCompletedAt NullTime          `json:"test_completed_at"`
+	Variants        []CampaignVariant `json:"variants" gorm:"foreignKey:split_test_id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// CampaignVariant is a template which is sent to a part of the split test group.
+type CampaignVariant struct {
+	ID              int64         `json:"id" gorm:"column:id; primary_key:yes"`
+	UserID          int64         `json:"-" gorm:"column:user_id; index"`
+	SplitTestID     int64         `json:"-" gorm:"column:split_test_id"`
+	CampaignID      int64         `json:"-" gorm:"column:campaign_id"`
+	TemplateID      int64         `json:"-"`
+	TemplateVersion int64         `json:"template_version"`
+	BaseTemplate    *BaseTemplate `json:"template" gorm:"foreignKey:template_id"`
+	Name            string        `json:"name"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// VariantName returns the name of the variant at the given index, e.g. A, B, C...
+func VariantName(i int) string {
+	return string(rune('A' + i))
+}
+
+// Assign returns the variant which the subscriber receives during the test phase,
+// and false when the subscriber is not part of the test group. The assignment is
+// derived from the campaign and subscriber ids so that the winner phase can skip
+// the subscribers which have already received a variant.
+func (st *CampaignSplitTest) Assign(subscriberID int64) (*CampaignVariant, bool) {
+	if len(st.Variants) == 0 {
+		return nil, false
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(st.CampaignID, 10) + ":" + strconv.FormatInt(subscriberID, 10)))
+	sum := h.Sum64()
+
+	if sum%100 >= uint64(st.TestPercentage) {
+		return nil, false
+	}
+
+	return &st.Variants[(sum/100)%uint64(len(st.Variants))], true
+}
+
+// Variant returns the variant by the given id.
+func (st *CampaignSplitTest) Variant(id int64) (*CampaignVariant, bool) {
+	for i := range st.Variants {
+		if st.Variants[i].ID == id {
+			return &st.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// WaitElapsed reports whether the wait period after the test phase has elapsed.
+func (st *CampaignSplitTest) WaitElapsed(now time.Time) bool {
+	if !st.TestCompletedAt.Valid {
+		return false
+	}
+	return !now.Before(st.TestCompletedAt.Time.Add(time.Duration(st.WaitMinutes) * time.Minute))
+}
+
+// GetParams returns the campaigner params the test phase was started with.
+func (st *CampaignSplitTest) GetParams() (*CampaignerTopicParams, error) {
+	p := new(CampaignerTopicParams)
+	if st.ParamsJSON.IsNull() {
+		return p, nil
+	}
+
+	err := json.Unmarshal(st.ParamsJSON, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SetParams stores the campaigner params without the SES keys,
+// they are fetched again when the winner is sent.
+func (st *CampaignSplitTest) SetParams(p CampaignerTopicParams) error {
+	p.SesKeys = SesKeys{}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	st.ParamsJSON = b
+
+	return nil
+}
+
+// VariantStats represents the campaign stats of a single split test variant.
+type VariantStats struct {
+	VariantID  int64        `json:"variant_id"`
+	Name       string       `json:"name"`
+	Winner     bool         `json:"winner"`
+	TotalSent  int64        `json:"total_sent"`
+	Delivered  int64        `json:"delivered"`
+	Opens      *OpensStats  `json:"opens"`
+	Clicks     *ClicksStats `json:"clicks"`
+	Bounces    int64        `json:"bounces"`
+	Complaints int64        `json:"complaints"`
+}
@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitTestAssign(t *testing.T) {
+	st := &CampaignSplitTest{
+		CampaignID:     1,
+		TestPercentage: 20,
+		Variants: []CampaignVariant{
+			{ID: 1, Name: "A"},
+			{ID: 2, Name: "B"},
+		},
+	}
+
+	counts := make(map[int64]int)
+	inTest := 0
+	for id := int64(1); id <= 10000; id++ {
+		v, ok := st.Assign(id)
+		if !ok {
+			assert.Nil(t, v)
+			continue
+		}
+		inTest++
+		counts[v.ID]++
+
+		// the assignment is stable
+		again, ok := st.Assign(id)
+		assert.True(t, ok)
+		assert.Equal(t, v.ID, again.ID)
+	}
+
+	assert.InDelta(t, 2000, inTest, 200)
+	assert.InDelta(t, 1000, counts[1], 150)
+	assert.InDelta(t, 1000, counts[2], 150)
+
+	_, ok := (&CampaignSplitTest{TestPercentage: 100}).Assign(1)
+	assert.False(t, ok, "split test without variants has no test group")
+
+	v, ok := st.Variant(2)
+	assert.True(t, ok)
+	assert.Equal(t, "B", v.Name)
+	_, ok = st.Variant(3)
+	assert.False(t, ok)
+}
+
+func TestSplitTestWaitElapsed(t *testing.T) {
+	now := time.Now()
+	st := &CampaignSplitTest{WaitMinutes: 60}
+	assert.False(t, st.WaitElapsed(now))
+
+	st.TestCompletedAt.SetValid(now.Add(-30 * time.Minute))
+	assert.False(t, st.WaitElapsed(now))
+
+	st.TestCompletedAt.SetValid(now.Add(-60 * time.Minute))
+	assert.True(t, st.WaitElapsed(now))
+}
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// StringList is a list of values stored as comma separated string.
+type StringList []string
+
+// Value returns the comma separated values.
+func (l StringList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+// Scan splits the comma separated values.
+func (l *StringList) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		str = string(v)
+	case string:
+		str = v
+	default:
+		return errors.New("invalid Scan Source")
+	}
+
+	*l = StringList{}
+	if str != "" {
+		*l = strings.Split(str, ",")
+	}
+
+	return nil
+}
+
+// Contains checks if the value is in the list.
+func (l StringList) Contains(value string) bool {
+	for _, v := range l {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// StringMap is a map of string values stored as a json object, the nil map is stored as null.
+type StringMap map[string]string
+
+// Value returns the json object of the map.
+func (m StringMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan decodes the json object into the map.
+func (m *StringMap) Scan(value interface{}) error {
+	*m = nil
+
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("invalid Scan Source")
+	}
+
+	return json.Unmarshal(b, m)
+}
+
+// GormDataType returns the data type of the column, the maps are otherwise parsed as relations.
+func (StringMap) GormDataType() string {
+	return "text"
+}
@@ -20,6 +20,7 @@ type Subscriber struct {
 	Segments    []Segment         `json:"segments,omitempty" gorm:"many2many:subscribers_segments;"`
 	Blacklisted bool              `json:"blacklisted"`
 	Active      bool              `json:"active"`
+	PausedUntil *time.Time        `json:"paused_until"`
 	Metadata    map[string]string `json:"-" sql:"-" gorm:"-"`
 }
 
@@ -53,6 +54,21 @@ func (s *Subscriber) GetUnsubscribeURL(uuid, secret, appURL string) (string, err
 	return appURL + "/unsubscribe.html?" + params.Encode(), nil
 }
 
+// GetOneClickUnsubscribeURL generates and signs a token based on the subscriber ID and creates
+// the RFC 8058 one-click unsubscribe url with the email, uuid and token as query parameters.
+func (s *Subscriber) GetOneClickUnsubscribeURL(uuid, secret, appURL string) (string, error) {
+	t, err := s.GenerateUnsubscribeToken(secret)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Add("email", s.Email)
+	params.Add("uuid", uuid)
+	params.Add("t", t)
+
+	return appURL + "/api/unsubscribe/one-click?" + params.Encode(), nil
+}
+
 // GenerateUnsubscribeToken generates and signs a new unsubscribe token with the given key, from the
 // ID of the subscriber. When a subscriber wants to unsubscribe from future emails, we check this hash
 // against a newly generated hash and compare them, if they match we unsubscribe the user.
@@ -68,6 +84,44 @@ func (s *Subscriber) GenerateUnsubscribeToken(key string) (string, error) {
 	return utils.SignData(strconv.FormatInt(s.ID, 10), key)
 }
 
+// ConfirmTokenTTL is the duration for which the confirmation links are valid, an old link
+// can't activate a subscriber who has unsubscribed after the subscription was confirmed.
+const ConfirmTokenTTL = 48 * time.Hour
+
+// GetConfirmURL generates and signs a confirmation token based on the subscriber ID and the expiry time,
+// and creates a confirmation url of the form with the email, expiry and token as query parameters.
+func (s *Subscriber) GetConfirmURL(formUUID, secret, appURL string, expires time.Time) (string, error) {
+	t, err := s.GenerateConfirmToken(secret, expires)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Add("email", s.Email)
+	params.Add("e", strconv.FormatInt(expires.Unix(), 10))
+	params.Add("t", t)
+
+	return appURL + "/api/forms/" + url.PathEscape(formUUID) + "/confirm?" + params.Encode(), nil
+}
+
+// GenerateConfirmToken generates and signs a new subscription confirmation token with the given key, from the
+// ID of the subscriber and the expiry time. The data is prefixed so that the token differs from the unsubscribe token.
+func (s *Subscriber) GenerateConfirmToken(key string, expires time.Time) (string, error) {
+	if s.ID == 0 {
+		return "", errors.New("entities: unable to generate confirm token: subscriber ID is 0")
+	}
+
+	if key == "" {
+		return "", errors.New("entities: unable to generate confirm token: key is empty")
+	}
+
+	return utils.SignData("confirm:"+strconv.FormatInt(s.ID, 10)+":"+strconv.FormatInt(expires.Unix(), 10), key)
+}
+
+// IsPaused reports whether the sending of emails to the subscriber is paused at the given time.
+func (s Subscriber) IsPaused(t time.Time) bool {
+	return s.PausedUntil != nil && s.PausedUntil.After(t)
+}
+
 func (s Subscriber) GetID() int64 {
 	return s.Model.ID
 }
@@ -36,6 +36,23 @@ func TestSubscriberEntity(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, tt, "77de38e4b50e618a0ebb95db61e2f42697391659d82c064a5f81b9f48d85ccd5")
 
+	expires := time.Unix(1600000000, 0)
+	ct, err := sub.GenerateConfirmToken("secret", expires)
+	assert.Nil(t, err)
+	assert.NotEqual(t, tt, ct)
+
+	later, err := sub.GenerateConfirmToken("secret", expires.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.NotEqual(t, ct, later)
+
+	url, err = sub.GetConfirmURL("foobar", "secret", "http://example.com", expires)
+	assert.Nil(t, err)
+	assert.Equal(t, url, "http://example.com/api/forms/foobar/confirm?e=1600000000&email=john.doe%40example.com&t="+ct)
+
+	url, err = sub.GetOneClickUnsubscribeURL("foobar", "secret", "https://example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, url, "https://example.com/api/unsubscribe/one-click?email=john.doe%40example.com&t="+tt+"&uuid=foobar")
+
 	id := sub.GetID()
 	assert.Equal(t, subID, id)
 
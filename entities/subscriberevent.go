@@ -7,6 +7,12 @@ import (
 const (
 	SubscriberEventTypeCreated      EventType = "created"
 	SubscriberEventTypeUnsubscribed EventType = "unsubscribed"
+	SubscriberEventTypeConfirmed    EventType = "confirmed"
+	SubscriberEventTypeUpdated      EventType = "updated"
+	SubscriberEventTypeOptedIn      EventType = "opted_in"
+	SubscriberEventTypeOptedOut     EventType = "opted_out"
+	SubscriberEventTypePaused       EventType = "paused"
+	SubscriberEventTypeResumed      EventType = "resumed"
 )
 
 // SubscriberEvent represents an event saved on subscriber's change
@@ -15,6 +21,7 @@ type SubscriberEvent struct {
 	UserID       int64     `json:"user_id"`
 	SubscriberID int64     `json:"subscriber_id"`
 	EventType    EventType `json:"event_type"`
+	SegmentID    *int64    `json:"segment_id,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
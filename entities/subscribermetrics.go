@@ -9,5 +9,10 @@ type SubscriberMetrics struct {
 	UserID       int64     `json:"user_id"`
 	Created      int64     `json:"created"`
 	Unsubscribed int64     `json:"unsubscribed"`
+	Updated      int64     `json:"updated"`
+	OptedIn      int64     `json:"opted_in"`
+	OptedOut     int64     `json:"opted_out"`
+	Paused       int64     `json:"paused"`
+	Resumed      int64     `json:"resumed"`
 	Datetime     time.Time `json:"datetime"`
 }
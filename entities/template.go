@@ -17,6 +17,7 @@ var (
 const (
 	TagName           = "name"
 	TagUnsubscribeUrl = "unsubscribe_url"
+	TagConfirmUrl     = "confirm_url"
 )
 
 // BaseTemplate represents the base params of each template
@@ -25,6 +26,12 @@ type BaseTemplate struct {
 	UserID      int64  `json:"-"`
 	Name        string `json:"name"`
 	SubjectPart string `json:"subject_part"`
+	// Version is the current version of the template, it is incremented on every save.
+	Version int64 `json:"version"`
+	// LayoutID is the id of the layout which wraps the html part, zero when the template has no layout.
+	LayoutID int64 `json:"layout_id,omitempty"`
+	// InlineCSS is set when the styles of the html part are inlined into the style attributes on save.
+	InlineCSS bool `json:"inline_css"`
 }
 
 // GetID returns the id of the template
@@ -42,6 +49,17 @@ type Template struct {
 	BaseTemplate
 	HTMLPart string `json:"html_part" gorm:"-"`
 	TextPart string `json:"text_part"`
+	// HTMLSource and TextSource are the parts as written by the author, the parts which are
+	// sent are processed from them. The text part is generated when the text source is empty.
+	HTMLSource string `json:"html_source" gorm:"-"`
+	TextSource string `json:"text_source"`
+	// Partials and Layout hold the content of the partials and the layout which the template includes, they
+	// are stored with the version so it's always rendered with the same content. The partials are nil when
+	// the template isn't a version or the version was saved before the snapshots were stored.
+	Partials StringMap `json:"-" gorm:"-"`
+	Layout   string    `json:"-" gorm:"-"`
+	// Warnings are reported by the linter when the template is saved.
+	Warnings []TemplateWarning `json:"warnings,omitempty" gorm:"-"`
 }
 
 // GetBase returns the base of the template
@@ -55,10 +73,33 @@ func (t Template) GetBase() *BaseTemplate {
 		UserID:      t.UserID,
 		Name:        t.Name,
 		SubjectPart: t.SubjectPart,
+		Version:     t.Version,
+		LayoutID:    t.LayoutID,
+		InlineCSS:   t.InlineCSS,
 	}
 }
 
-// ValidateData checks if all template tags are covered with provided data
+// Snapshot returns the version of the template with its current content, the
+// html part of the version is stored separately.
+func (t Template) Snapshot() *TemplateVersion {
+	return &TemplateVersion{
+		UserID:      t.UserID,
+		TemplateID:  t.ID,
+		Version:     t.Version,
+		Name:        t.Name,
+		SubjectPart: t.SubjectPart,
+		TextPart:    t.TextPart,
+		TextSource:  t.TextSource,
+		LayoutID:    t.LayoutID,
+		InlineCSS:   t.InlineCSS,
+		Partials:    t.Partials,
+		Layout:      t.Layout,
+	}
+}
+
+// ValidateData checks if all template tags are covered with provided data. The tags with an
+// inline default and the keys with an inverted section, which renders when the data is
+// missing, are optional. The tags of the partials and the layout are not validated.
 func (t Template) ValidateData(data map[string]string) error {
 	g, _ := errgroup.WithContext(context.Background())
 
@@ -98,12 +139,37 @@ func validateData(templateString string, data map[string]string) error {
 		return fmt.Errorf("parse string: %w", err)
 	}
 
-	for _, tag := range template.Tags() {
-		if tag.Name() == TagName || tag.Name() == TagUnsubscribeUrl {
+	tags := template.Tags()
+
+	optional := make(map[string]bool)
+	for _, tag := range tags {
+		if tag.Type() == mustache.InvertedSection {
+			optional[tag.Name()] = true
+		}
+	}
+
+	for _, tag := range tags {
+		if tag.Type() == mustache.Partial {
+			continue
+		}
+
+		key := tag.Name()
+		if tag.Type() == mustache.Variable {
+			expr, err := ParseTagExpr(key)
+			if err != nil {
+				return fmt.Errorf("%s tag: %w", tag.Name(), err)
+			}
+			if expr.Optional() {
+				continue
+			}
+			key = expr.Key
+		}
+
+		if key == TagName || key == TagUnsubscribeUrl || key == TagConfirmUrl || optional[key] {
 			continue
 		}
 
-		_, exist := data[tag.Name()]
+		_, exist := data[key]
 		if !exist {
 			return fmt.Errorf("%s tag: %w", tag.Name(), ErrMissingDefaultData)
 		}
@@ -112,6 +178,47 @@ func validateData(templateString string, data map[string]string) error {
 	return nil
 }
 
+// TemplateVersion is an immutable snapshot of the template, a new version is created on
+// every save of the template. The html part of the version is stored in S3.
+type TemplateVersion struct {
+	Model
+	UserID      int64  `json:"-"`
+	TemplateID  int64  `json:"template_id"`
+	Version     int64  `json:"version"`
+	Name        string `json:"name"`
+	SubjectPart string `json:"subject_part"`
+	TextPart    string `json:"text_part"`
+	TextSource  string `json:"text_source"`
+	LayoutID    int64  `json:"layout_id,omitempty"`
+	InlineCSS   bool   `json:"inline_css"`
+	// Partials maps the names of the partials which the version includes to their content, and
+	// Layout is the content of the layout which wraps the html part.
+	Partials StringMap `json:"-"`
+	Layout   string    `json:"-"`
+}
+
+func (v TemplateVersion) GetID() int64 {
+	return v.Model.ID
+}
+
+// TemplateDiff holds the unified diffs of the template parts between two versions,
+// the diff of a part which hasn't changed is empty.
+type TemplateDiff struct {
+	From        int64  `json:"from"`
+	To          int64  `json:"to"`
+	Name        string `json:"name"`
+	SubjectPart string `json:"subject_part"`
+	HTMLPart    string `json:"html_part"`
+	TextPart    string `json:"text_part"`
+}
+
+// TemplatePreview holds the parts of the template rendered with the preview data.
+type TemplatePreview struct {
+	SubjectPart string `json:"subject_part"`
+	HTMLPart    string `json:"html_part"`
+	TextPart    string `json:"text_part"`
+}
+
 type TemplateCollection struct {
 	NextToken  string         `json:"next_token"`
 	Collection []TemplateMeta `json:"collection"`
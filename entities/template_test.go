@@ -86,3 +86,68 @@ func TestGetters(t *testing.T) {
 	tableName := template.BaseTemplate.TableName()
 	assert.Equal(t, "templates", tableName)
 }
+
+func TestValidateDataOptionalTags(t *testing.T) {
+	template := Template{
+		BaseTemplate: BaseTemplate{
+			Name:        "test-template",
+			SubjectPart: "Hello {{name | default: \"friend\" | upper}}",
+		},
+		HTMLPart: "{{> header}}<p>{{#city}}From {{city}}{{/city}}{{^city}}From nowhere{{/city}}</p>",
+		TextPart: "Joined {{joined_at | date: \"Jan 2, 2006\"}}, {{ plan | default: \"free\" }}",
+	}
+
+	err := template.ValidateData(map[string]string{})
+	assert.True(t, errors.Is(err, ErrMissingDefaultData))
+	assert.Contains(t, err.Error(), "joined_at")
+
+	err = template.ValidateData(map[string]string{
+		"joined_at": "2021-03-04",
+	})
+	assert.Nil(t, err)
+
+	template.TextPart = "{{plan | lower}}"
+	err = template.ValidateData(map[string]string{})
+	assert.True(t, errors.Is(err, ErrInvalidHelper))
+}
+
+func TestTagExpr(t *testing.T) {
+	data := map[string]string{
+		"name":      "Jane",
+		"joined_at": "2021-03-04T10:00:00Z",
+		"blank":     " ",
+	}
+
+	tests := []struct {
+		tag      string
+		expected string
+		optional bool
+	}{
+		{tag: "name", expected: "Jane"},
+		{tag: "name | upper", expected: "JANE"},
+		{tag: `missing | default: "friend" | upper`, expected: "FRIEND", optional: true},
+		{tag: `blank|default:"a | b"`, expected: "a | b", optional: true},
+		{tag: `name | default: "Mr. \"X\""`, expected: "Jane", optional: true},
+		{tag: "joined_at | date", expected: "March 4, 2021"},
+		{tag: `joined_at | date: "02.01.2006"`, expected: "04.03.2021"},
+		{tag: `name | date: "02.01.2006"`, expected: "Jane"},
+	}
+	for _, tt := range tests {
+		expr, err := ParseTagExpr(tt.tag)
+		assert.Nil(t, err, tt.tag)
+		assert.Equal(t, tt.expected, expr.Eval(data), tt.tag)
+		assert.Equal(t, tt.optional, expr.Optional(), tt.tag)
+	}
+
+	for _, tag := range []string{
+		"| upper",
+		"name | lower",
+		"name | default",
+		"name | default: friend",
+		`name | default: "friend`,
+		`name | upper: "x"`,
+	} {
+		_, err := ParseTagExpr(tag)
+		assert.True(t, errors.Is(err, ErrInvalidHelper), tag)
+	}
+}
@@ -0,0 +1,175 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Helpers which are applied to the values of the variable tags with a pipe,
+// e.g. {{name | default: "friend" | upper}} or {{signup_date | date: "Jan 2, 2006"}}.
+const (
+	HelperDefault = "default"
+	HelperDate    = "date"
+	HelperUpper   = "upper"
+)
+
+// DefaultDateLayout is the layout of the date helper when none is given, the
+// layouts are written with the Go reference time Mon Jan 2 15:04:05 MST 2006.
+const DefaultDateLayout = "January 2, 2006"
+
+var ErrInvalidHelper = errors.New("invalid helper")
+
+// dateLayouts are the layouts which the values of the date helper are parsed with.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// TagHelper is a helper of the tag along with its argument.
+type TagHelper struct {
+	Name string
+	Arg  string
+}
+
+// TagExpr is the parsed name of a variable tag, the data key and the helpers
+// which are applied to its value from left to right.
+type TagExpr struct {
+	Key     string
+	Helpers []TagHelper
+}
+
+// ParseTagExpr parses the name of the variable tag, the names without
+// helpers are parsed as plain keys.
+func ParseTagExpr(name string) (TagExpr, error) {
+	parts, err := splitPipes(name)
+	if err != nil {
+		return TagExpr{}, err
+	}
+
+	expr := TagExpr{Key: strings.TrimSpace(parts[0])}
+	if expr.Key == "" {
+		return TagExpr{}, fmt.Errorf("%w: missing key in '%s'", ErrInvalidHelper, name)
+	}
+
+	for _, p := range parts[1:] {
+		h := TagHelper{Name: strings.TrimSpace(p)}
+		hasArg := false
+		if i := strings.Index(p, ":"); i >= 0 {
+			hasArg = true
+			h.Name = strings.TrimSpace(p[:i])
+			h.Arg, err = strconv.Unquote(strings.TrimSpace(p[i+1:]))
+			if err != nil {
+				return TagExpr{}, fmt.Errorf("%w: the argument of %s must be a quoted string", ErrInvalidHelper, h.Name)
+			}
+		}
+
+		switch h.Name {
+		case HelperDefault:
+			if !hasArg {
+				return TagExpr{}, fmt.Errorf("%w: %s requires an argument", ErrInvalidHelper, h.Name)
+			}
+		case HelperDate:
+			if !hasArg {
+				h.Arg = DefaultDateLayout
+			}
+		case HelperUpper:
+			if hasArg {
+				return TagExpr{}, fmt.Errorf("%w: %s takes no argument", ErrInvalidHelper, h.Name)
+			}
+		default:
+			return TagExpr{}, fmt.Errorf("%w: unknown helper '%s'", ErrInvalidHelper, h.Name)
+		}
+
+		expr.Helpers = append(expr.Helpers, h)
+	}
+
+	return expr, nil
+}
+
+// splitPipes splits the name of the tag by the pipes which are not quoted.
+func splitPipes(name string) ([]string, error) {
+	var (
+		parts   []string
+		start   int
+		quoted  bool
+		escaped bool
+	)
+	for i, r := range name {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && r == '|':
+			parts = append(parts, name[start:i])
+			start = i + 1
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote in '%s'", ErrInvalidHelper, name)
+	}
+
+	return append(parts, name[start:]), nil
+}
+
+// Optional reports whether the tag has an inline default value, so its data is not required.
+func (e TagExpr) Optional() bool {
+	for _, h := range e.Helpers {
+		if h.Name == HelperDefault {
+			return true
+		}
+	}
+	return false
+}
+
+// Eval applies the helpers to the value of the key. The values of the date helper
+// which can't be parsed as dates are left as they are.
+func (e TagExpr) Eval(data map[string]string) string {
+	val := data[e.Key]
+	for _, h := range e.Helpers {
+		switch h.Name {
+		case HelperDefault:
+			if strings.TrimSpace(val) == "" {
+				val = h.Arg
+			}
+		case HelperDate:
+			for _, layout := range dateLayouts {
+				t, err := time.Parse(layout, strings.TrimSpace(val))
+				if err == nil {
+					val = t.Format(h.Arg)
+					break
+				}
+			}
+		case HelperUpper:
+			val = strings.ToUpper(val)
+		}
+	}
+	return val
+}
+
+// TemplateHelpers maps the keys which replace the helper tags of a parsed template to their expressions.
+type TemplateHelpers map[string]TagExpr
+
+// Data returns the data along with the values of the helper tags which the template is rendered with.
+func (th TemplateHelpers) Data(data map[string]string) map[string]string {
+	if len(th) == 0 {
+		return data
+	}
+
+	res := make(map[string]string, len(data)+len(th))
+	for k, v := range data {
+		res[k] = v
+	}
+	for k, e := range th {
+		res[k] = e.Eval(data)
+	}
+
+	return res
+}
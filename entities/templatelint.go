@@ -0,0 +1,21 @@
+package entities
+
+// Codes of the warnings which the linter reports for the templates.
+const (
+	LintMissingUnsubscribe = "missing_unsubscribe_url"
+	LintInvalidURL         = "invalid_url"
+	LintRelativeURL        = "relative_url"
+	LintHTMLSize           = "html_size"
+	LintMissingAlt         = "missing_alt"
+	LintSpamSubject        = "spam_subject"
+	LintUnbalancedTag      = "unbalanced_tag"
+	LintUnknownTag         = "unknown_tag"
+)
+
+// TemplateWarning is an issue of the template which doesn't prevent it from being sent, but might
+// hurt its deliverability or rendering. The part is the json name of the template part, e.g. html_part.
+type TemplateWarning struct {
+	Code    string `json:"code"`
+	Part    string `json:"part"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,131 @@
+package entities
+
+import (
+	"math"
+	"time"
+)
+
+// Intervals of the stats time series.
+const (
+	StatsIntervalHour  = "hour"
+	StatsIntervalDay   = "day"
+	StatsIntervalWeek  = "week"
+	StatsIntervalMonth = "month"
+)
+
+// TruncateToInterval returns the beginning of the interval which contains t in UTC,
+// the weeks start on Monday.
+func TruncateToInterval(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	y, m, d := t.Date()
+
+	switch interval {
+	case StatsIntervalHour:
+		return t.Truncate(time.Hour)
+	case StatsIntervalWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+	case StatsIntervalMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// NextInterval returns the beginning of the interval which follows the interval starting at t.
+func NextInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case StatsIntervalHour:
+		return t.Add(time.Hour)
+	case StatsIntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case StatsIntervalMonth:
+		return t.AddDate(0, 1, 0)
+	}
+
+	return t.AddDate(0, 0, 1)
+}
+
+// StatsBucket holds the campaign events which happened in a single interval of the time series.
+// The rates of the bucket are relative to the totals of the campaign, so they show which part
+// of the delivered emails were opened or clicked in the interval.
+type StatsBucket struct {
+	Time       time.Time   `json:"time"`
+	Sent       int64       `json:"sent"`
+	Delivered  int64       `json:"delivered"`
+	Opens      OpensStats  `json:"opens"`
+	Clicks     ClicksStats `json:"clicks"`
+	Bounces    int64       `json:"bounces"`
+	Complaints int64       `json:"complaints"`
+	OpenRate   float64     `json:"open_rate"`
+	ClickRate  float64     `json:"click_rate"`
+	BounceRate float64     `json:"bounce_rate"`
+}
+
+// CampaignStatsTimeSeries represents the campaign stats bucketed by the interval.
+type CampaignStatsTimeSeries struct {
+	Interval   string        `json:"interval"`
+	TotalSent  int64         `json:"total_sent"`
+	Delivered  int64         `json:"delivered"`
+	Opens      OpensStats    `json:"opens"`
+	Clicks     ClicksStats   `json:"clicks"`
+	Bounces    int64         `json:"bounces"`
+	Complaints int64         `json:"complaints"`
+	OpenRate   float64       `json:"open_rate"`
+	ClickRate  float64       `json:"click_rate"`
+	BounceRate float64       `json:"bounce_rate"`
+	Buckets    []StatsBucket `json:"buckets"`
+}
+
+// Fill sorts the buckets, adds the empty buckets between the first and the last bucket
+// and calculates the rates of the time series and each of the buckets.
+func (ts *CampaignStatsTimeSeries) Fill(buckets map[time.Time]*StatsBucket) {
+	var first, last time.Time
+	for t := range buckets {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	ts.Buckets = []StatsBucket{}
+	if len(buckets) == 0 {
+		ts.setRates()
+		return
+	}
+
+	for t := first; !t.After(last); t = NextInterval(t, ts.Interval) {
+		b, ok := buckets[t]
+		if !ok {
+			b = &StatsBucket{Time: t}
+		}
+		b.OpenRate = rate(b.Opens.Unique, ts.Delivered)
+		b.ClickRate = rate(b.Clicks.UniqueClicks, ts.Delivered)
+		b.BounceRate = rate(b.Bounces, ts.TotalSent)
+		ts.Buckets = append(ts.Buckets, *b)
+	}
+
+	ts.setRates()
+}
+
+func (ts *CampaignStatsTimeSeries) setRates() {
+	ts.OpenRate = rate(ts.Opens.Unique, ts.Delivered)
+	ts.ClickRate = rate(ts.Clicks.UniqueClicks, ts.Delivered)
+	ts.BounceRate = rate(ts.Bounces, ts.TotalSent)
+}
+
+// rate returns the ratio of n to total rounded to four decimals.
+func rate(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return roundRate(float64(n) / float64(total))
+}
+
+// roundRate rounds the rate to four decimals.
+func roundRate(r float64) float64 {
+	return math.Round(r*10000) / 10000
+}
@@ -0,0 +1,120 @@
+package entities
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/mailbadger/app/utils"
+)
+
+// SES tags of the emails whose opens or clicks are tracked by the app, the SES
+// open and click events of these emails are skipped so they are not counted twice.
+const (
+	TagTrackedOpens  = "tracked_opens"
+	TagTrackedClicks = "tracked_clicks"
+)
+
+// ErrInvalidTrackingSignature is returned when the tracking url is not signed by the app.
+var ErrInvalidTrackingSignature = errors.New("entities: invalid tracking signature")
+
+// TrackingParams identify the campaign email of a tracked open or click.
+type TrackingParams struct {
+	UserID       int64
+	CampaignID   int64
+	VariantID    int64
+	SubscriberID int64
+}
+
+// OpenURL returns the signed url of the open tracking pixel.
+func (p TrackingParams) OpenURL(secret, appURL string) (string, error) {
+	q, err := p.values(secret, "")
+	if err != nil {
+		return "", err
+	}
+
+	return appURL + "/api/t/open?" + q.Encode(), nil
+}
+
+// ClickURL returns the signed url which records the click and redirects to the link.
+func (p TrackingParams) ClickURL(secret, appURL, link string) (string, error) {
+	q, err := p.values(secret, link)
+	if err != nil {
+		return "", err
+	}
+	q.Set("url", link)
+
+	return appURL + "/api/t/click?" + q.Encode(), nil
+}
+
+func (p TrackingParams) values(secret, link string) (url.Values, error) {
+	sig, err := p.sign(secret, link)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("u", strconv.FormatInt(p.UserID, 10))
+	q.Set("c", strconv.FormatInt(p.CampaignID, 10))
+	q.Set("s", strconv.FormatInt(p.SubscriberID, 10))
+	if p.VariantID != 0 {
+		q.Set("v", strconv.FormatInt(p.VariantID, 10))
+	}
+	q.Set("sig", sig)
+
+	return q, nil
+}
+
+func (p TrackingParams) sign(secret, link string) (string, error) {
+	if secret == "" {
+		return "", errors.New("entities: unable to sign tracking params: key is empty")
+	}
+
+	data := fmt.Sprintf("track:%d:%d:%d:%d:%s", p.UserID, p.CampaignID, p.VariantID, p.SubscriberID, link)
+	return utils.SignData(data, secret)
+}
+
+// ParseTrackingParams parses the query of a tracking url and verifies its signature,
+// the link is empty for the open tracking urls.
+func ParseTrackingParams(q url.Values, secret string) (TrackingParams, string, error) {
+	var (
+		p   TrackingParams
+		err error
+	)
+
+	ids := []struct {
+		key      string
+		value    *int64
+		optional bool
+	}{
+		{"u", &p.UserID, false},
+		{"c", &p.CampaignID, false},
+		{"s", &p.SubscriberID, false},
+		{"v", &p.VariantID, true},
+	}
+	for _, id := range ids {
+		v := q.Get(id.key)
+		if v == "" && id.optional {
+			continue
+		}
+
+		*id.value, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return p, "", fmt.Errorf("entities: parse tracking param %q: %w", id.key, err)
+		}
+	}
+
+	link := q.Get("url")
+	sig, err := p.sign(secret, link)
+	if err != nil {
+		return p, "", err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(sig), []byte(q.Get("sig"))) != 1 {
+		return p, "", ErrInvalidTrackingSignature
+	}
+
+	return p, link, nil
+}
@@ -0,0 +1,59 @@
+package entities
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackingParams(t *testing.T) {
+	secret := "secretexmplkeythatis32characters"
+	p := TrackingParams{UserID: 1, CampaignID: 2, VariantID: 3, SubscriberID: 4}
+
+	open, err := p.OpenURL(secret, "https://app.example.com")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(open, "https://app.example.com/api/t/open?"))
+
+	u, err := url.Parse(open)
+	assert.Nil(t, err)
+
+	parsed, link, err := ParseTrackingParams(u.Query(), secret)
+	assert.Nil(t, err)
+	assert.Equal(t, p, parsed)
+	assert.Empty(t, link)
+
+	click, err := p.ClickURL(secret, "https://app.example.com", "https://example.com/?a=1&b=2")
+	assert.Nil(t, err)
+
+	u, err = url.Parse(click)
+	assert.Nil(t, err)
+
+	parsed, link, err = ParseTrackingParams(u.Query(), secret)
+	assert.Nil(t, err)
+	assert.Equal(t, p, parsed)
+	assert.Equal(t, "https://example.com/?a=1&b=2", link)
+
+	// the link and the ids can't be changed without invalidating the signature.
+	q := u.Query()
+	q.Set("url", "https://evil.example.com")
+	_, _, err = ParseTrackingParams(q, secret)
+	assert.Equal(t, ErrInvalidTrackingSignature, err)
+
+	q = u.Query()
+	q.Set("s", "5")
+	_, _, err = ParseTrackingParams(q, secret)
+	assert.Equal(t, ErrInvalidTrackingSignature, err)
+
+	_, _, err = ParseTrackingParams(u.Query(), "anothersecretkeythatis32character")
+	assert.Equal(t, ErrInvalidTrackingSignature, err)
+
+	q = u.Query()
+	q.Del("c")
+	_, _, err = ParseTrackingParams(q, secret)
+	assert.NotNil(t, err)
+
+	_, err = p.OpenURL("", "https://app.example.com")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,87 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// Transactional message statuses
+const (
+	TransactionalStatusQueued     = "queued"
+	TransactionalStatusSent       = "sent"
+	TransactionalStatusFailed     = "failed"
+	TransactionalStatusDelivered  = "delivered"
+	TransactionalStatusBounced    = "bounced"
+	TransactionalStatusComplained = "complained"
+)
+
+// Transactional message events which are counted instead of setting the status
+const (
+	TransactionalEventOpen  = "open"
+	TransactionalEventClick = "click"
+)
+
+// TagTransactionalID is the mail tag which attributes the SES events to the transactional message.
+const TagTransactionalID = "transactional_id"
+
+// TransactionalMessage represents a single email sent through the transactional API,
+// the record holds the send result and the events of the message.
+type TransactionalMessage struct {
+	Model
+	UserID      int64       `json:"-" gorm:"column:user_id; index"`
+	EventID     ksuid.KSUID `json:"event_id"`
+	TemplateID  int64       `json:"template_id"`
+	Recipient   string      `json:"recipient"`
+	Source      string      `json:"source"`
+	TagsJSON    JSON        `json:"tags" gorm:"column:tags; type:json"`
+	Status      string      `json:"status"`
+	Description string      `json:"description"`
+	MessageID   *string     `json:"message_id"`
+	Opens       int64       `json:"opens"`
+	Clicks      int64       `json:"clicks"`
+}
+
+// GetTags returns the custom tags of the message.
+func (m *TransactionalMessage) GetTags() (map[string]string, error) {
+	tags := make(map[string]string)
+	if m.TagsJSON.IsNull() {
+		return tags, nil
+	}
+
+	err := json.Unmarshal(m.TagsJSON, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// SetTags stores the custom tags of the message.
+func (m *TransactionalMessage) SetTags(tags map[string]string) error {
+	if len(tags) == 0 {
+		m.TagsJSON = nil
+		return nil
+	}
+
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	m.TagsJSON = b
+
+	return nil
+}
+
+func (m TransactionalMessage) GetID() int64 {
+	return m.Model.ID
+}
+
+func (m TransactionalMessage) GetCreatedAt() time.Time {
+	return m.Model.CreatedAt
+}
+
+func (m TransactionalMessage) GetUpdatedAt() time.Time {
+	return m.Model.UpdatedAt
+}
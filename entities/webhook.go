@@ -0,0 +1,110 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// Webhook event types
+const (
+	WebhookEventSubscriberCreated      = "subscriber.created"
+	WebhookEventSubscriberUnsubscribed = "subscriber.unsubscribed"
+	WebhookEventEmailBounced           = "email.bounced"
+	WebhookEventEmailComplained        = "email.complained"
+	WebhookEventEmailOpened            = "email.opened"
+	WebhookEventEmailClicked           = "email.clicked"
+	WebhookEventCampaignSent           = "campaign.sent"
+	WebhookEventCampaignFailed         = "campaign.failed"
+)
+
+// WebhookEventTypes are the event types the webhooks can be subscribed to.
+var WebhookEventTypes = []string{
+	WebhookEventSubscriberCreated,
+	WebhookEventSubscriberUnsubscribed,
+	WebhookEventEmailBounced,
+	WebhookEventEmailComplained,
+	WebhookEventEmailOpened,
+	WebhookEventEmailClicked,
+	WebhookEventCampaignSent,
+	WebhookEventCampaignFailed,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// Webhook represents the user's endpoint which receives the events, the payloads
+// are signed with the secret of the webhook.
+type Webhook struct {
+	Model
+	UserID int64      `json:"-" gorm:"column:user_id; index"`
+	URL    string     `json:"url"`
+	Secret string     `json:"secret"`
+	Events StringList `json:"events"`
+	Active bool       `json:"active"`
+}
+
+// IsSubscribed checks if the webhook receives the event type.
+func (w *Webhook) IsSubscribed(eventType string) bool {
+	return w.Active && w.Events.Contains(eventType)
+}
+
+func (w Webhook) GetID() int64 {
+	return w.Model.ID
+}
+
+func (w Webhook) GetCreatedAt() time.Time {
+	return w.Model.CreatedAt
+}
+
+func (w Webhook) GetUpdatedAt() time.Time {
+	return w.Model.UpdatedAt
+}
+
+// WebhookEvent is the payload posted to the webhook endpoints.
+type WebhookEvent struct {
+	ID        ksuid.KSUID `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery holds the payload of an event sent to the webhook along with
+// the result of the last delivery attempt.
+type WebhookDelivery struct {
+	Model
+	UserID         int64       `json:"-" gorm:"column:user_id; index"`
+	WebhookID      int64       `json:"webhook_id"`
+	Webhook        *Webhook    `json:"-"`
+	EventID        ksuid.KSUID `json:"event_id"`
+	EventType      string      `json:"event_type"`
+	Payload        JSON        `json:"payload"`
+	Status         string      `json:"status"`
+	Attempts       int         `json:"attempts"`
+	ResponseStatus int         `json:"response_status"`
+	ErrorMessage   string      `json:"error_message"`
+	NextAttemptAt  NullTime    `json:"next_attempt_at"`
+}
+
+func (d WebhookDelivery) GetID() int64 {
+	return d.Model.ID
+}
+
+func (d WebhookDelivery) GetCreatedAt() time.Time {
+	return d.Model.CreatedAt
+}
+
+func (d WebhookDelivery) GetUpdatedAt() time.Time {
+	return d.Model.UpdatedAt
+}
+
+// WebhookTopicParams represent the request params used
+// by the webhooks consumer.
+type WebhookTopicParams struct {
+	DeliveryID int64 `json:"delivery_id"`
+	UserID     int64 `json:"user_id"`
+}
@@ -14,6 +14,7 @@ default allow = false
 # Allow admins to do anything.
 allow {
 	user_is_admin
+	scope_allowed
 }
 
 user_is_admin {
@@ -25,4 +26,25 @@ allow {
     permissions := role_permissions[r]
     p := permissions[_]
     p == {"method": input.method, "path": input.path}
-}
\ No newline at end of file
+    scope_allowed
+}
+
+# Requests authenticated with an api key are limited to the scopes of the key,
+# a scope grants read (GET) or write access to the resource after /api in the path.
+scope_allowed {
+	not input.api_key
+}
+
+scope_allowed {
+	input.api_key
+	resource := split(input.path, "/")[2]
+	input.scopes[_] == sprintf("%s:%s", [resource, access])
+}
+
+access = "read" {
+	input.method == "GET"
+}
+
+access = "write" {
+	input.method != "GET"
+}
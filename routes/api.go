@@ -12,9 +12,10 @@ import (
 	"github.com/mailbadger/app/emails"
 	"github.com/mailbadger/app/routes/middleware"
 	"github.com/mailbadger/app/services/boundaries"
+	"github.com/mailbadger/app/services/jobs"
 	"github.com/mailbadger/app/services/reports"
-	"github.com/mailbadger/app/services/subscribers"
 	templatesvc "github.com/mailbadger/app/services/templates"
+	"github.com/mailbadger/app/services/webhooks"
 	"github.com/mailbadger/app/session"
 	"github.com/mailbadger/app/sqs"
 	"github.com/mailbadger/app/storage"
@@ -32,10 +33,13 @@ type API struct {
 	emailSender  emails.Sender
 	templatesvc  templatesvc.Service
 	boundarysvc  boundaries.Service
-	subscrsvc    subscribers.Service
 	reportsvc    reports.Service
+	webhooksvc   webhooks.Service
+	jobsvc       jobs.Service
 
 	campaignerQueueURL sqs.CampaignerQueueURL
+	sendEmailQueueURL  sqs.SendEmailQueueURL
+	eventsQueueURL     sqs.EventsQueueURL
 	appDir             string
 	appURL             string
 
@@ -45,6 +49,7 @@ type API struct {
 	verifyEmail            bool
 	recaptchaSecret        string
 	unsubscribeTokenSecret string
+	encryptionKey          string
 	systemEmail            string
 	social                 config.Social
 }
@@ -58,9 +63,12 @@ func From(
 	emailSender emails.Sender,
 	templatesvc templatesvc.Service,
 	boundarysvc boundaries.Service,
-	subscrsvc subscribers.Service,
 	reportsvc reports.Service,
+	webhooksvc webhooks.Service,
+	jobsvc jobs.Service,
 	campaignerQueueURL sqs.CampaignerQueueURL,
+	sendEmailQueueURL sqs.SendEmailQueueURL,
+	eventsQueueURL sqs.EventsQueueURL,
 	conf config.Config,
 ) API {
 	return New(
@@ -72,9 +80,12 @@ func From(
 		emailSender,
 		templatesvc,
 		boundarysvc,
-		subscrsvc,
 		reportsvc,
+		webhooksvc,
+		jobsvc,
 		campaignerQueueURL,
+		sendEmailQueueURL,
+		eventsQueueURL,
 		conf.Server.AppDir,
 		conf.Server.AppURL,
 		conf.Storage.S3.FilesBucket,
@@ -82,6 +93,7 @@ func From(
 		conf.Server.VerifyEmailOnSignup,
 		conf.Server.RecaptchaSecret,
 		conf.Server.UnsubscribeSecret,
+		conf.Server.EncryptionKey,
 		conf.Server.SystemEmailSource,
 		conf.Social,
 	)
@@ -95,9 +107,12 @@ func New(
 	emailSender emails.Sender,
 	templatesvc templatesvc.Service,
 	boundarysvc boundaries.Service,
-	subscrsvc subscribers.Service,
 	reportsvc reports.Service,
+	webhooksvc webhooks.Service,
+	jobsvc jobs.Service,
 	campaignerQueueURL sqs.CampaignerQueueURL,
+	sendEmailQueueURL sqs.SendEmailQueueURL,
+	eventsQueueURL sqs.EventsQueueURL,
 	appDir string,
 	appURL string,
 	filesBucket string,
@@ -105,6 +120,7 @@ func New(
 	verifyEmail bool,
 	recaptchaSecret string,
 	unsubscribeTokenSecret string,
+	encryptionKey string,
 	systemEmail string,
 	social config.Social,
 ) API {
@@ -117,9 +133,12 @@ func New(
 		emailSender:            emailSender,
 		templatesvc:            templatesvc,
 		boundarysvc:            boundarysvc,
-		subscrsvc:              subscrsvc,
 		reportsvc:              reportsvc,
+		webhooksvc:             webhooksvc,
+		jobsvc:                 jobsvc,
 		campaignerQueueURL:     campaignerQueueURL,
+		sendEmailQueueURL:      sendEmailQueueURL,
+		eventsQueueURL:         eventsQueueURL,
 		appDir:                 appDir,
 		appURL:                 appURL,
 		filesBucket:            filesBucket,
@@ -127,6 +146,7 @@ func New(
 		verifyEmail:            verifyEmail,
 		recaptchaSecret:        recaptchaSecret,
 		unsubscribeTokenSecret: unsubscribeTokenSecret,
+		encryptionKey:          encryptionKey,
 		systemEmail:            systemEmail,
 		social:                 social,
 	}
@@ -154,17 +174,7 @@ func (api API) Handler() http.Handler {
 		}
 
 		if strings.HasPrefix(c.Request.URL.Path, "/unsubscribe.html") {
-			email := c.Query("email")
-			t := c.Query("t")
-			uuid := c.Query("uuid")
-			failed := c.Query("failed")
-
-			c.HTML(http.StatusOK, "unsubscribe.html", gin.H{
-				"email":  email,
-				"t":      t,
-				"uuid":   uuid,
-				"failed": failed,
-			})
+			actions.GetPreferences(api.store, api.unsubscribeTokenSecret)(c)
 			return
 		}
 
@@ -173,6 +183,13 @@ func (api API) Handler() http.Handler {
 			return
 		}
 
+		if strings.HasPrefix(c.Request.URL.Path, "/subscription-confirmed.html") {
+			c.HTML(http.StatusOK, "subscription-confirmed.html", gin.H{
+				"failed": c.Query("failed"),
+			})
+			return
+		}
+
 		c.File(api.appDir + "/index.html")
 	})
 
@@ -266,14 +283,43 @@ func (api API) SetGuestRoutes(handler *gin.Engine, middleware ...gin.HandlerFunc
 			api.appURL,
 		),
 	)
-	guest.POST("/hooks/:uuid", actions.HandleHook(api.store))
+	guest.POST("/hooks/:uuid", actions.HandleHook(api.sqsPublisher, api.eventsQueueURL))
 	guest.POST("/unsubscribe",
 		actions.PostUnsubscribe(
 			api.store,
+			api.webhooksvc,
+			api.unsubscribeTokenSecret,
+			api.appURL,
+		),
+	)
+	guest.POST("/unsubscribe/one-click", actions.PostOneClickUnsubscribe(api.store, api.webhooksvc, api.unsubscribeTokenSecret))
+	guest.POST("/preferences",
+		actions.PostPreferences(
+			api.store,
+			api.webhooksvc,
+			api.unsubscribeTokenSecret,
+			api.appURL,
+		),
+	)
+
+	// the id param of the public form routes is the uuid of the form.
+	guest.POST("/forms/:id/subscribe",
+		actions.PostFormSubscribe(
+			api.store,
+			api.boundarysvc,
+			api.templatesvc,
+			api.webhooksvc,
+			api.sqsPublisher,
+			api.sendEmailQueueURL,
 			api.unsubscribeTokenSecret,
 			api.appURL,
 		),
 	)
+	guest.GET("/forms/:id/confirm", actions.GetFormConfirm(api.store, api.unsubscribeTokenSecret, api.appURL))
+
+	// the tracking urls of the campaign emails, signed with the unsubscribe token secret.
+	guest.GET("/t/open", actions.GetTrackOpen(api.store, api.webhooksvc, api.unsubscribeTokenSecret))
+	guest.GET("/t/click", actions.GetTrackClick(api.store, api.webhooksvc, api.unsubscribeTokenSecret))
 }
 
 // SetAuthorizedRoutes sets the authorized routes to the gin engine handler along with
@@ -299,6 +345,20 @@ func (api API) SetAuthorizedRoutes(handler *gin.Engine, middlewares ...gin.Handl
 			templates.POST("", actions.PostTemplate(api.templatesvc, api.store))
 			templates.PUT("/:id", actions.PutTemplate(api.templatesvc, api.store))
 			templates.DELETE("/:id", actions.DeleteTemplate(api.templatesvc))
+			templates.GET("/:id/versions", middleware.PaginateWithCursor(), actions.GetTemplateVersions(api.templatesvc))
+			templates.GET("/:id/versions/diff", actions.GetTemplateVersionsDiff(api.templatesvc))
+			templates.GET("/:id/versions/:version", actions.GetTemplateVersion(api.templatesvc))
+			templates.POST("/:id/versions/:version/restore", actions.PostTemplateVersionRestore(api.templatesvc))
+			templates.POST("/:id/preview", actions.PostTemplatePreview(api.templatesvc, api.store, api.unsubscribeTokenSecret, api.appURL))
+		}
+
+		partials := authorized.Group("/partials")
+		{
+			partials.GET("", middleware.PaginateWithCursor(), actions.GetPartials(api.store))
+			partials.GET("/:id", actions.GetPartial(api.store))
+			partials.POST("", actions.PostPartial(api.store, api.templatesvc))
+			partials.PUT("/:id", actions.PutPartial(api.store, api.templatesvc))
+			partials.DELETE("/:id", actions.DeletePartial(api.store))
 		}
 
 		campaigns := authorized.Group("/campaigns")
@@ -309,13 +369,27 @@ func (api API) SetAuthorizedRoutes(handler *gin.Engine, middlewares ...gin.Handl
 			campaigns.PUT("/:id", actions.PutCampaign(api.store))
 			campaigns.DELETE("/:id", actions.DeleteCampaign(api.store))
 			campaigns.POST("/:id/start", actions.StartCampaign(api.store, api.sqsPublisher, api.campaignerQueueURL))
+			campaigns.POST("/:id/pause", actions.PauseCampaign(api.store))
+			campaigns.POST("/:id/resume", actions.ResumeCampaign(api.store, api.sqsPublisher, api.campaignerQueueURL))
+			campaigns.POST("/:id/cancel", actions.CancelCampaign(api.store))
+			campaigns.POST("/:id/test-send", actions.PostCampaignTestSend(
+				api.store,
+				api.templatesvc,
+				api.sqsPublisher,
+				api.sendEmailQueueURL,
+				api.unsubscribeTokenSecret,
+				api.appURL,
+			))
 			campaigns.GET("/:id/opens", middleware.PaginateWithCursor(), actions.GetCampaignOpens(api.store))
 			campaigns.GET("/:id/stats", actions.GetCampaignStats(api.store))
+			campaigns.GET("/:id/stats/timeseries", actions.GetCampaignStatsTimeSeries(api.store))
 			campaigns.GET("/:id/clicks", actions.GetCampaignClicksStats(api.store))
 			campaigns.GET("/:id/complaints", middleware.PaginateWithCursor(), actions.GetCampaignComplaints(api.store))
 			campaigns.GET("/:id/bounces", middleware.PaginateWithCursor(), actions.GetCampaignBounces(api.store))
 			campaigns.PATCH("/:id/schedule", actions.PatchCampaignSchedule(api.store))
 			campaigns.DELETE("/:id/schedule", actions.DeleteCampaignSchedule(api.store))
+			campaigns.PUT("/:id/split-test", actions.PutCampaignSplitTest(api.store))
+			campaigns.DELETE("/:id/split-test", actions.DeleteCampaignSplitTest(api.store))
 		}
 
 		segments := authorized.Group("/segments")
@@ -331,33 +405,100 @@ func (api API) SetAuthorizedRoutes(handler *gin.Engine, middlewares ...gin.Handl
 			segments.DELETE("/:id/subscribers/:sub_id", actions.DetachSubscriber(api.store))
 		}
 
+		forms := authorized.Group("/forms")
+		{
+			forms.GET("", middleware.PaginateWithCursor(), actions.GetForms(api.store))
+			forms.GET("/:id", actions.GetForm(api.store))
+			forms.POST("", actions.PostForm(api.store))
+			forms.PUT("/:id", actions.PutForm(api.store))
+			forms.DELETE("/:id", actions.DeleteForm(api.store))
+		}
+
+		transactional := authorized.Group("/transactional")
+		{
+			transactional.GET("", middleware.PaginateWithCursor(), actions.GetTransactionalMessages(api.store))
+			transactional.GET("/:id", actions.GetTransactionalMessage(api.store))
+			transactional.POST("/send", actions.PostTransactionalSend(
+				api.store,
+				api.templatesvc,
+				api.sqsPublisher,
+				api.sendEmailQueueURL,
+			))
+		}
+
 		subscribers := authorized.Group("/subscribers")
 		{
 			subscribers.GET("", middleware.PaginateWithCursor(), actions.GetSubscribers(api.store))
 			subscribers.GET("/:id", actions.GetSubscriber(api.store))
 			subscribers.GET("/export/download", actions.DownloadSubscribersReport(api.store, api.s3Client, api.filesBucket))
-			subscribers.POST("", actions.PostSubscriber(api.boundarysvc, api.store))
+			subscribers.POST("", actions.PostSubscriber(api.boundarysvc, api.store, api.webhooksvc))
 			subscribers.PUT("/:id", actions.PutSubscriber(api.store))
 			subscribers.DELETE("/:id", actions.DeleteSubscriber(api.store))
 			subscribers.POST("/import", actions.ImportSubscribers(
-				api.subscrsvc,
+				api.jobsvc,
 				api.boundarysvc,
 				api.store,
 				api.s3Client,
 				api.filesBucket,
 			))
-			subscribers.POST("/bulk-remove", actions.BulkRemoveSubscribers(api.subscrsvc, api.s3Client, api.filesBucket))
-			subscribers.POST("/export", actions.ExportSubscribers(api.reportsvc, api.filesBucket))
+			subscribers.POST("/bulk-remove", actions.BulkRemoveSubscribers(api.jobsvc))
+			subscribers.POST("/export", actions.ExportSubscribers(api.store, api.reportsvc, api.jobsvc))
+		}
+
+		apiKeys := authorized.Group("/api-keys")
+		{
+			apiKeys.GET("", actions.GetAPIKeys(api.store))
+			apiKeys.POST("", actions.PostAPIKey(api.store))
+			apiKeys.PUT("/:id", actions.PutAPIKey(api.store))
+			apiKeys.POST("/:id/rotate", actions.RotateAPIKey(api.store))
+			apiKeys.DELETE("/:id", actions.DeleteAPIKey(api.store))
+		}
+
+		webhooks := authorized.Group("/webhooks")
+		{
+			webhooks.GET("", middleware.PaginateWithCursor(), actions.GetWebhooks(api.store))
+			webhooks.GET("/:id", actions.GetWebhook(api.store))
+			webhooks.POST("", actions.PostWebhook(api.store))
+			webhooks.PUT("/:id", actions.PutWebhook(api.store))
+			webhooks.DELETE("/:id", actions.DeleteWebhook(api.store))
+			webhooks.GET("/:id/deliveries", middleware.PaginateWithCursor(), actions.GetWebhookDeliveries(api.store))
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", actions.PostWebhookRedeliver(api.store, api.webhooksvc))
+		}
+
+		reports := authorized.Group("/reports")
+		{
+			reports.POST("", actions.PostReport(api.store, api.reportsvc, api.jobsvc))
+			reports.GET("/download", actions.GetReportDownload(api.store, api.s3Client, api.filesBucket))
+		}
+
+		jobs := authorized.Group("/jobs")
+		{
+			jobs.GET("", middleware.PaginateWithCursor(), actions.GetJobs(api.store))
+			jobs.GET("/:id", actions.GetJob(api.store))
+			jobs.GET("/:id/rejects", actions.GetJobRejects(api.store, api.s3Client, api.filesBucket))
+			jobs.POST("/:id/retry", actions.PostJobRetry(api.store, api.jobsvc))
+		}
+
+		metrics := authorized.Group("/metrics")
+		{
+			metrics.GET("/subscribers", actions.GetSubscriberMetrics(api.store))
 		}
 
 		ses := authorized.Group(("/ses"))
 		{
 			ses.GET("/keys", actions.GetSESKeys(api.store))
-			ses.POST("/keys", actions.PostSESKeys(api.store, api.appURL))
+			ses.POST("/keys", actions.PostSESKeys(api.store, api.jobsvc))
 			ses.DELETE("/keys", actions.DeleteSESKeys(api.store))
 			ses.GET("/quota", actions.GetSESQuota(api.store))
 		}
 
+		smtp := authorized.Group("/smtp")
+		{
+			smtp.GET("/settings", actions.GetSMTPSettings(api.store))
+			smtp.POST("/settings", actions.PostSMTPSettings(api.store, api.encryptionKey))
+			smtp.DELETE("/settings", actions.DeleteSMTPSettings(api.store))
+		}
+
 		s3 := authorized.Group("/s3")
 		{
 			s3.POST("/sign", actions.GetSignedURL(api.s3Client, api.filesBucket))
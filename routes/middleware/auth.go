@@ -3,6 +3,7 @@ package middleware
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/csrf"
 	"github.com/sirupsen/logrus"
@@ -23,6 +24,9 @@ const (
 	userKey    = "user"
 )
 
+// lastUsedInterval is the minimum interval between the updates of the api key last used time.
+const lastUsedInterval = time.Minute
+
 // GetUser returns the user set in the context
 func GetUser(c *gin.Context) *entities.User {
 	val, ok := c.Get(userKey)
@@ -46,11 +50,15 @@ func Authorized(
 	compiler *ast.Compiler,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var u *entities.User
+		var (
+			u   *entities.User
+			key *entities.APIKey
+		)
 
 		authHeader := c.GetHeader(APIKeyAuth)
 		if authHeader != "" {
-			key, err := storage.GetAPIKey(authHeader)
+			var err error
+			key, err = storage.GetAPIKey(entities.HashAPIKey(authHeader))
 			if err != nil {
 				logger.From(c).WithError(err).Error("unable to fetch api key")
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "You are not authorized to perform this request."})
@@ -80,6 +88,11 @@ func Authorized(
 			"method": c.Request.Method,
 			"path":   c.FullPath(),
 		}
+		// requests with api keys are limited to the scopes of the key.
+		if key != nil {
+			input["api_key"] = true
+			input["scopes"] = key.GrantedScopes()
+		}
 		rego := rego.New(
 			rego.Query("data.rbac.authz.allow"),
 			rego.Compiler(compiler),
@@ -111,6 +124,16 @@ func Authorized(
 			return
 		}
 
+		// the last used time is not updated on every request to spare the writes.
+		if key != nil {
+			now := time.Now()
+			if !key.LastUsedAt.Valid || now.Sub(key.LastUsedAt.Time) > lastUsedInterval {
+				if err := storage.UpdateAPIKeyLastUsed(key.ID, now); err != nil {
+					logger.From(c).WithError(err).WithField("api_key_id", key.ID).Warn("auth: unable to update api key last used time")
+				}
+			}
+		}
+
 		c.Set(userKey, u)
 
 		entry := logger.From(c).WithField("user_id", u.ID)
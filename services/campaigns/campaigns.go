@@ -7,7 +7,6 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
-	"github.com/cbroglie/mustache"
 
 	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/entities"
@@ -19,10 +18,9 @@ type Service interface {
 	PrepareSubscriberEmailData(
 		s entities.Subscriber,
 		msg entities.CampaignerTopicParams,
-		campaignID int64,
-		html *mustache.Template,
-		sub *mustache.Template,
-		text *mustache.Template,
+		campaign *entities.Campaign,
+		variantID int64,
+		tmpl *entities.CampaignTemplateData,
 	) (*entities.SenderTopicParams, error)
 	PublishSubscriberEmailParams(ctx context.Context, params *entities.SenderTopicParams, queueURL *string) error
 }
@@ -32,6 +30,7 @@ type service struct {
 	db                storage.Storage
 	sqsclient         awssqs.SendReceiveMessageAPI
 	unsubscribeSecret string
+	unsubscribeMailto string
 	appURL            string
 }
 
@@ -40,6 +39,7 @@ func From(db storage.Storage, sqsclient awssqs.SendReceiveMessageAPI, conf confi
 		db,
 		sqsclient,
 		conf.Server.UnsubscribeSecret,
+		conf.Server.UnsubscribeMailto,
 		conf.Server.AppURL,
 	)
 }
@@ -48,12 +48,14 @@ func New(
 	db storage.Storage,
 	sqsclient awssqs.SendReceiveMessageAPI,
 	secret string,
+	mailto string,
 	appURL string,
 ) Service {
 	return &service{
 		db:                db,
 		sqsclient:         sqsclient,
 		unsubscribeSecret: secret,
+		unsubscribeMailto: mailto,
 		appURL:            appURL,
 	}
 }
@@ -61,10 +63,9 @@ func New(
 func (svc *service) PrepareSubscriberEmailData(
 	s entities.Subscriber,
 	msg entities.CampaignerTopicParams,
-	campaignID int64,
-	html *mustache.Template,
-	sub *mustache.Template,
-	text *mustache.Template,
+	campaign *entities.Campaign,
+	variantID int64,
+	tmpl *entities.CampaignTemplateData,
 ) (*entities.SenderTopicParams, error) {
 
 	var (
@@ -95,15 +96,36 @@ func (svc *service) PrepareSubscriberEmailData(
 
 	m[entities.TagUnsubscribeUrl] = url
 
-	err = html.FRender(&htmlBuf, m)
+	oneClickURL, err := s.GetOneClickUnsubscribeURL(msg.UserUUID, svc.unsubscribeSecret, svc.appURL)
+	if err != nil {
+		return nil, fmt.Errorf("campaign service: get one-click unsubscribe url: %w", err)
+	}
+
+	m = tmpl.Helpers.Data(m)
+
+	err = tmpl.HTMLPart.FRender(&htmlBuf, m)
 	if err != nil {
 		return nil, fmt.Errorf("campaign service: prepare email data: render html: %w", err)
 	}
-	err = sub.FRender(&subBuf, m)
+	htmlPart := htmlBuf.Bytes()
+	if campaign.TrackOpens || campaign.TrackClicks {
+		tracked, err := svc.track(htmlBuf.String(), campaign, entities.TrackingParams{
+			UserID:       msg.UserID,
+			CampaignID:   campaign.ID,
+			VariantID:    variantID,
+			SubscriberID: s.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("campaign service: prepare email data: %w", err)
+		}
+		htmlPart = []byte(tracked)
+	}
+
+	err = tmpl.SubjectPart.FRender(&subBuf, m)
 	if err != nil {
 		return nil, fmt.Errorf("campaign service: prepare email data: render subject: %w", err)
 	}
-	err = text.FRender(&textBuf, m)
+	err = tmpl.TextPart.FRender(&textBuf, m)
 	if err != nil {
 		return nil, fmt.Errorf("campaign service: prepare email data: render text: %w", err)
 	}
@@ -114,13 +136,18 @@ func (svc *service) PrepareSubscriberEmailData(
 		SubscriberEmail:        s.Email,
 		Source:                 msg.Source,
 		ConfigurationSetExists: msg.ConfigurationSetExists,
-		CampaignID:             campaignID,
+		CampaignID:             campaign.ID,
+		VariantID:              variantID,
 		SesKeys:                msg.SesKeys,
-		HTMLPart:               htmlBuf.Bytes(),
+		HTMLPart:               htmlPart,
 		SubjectPart:            subBuf.Bytes(),
 		TextPart:               textBuf.Bytes(),
 		UserUUID:               msg.UserUUID,
 		UserID:                 msg.UserID,
+		UnsubscribeURL:         oneClickURL,
+		UnsubscribeMailto:      svc.unsubscribeMailto,
+		TrackOpens:             campaign.TrackOpens,
+		TrackClicks:            campaign.TrackClicks,
 	}
 
 	return &sender, nil
@@ -95,12 +95,29 @@ func (sched *Scheduler) execute(ctx context.Context) error {
 			logEntry.WithError(err).Error("sched: failed to validate template data")
 			continue
 		}
+		if campaign.SplitTest != nil {
+			err = sched.validateVariants(campaign.SplitTest, templateData)
+			if err != nil {
+				logEntry.WithError(err).Error("sched: failed to validate split test variants")
+				continue
+			}
+		}
+		campaign.TemplateVersion = template.Version
 
-		sesKeys, err := sched.s.GetSesKeys(u.ID)
+		// SES keys are required only when the user doesn't deliver through SMTP.
+		sesKeys := &entities.SesKeys{}
+		usesSMTP, err := sched.s.HasSMTPSettings(u.ID)
 		if err != nil {
-			logEntry.WithError(err).Error("sched: failed to get ses keys")
+			logEntry.WithError(err).Error("sched: failed to get smtp settings")
 			continue
 		}
+		if !usesSMTP {
+			sesKeys, err = sched.s.GetSesKeys(u.ID)
+			if err != nil {
+				logEntry.WithError(err).Error("sched: failed to get ses keys")
+				continue
+			}
+		}
 
 		segmentIDs, err := cs.GetSegmentIDs()
 		if err != nil {
@@ -114,25 +131,30 @@ func (sched *Scheduler) execute(ctx context.Context) error {
 			continue
 		}
 
-		sender, err := emails.NewSesSenderFromCreds(sesKeys.AccessKey, sesKeys.SecretKey, sesKeys.Region)
-		if err != nil {
-			logEntry.WithError(err).Error("sched: failed to create new ses sender")
-			continue
-		}
+		configSetExists := false
+		if !usesSMTP {
+			sender, err := emails.NewSesSenderFromCreds(sesKeys.AccessKey, sesKeys.SecretKey, sesKeys.Region)
+			if err != nil {
+				logEntry.WithError(err).Error("sched: failed to create new ses sender")
+				continue
+			}
 
-		_, err = sender.DescribeConfigurationSet(&ses.DescribeConfigurationSetInput{
-			ConfigurationSetName: aws.String(emails.ConfigurationSetName),
-		})
+			_, err = sender.DescribeConfigurationSet(&ses.DescribeConfigurationSetInput{
+				ConfigurationSetName: aws.String(emails.ConfigurationSetName),
+			})
+			configSetExists = err == nil
+		}
 
 		params := &entities.CampaignerTopicParams{
 			EventID:                cs.ID,
 			CampaignID:             cs.CampaignID,
 			SegmentIDs:             segmentIDs,
 			TemplateData:           templateData,
+			TemplateVersion:        campaign.TemplateVersion,
 			Source:                 fmt.Sprintf("%s <%s>", cs.FromName, cs.Source),
 			UserID:                 u.ID,
 			UserUUID:               u.UUID,
-			ConfigurationSetExists: err == nil,
+			ConfigurationSetExists: configSetExists,
 			SesKeys:                *sesKeys,
 		}
 		paramsByte, err := json.Marshal(params)
@@ -140,17 +162,55 @@ func (sched *Scheduler) execute(ctx context.Context) error {
 			logEntry.WithError(err).Error("sched: failed to marshal params for campaigner")
 			continue
 		}
-		err = sched.p.SendMessage(ctx, sched.sendCampaignQueueURL, paramsByte)
-		if err != nil {
-			logEntry.WithError(err).Error("sched: failed to publish campaign to campaigner")
-			continue
+		if campaign.SplitTest != nil {
+			campaign.SplitTest.Status = entities.SplitTestStatusTesting
+			campaign.SplitTest.WinnerVariantID = 0
+			campaign.SplitTest.TestCompletedAt = entities.NullTime{}
+			err = sched.s.UpdateCampaignSplitTest(campaign.SplitTest)
+			if err != nil {
+				logEntry.WithError(err).Error("sched: failed to update split test")
+				continue
+			}
 		}
+		// the status is saved before the campaign is published, the campaigner completes
+		// the campaign only while it's sending.
 		campaign.Status = entities.StatusSending
 		err = sched.s.UpdateCampaign(campaign)
 		if err != nil {
 			logEntry.WithError(err).Error("sched: failed to update status of campaign")
 			continue
 		}
+		err = sched.p.SendMessage(ctx, sched.sendCampaignQueueURL, paramsByte)
+		if err != nil {
+			logEntry.WithError(err).Error("sched: failed to publish campaign to campaigner")
+
+			_, err = sched.s.SwapCampaignStatus(campaign.ID, u.ID, entities.StatusSending, entities.StatusScheduled)
+			if err != nil {
+				logEntry.WithError(err).Error("sched: failed to revert status of campaign")
+			}
+			continue
+		}
+	}
+
+	return sched.sendSplitTestWinners(ctx, time.Now())
+}
+
+// validateVariants validates the default template data against the templates of the split test
+// variants, and pins the current versions of the templates to the variants.
+func (sched *Scheduler) validateVariants(st *entities.CampaignSplitTest, templateData map[string]string) error {
+	for i := range st.Variants {
+		v := &st.Variants[i]
+		template, err := sched.s.GetTemplate(v.TemplateID, st.UserID)
+		if err != nil {
+			return fmt.Errorf("get template of variant %s: %w", v.Name, err)
+		}
+
+		err = template.ValidateData(templateData)
+		if err != nil {
+			return fmt.Errorf("validate template data of variant %s: %w", v.Name, err)
+		}
+
+		v.TemplateVersion = template.Version
 	}
 
 	return nil
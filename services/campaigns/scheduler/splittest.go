@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// sendSplitTestWinners picks the winner of every split test whose wait period has elapsed,
+// and publishes the campaign again so that the winner is sent to the rest of the subscribers.
+func (sched *Scheduler) sendSplitTestWinners(ctx context.Context, now time.Time) error {
+	tests, err := sched.s.GetWaitingSplitTests()
+	if err != nil {
+		return fmt.Errorf("scheduler: failed to get waiting split tests: %w", err)
+	}
+
+	for i := range tests {
+		st := &tests[i]
+		if !st.WaitElapsed(now) {
+			continue
+		}
+
+		logEntry := logrus.WithFields(logrus.Fields{
+			"campaign_id":   st.CampaignID,
+			"user_id":       st.UserID,
+			"split_test_id": st.ID,
+		})
+
+		winner, err := sched.pickWinner(st)
+		if err != nil {
+			logEntry.WithError(err).Error("sched: failed to pick split test winner")
+			continue
+		}
+
+		params, err := st.GetParams()
+		if err != nil {
+			logEntry.WithError(err).Error("sched: failed to unmarshal split test params")
+			continue
+		}
+
+		// SES keys are not stored with the split test.
+		usesSMTP, err := sched.s.HasSMTPSettings(st.UserID)
+		if err != nil {
+			logEntry.WithError(err).Error("sched: failed to get smtp settings")
+			continue
+		}
+		if !usesSMTP {
+			sesKeys, err := sched.s.GetSesKeys(st.UserID)
+			if err != nil {
+				logEntry.WithError(err).Error("sched: failed to get ses keys")
+				continue
+			}
+			params.SesKeys = *sesKeys
+		}
+
+		paramsByte, err := json.Marshal(params)
+		if err != nil {
+			logEntry.WithError(err).Error("sched: failed to marshal params for campaigner")
+			continue
+		}
+
+		// the split test is completed before publishing, so that the campaigner sends the winner.
+		st.Status = entities.SplitTestStatusCompleted
+		st.WinnerVariantID = winner.ID
+		err = sched.s.UpdateCampaignSplitTest(st)
+		if err != nil {
+			logEntry.WithError(err).Error("sched: failed to update split test")
+			continue
+		}
+
+		err = sched.p.SendMessage(ctx, sched.sendCampaignQueueURL, paramsByte)
+		if err != nil {
+			logEntry.WithError(err).Error("sched: failed to publish split test winner to campaigner")
+
+			// retry on the next tick.
+			st.Status = entities.SplitTestStatusWaiting
+			st.WinnerVariantID = 0
+			err = sched.s.UpdateCampaignSplitTest(st)
+			if err != nil {
+				logEntry.WithError(err).Error("sched: failed to revert split test status")
+			}
+			continue
+		}
+
+		logEntry.WithField("variant_id", winner.ID).Info("sched: split test winner chosen")
+	}
+
+	return nil
+}
+
+// pickWinner returns the variant with the most unique opens or unique clicks,
+// depending on the winner criteria. Ties go to the first variant.
+func (sched *Scheduler) pickWinner(st *entities.CampaignSplitTest) (*entities.CampaignVariant, error) {
+	if len(st.Variants) == 0 {
+		return nil, fmt.Errorf("split test %d has no variants", st.ID)
+	}
+
+	var (
+		winner = &st.Variants[0]
+		best   int64
+	)
+	for i := range st.Variants {
+		v := &st.Variants[i]
+		stats, err := sched.s.GetCampaignVariantStats(st.CampaignID, v.ID, st.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		score := stats.Opens.Unique
+		if st.WinnerCriteria == entities.WinnerCriteriaClicks {
+			score = stats.Clicks.UniqueClicks
+		}
+
+		if score > best {
+			winner = v
+			best = score
+		}
+	}
+
+	return winner, nil
+}
@@ -0,0 +1,114 @@
+package campaigns
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/mailbadger/app/entities"
+)
+
+var (
+	hrefRegex = regexp.MustCompile(`(?is)(<a\s[^>]*?\bhref\s*=\s*)("[^"]*"|'[^']*')`)
+	bodyRegex = regexp.MustCompile(`(?i)</body\s*>`)
+)
+
+// trackLinks replaces the http links of the anchors in the html with the tracking urls. The links
+// of the app, e.g. the unsubscribe url, and the links starting with any of the excluded links are
+// not replaced.
+func trackLinks(body string, excluded []string, appURL string, clickURL func(link string) (string, error)) (string, error) {
+	var err error
+
+	tracked := hrefRegex.ReplaceAllStringFunc(body, func(m string) string {
+		if err != nil {
+			return m
+		}
+
+		parts := hrefRegex.FindStringSubmatch(m)
+		quote := parts[2][:1]
+		link := strings.TrimSpace(html.UnescapeString(parts[2][1 : len(parts[2])-1]))
+
+		if !isTrackable(link, excluded, appURL) {
+			return m
+		}
+
+		var u string
+		u, err = clickURL(link)
+		if err != nil {
+			return m
+		}
+
+		return parts[1] + quote + html.EscapeString(u) + quote
+	})
+	if err != nil {
+		return "", fmt.Errorf("track links: %w", err)
+	}
+
+	return tracked, nil
+}
+
+func isTrackable(link string, excluded []string, appURL string) bool {
+	lower := strings.ToLower(link)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return false
+	}
+
+	// links with template tags that are not rendered are left as they are.
+	if strings.Contains(link, "{{") {
+		return false
+	}
+
+	if appURL != "" && strings.HasPrefix(link, appURL) {
+		return false
+	}
+
+	for _, e := range excluded {
+		if e != "" && strings.HasPrefix(link, e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addOpenPixel inserts the 1x1 tracking image at the end of the html body.
+func addOpenPixel(body, openURL string) string {
+	pixel := `<img src="` + html.EscapeString(openURL) + `" width="1" height="1" alt="" style="display:none;border:0;width:1px;height:1px;" />`
+
+	loc := bodyRegex.FindAllStringIndex(body, -1)
+	if len(loc) == 0 {
+		return body + pixel
+	}
+
+	i := loc[len(loc)-1][0]
+	return body[:i] + pixel + body[i:]
+}
+
+// track adds the open pixel and replaces the links of the rendered html, depending on
+// the tracking settings of the campaign. The tracking urls are signed with the unsubscribe secret.
+func (svc *service) track(body string, campaign *entities.Campaign, p entities.TrackingParams) (string, error) {
+	if campaign.TrackClicks {
+		excluded, err := campaign.GetExcludedLinks()
+		if err != nil {
+			return "", fmt.Errorf("get excluded links: %w", err)
+		}
+
+		body, err = trackLinks(body, excluded, svc.appURL, func(link string) (string, error) {
+			return p.ClickURL(svc.unsubscribeSecret, svc.appURL, link)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if campaign.TrackOpens {
+		u, err := p.OpenURL(svc.unsubscribeSecret, svc.appURL)
+		if err != nil {
+			return "", fmt.Errorf("open url: %w", err)
+		}
+		body = addOpenPixel(body, u)
+	}
+
+	return body, nil
+}
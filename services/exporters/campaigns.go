@@ -0,0 +1,84 @@
+package exporters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage"
+)
+
+// CampaignResultsExporter exports the results of the campaign for each recipient.
+type CampaignResultsExporter struct {
+	s3      s3iface.S3API
+	storage storage.Storage
+}
+
+func NewCampaignResultsExporter(s3 s3iface.S3API, storage storage.Storage) *CampaignResultsExporter {
+	return &CampaignResultsExporter{
+		s3:      s3,
+		storage: storage,
+	}
+}
+
+var campaignResultsColumns = []column{
+	{"Email", "email"},
+	{"Subscriber ID", "subscriber_id"},
+	{"Status", "status"},
+	{"Description", "description"},
+	{"Delivered", "delivered"},
+	{"Opens", "opens"},
+	{"Clicked Links", "clicked_links"},
+	{"Bounce Type", "bounce_type"},
+	{"Complained", "complained"},
+	{"Sent At", "sent_at"},
+}
+
+func (ce *CampaignResultsExporter) Export(c context.Context, userID int64, report *entities.Report, bucket string) error {
+	id := campaignID(report)
+	if id == 0 {
+		return errors.New("campaign results export: campaign id is not set")
+	}
+
+	total, err := ce.storage.CountSendLogs(userID, id)
+	if err != nil {
+		return fmt.Errorf("count campaign send logs: %w", err)
+	}
+
+	var nextID string
+
+	return export(c, ce.s3, ce.storage, report, bucket, campaignResultsColumns, total, func(w rowWriter) (bool, error) {
+		results, err := ce.storage.SeekCampaignResults(id, userID, nextID, seekLimit)
+		if err != nil {
+			return false, fmt.Errorf("get campaign results: %w", err)
+		}
+
+		for _, r := range results {
+			err = w.Write([]interface{}{
+				r.Email,
+				r.SubscriberID,
+				r.Status,
+				r.Description,
+				r.Delivered,
+				r.Opens,
+				r.ClickedLinks,
+				r.BounceType,
+				r.Complained,
+				r.SentAt,
+			})
+			if err != nil {
+				return false, fmt.Errorf("write campaign result: %w", err)
+			}
+		}
+
+		if len(results) < int(seekLimit) {
+			return false, nil
+		}
+
+		nextID = results[len(results)-1].SendLogID
+		return true, nil
+	})
+}
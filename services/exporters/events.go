@@ -0,0 +1,197 @@
+package exporters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage"
+)
+
+// BouncesExporter exports the bounces of all campaigns or of a single campaign.
+type BouncesExporter struct {
+	s3      s3iface.S3API
+	storage storage.Storage
+}
+
+func NewBouncesExporter(s3 s3iface.S3API, storage storage.Storage) *BouncesExporter {
+	return &BouncesExporter{
+		s3:      s3,
+		storage: storage,
+	}
+}
+
+var bouncesColumns = []column{
+	{"Campaign ID", "campaign_id"},
+	{"Recipient", "recipient"},
+	{"Type", "type"},
+	{"Sub Type", "sub_type"},
+	{"Action", "action"},
+	{"Status", "status"},
+	{"Diagnostic Code", "diagnostic_code"},
+	{"Created At", "created_at"},
+}
+
+func (be *BouncesExporter) Export(c context.Context, userID int64, report *entities.Report, bucket string) error {
+	total, err := be.storage.CountBounces(userID, campaignID(report))
+	if err != nil {
+		return fmt.Errorf("count bounces: %w", err)
+	}
+
+	var nextID int64
+
+	return export(c, be.s3, be.storage, report, bucket, bouncesColumns, total, func(w rowWriter) (bool, error) {
+		bounces, err := be.storage.SeekBounces(userID, campaignID(report), nextID, seekLimit)
+		if err != nil {
+			return false, fmt.Errorf("get bounces: %w", err)
+		}
+
+		for _, b := range bounces {
+			err = w.Write([]interface{}{
+				b.CampaignID,
+				b.Recipient,
+				b.Type,
+				b.SubType,
+				b.Action,
+				b.Status,
+				b.DiagnosticCode,
+				b.CreatedAt,
+			})
+			if err != nil {
+				return false, fmt.Errorf("write bounce: %w", err)
+			}
+		}
+
+		if len(bounces) < int(seekLimit) {
+			return false, nil
+		}
+
+		nextID = bounces[len(bounces)-1].ID
+		return true, nil
+	})
+}
+
+// ComplaintsExporter exports the complaints of all campaigns or of a single campaign.
+type ComplaintsExporter struct {
+	s3      s3iface.S3API
+	storage storage.Storage
+}
+
+func NewComplaintsExporter(s3 s3iface.S3API, storage storage.Storage) *ComplaintsExporter {
+	return &ComplaintsExporter{
+		s3:      s3,
+		storage: storage,
+	}
+}
+
+var complaintsColumns = []column{
+	{"Campaign ID", "campaign_id"},
+	{"Recipient", "recipient"},
+	{"Type", "type"},
+	{"User Agent", "user_agent"},
+	{"Created At", "created_at"},
+}
+
+func (ce *ComplaintsExporter) Export(c context.Context, userID int64, report *entities.Report, bucket string) error {
+	total, err := ce.storage.CountComplaints(userID, campaignID(report))
+	if err != nil {
+		return fmt.Errorf("count complaints: %w", err)
+	}
+
+	var nextID int64
+
+	return export(c, ce.s3, ce.storage, report, bucket, complaintsColumns, total, func(w rowWriter) (bool, error) {
+		complaints, err := ce.storage.SeekComplaints(userID, campaignID(report), nextID, seekLimit)
+		if err != nil {
+			return false, fmt.Errorf("get complaints: %w", err)
+		}
+
+		for _, cm := range complaints {
+			err = w.Write([]interface{}{
+				cm.CampaignID,
+				cm.Recipient,
+				cm.Type,
+				cm.UserAgent,
+				cm.CreatedAt,
+			})
+			if err != nil {
+				return false, fmt.Errorf("write complaint: %w", err)
+			}
+		}
+
+		if len(complaints) < int(seekLimit) {
+			return false, nil
+		}
+
+		nextID = complaints[len(complaints)-1].ID
+		return true, nil
+	})
+}
+
+// SendLogsExporter exports the send logs of all campaigns or of a single campaign.
+type SendLogsExporter struct {
+	s3      s3iface.S3API
+	storage storage.Storage
+}
+
+func NewSendLogsExporter(s3 s3iface.S3API, storage storage.Storage) *SendLogsExporter {
+	return &SendLogsExporter{
+		s3:      s3,
+		storage: storage,
+	}
+}
+
+var sendLogsColumns = []column{
+	{"ID", "id"},
+	{"Campaign ID", "campaign_id"},
+	{"Subscriber ID", "subscriber_id"},
+	{"Message ID", "message_id"},
+	{"Status", "status"},
+	{"Description", "description"},
+	{"Created At", "created_at"},
+}
+
+func (se *SendLogsExporter) Export(c context.Context, userID int64, report *entities.Report, bucket string) error {
+	total, err := se.storage.CountSendLogs(userID, campaignID(report))
+	if err != nil {
+		return fmt.Errorf("count send logs: %w", err)
+	}
+
+	var nextID string
+
+	return export(c, se.s3, se.storage, report, bucket, sendLogsColumns, total, func(w rowWriter) (bool, error) {
+		logs, err := se.storage.SeekSendLogs(userID, campaignID(report), nextID, seekLimit)
+		if err != nil {
+			return false, fmt.Errorf("get send logs: %w", err)
+		}
+
+		for _, l := range logs {
+			var messageID string
+			if l.MessageID != nil {
+				messageID = *l.MessageID
+			}
+
+			err = w.Write([]interface{}{
+				l.ID.String(),
+				l.CampaignID,
+				l.SubscriberID,
+				messageID,
+				l.Status,
+				l.Description,
+				l.CreatedAt,
+			})
+			if err != nil {
+				return false, fmt.Errorf("write send log: %w", err)
+			}
+		}
+
+		if len(logs) < int(seekLimit) {
+			return false, nil
+		}
+
+		nextID = logs[len(logs)-1].ID.String()
+		return true, nil
+	})
+}
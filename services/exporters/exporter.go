@@ -2,11 +2,51 @@ package exporters
 
 import (
 	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 
 	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage"
 )
 
+// ErrUnsupportedResource is returned when there is no exporter for the resource of the report.
+var ErrUnsupportedResource = errors.New("exporters: unsupported resource")
+
 // Exporter represents type for creating exporters for different resource
 type Exporter interface {
 	Export(c context.Context, userID int64, report *entities.Report, bucket string) error
 }
+
+// Exporters dispatches the export of the report to the exporter of its resource.
+type Exporters map[string]Exporter
+
+// New returns the exporters of all the resources which can be exported.
+func New(s3 s3iface.S3API, storage storage.Storage) Exporters {
+	return Exporters{
+		entities.SubscribersResource:     NewSubscribersExporter(s3, storage),
+		entities.CampaignResultsResource: NewCampaignResultsExporter(s3, storage),
+		entities.BouncesResource:         NewBouncesExporter(s3, storage),
+		entities.ComplaintsResource:      NewComplaintsExporter(s3, storage),
+		entities.SendLogsResource:        NewSendLogsExporter(s3, storage),
+	}
+}
+
+func (e Exporters) Export(c context.Context, userID int64, report *entities.Report, bucket string) error {
+	exp, ok := e[report.Resource]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedResource, report.Resource)
+	}
+
+	return exp.Export(c, userID, report, bucket)
+}
+
+// campaignID returns the campaign id of the report, 0 is returned when the report is not for a single campaign.
+func campaignID(report *entities.Report) int64 {
+	if report.CampaignID == nil {
+		return 0
+	}
+
+	return *report.CampaignID
+}
@@ -1,15 +1,10 @@
 package exporters
 
 import (
-	"bytes"
 	"context"
-	"encoding/csv"
 	"fmt"
-	"strconv"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 
 	"github.com/mailbadger/app/entities"
@@ -28,98 +23,70 @@ func NewSubscribersExporter(s3 s3iface.S3API, storage storage.Storage) *Subscrib
 	}
 }
 
-func (se *SubscribersExporter) Export(c context.Context, userID int64, report *entities.Report, bucket string) error {
-	var (
-		err    error
-		nextID int64
-		limit  int64 = 1000
-
-		buf bytes.Buffer
-	)
-
-	writer := csv.NewWriter(&buf)
+// subscribersColumns are the columns of the subscribers export,
+// change them along with writeSubscribers.
+var subscribersColumns = []column{
+	{"Name", "name"},
+	{"Email", "email"},
+	{"User ID", "user_id"},
+	{"Segments", "segments"},
+	{"Active", "active"},
+	{"Metadata", "metadata"},
+	{"Blacklisted", "blacklisted"},
+	{"Created At", "created_at"},
+}
 
-	// writing headers
-	// change this function to change the headers
-	err = writeHeaders(writer)
+func (se *SubscribersExporter) Export(c context.Context, userID int64, report *entities.Report, bucket string) error {
+	total, err := se.storage.GetTotalSubscribers(userID)
 	if err != nil {
-		return fmt.Errorf("write headers: %w", err)
+		return fmt.Errorf("get total subscribers: %w", err)
 	}
 
-	for {
-		subscribers, err := se.storage.SeekSubscribersByUserID(userID, nextID, limit)
+	var nextID int64
+
+	return export(c, se.s3, se.storage, report, bucket, subscribersColumns, total, func(w rowWriter) (bool, error) {
+		subscribers, err := se.storage.SeekSubscribersByUserID(userID, nextID, seekLimit)
 		if err != nil {
-			return fmt.Errorf("get subscribers: %w", err)
+			return false, fmt.Errorf("get subscribers: %w", err)
 		}
 
 		// writing subscribers
-		err = writeSubscribers(writer, subscribers)
+		err = writeSubscribers(w, subscribers)
 		if err != nil {
-			return fmt.Errorf("write %d subscribers with id greater than %d: %w", limit, nextID, err)
+			return false, fmt.Errorf("write %d subscribers with id greater than %d: %w", seekLimit, nextID, err)
 		}
 
-		if len(subscribers) < int(limit) {
-			break
+		if len(subscribers) < int(seekLimit) {
+			return false, nil
 		}
 
 		nextID = subscribers[len(subscribers)-1].ID
-	}
-
-	writer.Flush()
-
-	_, err = se.s3.PutObject(&s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(fmt.Sprintf("subscribers/export/%d/%s", userID, report.FileName)),
-		Body:   bytes.NewReader(buf.Bytes()),
+		return true, nil
 	})
-	if err != nil {
-		return fmt.Errorf("put object: %w", err)
-	}
-
-	return nil
 }
 
-// writeHeaders writes headers to csv
-func writeHeaders(writer *csv.Writer) error {
-	return writer.Write([]string{
-		"Name",
-		"Email",
-		"User ID",
-		"Segments",
-		"Active",
-		"Metadata",
-		"Blacklisted",
-		"Created At",
-	})
-}
-
-// writeSubscribers writes the given subscribers into the csv
-func writeSubscribers(writer *csv.Writer, subscribers []entities.Subscriber) error {
+// writeSubscribers writes the given subscribers into the export
+func writeSubscribers(w rowWriter, subscribers []entities.Subscriber) error {
 	for _, s := range subscribers {
-		_, err := s.GetMetadata()
+		metadata, err := s.GetMetadata()
 		if err != nil {
 			return fmt.Errorf("get metadata: %w", err)
 		}
 
-		formattedSegments, err := formatSegments(s.Segments)
-		if err != nil {
-			return fmt.Errorf("format segments: %w", err)
+		segments := make([]string, len(s.Segments))
+		for i, seg := range s.Segments {
+			segments[i] = seg.Name
 		}
 
-		formatMetadata, err := formatMetadata(s.Metadata)
-		if err != nil {
-			return fmt.Errorf("format metadata: %w", err)
-		}
-
-		err = writer.Write([]string{
+		err = w.Write([]interface{}{
 			s.Name,
 			s.Email,
-			strconv.FormatInt(s.UserID, 10),
-			formattedSegments,
-			strconv.FormatBool(s.Active),
-			formatMetadata,
-			strconv.FormatBool(s.Blacklisted),
-			s.GetCreatedAt().Format("2006-01-02 15:04:05"),
+			s.UserID,
+			segments,
+			s.Active,
+			metadata,
+			s.Blacklisted,
+			s.GetCreatedAt(),
 		})
 		if err != nil {
 			return fmt.Errorf("write: %w", err)
@@ -129,26 +96,6 @@ func writeSubscribers(writer *csv.Writer, subscribers []entities.Subscriber) err
 	return nil
 }
 
-// formatSegments returns segments divided by ;
-func formatSegments(segments []entities.Segment) (string, error) {
-	var b strings.Builder
-
-	if len(segments) == 0 {
-		return "", nil
-	}
-
-	for _, s := range segments {
-		_, err := fmt.Fprintf(&b, "%s; ", s.Name)
-		if err != nil {
-			return "", fmt.Errorf("fprintf: %w", err)
-		}
-	}
-
-	s := b.String()
-	s = s[:b.Len()-2] // remove trailing "; "
-	return s, nil
-}
-
 // formatMetadata returns metadata formatted in key = value pairs divided by ;
 func formatMetadata(metadata map[string]string) (string, error) {
 	var b strings.Builder
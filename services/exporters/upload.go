@@ -0,0 +1,140 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// partSize is the size of the parts of the multipart upload, S3 requires
+// all parts except the last one to be at least 5 MiB.
+const partSize = 5 * 1024 * 1024
+
+// multipartUpload streams the written data to the bucket in parts, so at most one part
+// is kept in memory. The files smaller than a part are uploaded with a single request.
+type multipartUpload struct {
+	ctx         context.Context
+	s3          s3iface.S3API
+	bucket      string
+	key         string
+	contentType string
+
+	buf      bytes.Buffer
+	uploadID *string
+	parts    []*s3.CompletedPart
+}
+
+func newMultipartUpload(ctx context.Context, s3Client s3iface.S3API, bucket, key, contentType string) *multipartUpload {
+	return &multipartUpload{
+		ctx:         ctx,
+		s3:          s3Client,
+		bucket:      bucket,
+		key:         key,
+		contentType: contentType,
+	}
+}
+
+func (u *multipartUpload) Write(p []byte) (int, error) {
+	n, _ := u.buf.Write(p)
+
+	for u.buf.Len() >= partSize {
+		err := u.uploadPart(u.buf.Next(partSize))
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close uploads the remaining data and completes the upload.
+func (u *multipartUpload) Close() error {
+	if u.uploadID == nil {
+		_, err := u.s3.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(u.bucket),
+			Key:         aws.String(u.key),
+			ContentType: aws.String(u.contentType),
+			Body:        bytes.NewReader(u.buf.Bytes()),
+		})
+		if err != nil {
+			return fmt.Errorf("put object: %w", err)
+		}
+		return nil
+	}
+
+	if u.buf.Len() > 0 {
+		err := u.uploadPart(u.buf.Bytes())
+		if err != nil {
+			return err
+		}
+		u.buf.Reset()
+	}
+
+	_, err := u.s3.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        u.uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: u.parts},
+	})
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// Abort aborts the multipart upload so the uploaded parts are removed from the bucket.
+func (u *multipartUpload) Abort() error {
+	if u.uploadID == nil {
+		return nil
+	}
+
+	_, err := u.s3.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(u.key),
+		UploadId: u.uploadID,
+	})
+	if err != nil {
+		return fmt.Errorf("abort multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+func (u *multipartUpload) uploadPart(part []byte) error {
+	if err := u.ctx.Err(); err != nil {
+		return err
+	}
+
+	if u.uploadID == nil {
+		out, err := u.s3.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(u.bucket),
+			Key:         aws.String(u.key),
+			ContentType: aws.String(u.contentType),
+		})
+		if err != nil {
+			return fmt.Errorf("create multipart upload: %w", err)
+		}
+		u.uploadID = out.UploadId
+	}
+
+	num := aws.Int64(int64(len(u.parts) + 1))
+	out, err := u.s3.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String(u.bucket),
+		Key:        aws.String(u.key),
+		UploadId:   u.uploadID,
+		PartNumber: num,
+		Body:       bytes.NewReader(part),
+	})
+	if err != nil {
+		return fmt.Errorf("upload part %d: %w", *num, err)
+	}
+
+	u.parts = append(u.parts, &s3.CompletedPart{ETag: out.ETag, PartNumber: num})
+
+	return nil
+}
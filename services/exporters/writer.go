@@ -0,0 +1,224 @@
+package exporters
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage"
+)
+
+// seekLimit is the number of records fetched at once while exporting.
+const seekLimit int64 = 1000
+
+// column represents a column of the export, the header is used in the csv files
+// and the key in the json lines.
+type column struct {
+	header string
+	key    string
+}
+
+// rowWriter writes the rows of the export in the format of the report.
+type rowWriter interface {
+	Write(row []interface{}) error
+	Flush() error
+}
+
+// newRowWriter returns a writer for the format, the csv headers are written right away.
+func newRowWriter(w io.Writer, format string, columns []column) (rowWriter, error) {
+	if format == entities.ReportFormatJSON {
+		return &jsonWriter{w: w, columns: columns}, nil
+	}
+
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.header
+	}
+
+	err := cw.w.Write(headers)
+	if err != nil {
+		return nil, fmt.Errorf("write headers: %w", err)
+	}
+
+	return cw, nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvWriter) Write(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, v := range row {
+		s, err := formatValue(v)
+		if err != nil {
+			return err
+		}
+		record[i] = s
+	}
+
+	return cw.w.Write(record)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// formatValue formats the value of a csv cell.
+func formatValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case time.Time:
+		if val.IsZero() {
+			return "", nil
+		}
+		return val.Format("2006-01-02 15:04:05"), nil
+	case []string:
+		return strings.Join(val, "; "), nil
+	case map[string]string:
+		return formatMetadata(val)
+	}
+
+	return "", fmt.Errorf("unsupported value type %T", v)
+}
+
+type jsonWriter struct {
+	w       io.Writer
+	columns []column
+}
+
+// Write writes the row as a json object on a single line, the keys are kept in the order of the columns.
+func (jw *jsonWriter) Write(row []interface{}) error {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, v := range row {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		k, err := json.Marshal(jw.columns[i].key)
+		if err != nil {
+			return fmt.Errorf("marshal key: %w", err)
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", jw.columns[i].key, err)
+		}
+
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(val)
+	}
+	b.WriteString("}\n")
+
+	_, err := jw.w.Write(b.Bytes())
+	return err
+}
+
+func (jw *jsonWriter) Flush() error {
+	return nil
+}
+
+// countingWriter counts the rows written to the underlying row writer.
+type countingWriter struct {
+	rowWriter
+	rows int64
+}
+
+func (cw *countingWriter) Write(row []interface{}) error {
+	err := cw.rowWriter.Write(row)
+	if err == nil {
+		cw.rows++
+	}
+	return err
+}
+
+// export writes the rows of the report and streams the file to the bucket, writeRows
+// is called until it returns false. The number of exported rows is saved on the report
+// after each call so the progress of the export can be followed.
+func export(
+	c context.Context,
+	s3Client s3iface.S3API,
+	store storage.Storage,
+	report *entities.Report,
+	bucket string,
+	columns []column,
+	total int64,
+	writeRows func(w rowWriter) (bool, error),
+) (err error) {
+	report.TotalRows = total
+	report.RowsExported = 0
+
+	upload := newMultipartUpload(c, s3Client, bucket, report.Key(), report.ContentType())
+	defer func() {
+		if err == nil {
+			return
+		}
+		if abortErr := upload.Abort(); abortErr != nil {
+			logrus.WithError(abortErr).WithField("report_id", report.ID).Error("export: unable to abort upload")
+		}
+	}()
+
+	var out io.Writer = upload
+	var gz *gzip.Writer
+	if report.Gzip {
+		gz = gzip.NewWriter(upload)
+		out = gz
+	}
+
+	rw, err := newRowWriter(out, report.Format, columns)
+	if err != nil {
+		return err
+	}
+	w := &countingWriter{rowWriter: rw}
+
+	for {
+		more, err := writeRows(w)
+		if err != nil {
+			return err
+		}
+
+		report.RowsExported = w.rows
+		if updateErr := store.UpdateReportProgress(report); updateErr != nil {
+			logrus.WithError(updateErr).WithField("report_id", report.ID).Warn("export: unable to update report progress")
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	if gz != nil {
+		err = gz.Close()
+		if err != nil {
+			return fmt.Errorf("gzip close: %w", err)
+		}
+	}
+
+	return upload.Close()
+}
@@ -0,0 +1,266 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/config"
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/events"
+	"github.com/mailbadger/app/services/reports"
+	"github.com/mailbadger/app/services/subscribers"
+	"github.com/mailbadger/app/storage"
+)
+
+type handlers struct {
+	store     storage.Storage
+	s3        s3iface.S3API
+	subscrsvc subscribers.Service
+	reportsvc reports.Service
+	bucket    string
+	appURL    string
+}
+
+// NewHandlers returns the handlers of all the job types.
+func NewHandlers(
+	store storage.Storage,
+	s3Client s3iface.S3API,
+	subscrsvc subscribers.Service,
+	reportsvc reports.Service,
+	conf config.Config,
+) Handlers {
+	h := &handlers{
+		store:     store,
+		s3:        s3Client,
+		subscrsvc: subscrsvc,
+		reportsvc: reportsvc,
+		bucket:    conf.Storage.S3.FilesBucket,
+		appURL:    conf.Server.AppURL,
+	}
+
+	return Handlers{
+		entities.JobTypeImportSubscribers:     h.importSubscribers,
+		entities.JobTypeBulkRemoveSubscribers: h.bulkRemoveSubscribers,
+		entities.JobTypeExportReport:          h.exportReport,
+		entities.JobTypeSESKeys:               h.createSESKeys,
+	}
+}
+
+func (h *handlers) importSubscribers(ctx context.Context, job *entities.Job, p *Progress) error {
+	payload := new(entities.ImportSubscribersJob)
+	if err := decodePayload(job, payload); err != nil {
+		return err
+	}
+
+	var segs []entities.Segment
+	if len(payload.SegmentIDs) > 0 {
+		var err error
+		segs, err = h.store.GetSegmentsByIDs(job.UserID, payload.SegmentIDs)
+		if err != nil {
+			return fmt.Errorf("import subscribers: get segments: %w", err)
+		}
+	}
+
+	r, format, err := h.getFile(p, fmt.Sprintf("subscribers/import/%d/%s", job.UserID, payload.Filename))
+	if err != nil {
+		return fmt.Errorf("import subscribers: %w", err)
+	}
+
+	var rejects bytes.Buffer
+	res, err := h.subscrsvc.ImportSubscribersFromFile(ctx, job.UserID, r, subscribers.ImportOptions{
+		Format:   format,
+		Segments: segs,
+		Mapping:  payload.Mapping,
+		Mode:     payload.Mode,
+		Rejects:  &rejects,
+	}, p.Add)
+	if err != nil {
+		var merr *subscribers.MappingError
+		if errors.Is(err, subscribers.ErrInvalidFormat) ||
+			errors.Is(err, subscribers.ErrInvalidColumnsNum) ||
+			errors.As(err, &merr) {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	if res.Rejected > 0 {
+		res.RejectsFile = fmt.Sprintf("import_%d_rejects.csv", job.ID)
+		_, err = h.s3.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(h.bucket),
+			Key:         aws.String(RejectsKey(job.UserID, res.RejectsFile)),
+			Body:        bytes.NewReader(rejects.Bytes()),
+			ContentType: aws.String("text/csv"),
+		})
+		if err != nil {
+			return fmt.Errorf("import subscribers: upload rejects: %w", err)
+		}
+	}
+
+	job.Result, err = json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("import subscribers: marshal result: %w", err)
+	}
+
+	return nil
+}
+
+// RejectsKey returns the key of the file with the rejected rows of the import.
+func RejectsKey(userID int64, filename string) string {
+	return fmt.Sprintf("subscribers/import/%d/rejects/%s", userID, filename)
+}
+
+func (h *handlers) bulkRemoveSubscribers(ctx context.Context, job *entities.Job, p *Progress) error {
+	payload := new(entities.BulkRemoveSubscribersJob)
+	if err := decodePayload(job, payload); err != nil {
+		return err
+	}
+
+	r, format, err := h.getFile(p, fmt.Sprintf("subscribers/remove/%d/%s", job.UserID, payload.Filename))
+	if err != nil {
+		return fmt.Errorf("bulk remove subscribers: %w", err)
+	}
+
+	err = h.subscrsvc.RemoveSubscribersFromFile(ctx, payload.Filename, job.UserID, ioutil.NopCloser(r), format, p.Add)
+	if errors.Is(err, subscribers.ErrInvalidFormat) || errors.Is(err, subscribers.ErrInvalidColumnsNum) {
+		return Permanent(err)
+	}
+
+	return err
+}
+
+// getFile fetches the file from the bucket and detects its format, the total of the job
+// is set to the number of rows without the header.
+func (h *handlers) getFile(p *Progress, key string) (io.Reader, string, error) {
+	res, err := h.s3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(h.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, "", Permanent(fmt.Errorf("file not found: %w", err))
+		}
+		return nil, "", fmt.Errorf("get object: %w", err)
+	}
+	defer res.Body.Close()
+
+	format, err := subscribers.DetectFormat(key, aws.StringValue(res.ContentType))
+	if err != nil {
+		return nil, "", Permanent(err)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("read object: %w", err)
+	}
+
+	rows, err := subscribers.CountRows(bytes.NewReader(b), format)
+	if err != nil {
+		if errors.Is(err, subscribers.ErrInvalidFormat) {
+			return nil, "", Permanent(err)
+		}
+		return nil, "", fmt.Errorf("count rows: %w", err)
+	}
+	p.SetTotal(rows)
+
+	return bytes.NewReader(b), format, nil
+}
+
+func (h *handlers) exportReport(ctx context.Context, job *entities.Job, p *Progress) error {
+	payload := new(entities.ExportReportJob)
+	if err := decodePayload(job, payload); err != nil {
+		return err
+	}
+
+	report, err := h.store.GetReport(payload.ReportID, job.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Permanent(fmt.Errorf("export report: report %d not found", payload.ReportID))
+		}
+		return fmt.Errorf("export report: get report: %w", err)
+	}
+
+	// the report is failed by the previous attempt.
+	if report.Status != entities.StatusInProgress {
+		report.Status = entities.StatusInProgress
+		err = h.store.UpdateReport(report)
+		if err != nil {
+			return fmt.Errorf("export report: update report: %w", err)
+		}
+	}
+
+	_, err = h.reportsvc.GenerateExportReport(ctx, job.UserID, report, h.bucket)
+	if err != nil {
+		return fmt.Errorf("export report: %w", err)
+	}
+
+	job.Total = report.TotalRows
+	job.Processed = report.RowsExported
+
+	return nil
+}
+
+func (h *handlers) createSESKeys(ctx context.Context, job *entities.Job, p *Progress) error {
+	payload := new(entities.SESKeysJob)
+	if err := decodePayload(job, payload); err != nil {
+		return err
+	}
+
+	_, err := h.store.GetSesKeys(job.UserID)
+	if err == nil {
+		return Permanent(errors.New("ses keys: the keys are already set"))
+	}
+
+	sender, err := emails.NewSesSenderFromCreds(payload.AccessKey, payload.SecretKey, payload.Region)
+	if err != nil {
+		return Permanent(fmt.Errorf("ses keys: create SES sender: %w", err))
+	}
+
+	snsClient, err := events.NewEventsClient(payload.AccessKey, payload.SecretKey, payload.Region)
+	if err != nil {
+		return Permanent(fmt.Errorf("ses keys: create SNS client: %w", err))
+	}
+
+	user, err := h.store.GetUser(job.UserID)
+	if err != nil {
+		return fmt.Errorf("ses keys: get user: %w", err)
+	}
+
+	err = createAWSResources(sender, snsClient, user.UUID, h.appURL)
+	if err != nil {
+		return err
+	}
+
+	err = h.store.CreateSesKeys(&entities.SesKeys{
+		AccessKey: payload.AccessKey,
+		SecretKey: payload.SecretKey,
+		Region:    payload.Region,
+		UserID:    job.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("ses keys: create keys: %w", err)
+	}
+
+	return nil
+}
+
+func decodePayload(job *entities.Job, payload interface{}) error {
+	err := json.Unmarshal(job.Payload, payload)
+	if err != nil {
+		return Permanent(fmt.Errorf("unmarshal payload: %w", err))
+	}
+	return nil
+}
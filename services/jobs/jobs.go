@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+)
+
+// The failed jobs are attempted again with exponential backoff starting from the base delay.
+const (
+	baseDelay = time.Minute
+	maxDelay  = 12 * time.Hour
+)
+
+var (
+	// ErrRetry is returned when the job failed and it should be attempted again.
+	ErrRetry = errors.New("jobs: job failed, retrying")
+	// ErrNotFailed is returned when a job which didn't fail is retried.
+	ErrNotFailed = errors.New("jobs: only the failed jobs can be retried")
+	// ErrUnknownType is returned when there is no handler for the type of the job.
+	ErrUnknownType = errors.New("jobs: unknown job type")
+	// ErrNotRetryable is returned when a job whose payload is cleared is retried.
+	ErrNotRetryable = errors.New("jobs: the job can't be retried")
+)
+
+// credentialsJobTypes are the job types whose payload holds credentials, the payload
+// is cleared once the job is finished so the credentials aren't kept in the jobs.
+var credentialsJobTypes = map[string]bool{
+	entities.JobTypeSESKeys: true,
+}
+
+// Service creates the jobs of the users and enqueues them to the jobs consumer.
+type Service interface {
+	Enqueue(ctx context.Context, userID int64, jobType string, payload interface{}) (*entities.Job, error)
+	Retry(ctx context.Context, job *entities.Job) error
+}
+
+type service struct {
+	store     storage.Storage
+	publisher sqs.PublisherAPI
+	queueURL  sqs.JobsQueueURL
+}
+
+// New returns a new jobs service.
+func New(store storage.Storage, publisher sqs.PublisherAPI, queueURL sqs.JobsQueueURL) Service {
+	return &service{
+		store:     store,
+		publisher: publisher,
+		queueURL:  queueURL,
+	}
+}
+
+// Enqueue creates a pending job with the payload and sends it to the jobs consumer. When the
+// job can't be sent it is marked as failed, so it can be retried by the user.
+func (s *service) Enqueue(ctx context.Context, userID int64, jobType string, payload interface{}) (*entities.Job, error) {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: marshal payload: %w", err)
+	}
+
+	job := &entities.Job{
+		UserID:      userID,
+		Type:        jobType,
+		Status:      entities.JobStatusPending,
+		Payload:     p,
+		MaxAttempts: entities.JobMaxAttempts,
+	}
+
+	err = s.store.CreateJob(job)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: create job: %w", err)
+	}
+
+	return job, s.publish(ctx, job)
+}
+
+// Retry resets the failed job and sends it to the jobs consumer again.
+func (s *service) Retry(ctx context.Context, job *entities.Job) error {
+	if job.Status != entities.JobStatusFailed {
+		return ErrNotFailed
+	}
+	if credentialsJobTypes[job.Type] {
+		return ErrNotRetryable
+	}
+
+	job.Status = entities.JobStatusPending
+	job.Attempts = 0
+	job.Processed = 0
+	job.Total = 0
+	job.Error = ""
+	job.Result = nil
+	job.StartedAt = entities.NullTime{}
+	job.CompletedAt = entities.NullTime{}
+
+	err := s.store.UpdateJob(job)
+	if err != nil {
+		return fmt.Errorf("jobs: update job: %w", err)
+	}
+
+	return s.publish(ctx, job)
+}
+
+func (s *service) publish(ctx context.Context, job *entities.Job) error {
+	msg, err := json.Marshal(entities.JobTopicParams{
+		JobID:  job.ID,
+		UserID: job.UserID,
+	})
+	if err == nil {
+		err = s.publisher.SendMessage(ctx, s.queueURL, msg)
+	}
+	if err == nil {
+		return nil
+	}
+
+	job.Status = entities.JobStatusFailed
+	job.Error = "Unable to start the job."
+	job.CompletedAt.SetValid(time.Now().UTC())
+
+	if updateErr := s.store.UpdateJob(job); updateErr != nil {
+		return fmt.Errorf("jobs: update job: %w", updateErr)
+	}
+
+	return fmt.Errorf("jobs: publish job: %w", err)
+}
+
+// Backoff returns the delay before the next attempt of the job.
+func Backoff(attempts int) time.Duration {
+	d := baseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxDelay {
+			return maxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/storage"
+)
+
+// progressInterval is the min interval between the progress updates of a running job.
+const progressInterval = time.Second
+
+// Handler runs the jobs of a single type, the progress of the job is recorded with p.
+type Handler func(ctx context.Context, job *entities.Job, p *Progress) error
+
+// Handlers are the handlers of the job types.
+type Handlers map[string]Handler
+
+// Runner runs the jobs with the handlers of their types.
+type Runner struct {
+	store    storage.Storage
+	handlers Handlers
+}
+
+// NewRunner returns a new jobs runner.
+func NewRunner(store storage.Storage, handlers Handlers) *Runner {
+	return &Runner{
+		store:    store,
+		handlers: handlers,
+	}
+}
+
+// Run runs the job and saves its result. The failed job is kept pending and ErrRetry is returned
+// until it reaches the max attempts, the permanent errors fail the job right away.
+func (r *Runner) Run(ctx context.Context, job *entities.Job) error {
+	handler, ok := r.handlers[job.Type]
+	if !ok {
+		return r.finish(job, Permanent(fmt.Errorf("%w: %s", ErrUnknownType, job.Type)))
+	}
+
+	job.Status = entities.JobStatusRunning
+	job.Attempts++
+	job.Error = ""
+	if !job.StartedAt.Valid {
+		job.StartedAt.SetValid(time.Now().UTC())
+	}
+
+	err := r.store.UpdateJob(job)
+	if err != nil {
+		return fmt.Errorf("jobs: update job: %w", err)
+	}
+
+	err = handler(ctx, job, &Progress{store: r.store, job: job})
+
+	return r.finish(job, err)
+}
+
+func (r *Runner) finish(job *entities.Job, err error) error {
+	switch {
+	case err == nil:
+		job.Status = entities.JobStatusDone
+		job.CompletedAt.SetValid(time.Now().UTC())
+	case errors.Is(err, ErrPermanent) || job.Attempts >= job.MaxAttempts:
+		job.Status = entities.JobStatusFailed
+		job.Error = err.Error()
+		job.CompletedAt.SetValid(time.Now().UTC())
+	default:
+		job.Status = entities.JobStatusPending
+		job.Error = err.Error()
+	}
+
+	if job.IsFinished() && credentialsJobTypes[job.Type] {
+		job.Payload = entities.JSON("{}")
+	}
+
+	updateErr := r.store.UpdateJob(job)
+	if updateErr != nil {
+		return fmt.Errorf("jobs: update job: %w", updateErr)
+	}
+
+	if job.Status == entities.JobStatusPending {
+		return ErrRetry
+	}
+
+	return nil
+}
+
+// ErrPermanent is the error of the jobs which fail without retrying.
+var ErrPermanent = errors.New("jobs: permanent failure")
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func (e *permanentError) Is(target error) bool {
+	return target == ErrPermanent
+}
+
+// Permanent marks the error of the job as permanent, the job fails without retrying.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Progress records the processed items of the running job, the progress
+// is saved at most once in the progress interval.
+type Progress struct {
+	store storage.Storage
+	job   *entities.Job
+	saved time.Time
+}
+
+// SetTotal sets the total number of items of the job and resets the processed items.
+func (p *Progress) SetTotal(total int64) {
+	p.job.Total = total
+	p.job.Processed = 0
+	p.save(true)
+}
+
+// Add adds n processed items to the job.
+func (p *Progress) Add(n int64) {
+	p.job.Processed += n
+	p.save(false)
+}
+
+func (p *Progress) save(force bool) {
+	if !force && time.Since(p.saved) < progressInterval {
+		return
+	}
+	p.saved = time.Now()
+
+	err := p.store.UpdateJobProgress(p.job)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"job_id":  p.job.ID,
+			"user_id": p.job.UserID,
+		}).WithError(err).Warn("jobs: unable to update job progress")
+	}
+}
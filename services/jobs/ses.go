@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/sns"
+
+	"github.com/mailbadger/app/emails"
+	"github.com/mailbadger/app/events"
+)
+
+// createAWSResources creates the SNS topic which receives the SES events of the user
+// and the configuration set which publishes the events to the topic.
+func createAWSResources(
+	sender emails.Sender,
+	snsClient events.EventsClient,
+	uuid string,
+	appURL string,
+) error {
+	hookURLStr := fmt.Sprintf("%s/api/hooks/%s", appURL, uuid)
+	hookURL, err := url.Parse(hookURLStr)
+	if err != nil {
+		return fmt.Errorf("ses keys: unable to parse hook URL: %w", err)
+	}
+	snsRes, err := snsClient.CreateTopic(&sns.CreateTopicInput{
+		Name: aws.String(events.SNSTopicName),
+	})
+	if err != nil {
+		return fmt.Errorf("ses keys: unable to create SNS topic: %w", err)
+	}
+
+	topicArn := *snsRes.TopicArn
+
+	_, err = snsClient.Subscribe(&sns.SubscribeInput{
+		Protocol: aws.String(hookURL.Scheme),
+		Endpoint: aws.String(hookURLStr),
+		TopicArn: aws.String(topicArn),
+	})
+	if err != nil {
+		return fmt.Errorf("ses keys: unable to subscribe to topic: %w", err)
+	}
+
+	// Check if the configuration set is already created
+	cs, err := sender.DescribeConfigurationSet(&ses.DescribeConfigurationSetInput{
+		ConfigurationSetName: aws.String(emails.ConfigurationSetName),
+		ConfigurationSetAttributeNames: []*string{
+			aws.String("eventDestinations"),
+		},
+	})
+
+	if err != nil {
+		_, err = sender.CreateConfigurationSet(&ses.CreateConfigurationSetInput{
+			ConfigurationSet: &ses.ConfigurationSet{
+				Name: aws.String(emails.ConfigurationSetName),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("ses keys: unable to create configuration set: %w", err)
+		}
+	}
+
+	// Check if the event destination is set
+	eventFound := false
+	for _, e := range cs.EventDestinations {
+		if e.Name != nil && *e.Name == events.SNSTopicName {
+			eventFound = true
+		}
+	}
+
+	if eventFound {
+		return nil
+	}
+
+	_, err = sender.CreateConfigurationSetEventDestination(&ses.CreateConfigurationSetEventDestinationInput{
+		ConfigurationSetName: aws.String(emails.ConfigurationSetName),
+		EventDestination: &ses.EventDestination{
+			Name:    aws.String(events.SNSTopicName),
+			Enabled: aws.Bool(true),
+			MatchingEventTypes: []*string{
+				aws.String("send"),
+				aws.String("open"),
+				aws.String("click"),
+				aws.String("bounce"),
+				aws.String("reject"),
+				aws.String("delivery"),
+				aws.String("complaint"),
+				aws.String("renderingFailure"),
+			},
+			SNSDestination: &ses.SNSDestination{
+				TopicARN: aws.String(topicArn),
+			},
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("ses keys: unable to set event destination: %w", err)
+	}
+
+	return nil
+}
@@ -24,7 +24,7 @@ var (
 // Service represents all report functionalities
 type Service interface {
 	GenerateExportReport(c context.Context, userID int64, report *entities.Report, bucket string) (*entities.Report, error)
-	CreateExportReport(c context.Context, userID int64, resource string, note string, date time.Time) (*entities.Report, error)
+	CreateExportReport(c context.Context, userID int64, report *entities.Report, date time.Time) (*entities.Report, error)
 }
 
 type reportService struct {
@@ -68,8 +68,8 @@ func (r *reportService) GenerateExportReport(c context.Context, userID int64, re
 	return report, nil
 }
 
-// CreateExportReport creates export report
-func (r *reportService) CreateExportReport(c context.Context, userID int64, resource, note string, date time.Time) (*entities.Report, error) {
+// CreateExportReport creates export report for the resource, format and campaign of the given report
+func (r *reportService) CreateExportReport(c context.Context, userID int64, report *entities.Report, date time.Time) (*entities.Report, error) {
 	if r.isAnotherReportRunning(c, userID) {
 		return nil, ErrAnotherReportRunning
 	}
@@ -82,15 +82,15 @@ func (r *reportService) CreateExportReport(c context.Context, userID int64, reso
 		return nil, ErrLimitReached
 	}
 
-	report := &entities.Report{
-		UserID:   userID,
-		Resource: resource,
-		FileName: generateFilename(resource, date),
-		Type:     reportTypeExport,
-		Status:   entities.StatusInProgress,
-		Note:     note,
+	if report.Format == "" {
+		report.Format = entities.ReportFormatCSV
 	}
 
+	report.UserID = userID
+	report.FileName = generateFilename(report, date)
+	report.Type = reportTypeExport
+	report.Status = entities.StatusInProgress
+
 	err = r.storage.CreateReport(report)
 	if err != nil {
 		return nil, fmt.Errorf("create report: %w", err)
@@ -100,8 +100,12 @@ func (r *reportService) CreateExportReport(c context.Context, userID int64, reso
 }
 
 // generateFilename generates the report filename
-func generateFilename(resource string, date time.Time) string {
-	return fmt.Sprintf("%s_%d.csv", resource, date.Unix())
+func generateFilename(report *entities.Report, date time.Time) string {
+	if report.CampaignID != nil {
+		return fmt.Sprintf("%s_%d_%d.%s", report.Resource, *report.CampaignID, date.Unix(), report.FileExtension())
+	}
+
+	return fmt.Sprintf("%s_%d.%s", report.Resource, date.Unix(), report.FileExtension())
 }
 
 // isAnotherReportRunning returns true if there is report in progress for a user or false if all are done
@@ -0,0 +1,155 @@
+package subscribers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+)
+
+// Formats of the import and bulk remove files.
+const (
+	FormatCSV   = "csv"
+	FormatTSV   = "tsv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// ErrUnsupportedFormat is returned when the format of the file can't be detected.
+var ErrUnsupportedFormat = errors.New("importer: unsupported file format")
+
+var extFormats = map[string]string{
+	".csv":    FormatCSV,
+	".txt":    FormatCSV,
+	".tsv":    FormatTSV,
+	".tab":    FormatTSV,
+	".jsonl":  FormatJSONL,
+	".ndjson": FormatJSONL,
+	".xlsx":   FormatXLSX,
+}
+
+var contentTypeFormats = map[string]string{
+	"text/csv":                  FormatCSV,
+	"application/csv":           FormatCSV,
+	"text/tab-separated-values": FormatTSV,
+	"application/jsonl":         FormatJSONL,
+	"application/x-jsonlines":   FormatJSONL,
+	"application/x-ndjson":      FormatJSONL,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": FormatXLSX,
+}
+
+// DetectFormat returns the format of the file by its extension, or by the content type
+// when the extension is unknown. The files without an extension are read as csv.
+func DetectFormat(filename, contentType string) (string, error) {
+	ext := strings.ToLower(path.Ext(filename))
+	if f, ok := extFormats[ext]; ok {
+		return f, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if f, ok := contentTypeFormats[mediaType]; ok {
+			return f, nil
+		}
+	}
+
+	if ext == "" {
+		return FormatCSV, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, ext)
+}
+
+// rowReader reads the rows of the file, the first row is the header.
+type rowReader interface {
+	// Read returns the next row and its line number in the file. The malformed
+	// rows are returned as *rowError, the reading can continue after them.
+	Read() (row []string, line int, err error)
+}
+
+// rowError is returned for the rows which can't be parsed.
+type rowError struct {
+	line int
+	err  error
+}
+
+func (e *rowError) Error() string {
+	return fmt.Sprintf("importer: line %d: %s", e.line, e.err)
+}
+
+func (e *rowError) Unwrap() error {
+	return e.err
+}
+
+func newRowReader(r io.Reader, format string) (rowReader, error) {
+	switch format {
+	case FormatCSV, "":
+		return newCSVReader(r, ','), nil
+	case FormatTSV:
+		return newCSVReader(r, '\t'), nil
+	case FormatJSONL:
+		return newJSONLReader(r)
+	case FormatXLSX:
+		return newXLSXReader(r)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+type csvReader struct {
+	r *csv.Reader
+}
+
+func newCSVReader(r io.Reader, comma rune) *csvReader {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = comma == '\t'
+
+	return &csvReader{r: reader}
+}
+
+func (c *csvReader) Read() ([]string, int, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return record, perr.StartLine, &rowError{line: perr.StartLine, err: perr.Err}
+		}
+		return nil, 0, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	return record, line, nil
+}
+
+// CountRows returns the number of rows in the file without the header.
+func CountRows(r io.Reader, format string) (int64, error) {
+	rr, err := newRowReader(r, format)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for {
+		_, _, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var rerr *rowError
+		if err != nil && !errors.As(err, &rerr) {
+			return 0, err
+		}
+		count++
+	}
+
+	if count > 0 {
+		// the header is not counted.
+		count--
+	}
+
+	return count, nil
+}
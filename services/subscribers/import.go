@@ -0,0 +1,457 @@
+package subscribers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/validator"
+)
+
+// Subscriber fields which the columns of the import file can be mapped to,
+// the metadata keys are mapped with the metadata prefix, e.g. metadata.city.
+const (
+	FieldEmail     = "email"
+	FieldName      = "name"
+	MetadataPrefix = "metadata."
+)
+
+// importBatchSize is the number of rows which are imported at once.
+const importBatchSize = 500
+
+var metadataKeyRegexp = regexp.MustCompile(`^[\w.-]+$`)
+
+// ImportOptions are the options of the subscribers import.
+type ImportOptions struct {
+	// Format of the file, it defaults to FormatCSV.
+	Format string
+	// Segments are attached to the imported subscribers.
+	Segments []entities.Segment
+	// Mapping maps the columns of the file to the subscriber fields, the columns which
+	// are not mapped are ignored. When it is empty the email and name columns are mapped
+	// by their names and the rest of the columns are imported as metadata. The nested
+	// keys of the json lines files are mapped as dot separated columns.
+	Mapping map[string]string
+	// Mode handles the existing subscribers, it defaults to entities.ImportModeSkip.
+	Mode string
+	// Rejects receives the rows which can't be imported as csv, with the line
+	// number and the reason of each row.
+	Rejects io.Writer
+}
+
+// MappingError is returned when the column mapping of the import is not valid.
+type MappingError struct {
+	Reason string
+}
+
+func (e *MappingError) Error() string {
+	return "importer: invalid mapping: " + e.Reason
+}
+
+// ValidateMapping checks that the fields of the mapping are known and the email
+// field is mapped exactly once.
+func ValidateMapping(mapping map[string]string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	mapped := make(map[string]bool, len(mapping))
+	for column, field := range mapping {
+		if field == "" {
+			continue
+		}
+
+		if field != FieldEmail && field != FieldName {
+			key := strings.TrimPrefix(field, MetadataPrefix)
+			if key == field || !metadataKeyRegexp.MatchString(key) {
+				return &MappingError{Reason: fmt.Sprintf("unknown field '%s' for column '%s'", field, column)}
+			}
+		}
+
+		if mapped[field] {
+			return &MappingError{Reason: fmt.Sprintf("the field '%s' is mapped more than once", field)}
+		}
+		mapped[field] = true
+	}
+
+	if !mapped[FieldEmail] {
+		return &MappingError{Reason: "the email field must be mapped"}
+	}
+
+	return nil
+}
+
+// mapColumns returns the field of each column of the header, the ignored columns have an empty field.
+func mapColumns(header []string, mapping map[string]string) ([]string, error) {
+	fields := make([]string, len(header))
+
+	if len(mapping) == 0 {
+		var hasEmail bool
+		for i, h := range header {
+			h = strings.TrimSpace(h)
+			switch strings.ToLower(h) {
+			case FieldEmail:
+				fields[i] = FieldEmail
+				hasEmail = true
+			case FieldName:
+				fields[i] = FieldName
+			default:
+				if strings.HasPrefix(h, MetadataPrefix) {
+					fields[i] = h
+				} else if h != "" {
+					fields[i] = MetadataPrefix + h
+				}
+			}
+		}
+		if !hasEmail {
+			return nil, ErrInvalidFormat
+		}
+		return fields, nil
+	}
+
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(header))
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	for column, field := range mapping {
+		i, ok := index[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			if field == "" {
+				continue
+			}
+			return nil, &MappingError{Reason: fmt.Sprintf("the column '%s' is not found in the file", column)}
+		}
+		fields[i] = field
+	}
+
+	return fields, nil
+}
+
+type importRow struct {
+	line  int
+	email string
+	name  string
+	meta  map[string]string
+}
+
+// parseRow maps the record to the subscriber fields, the reason is returned when the record is not valid.
+func parseRow(record, fields []string) (row importRow, reason string) {
+	if len(record) != len(fields) {
+		return row, fmt.Sprintf("expected %d columns, got %d", len(fields), len(record))
+	}
+
+	for i, f := range fields {
+		val := strings.TrimSpace(record[i])
+		switch {
+		case f == FieldEmail:
+			row.email = val
+		case f == FieldName:
+			row.name = val
+		case strings.HasPrefix(f, MetadataPrefix) && val != "":
+			if row.meta == nil {
+				row.meta = make(map[string]string)
+			}
+			row.meta[strings.TrimPrefix(f, MetadataPrefix)] = val
+		}
+	}
+
+	if row.email == "" {
+		return row, "missing email"
+	}
+
+	if err := validator.Validator().Var(row.email, "email"); err != nil {
+		return row, "invalid email address"
+	}
+
+	return row, ""
+}
+
+type importer struct {
+	ctx      context.Context
+	s        *service
+	userID   int64
+	opts     ImportOptions
+	rejects  *csv.Writer
+	result   *entities.ImportSubscribersResult
+	hasName  bool
+	hasMeta  bool
+	seen     map[string]struct{}
+	batch    []importRow
+	progress func(n int64)
+}
+
+// ImportSubscribersFromFile imports the subscribers from the file in batches. The rows
+// which are not valid are written to the rejects writer and the import continues.
+func (s *service) ImportSubscribersFromFile(
+	ctx context.Context,
+	userID int64,
+	r io.Reader,
+	opts ImportOptions,
+	progress func(n int64),
+) (*entities.ImportSubscribersResult, error) {
+	reader, err := newRowReader(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	header, _, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("importer: empty file: %w", err)
+		}
+
+		return nil, fmt.Errorf("importer: read header: %w", err)
+	}
+
+	if len(header) < 1 {
+		return nil, ErrInvalidColumnsNum
+	}
+
+	fields, err := mapColumns(header, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Mode == "" {
+		opts.Mode = entities.ImportModeSkip
+	}
+	if opts.Rejects == nil {
+		opts.Rejects = io.Discard
+	}
+
+	imp := &importer{
+		ctx:      ctx,
+		s:        s,
+		userID:   userID,
+		opts:     opts,
+		rejects:  csv.NewWriter(opts.Rejects),
+		result:   new(entities.ImportSubscribersResult),
+		seen:     make(map[string]struct{}),
+		batch:    make([]importRow, 0, importBatchSize),
+		progress: progress,
+	}
+	for _, f := range fields {
+		imp.hasName = imp.hasName || f == FieldName
+		imp.hasMeta = imp.hasMeta || strings.HasPrefix(f, MetadataPrefix)
+	}
+
+	err = imp.rejects.Write(append(append([]string{"line"}, header...), "error"))
+	if err != nil {
+		return nil, fmt.Errorf("importer: write rejects header: %w", err)
+	}
+
+	for {
+		record, line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var rerr *rowError
+		if errors.As(err, &rerr) {
+			progress(1)
+			err = imp.reject(line, record, "malformed row: "+rerr.err.Error())
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("importer: read line: %w", err)
+		}
+
+		row, reason := parseRow(record, fields)
+		row.line = line
+		if reason == "" {
+			email := strings.ToLower(row.email)
+			if _, ok := imp.seen[email]; ok {
+				reason = "duplicate email in the file"
+			}
+			imp.seen[email] = struct{}{}
+		}
+
+		if reason != "" {
+			progress(1)
+			err = imp.reject(line, record, reason)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		imp.batch = append(imp.batch, row)
+		if len(imp.batch) == importBatchSize {
+			if err := imp.flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := imp.flush(); err != nil {
+		return nil, err
+	}
+
+	imp.rejects.Flush()
+	if err := imp.rejects.Error(); err != nil {
+		return nil, fmt.Errorf("importer: write rejects: %w", err)
+	}
+
+	return imp.result, nil
+}
+
+func (imp *importer) reject(line int, record []string, reason string) error {
+	imp.result.Rejected++
+
+	err := imp.rejects.Write(append(append([]string{strconv.Itoa(line)}, record...), reason))
+	if err != nil {
+		return fmt.Errorf("importer: write reject: %w", err)
+	}
+
+	return nil
+}
+
+// flush creates the new subscribers of the batch and handles the existing ones by the import mode.
+func (imp *importer) flush() error {
+	if len(imp.batch) == 0 {
+		return nil
+	}
+
+	emails := make([]string, len(imp.batch))
+	for i, row := range imp.batch {
+		emails[i] = row.email
+	}
+
+	existing, err := imp.s.db.GetSubscribersByEmails(emails, imp.userID)
+	if err != nil {
+		return fmt.Errorf("importer: get subscribers by emails: %w", err)
+	}
+
+	// the emails are unique case insensitively, the same as the dedup of the rows.
+	byEmail := make(map[string]*entities.Subscriber, len(existing))
+	for i := range existing {
+		byEmail[strings.ToLower(existing[i].Email)] = &existing[i]
+	}
+
+	var subs []*entities.Subscriber
+	for _, row := range imp.batch {
+		sub, ok := byEmail[strings.ToLower(row.email)]
+		if !ok {
+			sub = &entities.Subscriber{
+				UserID:   imp.userID,
+				Email:    row.email,
+				Name:     row.name,
+				Segments: imp.opts.Segments,
+				Active:   true,
+			}
+			if len(row.meta) > 0 {
+				sub.MetaJSON, err = json.Marshal(row.meta)
+				if err != nil {
+					return fmt.Errorf("importer: marshal metadata: %w", err)
+				}
+			}
+			subs = append(subs, sub)
+			continue
+		}
+
+		if imp.opts.Mode == entities.ImportModeSkip {
+			imp.result.Skipped++
+			continue
+		}
+
+		err = imp.update(sub, row)
+		if err != nil {
+			return err
+		}
+		imp.result.Updated++
+	}
+
+	err = imp.s.db.CreateSubscribers(subs)
+	if err != nil {
+		return fmt.Errorf("importer: create subscribers: %w", err)
+	}
+	imp.result.Created += int64(len(subs))
+
+	// the created subscribers are dispatched like the ones created through the api, the import
+	// isn't failed when the webhooks can't be dispatched since the subscribers are already created.
+	if len(subs) > 0 {
+		data := make([]interface{}, len(subs))
+		for i, sub := range subs {
+			data[i] = sub
+		}
+		err = imp.s.webhooksvc.DispatchAll(imp.ctx, imp.userID, entities.WebhookEventSubscriberCreated, data)
+		if err != nil {
+			logrus.WithField("user_id", imp.userID).WithError(err).Error("importer: unable to dispatch webhook events")
+		}
+	}
+
+	imp.progress(int64(len(imp.batch)))
+	imp.batch = imp.batch[:0]
+
+	return nil
+}
+
+// update sets the name and the metadata of the existing subscriber and attaches the segments of the import.
+func (imp *importer) update(sub *entities.Subscriber, row importRow) error {
+	if imp.hasName && row.name != "" {
+		sub.Name = row.name
+	}
+
+	if imp.hasMeta {
+		meta := row.meta
+		if imp.opts.Mode == entities.ImportModeMerge {
+			var err error
+			meta, err = sub.GetMetadata()
+			if err != nil {
+				return fmt.Errorf("importer: get metadata: %w", err)
+			}
+			for k, v := range row.meta {
+				meta[k] = v
+			}
+		}
+
+		if meta == nil {
+			meta = make(map[string]string)
+		}
+
+		metaJSON, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("importer: marshal metadata: %w", err)
+		}
+		sub.MetaJSON = metaJSON
+	}
+
+	for _, seg := range imp.opts.Segments {
+		if !hasSegment(sub.Segments, seg.ID) {
+			sub.Segments = append(sub.Segments, seg)
+		}
+	}
+
+	err := imp.s.db.UpdateSubscriber(sub)
+	if err != nil {
+		return fmt.Errorf("importer: update subscriber: %w", err)
+	}
+
+	return nil
+}
+
+func hasSegment(segments []entities.Segment, id int64) bool {
+	for _, s := range segments {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package subscribers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxJSONLineSize is the max size of a single line of the json lines file.
+const maxJSONLineSize = 1024 * 1024
+
+type jsonlRow struct {
+	line   int
+	fields map[string]string
+	err    error
+}
+
+// jsonlReader reads the objects of the json lines file as rows. The nested objects are
+// flattened with dot separated keys, e.g. {"metadata":{"city":"Paris"}} is read as the
+// metadata.city column. The header is made of the keys of all objects, so the whole file
+// is read upfront.
+type jsonlReader struct {
+	header []string
+	rows   []jsonlRow
+	pos    int
+}
+
+func newJSONLReader(r io.Reader) (*jsonlReader, error) {
+	jr := new(jsonlReader)
+	keys := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		var obj map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			jr.rows = append(jr.rows, jsonlRow{line: line, err: errors.New("expected a JSON object")})
+			continue
+		}
+
+		fields := make(map[string]string, len(obj))
+		flatten("", obj, fields)
+		for k := range fields {
+			if !keys[k] {
+				keys[k] = true
+				jr.header = append(jr.header, k)
+			}
+		}
+
+		jr.rows = append(jr.rows, jsonlRow{line: line, fields: fields})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("importer: read json lines: %w", err)
+	}
+
+	sortHeader(jr.header)
+
+	return jr, nil
+}
+
+func (jr *jsonlReader) Read() ([]string, int, error) {
+	if jr.pos == 0 {
+		jr.pos++
+		if len(jr.rows) == 0 {
+			return nil, 0, io.EOF
+		}
+		return jr.header, 0, nil
+	}
+
+	if jr.pos > len(jr.rows) {
+		return nil, 0, io.EOF
+	}
+
+	r := jr.rows[jr.pos-1]
+	jr.pos++
+
+	if r.err != nil {
+		return nil, r.line, &rowError{line: r.line, err: r.err}
+	}
+
+	row := make([]string, len(jr.header))
+	for i, k := range jr.header {
+		row[i] = r.fields[k]
+	}
+
+	return row, r.line, nil
+}
+
+// flatten sets the values of the object to the fields with dot separated keys,
+// the arrays are kept as json.
+func flatten(prefix string, obj map[string]interface{}, fields map[string]string) {
+	for k, v := range obj {
+		key := prefix + k
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flatten(key+".", val, fields)
+		case string:
+			fields[key] = val
+		case json.Number:
+			fields[key] = val.String()
+		case bool:
+			fields[key] = fmt.Sprint(val)
+		case nil:
+			fields[key] = ""
+		default:
+			b, _ := json.Marshal(val)
+			fields[key] = string(b)
+		}
+	}
+}
+
+// sortHeader orders the header by the email and name columns first, then by the
+// keys, since the keys of the json objects are not ordered.
+func sortHeader(header []string) {
+	rank := func(h string) int {
+		switch strings.ToLower(h) {
+		case FieldEmail:
+			return 0
+		case FieldName:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	sort.Slice(header, func(i, j int) bool {
+		ri, rj := rank(header[i]), rank(header[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return header[i] < header[j]
+	})
+}
@@ -2,8 +2,6 @@ package subscribers
 
 import (
 	"context"
-	"encoding/csv"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -13,17 +11,34 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/services/webhooks"
 	"github.com/mailbadger/app/storage"
 )
 
+// Service imports and removes the subscribers from csv, tsv, json lines and xlsx
+// files, the progress func is called with the number of processed rows of the file.
 type Service interface {
-	ImportSubscribersFromFile(ctx context.Context, userID int64, segments []entities.Segment, r io.Reader) error
-	RemoveSubscribersFromFile(ctx context.Context, filename string, userID int64, r io.ReadCloser) error
+	ImportSubscribersFromFile(
+		ctx context.Context,
+		userID int64,
+		r io.Reader,
+		opts ImportOptions,
+		progress func(n int64),
+	) (*entities.ImportSubscribersResult, error)
+	RemoveSubscribersFromFile(
+		ctx context.Context,
+		filename string,
+		userID int64,
+		r io.ReadCloser,
+		format string,
+		progress func(n int64),
+	) error
 }
 
 type service struct {
-	client s3iface.S3API
-	db     storage.Storage
+	client     s3iface.S3API
+	db         storage.Storage
+	webhooksvc webhooks.Service
 }
 
 var (
@@ -31,84 +46,8 @@ var (
 	ErrInvalidFormat     = errors.New("importer: csv file not formatted properly")
 )
 
-func New(client s3iface.S3API, db storage.Storage) Service {
-	return &service{client, db}
-}
-
-func (s *service) ImportSubscribersFromFile(
-	ctx context.Context,
-	userID int64,
-	segments []entities.Segment,
-	r io.Reader,
-) (err error) {
-	reader := csv.NewReader(r)
-
-	header, err := reader.Read()
-	if err != nil {
-		if err == io.EOF {
-			return fmt.Errorf("importer: empty file: %w", err)
-		}
-
-		return fmt.Errorf("importer: read header: %w", err)
-	}
-
-	if len(header) < 2 {
-		return ErrInvalidColumnsNum
-	}
-
-	if strings.ToLower(header[0]) != "email" || strings.ToLower(header[1]) != "name" {
-		return ErrInvalidFormat
-	}
-
-	for {
-		line, err := reader.Read()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("importer: read line: %w", err)
-		}
-		if len(line) < 2 {
-			continue
-		}
-		email := strings.TrimSpace(line[0])
-		name := strings.TrimSpace(line[1])
-
-		_, err = s.db.GetSubscriberByEmail(email, userID)
-		if err == nil {
-			continue
-		} else if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("importer: get subscriber by email: %w", err)
-		}
-
-		sub := &entities.Subscriber{
-			UserID:   userID,
-			Email:    email,
-			Name:     name,
-			Segments: segments,
-			Active:   true,
-		}
-
-		if len(line) > 2 {
-			meta := make(map[string]string, len(line)-2)
-			keys := header[2:]
-			for i, m := range line[2:] {
-				meta[keys[i]] = m
-			}
-			metaJSON, err := json.Marshal(meta)
-			if err != nil {
-				return fmt.Errorf("importer: marshal metadata: %w", err)
-			}
-			sub.MetaJSON = metaJSON
-		}
-
-		err = s.db.CreateSubscriber(sub)
-		if err != nil {
-			return fmt.Errorf("importer: create subscriber: %w", err)
-		}
-	}
-
-	return
+func New(client s3iface.S3API, db storage.Storage, webhooksvc webhooks.Service) Service {
+	return &service{client, db, webhooksvc}
 }
 
 func (s *service) RemoveSubscribersFromFile(
@@ -116,6 +55,8 @@ func (s *service) RemoveSubscribersFromFile(
 	filename string,
 	userID int64,
 	r io.ReadCloser,
+	format string,
+	progress func(n int64),
 ) (err error) {
 
 	defer func() {
@@ -124,8 +65,12 @@ func (s *service) RemoveSubscribersFromFile(
 		}
 	}()
 
-	reader := csv.NewReader(r)
-	header, err := reader.Read()
+	reader, err := newRowReader(r, format)
+	if err != nil {
+		return err
+	}
+
+	header, _, err := reader.Read()
 	if err != nil {
 		if err == io.EOF {
 			return fmt.Errorf("bulkremover: empty file '%s': %w", filename, err)
@@ -138,26 +83,39 @@ func (s *service) RemoveSubscribersFromFile(
 		return ErrInvalidColumnsNum
 	}
 
-	if strings.ToLower(header[0]) != "email" {
+	col := -1
+	for i, h := range header {
+		if strings.ToLower(strings.TrimSpace(h)) == FieldEmail {
+			col = i
+			break
+		}
+	}
+	if col == -1 {
 		return ErrInvalidFormat
 	}
 
 	for {
-		line, err := reader.Read()
-
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
+		line, _, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var rerr *rowError
+		if err != nil && !errors.As(err, &rerr) {
 			return fmt.Errorf("bulkremover: read line: %w", err)
 		}
-		if len(line) == 0 {
+		progress(1)
+
+		if err != nil || len(line) <= col {
+			continue
+		}
+
+		email := strings.TrimSpace(line[col])
+		if email == "" {
 			continue
 		}
 
-		email := strings.TrimSpace(line[0])
 		err = s.db.DeleteSubscriberByEmail(email, userID)
-		if err != nil && !errors.Is(gorm.ErrRecordNotFound, err) {
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fmt.Errorf("bulkremover: delete subscriber: %w", err)
 		}
 	}
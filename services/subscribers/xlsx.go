@@ -0,0 +1,297 @@
+package subscribers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// The limits of the xlsx files, so a malformed file can't exhaust the memory. The file is
+// read into the memory so its size is limited, the column references are limited to XFD,
+// the last column of a worksheet, and the parts of the file are limited by their decompressed size.
+const (
+	maxXLSXFileSize  = 64 << 20
+	maxXLSXColumns   = 16384
+	maxXLSXRowWidth  = 1024
+	maxXLSXPartSize  = 32 << 20
+	maxXLSXSheetSize = 256 << 20
+)
+
+// xlsxReader reads the rows of the first worksheet of the xlsx file. The cells are read
+// as their raw values, e.g. the dates are read as serial numbers.
+type xlsxReader struct {
+	dec     *xml.Decoder
+	sheet   io.Closer
+	limit   *io.LimitedReader
+	strings []string
+	width   int
+}
+
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		Text string   `xml:"t"`
+		Runs []string `xml:"r>t"`
+	} `xml:"is"`
+}
+
+type xlsxRow struct {
+	Num   int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+func newXLSXReader(r io.Reader) (*xlsxReader, error) {
+	b, err := io.ReadAll(io.LimitReader(r, maxXLSXFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("importer: read xlsx: %w", err)
+	}
+	if len(b) > maxXLSXFileSize {
+		return nil, fmt.Errorf("%w: the file is larger than %d MB", ErrInvalidFormat, maxXLSXFileSize>>20)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a xlsx file", ErrInvalidFormat)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("%w: worksheet %s not found", ErrInvalidFormat, sheetPath)
+	}
+
+	xr := new(xlsxReader)
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		xr.strings, err = readSharedStrings(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rc, err := sheet.Open()
+	if err != nil {
+		return nil, fmt.Errorf("importer: open worksheet: %w", err)
+	}
+	xr.sheet = rc
+	xr.limit = &io.LimitedReader{R: rc, N: maxXLSXSheetSize}
+	xr.dec = xml.NewDecoder(xr.limit)
+
+	return xr, nil
+}
+
+func (xr *xlsxReader) Read() ([]string, int, error) {
+	for {
+		tok, err := xr.dec.Token()
+		if err != nil {
+			xr.sheet.Close()
+			if xr.limit.N <= 0 {
+				return nil, 0, fmt.Errorf("%w: the worksheet is too large", ErrInvalidFormat)
+			}
+			if err == io.EOF {
+				return nil, 0, io.EOF
+			}
+			return nil, 0, fmt.Errorf("%w: read worksheet: %s", ErrInvalidFormat, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := xr.dec.DecodeElement(&row, &start); err != nil {
+			xr.sheet.Close()
+			if xr.limit.N <= 0 {
+				return nil, 0, fmt.Errorf("%w: the worksheet is too large", ErrInvalidFormat)
+			}
+			return nil, 0, fmt.Errorf("%w: read row: %s", ErrInvalidFormat, err)
+		}
+
+		values, err := xr.values(row)
+		if err != nil {
+			return nil, row.Num, &rowError{line: row.Num, err: err}
+		}
+
+		if isEmpty(values) {
+			continue
+		}
+
+		// the header sets the width of the rows, since the empty cells are not stored.
+		if xr.width == 0 {
+			xr.width = len(values)
+		}
+		for len(values) < xr.width {
+			values = append(values, "")
+		}
+		for len(values) > xr.width && values[len(values)-1] == "" {
+			values = values[:len(values)-1]
+		}
+
+		return values, row.Num, nil
+	}
+}
+
+func (xr *xlsxReader) values(row xlsxRow) ([]string, error) {
+	var values []string
+	for i, c := range row.Cells {
+		col := i
+		if c.Ref != "" {
+			var err error
+			col, err = columnIndex(c.Ref)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if col >= maxXLSXRowWidth {
+			return nil, fmt.Errorf("the row has more than %d columns", maxXLSXRowWidth)
+		}
+		for len(values) <= col {
+			values = append(values, "")
+		}
+
+		switch c.Type {
+		case "s":
+			idx, err := strconv.Atoi(c.Value)
+			if err != nil || idx < 0 || idx >= len(xr.strings) {
+				return nil, fmt.Errorf("invalid shared string in cell %s", c.Ref)
+			}
+			values[col] = xr.strings[idx]
+		case "inlineStr":
+			values[col] = c.Inline.Text + strings.Join(c.Inline.Runs, "")
+		case "b":
+			values[col] = strconv.FormatBool(c.Value == "1")
+		default:
+			values[col] = c.Value
+		}
+	}
+
+	return values, nil
+}
+
+// columnIndex returns the zero based column of the cell reference, e.g. 2 for C5.
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for _, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			col = col*26 + int(r-'A'+1)
+			if col > maxXLSXColumns {
+				return 0, fmt.Errorf("invalid cell reference %s", ref)
+			}
+			continue
+		}
+		break
+	}
+
+	if col == 0 {
+		return 0, fmt.Errorf("invalid cell reference %s", ref)
+	}
+
+	return col - 1, nil
+}
+
+// firstSheetPath returns the path of the first worksheet from the workbook and its relationships.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+
+	wb, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", fmt.Errorf("%w: workbook not found", ErrInvalidFormat)
+	}
+	if err := decodeXML(wb, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("%w: the workbook has no sheets", ErrInvalidFormat)
+	}
+
+	if f, ok := files["xl/_rels/workbook.xml.rels"]; ok {
+		if err := decodeXML(f, &rels); err != nil {
+			return "", err
+		}
+	}
+
+	for _, r := range rels.Relationships {
+		if r.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(r.Target, "/") {
+			return strings.TrimPrefix(r.Target, "/"), nil
+		}
+		return path.Join("xl", r.Target), nil
+	}
+
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var sst struct {
+		Items []struct {
+			Text string   `xml:"t"`
+			Runs []string `xml:"r>t"`
+		} `xml:"si"`
+	}
+	if err := decodeXML(f, &sst); err != nil {
+		return nil, err
+	}
+
+	res := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		res[i] = si.Text + strings.Join(si.Runs, "")
+	}
+
+	return res, nil
+}
+
+func decodeXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("importer: open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	lr := &io.LimitedReader{R: rc, N: maxXLSXPartSize}
+	err = xml.NewDecoder(lr).Decode(v)
+	if err != nil {
+		if lr.N <= 0 {
+			return fmt.Errorf("%w: %s is too large", ErrInvalidFormat, f.Name)
+		}
+		return fmt.Errorf("%w: decode %s: %s", ErrInvalidFormat, f.Name, err)
+	}
+
+	return nil
+}
+
+func isEmpty(values []string) bool {
+	for _, v := range values {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
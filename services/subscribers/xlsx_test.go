@@ -0,0 +1,105 @@
+package subscribers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testWorkbook = `<workbook><sheets><sheet r:id="rId1" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/></sheets></workbook>`
+
+func xlsxFile(t *testing.T, parts map[string]string) io.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+
+	return &buf
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref string
+		col int
+		err bool
+	}{
+		{ref: "A1", col: 0},
+		{ref: "C5", col: 2},
+		{ref: "Z1", col: 25},
+		{ref: "AA1", col: 26},
+		{ref: "XFD1", col: 16383},
+		{ref: "XFE1", err: true},
+		{ref: "ZZZZZZZZ1", err: true},
+		{ref: "1", err: true},
+	}
+
+	for _, tt := range tests {
+		col, err := columnIndex(tt.ref)
+		assert.Equal(t, tt.err, err != nil, tt.ref)
+		assert.Equal(t, tt.col, col, tt.ref)
+	}
+}
+
+func TestXLSXReaderLimits(t *testing.T) {
+	xr, err := newXLSXReader(xlsxFile(t, map[string]string{
+		"xl/workbook.xml": testWorkbook,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="inlineStr"><is><t>email</t></is></c></row>` +
+			`<row r="2"><c r="ZZZZZZZZ2"><v>1</v></c></row>` +
+			`<row r="3"><c r="AMK3"><v>1</v></c></row>` +
+			`<row r="4"><c r="A4" t="inlineStr"><is><t>jane@example.com</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	}))
+	assert.Nil(t, err)
+
+	values, _, err := xr.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"email"}, values)
+
+	// the rows with an invalid column or too many columns are rejected.
+	_, line, err := xr.Read()
+	assert.Equal(t, 2, line)
+	assert.Contains(t, err.Error(), "invalid cell reference ZZZZZZZZ2")
+
+	_, line, err = xr.Read()
+	assert.Equal(t, 3, line)
+	assert.Contains(t, err.Error(), "the row has more than 1024 columns")
+
+	values, _, err = xr.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"jane@example.com"}, values)
+
+	// the decompressed size of the shared strings is limited.
+	_, err = newXLSXReader(xlsxFile(t, map[string]string{
+		"xl/workbook.xml":          testWorkbook,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData></sheetData></worksheet>`,
+		"xl/sharedStrings.xml":     `<sst><si><t>` + strings.Repeat("a", maxXLSXPartSize) + `</t></si></sst>`,
+	}))
+	assert.True(t, errors.Is(err, ErrInvalidFormat))
+	assert.Contains(t, err.Error(), "xl/sharedStrings.xml is too large")
+
+	// the size of the file is limited before it's read into the memory.
+	_, err = newXLSXReader(io.LimitReader(zeroReader{}, maxXLSXFileSize+1))
+	assert.True(t, errors.Is(err, ErrInvalidFormat))
+	assert.Contains(t, err.Error(), "the file is larger than 64 MB")
+}
+
+// zeroReader reads an endless stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
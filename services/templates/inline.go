@@ -0,0 +1,493 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var errUnterminatedRule = errors.New("unterminated css rule")
+
+var (
+	cssCommentRegexp  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssCompoundRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:[.#][a-zA-Z0-9_-]+)*)$`)
+	cssSimpleRegexp   = regexp.MustCompile(`[.#][a-zA-Z0-9_-]+`)
+	styleAttrRegexp   = regexp.MustCompile(`(?i)(\s)style\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+// voidElements are the elements which have no end tag, so they never enclose other elements.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// cssDecl is a declaration of a css rule or a style attribute.
+type cssDecl struct {
+	prop      string
+	value     string
+	important bool
+}
+
+// cssCompound is a compound selector, e.g. td.content#main.
+type cssCompound struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+func (cc cssCompound) match(el element) bool {
+	if cc.tag != "" && cc.tag != "*" && cc.tag != el.tag {
+		return false
+	}
+	if cc.id != "" && cc.id != el.id {
+		return false
+	}
+	for _, class := range cc.classes {
+		if !el.classes[class] {
+			return false
+		}
+	}
+	return true
+}
+
+// cssSelector is a chain of compound selectors which are joined by the descendant or the child
+// combinators, the combinators[i] is the combinator between the parts i and i+1.
+type cssSelector struct {
+	parts       []cssCompound
+	combinators []byte
+	specificity int
+}
+
+// match reports whether the selector matches the element along with its ancestors.
+func (cs cssSelector) match(el element, ancestors []element) bool {
+	last := len(cs.parts) - 1
+	return cs.parts[last].match(el) && cs.matchAncestors(last-1, ancestors)
+}
+
+func (cs cssSelector) matchAncestors(i int, ancestors []element) bool {
+	if i < 0 {
+		return true
+	}
+
+	if cs.combinators[i] == '>' {
+		n := len(ancestors) - 1
+		return n >= 0 && cs.parts[i].match(ancestors[n]) && cs.matchAncestors(i-1, ancestors[:n])
+	}
+
+	for j := len(ancestors) - 1; j >= 0; j-- {
+		if cs.parts[i].match(ancestors[j]) && cs.matchAncestors(i-1, ancestors[:j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// cssRule is a rule of the stylesheet with a single selector.
+type cssRule struct {
+	selector cssSelector
+	decls    []cssDecl
+}
+
+// element is an open element of the document.
+type element struct {
+	tag     string
+	id      string
+	classes map[string]bool
+}
+
+// inlineCSS moves the rules of the style elements into the style attributes of the elements
+// which they match. Only the type, class and id selectors joined by the descendant and child
+// combinators are inlined, the rest of the rules (e.g. the media queries and the pseudo-classes)
+// are kept in the style elements. The markup is copied as it is, so the mustache tags are preserved.
+func inlineCSS(src string) (string, error) {
+	var (
+		rules []cssRule
+		kept  []string
+	)
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	inStyle := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if !errors.Is(z.Err(), io.EOF) {
+				return "", z.Err()
+			}
+			break
+		}
+
+		name, _ := z.TagName()
+		switch {
+		case tt == html.StartTagToken && string(name) == "style":
+			inStyle = true
+			kept = append(kept, "")
+		case tt == html.EndTagToken && string(name) == "style":
+			inStyle = false
+		case tt == html.TextToken && inStyle:
+			r, k, err := parseStylesheet(string(z.Text()))
+			if err != nil {
+				return "", err
+			}
+			rules = append(rules, r...)
+			kept[len(kept)-1] += k
+		}
+	}
+
+	if len(rules) == 0 {
+		return src, nil
+	}
+
+	// the rules with the same specificity are applied in the order of the stylesheets.
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].selector.specificity < rules[j].selector.specificity
+	})
+
+	var (
+		out       strings.Builder
+		ancestors []element
+		styles    int
+		skip      bool
+	)
+
+	inStyle = false
+	z = html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if !errors.Is(z.Err(), io.EOF) {
+				return "", z.Err()
+			}
+			break
+		}
+
+		raw := string(z.Raw())
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if tok.Data == "style" && tt == html.StartTagToken {
+				inStyle = true
+				skip = strings.TrimSpace(kept[styles]) == ""
+				if !skip {
+					out.WriteString(raw)
+					out.WriteString("\n" + kept[styles])
+				}
+				styles++
+				continue
+			}
+
+			el := newElement(tok)
+			if !inHead(ancestors) && el.tag != "head" {
+				raw = applyRules(raw, tok, el, ancestors, rules)
+			}
+			if tt == html.StartTagToken && !voidElements[el.tag] {
+				ancestors = append(ancestors, el)
+			}
+		case html.EndTagToken:
+			if tok.Data == "style" {
+				if !skip {
+					out.WriteString(raw)
+				}
+				inStyle, skip = false, false
+				continue
+			}
+
+			for i := len(ancestors) - 1; i >= 0; i-- {
+				if ancestors[i].tag == tok.Data {
+					ancestors = ancestors[:i]
+					break
+				}
+			}
+		case html.TextToken:
+			// the content of the style elements is replaced with the rules which were not inlined.
+			if inStyle {
+				continue
+			}
+		}
+
+		out.WriteString(raw)
+	}
+
+	return out.String(), nil
+}
+
+func newElement(tok html.Token) element {
+	el := element{tag: tok.Data, classes: make(map[string]bool)}
+	for _, a := range tok.Attr {
+		switch a.Key {
+		case "id":
+			el.id = strings.TrimSpace(a.Val)
+		case "class":
+			for _, class := range strings.Fields(a.Val) {
+				el.classes[class] = true
+			}
+		}
+	}
+	return el
+}
+
+func inHead(ancestors []element) bool {
+	for _, a := range ancestors {
+		if a.tag == "head" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules sets the style attribute of the raw start tag to the declarations of the rules which match the
+// element. The declarations of the style attribute take precedence over the rules, unless the rules are important.
+func applyRules(raw string, tok html.Token, el element, ancestors []element, rules []cssRule) string {
+	var matched []cssDecl
+	for _, r := range rules {
+		if r.selector.match(el, ancestors) {
+			matched = append(matched, r.decls...)
+		}
+	}
+	if len(matched) == 0 {
+		return raw
+	}
+
+	var inline []cssDecl
+	for _, a := range tok.Attr {
+		if a.Key == "style" {
+			inline = parseDecls(a.Val)
+		}
+	}
+
+	var (
+		props  []string
+		values = make(map[string]cssDecl)
+	)
+	set := func(decls []cssDecl, important bool) {
+		for _, d := range decls {
+			if d.important != important {
+				continue
+			}
+			if _, ok := values[d.prop]; !ok {
+				props = append(props, d.prop)
+			}
+			values[d.prop] = d
+		}
+	}
+	set(matched, false)
+	set(inline, false)
+	set(matched, true)
+	set(inline, true)
+
+	decls := make([]string, len(props))
+	for i, p := range props {
+		d := values[p]
+		decls[i] = d.prop + ": " + d.value
+		if d.important {
+			decls[i] += " !important"
+		}
+	}
+	style := `style="` + strings.ReplaceAll(strings.Join(decls, "; "), `"`, "&quot;") + `"`
+
+	if loc := styleAttrRegexp.FindStringSubmatchIndex(raw); loc != nil {
+		return raw[:loc[3]] + style + raw[loc[1]:]
+	}
+	end := len(raw) - 1
+	if strings.HasSuffix(raw, "/>") {
+		end--
+	}
+	return strings.TrimRight(raw[:end], " ") + " " + style + raw[end:]
+}
+
+// parseStylesheet parses the rules of the stylesheet which can be inlined, the rest of the rules are returned as they are.
+func parseStylesheet(css string) ([]cssRule, string, error) {
+	var (
+		rules []cssRule
+		kept  strings.Builder
+	)
+
+	css = cssCommentRegexp.ReplaceAllString(css, "")
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+
+		if css[0] == '@' {
+			end := atRuleEnd(css)
+			if end < 0 {
+				return nil, "", fmt.Errorf("%w: %s", errUnterminatedRule, firstLine(css))
+			}
+			kept.WriteString(css[:end] + "\n")
+			css = css[end:]
+			continue
+		}
+
+		open := strings.Index(css, "{")
+		end := strings.Index(css, "}")
+		if open < 0 || end < open {
+			return nil, "", fmt.Errorf("%w: %s", errUnterminatedRule, firstLine(css))
+		}
+
+		body := css[open+1 : end]
+		decls := parseDecls(body)
+		var unsupported []string
+		for _, s := range strings.Split(css[:open], ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			sel, ok := parseSelector(s)
+			if !ok {
+				unsupported = append(unsupported, s)
+				continue
+			}
+			rules = append(rules, cssRule{
+				selector: sel,
+				decls:    decls,
+			})
+		}
+		if len(unsupported) > 0 {
+			kept.WriteString(strings.Join(unsupported, ", ") + " {" + body + "}\n")
+		}
+
+		css = css[end+1:]
+	}
+
+	return rules, kept.String(), nil
+}
+
+// atRuleEnd returns the end of the at-rule, e.g. @import ...; or @media ... { ... }.
+func atRuleEnd(css string) int {
+	depth := 0
+	for i := 0; i < len(css); i++ {
+		switch css[i] {
+		case ';':
+			if depth == 0 {
+				return i + 1
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexAny(s, "\n{"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// parseSelector parses the selectors which can be inlined, the selectors with pseudo-classes,
+// attributes or sibling combinators depend on the state of the document and are not supported.
+func parseSelector(s string) (cssSelector, bool) {
+	if strings.ContainsAny(s, `:[]+~\()`) {
+		return cssSelector{}, false
+	}
+
+	var (
+		sel  cssSelector
+		comb byte = ' '
+	)
+	for _, f := range strings.Fields(strings.ReplaceAll(s, ">", " > ")) {
+		if f == ">" {
+			if len(sel.parts) == 0 || comb == '>' {
+				return cssSelector{}, false
+			}
+			comb = '>'
+			continue
+		}
+
+		m := cssCompoundRegexp.FindStringSubmatch(f)
+		if m == nil {
+			return cssSelector{}, false
+		}
+
+		cc := cssCompound{tag: strings.ToLower(m[1])}
+		if cc.tag != "" && cc.tag != "*" {
+			sel.specificity++
+		}
+		for _, simple := range cssSimpleRegexp.FindAllString(m[2], -1) {
+			if simple[0] == '#' {
+				if cc.id != "" && cc.id != simple[1:] {
+					return cssSelector{}, false
+				}
+				cc.id = simple[1:]
+				sel.specificity += 100
+				continue
+			}
+			cc.classes = append(cc.classes, simple[1:])
+			sel.specificity += 10
+		}
+
+		if len(sel.parts) > 0 {
+			sel.combinators = append(sel.combinators, comb)
+		}
+		sel.parts = append(sel.parts, cc)
+		comb = ' '
+	}
+
+	if len(sel.parts) == 0 || comb == '>' {
+		return cssSelector{}, false
+	}
+
+	return sel, true
+}
+
+// parseDecls parses the declarations of a rule or a style attribute, the
+// semicolons within the quotes and the parentheses don't end the declarations.
+func parseDecls(s string) []cssDecl {
+	var (
+		decls []cssDecl
+		start int
+		depth int
+		quote rune
+	)
+	add := func(d string) {
+		i := strings.Index(d, ":")
+		if i < 0 {
+			return
+		}
+
+		decl := cssDecl{
+			prop:  strings.ToLower(strings.TrimSpace(d[:i])),
+			value: strings.TrimSpace(d[i+1:]),
+		}
+		lower := strings.ToLower(decl.value)
+		if j := strings.LastIndex(lower, "!"); j >= 0 && strings.TrimSpace(lower[j+1:]) == "important" {
+			decl.important = true
+			decl.value = strings.TrimSpace(decl.value[:j])
+		}
+		if decl.prop != "" && decl.value != "" {
+			decls = append(decls, decl)
+		}
+	}
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ';' && depth <= 0:
+			add(s[start:i])
+			start = i + 1
+		}
+	}
+	add(s[start:])
+
+	return decls
+}
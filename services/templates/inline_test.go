@@ -0,0 +1,128 @@
+package templates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInlineCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		out  string
+	}{
+		{
+			name: "no styles",
+			src:  `<p class="a">{{name}}</p>`,
+			out:  `<p class="a">{{name}}</p>`,
+		},
+		{
+			name: "specificity",
+			src:  `<style>#main { color: red } p.a { color: blue } .a { color: green } p { color: black; margin: 0 }</style><p id="main" class="a">x</p><p class="a">y</p><p>z</p>`,
+			out:  `<p id="main" class="a" style="color: red; margin: 0">x</p><p class="a" style="color: blue; margin: 0">y</p><p style="color: black; margin: 0">z</p>`,
+		},
+		{
+			name: "source order",
+			src:  `<style>.a { color: red } .b { color: blue }</style><p class="b a">x</p>`,
+			out:  `<p class="b a" style="color: blue">x</p>`,
+		},
+		{
+			name: "important",
+			src:  `<style>p { color: red !important; margin: 0 } #main { color: blue; margin: 1px }</style><p id="main">x</p>`,
+			out:  `<p id="main" style="margin: 1px; color: red !important">x</p>`,
+		},
+		{
+			name: "existing style attribute",
+			src:  `<style>p { color: red; margin: 0; padding: 0 !important }</style><p style="color: blue; padding: 1px">x</p><p STYLE='font-weight: bold'>y</p>`,
+			out:  `<p style="color: blue; margin: 0; padding: 0 !important">x</p><p style="color: red; margin: 0; font-weight: bold; padding: 0 !important">y</p>`,
+		},
+		{
+			name: "important style attribute",
+			src:  `<style>p { color: red !important }</style><p style="color: blue !important">x</p>`,
+			out:  `<p style="color: blue !important">x</p>`,
+		},
+		{
+			name: "descendant combinator",
+			src:  `<style>table td { color: red } .wrap .cell { margin: 0 }</style><table><tr><td>x</td></tr></table><td>y</td><div class="wrap"><div><span class="cell">z</span></div></div>`,
+			out:  `<table><tr><td style="color: red">x</td></tr></table><td>y</td><div class="wrap"><div><span class="cell" style="margin: 0">z</span></div></div>`,
+		},
+		{
+			name: "child combinator",
+			src:  `<style>div > p { color: red } .a>.b { margin: 0 }</style><div><p>x</p><section><p>y</p></section></div><div class="a"><i class="b">z</i><b><i class="b">w</i></b></div>`,
+			out:  `<div><p style="color: red">x</p><section><p>y</p></section></div><div class="a"><i class="b" style="margin: 0">z</i><b><i class="b">w</i></b></div>`,
+		},
+		{
+			name: "void elements",
+			src:  `<style>div > img { border: 0 } div > span { color: red }</style><div><img src="a.png"><br/><span>x</span></div>`,
+			out:  `<div><img src="a.png" style="border: 0"><br/><span style="color: red">x</span></div>`,
+		},
+		{
+			name: "kept media queries and pseudo-classes",
+			src:  "<style>@media (max-width: 600px) { .a { width: 100% } } a:hover { color: red } p, a:visited { margin: 0 } .a { color: blue }</style><p class=\"a\">x</p>",
+			out:  "<style>\n@media (max-width: 600px) { .a { width: 100% } }\na:hover { color: red }\na:visited { margin: 0 }\n</style><p class=\"a\" style=\"margin: 0; color: blue\">x</p>",
+		},
+		{
+			name: "removed style element",
+			src:  `<html><head><style>p { color: red }</style><title>t</title></head><body><p>x</p></body></html>`,
+			out:  `<html><head><title>t</title></head><body><p style="color: red">x</p></body></html>`,
+		},
+		{
+			name: "mustache tags in attributes",
+			src:  `<style>a { color: red } td { color: blue }</style><a href="{{unsubscribe_url}}" title="{{#vip}}VIP{{/vip}}">x</a><td style="background: {{color}}">{{{body}}}</td>`,
+			out:  `<a href="{{unsubscribe_url}}" title="{{#vip}}VIP{{/vip}}" style="color: red">x</a><td style="color: blue; background: {{color}}">{{{body}}}</td>`,
+		},
+		{
+			name: "quoted values",
+			src:  `<style>p { font-family: "Open Sans", sans-serif; background: url("a;b.png") }</style><p>x</p>`,
+			out:  `<p style="font-family: &quot;Open Sans&quot;, sans-serif; background: url(&quot;a;b.png&quot;)">x</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := inlineCSS(tt.src)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestInlineCSSUnterminatedRule(t *testing.T) {
+	for _, src := range []string{
+		`<style>p { color: red</style><p>x</p>`,
+		`<style>@media print { p { color: red }</style><p>x</p>`,
+	} {
+		_, err := inlineCSS(src)
+		assert.True(t, errors.Is(err, errUnterminatedRule), src)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		sel         string
+		ok          bool
+		specificity int
+	}{
+		{sel: "*", ok: true, specificity: 0},
+		{sel: "p", ok: true, specificity: 1},
+		{sel: ".a.b", ok: true, specificity: 20},
+		{sel: "td.a#main", ok: true, specificity: 111},
+		{sel: "table > tr td.a", ok: true, specificity: 13},
+		{sel: "a:hover"},
+		{sel: "input[type=text]"},
+		{sel: "h1 + p"},
+		{sel: "h1 ~ p"},
+		{sel: "> p"},
+		{sel: "div >"},
+		{sel: "div > > p"},
+		{sel: "#a#b"},
+	}
+
+	for _, tt := range tests {
+		sel, ok := parseSelector(tt.sel)
+		assert.Equal(t, tt.ok, ok, tt.sel)
+		assert.Equal(t, tt.specificity, sel.specificity, tt.sel)
+	}
+}
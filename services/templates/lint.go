@@ -0,0 +1,291 @@
+package templates
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cbroglie/mustache"
+	"golang.org/x/net/html"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// gmailClipSize is the size of the html part over which Gmail clips the messages.
+const gmailClipSize = 102 * 1024
+
+const (
+	partHTML    = "html_part"
+	partText    = "text_part"
+	partSubject = "subject_part"
+)
+
+var (
+	mustacheTagRegexp    = regexp.MustCompile(`\{\{\{?[^{}]*\}?\}\}`)
+	spamPunctuationRegex = regexp.MustCompile(`[!?]{2,}|\${2,}`)
+	spamPhrasesRegexp    = regexp.MustCompile(`(?i)\b(100% free|free money|free cash|fast cash|cash bonus|act now|buy now|order now|click here|limited time|urgent|winner|congratulations|risk[- ]free|guaranteed?|no cost|earn money|double your)\b`)
+)
+
+// linkSchemes and imageSchemes are the schemes of the urls which the links and the images can point to.
+var (
+	linkSchemes  = map[string]bool{"http": true, "https": true, "mailto": true, "tel": true}
+	imageSchemes = map[string]bool{"http": true, "https": true, "cid": true, "data": true}
+)
+
+// optionalEndTags are the elements whose end tag may be omitted.
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true, "option": true,
+	"thead": true, "tbody": true, "tfoot": true, "tr": true, "td": true, "th": true, "colgroup": true,
+}
+
+// warnFunc reports a warning of the given part of the template.
+type warnFunc func(code, part, format string, args ...interface{})
+
+// lint checks the parsed template for the issues which hurt its deliverability or rendering. The tags
+// are looked up through the partials and the layout, while the markup is checked on the html part alone.
+func (s *service) lint(
+	template *entities.Template,
+	data *entities.CampaignTemplateData,
+	partials map[string]string,
+) ([]entities.TemplateWarning, error) {
+	var warnings []entities.TemplateWarning
+	warn := func(code, part, format string, args ...interface{}) {
+		warnings = append(warnings, entities.TemplateWarning{
+			Code:    code,
+			Part:    part,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	htmlKeys := tagKeys(data.HTMLPart.Tags(), partials, data.Helpers)
+	textKeys := tagKeys(data.TextPart.Tags(), partials, data.Helpers)
+	subjectKeys := tagKeys(data.SubjectPart.Tags(), partials, data.Helpers)
+
+	if _, ok := htmlKeys[entities.TagUnsubscribeUrl]; !ok {
+		warn(entities.LintMissingUnsubscribe, partHTML, "the html part doesn't include the {{%s}} tag", entities.TagUnsubscribeUrl)
+	}
+	if _, ok := textKeys[entities.TagUnsubscribeUrl]; !ok {
+		warn(entities.LintMissingUnsubscribe, partText, "the text part doesn't include the {{%s}} tag", entities.TagUnsubscribeUrl)
+	}
+
+	lintMarkup(template.HTMLPart, warn)
+
+	rendered, err := data.HTMLPart.Render(map[string]string{})
+	if err != nil {
+		return nil, fmt.Errorf("lint: render html part: %w", err)
+	}
+	if len(rendered) > gmailClipSize {
+		warn(entities.LintHTMLSize, partHTML, "the html part is %dKB, Gmail clips the messages over %dKB", len(rendered)/1024, gmailClipSize/1024)
+	}
+
+	lintSubject(template.SubjectPart, warn)
+
+	builtin := map[string]bool{
+		entities.TagName:           true,
+		entities.TagUnsubscribeUrl: true,
+		entities.TagConfirmUrl:     true,
+	}
+	parts := []struct {
+		name string
+		keys []string
+	}{
+		{partHTML, requiredKeys(htmlKeys)},
+		{partText, requiredKeys(textKeys)},
+		{partSubject, requiredKeys(subjectKeys)},
+	}
+
+	var wanted []string
+	for _, p := range parts {
+		for _, k := range p.keys {
+			if root := strings.Split(k, ".")[0]; !builtin[root] {
+				wanted = append(wanted, root)
+			}
+		}
+	}
+	if len(wanted) == 0 {
+		return warnings, nil
+	}
+
+	metadata, err := s.metadataKeys.get(template.UserID, wanted)
+	if err != nil {
+		return nil, fmt.Errorf("lint: get metadata keys: %w", err)
+	}
+
+	reported := make(map[string]bool)
+	for _, p := range parts {
+		for _, k := range p.keys {
+			root := strings.Split(k, ".")[0]
+			if builtin[root] || metadata[root] || reported[k] {
+				continue
+			}
+			reported[k] = true
+			warn(entities.LintUnknownTag, p.name, "the tag {{%s}} is not a metadata key of the subscribers", k)
+		}
+	}
+
+	return warnings, nil
+}
+
+// tagKeys returns the data keys of the tags along with the partials which they include, the keys are
+// required unless all of their tags have a default value or are inverted sections. The tags nested in
+// the sections are looked up in the context of the section's value first, so their keys are not required.
+func tagKeys(tags []mustache.Tag, partials map[string]string, helpers entities.TemplateHelpers) map[string]bool {
+	keys := make(map[string]bool)
+	seen := map[bool]map[string]bool{false: {}, true: {}}
+
+	var walk func(tags []mustache.Tag, nested bool)
+	walk = func(tags []mustache.Tag, nested bool) {
+		for _, tag := range tags {
+			switch tag.Type() {
+			case mustache.Variable:
+				name, optional := tag.Name(), false
+				if expr, ok := helpers[name]; ok {
+					name, optional = expr.Key, expr.Optional()
+				}
+				if name != "." {
+					keys[name] = keys[name] || (!optional && !nested)
+				}
+			case mustache.Section, mustache.InvertedSection:
+				section := tag.Type() == mustache.Section
+				keys[tag.Name()] = keys[tag.Name()] || (section && !nested)
+				walk(tag.Tags(), nested || section)
+			case mustache.Partial:
+				name := tag.Name()
+				if seen[nested][name] {
+					continue
+				}
+				seen[nested][name] = true
+
+				// the partials have already been checked when the template was parsed.
+				tmpl, err := mustache.ParseString(partials[name])
+				if err == nil {
+					walk(tmpl.Tags(), nested)
+				}
+			}
+		}
+	}
+	walk(tags, false)
+
+	return keys
+}
+
+// requiredKeys returns the sorted required keys.
+func requiredKeys(keys map[string]bool) []string {
+	res := make([]string, 0, len(keys))
+	for k, required := range keys {
+		if required {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// lintMarkup checks the urls of the links and the images, the alt texts of the images and the balance of the tags.
+func lintMarkup(src string, warn warnFunc) {
+	var open []string
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch tok.Data {
+			case "a":
+				if href, ok := attrValue(tok, "href"); ok && !strings.HasPrefix(strings.TrimSpace(href), "#") {
+					lintURL(href, "link", linkSchemes, warn)
+				}
+			case "img":
+				src, _ := attrValue(tok, "src")
+				lintURL(src, "image", imageSchemes, warn)
+				if _, ok := attrValue(tok, "alt"); !ok {
+					warn(entities.LintMissingAlt, partHTML, "the image %s has no alt text", strings.TrimSpace(src))
+				}
+			}
+
+			if tt == html.StartTagToken && !voidElements[tok.Data] {
+				open = append(open, tok.Data)
+			}
+		case html.EndTagToken:
+			i := len(open) - 1
+			for i >= 0 && open[i] != tok.Data {
+				i--
+			}
+			if i < 0 {
+				warn(entities.LintUnbalancedTag, partHTML, "the closing tag </%s> has no opening tag", tok.Data)
+				continue
+			}
+
+			for _, t := range open[i+1:] {
+				if !optionalEndTags[t] {
+					warn(entities.LintUnbalancedTag, partHTML, "the tag <%s> is not closed before </%s>", t, tok.Data)
+				}
+			}
+			open = open[:i]
+		}
+	}
+
+	for _, t := range open {
+		if !optionalEndTags[t] {
+			warn(entities.LintUnbalancedTag, partHTML, "the tag <%s> is not closed", t)
+		}
+	}
+}
+
+// lintURL checks the url of a link or an image, the urls which start with a tag are set when the
+// template is rendered and the tags within the urls are replaced with placeholders.
+func lintURL(raw, kind string, schemes map[string]bool, warn warnFunc) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "{{") {
+		return
+	}
+	if raw == "" {
+		warn(entities.LintInvalidURL, partHTML, "the %s has an empty url", kind)
+		return
+	}
+
+	u, err := url.Parse(mustacheTagRegexp.ReplaceAllString(raw, "x"))
+	switch {
+	case err != nil:
+		warn(entities.LintInvalidURL, partHTML, "the %s url %s is invalid", kind, raw)
+	case u.Scheme == "":
+		warn(entities.LintRelativeURL, partHTML, "the %s url %s is relative, the urls must be absolute", kind, raw)
+	case !schemes[strings.ToLower(u.Scheme)]:
+		warn(entities.LintInvalidURL, partHTML, "the %s url %s has an unsupported scheme", kind, raw)
+	case (u.Scheme == "http" || u.Scheme == "https") && u.Host == "":
+		warn(entities.LintInvalidURL, partHTML, "the %s url %s has no host", kind, raw)
+	}
+}
+
+// lintSubject checks the subject for the patterns which are common in spam, the tags are not checked.
+func lintSubject(subject string, warn warnFunc) {
+	subject = mustacheTagRegexp.ReplaceAllString(subject, "")
+
+	letters, upper := 0, 0
+	for _, r := range subject {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 5 && letters == upper {
+		warn(entities.LintSpamSubject, partSubject, "the subject is written in capital letters")
+	}
+
+	if m := spamPunctuationRegex.FindString(subject); m != "" {
+		warn(entities.LintSpamSubject, partSubject, "the subject contains the repeated punctuation %s", m)
+	}
+	if m := spamPhrasesRegexp.FindString(subject); m != "" {
+		warn(entities.LintSpamSubject, partSubject, "the subject contains the phrase '%s'", m)
+	}
+}
@@ -0,0 +1,243 @@
+package templates
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cbroglie/mustache"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/storage"
+)
+
+// recorder collects the warnings as code: message.
+type recorder struct {
+	warnings []string
+}
+
+func (r *recorder) warn(code, part, format string, args ...interface{}) {
+	r.warnings = append(r.warnings, code+": "+fmt.Sprintf(format, args...))
+}
+
+func TestLintMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		warnings []string
+	}{
+		{
+			name: "valid",
+			html: `<html><body><p>Hi<p><a href="https://example.com">x</a><a href="#top">top</a><img src="cid:logo" alt=""><br></body></html>`,
+		},
+		{
+			name: "links",
+			html: `<a href="javascript:alert(1)">x</a><a href="/about">x</a><a href="">x</a><a href="{{url}}">x</a>`,
+			warnings: []string{
+				"invalid_url: the link url javascript:alert(1) has an unsupported scheme",
+				"relative_url: the link url /about is relative, the urls must be absolute",
+				"invalid_url: the link has an empty url",
+			},
+		},
+		{
+			name: "images",
+			html: `<img src="logo.png"><img src="https://example.com/logo.png" alt="logo">`,
+			warnings: []string{
+				"relative_url: the image url logo.png is relative, the urls must be absolute",
+				"missing_alt: the image logo.png has no alt text",
+			},
+		},
+		{
+			name: "unbalanced",
+			html: `<div><span>x</div></a><table>`,
+			warnings: []string{
+				"unbalanced_tag: the tag <span> is not closed before </div>",
+				"unbalanced_tag: the closing tag </a> has no opening tag",
+				"unbalanced_tag: the tag <table> is not closed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(recorder)
+			lintMarkup(tt.html, r.warn)
+			assert.Equal(t, tt.warnings, r.warnings)
+		})
+	}
+}
+
+func TestLintURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		kind    string
+		schemes map[string]bool
+		warning string
+	}{
+		{raw: "https://example.com/a?b=c", kind: "link", schemes: linkSchemes},
+		{raw: " mailto:jane@example.com ", kind: "link", schemes: linkSchemes},
+		{raw: "HTTPS://example.com", kind: "link", schemes: linkSchemes},
+		{raw: "{{unsubscribe_url}}", kind: "link", schemes: linkSchemes},
+		{raw: "https://example.com/{{id}}?u={{name | lower}}", kind: "link", schemes: linkSchemes},
+		{raw: "data:image/png;base64,AAAA", kind: "image", schemes: imageSchemes},
+		{raw: "", kind: "link", schemes: linkSchemes, warning: "invalid_url: the link has an empty url"},
+		{raw: "example.com", kind: "link", schemes: linkSchemes, warning: "relative_url: the link url example.com is relative, the urls must be absolute"},
+		{raw: "data:text/html,x", kind: "link", schemes: linkSchemes, warning: "invalid_url: the link url data:text/html,x has an unsupported scheme"},
+		{raw: "tel:123", kind: "image", schemes: imageSchemes, warning: "invalid_url: the image url tel:123 has an unsupported scheme"},
+		{raw: "https:///path", kind: "link", schemes: linkSchemes, warning: "invalid_url: the link url https:///path has no host"},
+		{raw: "http://exa mple.com", kind: "link", schemes: linkSchemes, warning: "invalid_url: the link url http://exa mple.com is invalid"},
+	}
+
+	for _, tt := range tests {
+		r := new(recorder)
+		lintURL(tt.raw, tt.kind, tt.schemes, r.warn)
+		if tt.warning == "" {
+			assert.Empty(t, r.warnings, tt.raw)
+		} else {
+			assert.Equal(t, []string{tt.warning}, r.warnings, tt.raw)
+		}
+	}
+}
+
+func TestLintSubject(t *testing.T) {
+	tests := []struct {
+		subject  string
+		warnings []string
+	}{
+		{subject: "Welcome to the newsletter"},
+		{subject: "Free shipping on your next order"},
+		{subject: "Cash flow tips for small businesses"},
+		{subject: "Hi {{NAME}}, the OK news"},
+		{subject: "NEWS"},
+		{subject: "WEEKLY NEWS", warnings: []string{"spam_subject: the subject is written in capital letters"}},
+		{subject: "Hurry!!", warnings: []string{"spam_subject: the subject contains the repeated punctuation !!"}},
+		{subject: "Win $$$", warnings: []string{"spam_subject: the subject contains the repeated punctuation $$$"}},
+		{subject: "Get 100% free access", warnings: []string{"spam_subject: the subject contains the phrase '100% free'"}},
+		{subject: "Claim your cash bonus", warnings: []string{"spam_subject: the subject contains the phrase 'cash bonus'"}},
+		{subject: "Risk-free trial, act now", warnings: []string{"spam_subject: the subject contains the phrase 'Risk-free'"}},
+		{
+			subject: "BUY NOW?!",
+			warnings: []string{
+				"spam_subject: the subject is written in capital letters",
+				"spam_subject: the subject contains the repeated punctuation ?!",
+				"spam_subject: the subject contains the phrase 'BUY NOW'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		r := new(recorder)
+		lintSubject(tt.subject, r.warn)
+		assert.Equal(t, tt.warnings, r.warnings, tt.subject)
+	}
+}
+
+func TestTagKeys(t *testing.T) {
+	partials := map[string]string{
+		"header": "<h1>{{title}}</h1>",
+		"item":   "<li>{{label}}</li>",
+		"nested": "{{> header}}{{#footer}}{{note}}{{/footer}}",
+	}
+
+	tests := []struct {
+		src  string
+		keys map[string]bool
+	}{
+		{
+			src:  `Hi {{name}}, {{city | default: "nowhere"}} {{{bio}}}`,
+			keys: map[string]bool{"name": true, "city": false, "bio": true},
+		},
+		{
+			src:  `{{#items}}{{title}}{{.}}{{#tags}}{{tag}}{{/tags}}{{/items}}`,
+			keys: map[string]bool{"items": true, "title": false, "tags": false, "tag": false},
+		},
+		{
+			src:  `{{^items}}{{empty}}{{/items}}{{^missing}}x{{/missing}}`,
+			keys: map[string]bool{"items": false, "empty": true, "missing": false},
+		},
+		{
+			src:  `{{#footer}}{{unsubscribe_url}}{{/footer}}`,
+			keys: map[string]bool{"footer": true, "unsubscribe_url": false},
+		},
+		{
+			src:  `{{#items}}{{> item}}{{/items}}{{> nested}}`,
+			keys: map[string]bool{"items": true, "label": false, "title": true, "footer": true, "note": false},
+		},
+		{
+			src:  `{{#items}}{{> header}}{{/items}}{{> header}}`,
+			keys: map[string]bool{"items": true, "title": true},
+		},
+		{
+			src:  `{{user.city}}`,
+			keys: map[string]bool{"user.city": true},
+		},
+	}
+
+	for _, tt := range tests {
+		hr := newHelperRewriter()
+		src, err := hr.rewrite(tt.src)
+		assert.Nil(t, err, tt.src)
+
+		tmpl, err := mustache.ParseStringPartials(src, &mustache.StaticProvider{Partials: partials})
+		assert.Nil(t, err, tt.src)
+
+		assert.Equal(t, tt.keys, tagKeys(tmpl.Tags(), partials, hr.helpers), tt.src)
+	}
+}
+
+// metadataStore counts the lookups of the metadata keys.
+type metadataStore struct {
+	storage.Storage
+	keys    []string
+	lookups int
+}
+
+func (s *metadataStore) GetSubscriberMetadataKeys(userID int64) ([]string, error) {
+	s.lookups++
+	return s.keys, nil
+}
+
+func TestMetadataKeys(t *testing.T) {
+	db := &metadataStore{keys: []string{"city"}}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	mk := newMetadataKeys(db)
+	mk.now = func() time.Time { return now }
+
+	keys, err := mk.get(1, []string{"city"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"city": true}, keys)
+	assert.Equal(t, 1, db.lookups)
+
+	// the cached keys are used while they include the wanted keys.
+	now = now.Add(10 * time.Minute)
+	_, err = mk.get(1, []string{"city"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, db.lookups)
+
+	// the keys which aren't cached are looked up, at most once per reload interval.
+	db.keys = []string{"city", "country"}
+	keys, err = mk.get(1, []string{"country"})
+	assert.Nil(t, err)
+	assert.True(t, keys["country"])
+	assert.Equal(t, 2, db.lookups)
+
+	_, err = mk.get(1, []string{"unknown"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, db.lookups)
+
+	now = now.Add(metadataKeysReload)
+	_, err = mk.get(1, []string{"unknown"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, db.lookups)
+
+	// the keys are cached per user and expire.
+	_, err = mk.get(2, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, db.lookups)
+
+	now = now.Add(metadataKeysTTL)
+	_, err = mk.get(1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, db.lookups)
+	assert.Len(t, mk.entries, 1)
+}
@@ -0,0 +1,87 @@
+package templates
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mailbadger/app/storage"
+)
+
+const (
+	// metadataKeysTTL is the duration for which the metadata keys of the user's subscribers are cached.
+	metadataKeysTTL = time.Hour
+	// metadataKeysReload is the duration after which the cached keys are reloaded when a template
+	// includes a key which isn't cached, since the new keys are added by the imports and the forms.
+	metadataKeysReload = time.Minute
+)
+
+// metadataKeys caches the metadata keys of the subscribers per user, looking them up scans
+// the metadata of all of the user's subscribers so it's not done on every save of the templates.
+type metadataKeys struct {
+	db      storage.Storage
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[int64]metadataKeysEntry
+}
+
+type metadataKeysEntry struct {
+	keys     map[string]bool
+	loadedAt time.Time
+}
+
+func newMetadataKeys(db storage.Storage) *metadataKeys {
+	return &metadataKeys{
+		db:      db,
+		now:     time.Now,
+		entries: make(map[int64]metadataKeysEntry),
+	}
+}
+
+// get returns the metadata keys of the user's subscribers, the cached keys are reloaded when
+// they expire or when they don't include all of the wanted keys and they weren't just loaded.
+func (mk *metadataKeys) get(userID int64, wanted []string) (map[string]bool, error) {
+	now := mk.now()
+
+	mk.mu.Lock()
+	e, ok := mk.entries[userID]
+	mk.mu.Unlock()
+
+	if ok && now.Sub(e.loadedAt) < metadataKeysTTL {
+		if now.Sub(e.loadedAt) < metadataKeysReload || includesAll(e.keys, wanted) {
+			return e.keys, nil
+		}
+	}
+
+	// the keys are loaded without the lock, so the saves of the other users don't wait for them.
+	list, err := mk.db.GetSubscriberMetadataKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(list))
+	for _, k := range list {
+		keys[k] = true
+	}
+
+	mk.mu.Lock()
+	defer mk.mu.Unlock()
+
+	for id, e := range mk.entries {
+		if now.Sub(e.loadedAt) >= metadataKeysTTL {
+			delete(mk.entries, id)
+		}
+	}
+	mk.entries[userID] = metadataKeysEntry{keys: keys, loadedAt: now}
+
+	return keys, nil
+}
+
+// includesAll reports whether all of the wanted keys are in the set.
+func includesAll(keys map[string]bool, wanted []string) bool {
+	for _, k := range wanted {
+		if !keys[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,287 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+
+	"github.com/mailbadger/app/entities"
+)
+
+var (
+	ErrParsePartial    = errors.New("failed to parse the partial")
+	ErrPartialNotFound = errors.New("partial not found")
+	ErrPartialCycle    = errors.New("the partial includes itself")
+	ErrLayoutNotFound  = errors.New("layout not found")
+	ErrLayoutContent   = errors.New("the layout must include the content with {{> content}}")
+)
+
+// helperTagRegexp matches the tags with helpers, e.g. {{name | upper}} or {{{name | default: "friend"}}}.
+// The helpers are supported only with the default delimiters.
+var helperTagRegexp = regexp.MustCompile(`\{\{(\{|&)?([^{}]*\|[^{}]*?)(\})?\}\}`)
+
+// helperRewriter replaces the helper tags of the sources with plain variable tags whose
+// keys hold the values of the helpers, since mustache looks up the data by the tag names.
+type helperRewriter struct {
+	helpers entities.TemplateHelpers
+	keys    map[string]string
+}
+
+func newHelperRewriter() *helperRewriter {
+	return &helperRewriter{
+		helpers: make(entities.TemplateHelpers),
+		keys:    make(map[string]string),
+	}
+}
+
+func (hr *helperRewriter) rewrite(src string) (string, error) {
+	var err error
+	res := helperTagRegexp.ReplaceAllStringFunc(src, func(tag string) string {
+		m := helperTagRegexp.FindStringSubmatch(tag)
+		prefix, name := m[1], strings.TrimSpace(m[2])
+		if strings.HasPrefix(name, "&") {
+			prefix, name = "&", strings.TrimSpace(name[1:])
+		}
+		// the sections, partials and comments are left as they are.
+		if name != "" && strings.ContainsAny(name[:1], "#^/>!=<{") {
+			return tag
+		}
+
+		key, ok := hr.keys[name]
+		if !ok {
+			expr, perr := entities.ParseTagExpr(name)
+			if perr != nil {
+				if err == nil {
+					err = perr
+				}
+				return tag
+			}
+			// the keys of the data can't contain colons, so the helper keys never clash with them.
+			key = fmt.Sprintf("helper:%d", len(hr.keys))
+			hr.keys[name] = key
+			hr.helpers[key] = expr
+		}
+
+		return "{{" + prefix + key + m[3] + "}}"
+	})
+
+	return res, err
+}
+
+// parseTemplate parses the parts of the template along with its partials, which are returned with their helper
+// tags rewritten. The html part is wrapped by the layout of the template, which includes it as the content partial.
+// The versions are parsed with the partials and the layout which they were saved with, the other templates with the
+// current partials of the user, whose content is recorded on the template so it's stored with its next version.
+func (s *service) parseTemplate(template *entities.Template) (*entities.CampaignTemplateData, map[string]string, error) {
+	var (
+		partials map[string]string
+		layout   string
+		live     = template.Partials == nil
+	)
+	if live {
+		all, err := s.db.GetAllPartials(template.UserID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get partials: %w", err)
+		}
+
+		partials = make(map[string]string, len(all)+1)
+		for _, p := range all {
+			if p.Kind == entities.PartialKindLayout {
+				if p.ID == template.LayoutID {
+					layout = p.Content
+				}
+				continue
+			}
+			partials[p.Name] = p.Content
+		}
+	} else {
+		partials = make(map[string]string, len(template.Partials)+1)
+		for name, content := range template.Partials {
+			partials[name] = content
+		}
+		layout = template.Layout
+	}
+
+	html := template.HTMLPart
+	if template.LayoutID != 0 {
+		if layout == "" {
+			return nil, nil, ErrLayoutNotFound
+		}
+		partials[entities.PartialContent] = template.HTMLPart
+		html = layout
+	}
+
+	// the sources of the partials are kept for the snapshot, since they are rewritten in place.
+	sources := make(map[string]string, len(partials))
+	for name, content := range partials {
+		sources[name] = content
+	}
+
+	var err error
+	hr := newHelperRewriter()
+	for name, content := range partials {
+		partials[name], err = hr.rewrite(content)
+		if err != nil {
+			if name == entities.PartialContent {
+				return nil, nil, fmt.Errorf("%w: %s", ErrParseHTMLPart, err)
+			}
+			return nil, nil, fmt.Errorf("%w %s: %s", ErrParsePartial, name, err)
+		}
+	}
+	if template.LayoutID != 0 {
+		_, err = mustache.ParseString(partials[entities.PartialContent])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrParseHTMLPart, err)
+		}
+	}
+	provider := &mustache.StaticProvider{Partials: partials}
+
+	used := make(map[string]bool)
+	data := &entities.CampaignTemplateData{
+		Template: template,
+		Helpers:  hr.helpers,
+	}
+	parts := []struct {
+		res      **mustache.Template
+		src      string
+		errParse error
+	}{
+		{&data.HTMLPart, html, ErrParseHTMLPart},
+		{&data.TextPart, template.TextPart, ErrParseTextPart},
+		{&data.SubjectPart, template.SubjectPart, ErrParseSubjectPart},
+	}
+	for _, p := range parts {
+		src, err := hr.rewrite(p.src)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", p.errParse, err)
+		}
+
+		*p.res, err = mustache.ParseStringPartials(src, provider)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", p.errParse, err)
+		}
+
+		err = checkPartials((*p.res).Tags(), partials, nil, used)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if live {
+		template.Partials = make(entities.StringMap, len(used))
+		for name := range used {
+			if name != entities.PartialContent {
+				template.Partials[name] = sources[name]
+			}
+		}
+		template.Layout = layout
+	}
+
+	return data, partials, nil
+}
+
+// ValidatePartial checks the content of the partial and the partials which it includes. The
+// layouts must include the html part of the templates with the content partial.
+func (s *service) ValidatePartial(c context.Context, partial *entities.Partial) error {
+	if partial.Kind == entities.PartialKindPartial && partial.Name == entities.PartialContent {
+		return fmt.Errorf("%w: the name %s is reserved", ErrParsePartial, entities.PartialContent)
+	}
+
+	_, err := newHelperRewriter().rewrite(partial.Content)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrParsePartial, err)
+	}
+
+	tmpl, err := mustache.ParseString(partial.Content)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrParsePartial, err)
+	}
+
+	all, err := s.db.GetAllPartials(partial.UserID)
+	if err != nil {
+		return fmt.Errorf("get partials: %w", err)
+	}
+
+	partials := make(map[string]string, len(all)+1)
+	for _, p := range all {
+		if p.Kind == entities.PartialKindPartial && p.ID != partial.ID {
+			partials[p.Name] = p.Content
+		}
+	}
+
+	var path []string
+	if partial.Kind == entities.PartialKindLayout {
+		if !includes(tmpl.Tags(), entities.PartialContent) {
+			return ErrLayoutContent
+		}
+		partials[entities.PartialContent] = ""
+	} else {
+		partials[partial.Name] = partial.Content
+		path = []string{partial.Name}
+	}
+
+	return checkPartials(tmpl.Tags(), partials, path, nil)
+}
+
+// checkPartials checks that the partials which the tags include exist and don't include themselves,
+// the path holds the names of the partials which include the tags. The names of the included partials
+// are added to used, unless it's nil.
+func checkPartials(tags []mustache.Tag, partials map[string]string, path []string, used map[string]bool) error {
+	for _, tag := range tags {
+		switch tag.Type() {
+		case mustache.Section, mustache.InvertedSection:
+			err := checkPartials(tag.Tags(), partials, path, used)
+			if err != nil {
+				return err
+			}
+		case mustache.Partial:
+			name := tag.Name()
+			for _, p := range path {
+				if p == name {
+					return fmt.Errorf("%w: %s", ErrPartialCycle, strings.Join(append(path, name), " > "))
+				}
+			}
+
+			content, ok := partials[name]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrPartialNotFound, name)
+			}
+			if used != nil {
+				used[name] = true
+			}
+
+			tmpl, err := mustache.ParseString(content)
+			if err != nil {
+				return fmt.Errorf("%w %s: %s", ErrParsePartial, name, err)
+			}
+
+			err = checkPartials(tmpl.Tags(), partials, append(path[:len(path):len(path)], name), used)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// includes reports whether the tags include the partial.
+func includes(tags []mustache.Tag, name string) bool {
+	for _, tag := range tags {
+		switch tag.Type() {
+		case mustache.Section, mustache.InvertedSection:
+			if includes(tag.Tags(), name) {
+				return true
+			}
+		case mustache.Partial:
+			if tag.Name() == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
-	"github.com/cbroglie/mustache"
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
 
 	"github.com/mailbadger/app/config"
 	"github.com/mailbadger/app/entities"
@@ -25,22 +27,35 @@ var (
 	ErrParseHTMLPart    = errors.New("failed to parse HTMLPart")
 	ErrParseTextPart    = errors.New("failed to parse TextPart")
 	ErrParseSubjectPart = errors.New("failed to parse SubjectPart")
+
+	ErrVersionConflict = errors.New("the template has been modified by another save")
+	ErrLintWarnings    = errors.New("the template has lint warnings")
 )
 
 type Service interface {
-	AddTemplate(c context.Context, input *entities.Template) error
-	UpdateTemplate(c context.Context, input *entities.Template) error
+	AddTemplate(c context.Context, input *entities.Template, strict bool) error
+	UpdateTemplate(c context.Context, input *entities.Template, strict bool) error
 	GetTemplates(c context.Context, userID int64, p *storage.PaginationCursor, scopeMap map[string]string) error
 	DeleteTemplate(c context.Context, templateID, userID int64) error
 	GetTemplate(c context.Context, templateID int64, userID int64) (*entities.Template, error)
 	ParseTemplate(c context.Context, templateID int64, userID int64) (*entities.CampaignTemplateData, error)
+	GetTemplateVersion(c context.Context, templateID, version, userID int64) (*entities.Template, error)
+	ParseTemplateVersion(c context.Context, templateID, version, userID int64) (*entities.CampaignTemplateData, error)
+	GetTemplateVersions(c context.Context, templateID, userID int64, p *storage.PaginationCursor) error
+	DiffTemplateVersions(c context.Context, templateID, from, to, userID int64) (*entities.TemplateDiff, error)
+	RestoreTemplateVersion(c context.Context, templateID, version, userID int64) (*entities.Template, error)
+	ValidatePartial(c context.Context, partial *entities.Partial) error
 }
 
+// deleteObjectsLimit is the max number of keys which can be deleted by a single request.
+const deleteObjectsLimit = 1000
+
 // service implements the Service interface
 type service struct {
 	db              storage.Storage
 	s3              s3iface.S3API
 	templatesBucket string
+	metadataKeys    *metadataKeys
 }
 
 func From(db storage.Storage, s3 s3iface.S3API, conf config.Config) Service {
@@ -52,76 +67,132 @@ func New(db storage.Storage, s3 s3iface.S3API, bucket string) Service {
 		db:              db,
 		s3:              s3,
 		templatesBucket: bucket,
+		metadataKeys:    newMetadataKeys(db),
 	}
 }
 
-func (s service) AddTemplate(c context.Context, template *entities.Template) error {
-	// parse string to validate template params
-	_, err := mustache.ParseString(template.HTMLPart)
-	if err != nil {
-		return ErrParseHTMLPart
-	}
-	// parse string to validate template params
-	_, err = mustache.ParseString(template.TextPart)
+// AddTemplate creates the template, the warnings of the linter are set to the template. When strict
+// is set, the template is not created if the linter reports any warnings.
+func (s service) AddTemplate(c context.Context, template *entities.Template, strict bool) error {
+	err := s.check(template, strict)
 	if err != nil {
-		return ErrParseTextPart
-	}
-	// parse string to validate template params
-	_, err = mustache.ParseString(template.SubjectPart)
-	if err != nil {
-		return ErrParseSubjectPart
+		return err
 	}
 
+	template.Version = 1
 	err = s.db.CreateTemplate(template)
 	if err != nil {
 		return fmt.Errorf("create template: %w", err)
 	}
 
-	s3Input := &s3.PutObjectInput{
-		Bucket: aws.String(s.templatesBucket),
-		Key:    aws.String(templateKey(template.UserID, template.ID)),
-		Body:   bytes.NewReader([]byte(template.HTMLPart)),
+	err = s.putHTMLPart(template)
+	if err != nil {
+		return err
 	}
 
-	_, err = s.s3.PutObject(s3Input)
+	return nil
+}
+
+// UpdateTemplate saves the template as a new version, the warnings of the linter are set to the
+// template. When strict is set, the template is not updated if the linter reports any warnings.
+func (s service) UpdateTemplate(c context.Context, template *entities.Template, strict bool) error {
+	err := s.check(template, strict)
 	if err != nil {
-		return fmt.Errorf("upload template: put s3 object: %w", err)
+		return err
+	}
+
+	// the html part is uploaded before the new version is committed, the template is locked
+	// by the transaction so the html part of an existing version is never overwritten.
+	template.Version++
+	saved, err := s.db.SaveTemplateVersion(template, func() error {
+		return s.putHTMLPart(template)
+	})
+	if err != nil {
+		template.Version--
+		return fmt.Errorf("save template version: %w", err)
+	}
+	if !saved {
+		template.Version--
+		return ErrVersionConflict
 	}
 
 	return nil
 }
 
-func (s service) UpdateTemplate(c context.Context, template *entities.Template) error {
-	// parse string to validate template params
-	_, err := mustache.ParseString(template.HTMLPart)
+// check processes the template and parses it to validate its params, partials and layout,
+// then lints it. The lint warnings are returned as an error only when strict is set. The
+// template is parsed with the current partials, which are stored with its new version.
+func (s service) check(template *entities.Template, strict bool) error {
+	err := process(template)
 	if err != nil {
-		return ErrParseHTMLPart
+		return err
 	}
-	// parse string to validate template params
-	_, err = mustache.ParseString(template.TextPart)
+
+	template.Partials, template.Layout = nil, ""
+
+	data, partials, err := s.parseTemplate(template)
 	if err != nil {
-		return ErrParseTextPart
+		return err
 	}
-	// parse string to validate template params
-	_, err = mustache.ParseString(template.SubjectPart)
+
+	template.Warnings, err = s.lint(template, data, partials)
 	if err != nil {
-		return ErrParseSubjectPart
+		return err
+	}
+	if strict && len(template.Warnings) > 0 {
+		return ErrLintWarnings
 	}
 
-	s3Input := &s3.PutObjectInput{
-		Bucket: aws.String(s.templatesBucket),
-		Key:    aws.String(templateKey(template.UserID, template.ID)),
-		Body:   bytes.NewReader([]byte(template.HTMLPart)),
+	return nil
+}
+
+// process generates the parts of the template which are sent from its sources. The styles of the html source are
+// inlined when the template inlines the css, and the text part is generated from the html source when its source is empty.
+func process(template *entities.Template) error {
+	if template.HTMLSource == "" {
+		template.HTMLSource = template.HTMLPart
+	}
+
+	template.HTMLPart = template.HTMLSource
+	if template.InlineCSS {
+		html, err := inlineCSS(template.HTMLSource)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrParseHTMLPart, err)
+		}
+		template.HTMLPart = html
+	}
+
+	template.TextPart = template.TextSource
+	if strings.TrimSpace(template.TextSource) == "" {
+		template.TextPart = htmlToText(template.HTMLSource)
 	}
 
-	_, err = s.s3.PutObject(s3Input)
+	return nil
+}
+
+// putHTMLPart uploads the html part of the current version of the template, the html
+// source is uploaded separately only when it differs from the html part.
+func (s service) putHTMLPart(template *entities.Template) error {
+	_, err := s.s3.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.templatesBucket),
+		Key:    aws.String(templateKey(template.UserID, template.ID, template.Version)),
+		Body:   bytes.NewReader([]byte(template.HTMLPart)),
+	})
 	if err != nil {
 		return fmt.Errorf("upload template: put s3 object: %w", err)
 	}
 
-	err = s.db.UpdateTemplate(template)
+	if !template.InlineCSS {
+		return nil
+	}
+
+	_, err = s.s3.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.templatesBucket),
+		Key:    aws.String(sourceKey(template.UserID, template.ID, template.Version)),
+		Body:   bytes.NewReader([]byte(template.HTMLSource)),
+	})
 	if err != nil {
-		return fmt.Errorf("update template: %w", err)
+		return fmt.Errorf("upload template source: put s3 object: %w", err)
 	}
 
 	return nil
@@ -133,14 +204,44 @@ func (s service) GetTemplates(c context.Context, userID int64, p *storage.Pagina
 	return s.db.GetTemplates(userID, p, scopeMap)
 }
 
-// DeleteTemplate deletes the given template
+// DeleteTemplate deletes the given template along with the html parts and sources of all of its versions
 func (s *service) DeleteTemplate(c context.Context, templateID, userID int64) error {
-	_, err := s.s3.DeleteObject(&s3.DeleteObjectInput{
-		Bucket: aws.String(s.templatesBucket),
-		Key:    aws.String(templateKey(userID, templateID)),
-	})
+	template, err := s.db.GetTemplate(templateID, userID)
 	if err != nil {
-		return fmt.Errorf("delete object: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("get template: %w", err)
+	}
+
+	// the versions are numbered sequentially, the keys of the missing versions and sources are ignored by S3.
+	var keys []string
+	for v := int64(0); v <= template.Version; v++ {
+		keys = append(keys, templateKey(userID, templateID, v), sourceKey(userID, templateID, v))
+	}
+
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > deleteObjectsLimit {
+			n = deleteObjectsLimit
+		}
+
+		objects := make([]*s3.ObjectIdentifier, n)
+		for i, key := range keys[:n] {
+			objects[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+		}
+		keys = keys[n:]
+
+		_, err = s.s3.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(s.templatesBucket),
+			Delete: &s3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("delete objects: %w", err)
+		}
 	}
 
 	err = s.db.DeleteTemplate(templateID, userID)
@@ -158,22 +259,160 @@ func (s service) GetTemplate(c context.Context, templateID int64, userID int64)
 		return nil, fmt.Errorf("get template: %w", err)
 	}
 
+	err = s.getHTMLParts(template)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// GetTemplateVersion returns the given version of the template, the template of the
+// result holds the content of the version.
+func (s service) GetTemplateVersion(c context.Context, templateID, version, userID int64) (*entities.Template, error) {
+	v, err := s.db.GetTemplateVersion(templateID, version, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get template version: %w", err)
+	}
+
+	template := &entities.Template{
+		BaseTemplate: entities.BaseTemplate{
+			Model: entities.Model{
+				ID:        v.TemplateID,
+				CreatedAt: v.CreatedAt,
+				UpdatedAt: v.UpdatedAt,
+			},
+			UserID:      v.UserID,
+			Name:        v.Name,
+			SubjectPart: v.SubjectPart,
+			Version:     v.Version,
+			LayoutID:    v.LayoutID,
+			InlineCSS:   v.InlineCSS,
+		},
+		TextPart:   v.TextPart,
+		TextSource: v.TextSource,
+		Partials:   v.Partials,
+		Layout:     v.Layout,
+	}
+
+	err = s.getHTMLParts(template)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// GetTemplateVersions populates a pagination object with the versions of the template.
+func (s service) GetTemplateVersions(c context.Context, templateID, userID int64, p *storage.PaginationCursor) error {
+	_, err := s.db.GetTemplate(templateID, userID)
+	if err != nil {
+		return fmt.Errorf("get template: %w", err)
+	}
+
+	return s.db.GetTemplateVersions(templateID, userID, p)
+}
+
+// DiffTemplateVersions returns the unified diffs of the template parts between the two versions.
+func (s service) DiffTemplateVersions(c context.Context, templateID, from, to, userID int64) (*entities.TemplateDiff, error) {
+	a, err := s.GetTemplateVersion(c, templateID, from, userID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.GetTemplateVersion(c, templateID, to, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &entities.TemplateDiff{From: from, To: to}
+	parts := []struct {
+		res  *string
+		a, b string
+	}{
+		{&diff.Name, a.Name, b.Name},
+		{&diff.SubjectPart, a.SubjectPart, b.SubjectPart},
+		{&diff.HTMLPart, a.HTMLPart, b.HTMLPart},
+		{&diff.TextPart, a.TextPart, b.TextPart},
+	}
+	for _, p := range parts {
+		*p.res, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(p.a),
+			B:        difflib.SplitLines(p.b),
+			FromFile: fmt.Sprintf("v%d", from),
+			ToFile:   fmt.Sprintf("v%d", to),
+			Context:  3,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("diff versions: %w", err)
+		}
+	}
+
+	return diff, nil
+}
+
+// RestoreTemplateVersion saves the content of the given version as a new version of the
+// template. The name of the template is kept, since it might be taken by another template.
+func (s service) RestoreTemplateVersion(c context.Context, templateID, version, userID int64) (*entities.Template, error) {
+	template, err := s.db.GetTemplate(templateID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get template: %w", err)
+	}
+
+	v, err := s.GetTemplateVersion(c, templateID, version, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	template.SubjectPart = v.SubjectPart
+	template.HTMLSource = v.HTMLSource
+	template.TextSource = v.TextSource
+	template.LayoutID = v.LayoutID
+	template.InlineCSS = v.InlineCSS
+
+	err = s.UpdateTemplate(c, template, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// getHTMLParts fetches the html part and the html source of the template version from S3.
+func (s service) getHTMLParts(template *entities.Template) (err error) {
+	template.HTMLPart, err = s.getHTMLPart(templateKey(template.UserID, template.ID, template.Version))
+	if err != nil {
+		return err
+	}
+
+	template.HTMLSource = template.HTMLPart
+	if template.InlineCSS {
+		template.HTMLSource, err = s.getHTMLPart(sourceKey(template.UserID, template.ID, template.Version))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getHTMLPart fetches the html part with the given key from S3.
+func (s service) getHTMLPart(key string) (html string, err error) {
 	resp, err := s.s3.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(s.templatesBucket),
-		Key:    aws.String(templateKey(template.UserID, template.ID)),
+		Key:    aws.String(key),
 	})
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
 			case s3.ErrCodeNoSuchKey:
-				return nil, ErrHTMLPartNotFound
+				return "", ErrHTMLPartNotFound
 			case s3.ErrCodeInvalidObjectState:
-				return nil, ErrHTMLPartInvalidState
+				return "", ErrHTMLPartInvalidState
 			default:
-				return nil, fmt.Errorf("get object: %w", aerr)
+				return "", fmt.Errorf("get object: %w", aerr)
 			}
 		}
-		return nil, fmt.Errorf("get object: %w", err)
+		return "", fmt.Errorf("get object: %w", err)
 	}
 
 	defer func() {
@@ -184,12 +423,10 @@ func (s service) GetTemplate(c context.Context, templateID int64, userID int64)
 
 	htmlBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read: %w", err)
+		return "", fmt.Errorf("read: %w", err)
 	}
 
-	template.HTMLPart = string(htmlBytes)
-
-	return
+	return string(htmlBytes), nil
 }
 
 func (s *service) ParseTemplate(c context.Context, templateID int64, userID int64) (*entities.CampaignTemplateData, error) {
@@ -198,27 +435,32 @@ func (s *service) ParseTemplate(c context.Context, templateID int64, userID int6
 		return nil, fmt.Errorf("campaign service: get template: %w", err)
 	}
 
-	html, err := mustache.ParseString(template.HTMLPart)
-	if err != nil {
-		return nil, fmt.Errorf("campaign service: parse html part: %w", err)
-	}
-	text, err := mustache.ParseString(template.TextPart)
+	data, _, err := s.parseTemplate(template)
+	return data, err
+}
+
+// ParseTemplateVersion parses the given version of the template, the campaigns are
+// sent with the version which was pinned when they were started.
+func (s *service) ParseTemplateVersion(c context.Context, templateID, version, userID int64) (*entities.CampaignTemplateData, error) {
+	template, err := s.GetTemplateVersion(c, templateID, version, userID)
 	if err != nil {
-		return nil, fmt.Errorf("campaign service: parse text part: %w", err)
+		return nil, fmt.Errorf("campaign service: get template version: %w", err)
 	}
-	sub, err := mustache.ParseString(template.SubjectPart)
-	if err != nil {
-		return nil, fmt.Errorf("campaign service: parse subject part: %w", err)
+
+	data, _, err := s.parseTemplate(template)
+	return data, err
+}
+
+// templateKey generates the key of the template version, the templates which were
+// created before the versioning are kept under the unversioned key as version 0.
+func templateKey(userID, templateID, version int64) string {
+	if version == 0 {
+		return fmt.Sprintf("templates/%d/%d", userID, templateID)
 	}
-	return &entities.CampaignTemplateData{
-		Template:    template,
-		HTMLPart:    html,
-		SubjectPart: sub,
-		TextPart:    text,
-	}, nil
+	return fmt.Sprintf("templates/%d/%d/v%d", userID, templateID, version)
 }
 
-// templateKey generates template key
-func templateKey(userID, templateID int64) string {
-	return fmt.Sprintf("templates/%d/%d", userID, templateID)
+// sourceKey generates the key of the html source of the template version.
+func sourceKey(userID, templateID, version int64) string {
+	return templateKey(userID, templateID, version) + "/source"
 }
@@ -0,0 +1,278 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	partialTagRegexp = regexp.MustCompile(`\{\{>\s*[^{}]*\}\}`)
+	blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+// skippedElements are the elements whose content is not a part of the text.
+var skippedElements = map[string]bool{
+	"head": true, "title": true, "style": true, "script": true,
+}
+
+// textList is an open list of the document, n is the number of its items.
+type textList struct {
+	ordered bool
+	n       int
+}
+
+// textLink is an open link of the document, start is the position of its text.
+type textLink struct {
+	href  string
+	start int
+}
+
+// textWriter writes the text of the html part, the newlines holds the number of the trailing newlines.
+type textWriter struct {
+	buf      strings.Builder
+	newlines int
+	space    bool
+
+	skip     int
+	pre      int
+	lists    []textList
+	link     *textLink
+	heading  int
+	links    []string
+	linkRefs map[string]int
+}
+
+func (w *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+
+	w.buf.WriteString(s)
+	trimmed := strings.TrimRight(s, "\n")
+	if trimmed == "" {
+		w.newlines += len(s)
+	} else {
+		w.newlines = len(s) - len(trimmed)
+	}
+	w.space = unicode.IsSpace(rune(s[len(s)-1]))
+}
+
+// block ends the current line and separates the next block with the given number of newlines.
+func (w *textWriter) block(n int) {
+	if w.buf.Len() == 0 || w.newlines >= n {
+		return
+	}
+	w.write(strings.Repeat("\n", n-w.newlines))
+}
+
+// text writes the text collapsing its whitespace, unless it's preformatted.
+func (w *textWriter) text(s string) {
+	if w.pre > 0 {
+		w.write(s)
+		return
+	}
+	if s == "" {
+		return
+	}
+
+	first, _ := utf8.DecodeRuneInString(s)
+	last, _ := utf8.DecodeLastRuneInString(s)
+	s = strings.Join(strings.Fields(s), " ")
+	if unicode.IsSpace(first) && w.buf.Len() > 0 && w.newlines == 0 && !w.space {
+		w.write(" ")
+	}
+	if s == "" {
+		return
+	}
+
+	w.write(s)
+	if unicode.IsSpace(last) {
+		w.write(" ")
+	}
+}
+
+// htmlToText generates a readable text part from the html part. The headings are underlined, the items of the
+// lists are indented with their markers, and the links are numbered and listed as footnotes after the text.
+func htmlToText(src string) string {
+	w := &textWriter{linkRefs: make(map[string]int)}
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if skippedElements[tok.Data] {
+				if tt == html.StartTagToken {
+					w.skip++
+				}
+				continue
+			}
+			if w.skip == 0 {
+				w.start(tok)
+			}
+		case html.EndTagToken:
+			if skippedElements[tok.Data] {
+				if w.skip > 0 {
+					w.skip--
+				}
+				continue
+			}
+			if w.skip == 0 {
+				w.end(tok)
+			}
+		case html.TextToken:
+			if w.skip == 0 {
+				w.text(partialTagRegexp.ReplaceAllString(tok.Data, ""))
+			}
+		}
+	}
+
+	lines := strings.Split(w.buf.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRightFunc(l, unicode.IsSpace)
+	}
+	text := strings.TrimSpace(blankLinesRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+
+	if len(w.links) > 0 {
+		var footnotes strings.Builder
+		footnotes.WriteString("\n\nLinks:\n")
+		for i, href := range w.links {
+			footnotes.WriteString(fmt.Sprintf("[%d] %s\n", i+1, href))
+		}
+		text += strings.TrimRight(footnotes.String(), "\n")
+	}
+
+	return text
+}
+
+func (w *textWriter) start(tok html.Token) {
+	switch tok.Data {
+	case "p", "table", "blockquote":
+		w.block(2)
+	case "div", "tr", "section", "article", "header", "footer", "center":
+		w.block(1)
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.block(2)
+		w.heading = w.buf.Len()
+	case "ul", "ol":
+		if len(w.lists) == 0 {
+			w.block(2)
+		}
+		w.lists = append(w.lists, textList{ordered: tok.Data == "ol"})
+	case "li":
+		w.block(1)
+		marker := "- "
+		if n := len(w.lists); n > 0 {
+			l := &w.lists[n-1]
+			l.n++
+			if l.ordered {
+				marker = fmt.Sprintf("%d. ", l.n)
+			}
+			marker = strings.Repeat("  ", n-1) + marker
+		}
+		w.write(marker)
+	case "td", "th":
+		if w.newlines == 0 && w.buf.Len() > 0 && !w.space {
+			w.write(" ")
+		}
+	case "pre":
+		w.block(2)
+		w.pre++
+	case "br":
+		w.write("\n")
+	case "hr":
+		w.block(2)
+		w.write(strings.Repeat("-", 40))
+		w.block(2)
+	case "img":
+		if alt, _ := attrValue(tok, "alt"); strings.TrimSpace(alt) != "" {
+			w.text(strings.TrimSpace(alt))
+		}
+	case "a":
+		href, _ := attrValue(tok, "href")
+		w.link = &textLink{href: strings.TrimSpace(href), start: w.buf.Len()}
+	}
+}
+
+func (w *textWriter) end(tok html.Token) {
+	switch tok.Data {
+	case "p", "table", "blockquote":
+		w.block(2)
+	case "div", "tr", "section", "article", "header", "footer", "center":
+		w.block(1)
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.TrimSpace(w.buf.String()[w.heading:])
+		if i := strings.LastIndex(text, "\n"); i >= 0 {
+			text = text[i+1:]
+		}
+		if text != "" {
+			underline := "-"
+			if tok.Data == "h1" {
+				underline = "="
+			}
+			w.block(1)
+			w.write(strings.Repeat(underline, utf8.RuneCountInString(text)))
+		}
+		w.block(2)
+	case "ul", "ol":
+		if len(w.lists) > 0 {
+			w.lists = w.lists[:len(w.lists)-1]
+		}
+		if len(w.lists) == 0 {
+			w.block(2)
+		} else {
+			w.block(1)
+		}
+	case "li":
+		w.block(1)
+	case "pre":
+		if w.pre > 0 {
+			w.pre--
+		}
+		w.block(2)
+	case "a":
+		if w.link == nil {
+			return
+		}
+		l := w.link
+		w.link = nil
+
+		text := strings.TrimSpace(w.buf.String()[l.start:])
+		if text == "" || l.href == "" || strings.HasPrefix(l.href, "#") ||
+			text == l.href || text == strings.TrimPrefix(l.href, "mailto:") {
+			return
+		}
+
+		n, ok := w.linkRefs[l.href]
+		if !ok {
+			w.links = append(w.links, l.href)
+			n = len(w.links)
+			w.linkRefs[l.href] = n
+		}
+		if w.space {
+			w.write(fmt.Sprintf("[%d]", n))
+		} else {
+			w.write(fmt.Sprintf(" [%d]", n))
+		}
+	}
+}
+
+// attrValue returns the value of the attribute of the token and whether it's set.
+func attrValue(tok html.Token, key string) (string, bool) {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		text string
+	}{
+		{
+			name: "paragraphs",
+			src:  "<p>Hello   {{name}},\n  welcome!</p><p>Second<br>line</p><div>a</div><div>b</div>",
+			text: "Hello {{name}}, welcome!\n\nSecond\nline\n\na\nb",
+		},
+		{
+			name: "skipped elements",
+			src:  `<html><head><title>Title</title><style>p { color: red }</style></head><body><script>alert("x")</script><p>Body</p><style>.a { margin: 0 }</style></body></html>`,
+			text: "Body",
+		},
+		{
+			name: "headings",
+			src:  `<h1>Weekly news</h1><h2>Ünïcode</h2><p>x</p>`,
+			text: "Weekly news\n===========\n\nÜnïcode\n-------\n\nx",
+		},
+		{
+			name: "lists",
+			src:  `<p>Items:</p><ul><li>one</li><li>two<ol><li>first</li><li>second<ul><li>deep</li></ul></li></ol></li><li>three</li></ul><p>after</p>`,
+			text: "Items:\n\n- one\n- two\n  1. first\n  2. second\n    - deep\n- three\n\nafter",
+		},
+		{
+			name: "links",
+			src:  `<p>Read <a href="https://example.com/a">the post</a> and <a href="https://example.com/b">this</a>, or <a href="https://example.com/a">again</a>.</p>`,
+			text: "Read the post [1] and this [2], or again [1].\n\nLinks:\n[1] https://example.com/a\n[2] https://example.com/b",
+		},
+		{
+			name: "links without footnotes",
+			src:  `<a href="#top">top</a> <a href="https://example.com">https://example.com</a> <a href="mailto:jane@example.com">jane@example.com</a> <a href="https://example.com/x"></a>`,
+			text: "top https://example.com jane@example.com",
+		},
+		{
+			name: "mustache links",
+			src:  `<p><a href="{{unsubscribe_url}}">Unsubscribe</a></p>`,
+			text: "Unsubscribe [1]\n\nLinks:\n[1] {{unsubscribe_url}}",
+		},
+		{
+			name: "tables and images",
+			src:  `<table><tr><td>Name</td><td>{{name}}</td></tr><tr><td><img src="logo.png" alt="Logo"></td><td><img src="x.png"></td></tr></table>`,
+			text: "Name {{name}}\nLogo",
+		},
+		{
+			name: "preformatted",
+			src:  "<p>code:</p><pre>  a  b\n    c</pre><hr><p>end</p>",
+			text: "code:\n\n  a  b\n    c\n\n----------------------------------------\n\nend",
+		},
+		{
+			name: "partials and entities",
+			src:  `<p>{{> header}}Tom &amp; Jerry &lt;3</p>`,
+			text: "Tom & Jerry <3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.text, htmlToText(tt.src))
+		})
+	}
+}
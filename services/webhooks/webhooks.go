@@ -0,0 +1,363 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/ksuid"
+
+	"github.com/mailbadger/app/entities"
+	"github.com/mailbadger/app/sqs"
+	"github.com/mailbadger/app/storage"
+)
+
+// Webhook request headers
+const (
+	HeaderEvent     = "X-Mailbadger-Event"
+	HeaderDelivery  = "X-Mailbadger-Delivery"
+	HeaderSignature = "X-Mailbadger-Signature"
+)
+
+// Delivery retry parameters, the delivery is retried with exponential backoff
+// starting from the base delay up to the max attempts.
+const (
+	MaxAttempts   = 10
+	baseDelay     = 30 * time.Second
+	maxDelay      = 12 * time.Hour
+	maxDrainBytes = 4096
+	timeout       = 10 * time.Second
+)
+
+var (
+	// ErrRetry is returned when the delivery failed and it should be attempted again.
+	ErrRetry = errors.New("webhooks: delivery failed, retrying")
+
+	// ErrAddressNotAllowed is returned when the webhook host resolves to a loopback, private,
+	// link-local, unspecified or any other address which is not publicly routable.
+	ErrAddressNotAllowed = errors.New("webhooks: address not allowed")
+)
+
+// Service dispatches the user's events to the subscribed webhooks and delivers them.
+type Service interface {
+	Dispatch(ctx context.Context, userID int64, eventType string, data interface{}) error
+	DispatchAll(ctx context.Context, userID int64, eventType string, data []interface{}) error
+	Redeliver(ctx context.Context, d *entities.WebhookDelivery) (*entities.WebhookDelivery, error)
+	Deliver(ctx context.Context, d *entities.WebhookDelivery) error
+}
+
+type service struct {
+	store     storage.Storage
+	publisher sqs.PublisherAPI
+	queueURL  sqs.WebhookQueueURL
+	client    *http.Client
+}
+
+// New returns a new webhooks service.
+func New(store storage.Storage, publisher sqs.PublisherAPI, queueURL sqs.WebhookQueueURL) Service {
+	return &service{
+		store:     store,
+		publisher: publisher,
+		queueURL:  queueURL,
+		client:    newClient(),
+	}
+}
+
+// newClient returns the http client used to deliver the webhooks. The addresses are checked
+// when connecting, after the host is resolved, so the webhooks can't reach the internal
+// network even when the host resolves to a different address on each lookup.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: controlAddress,
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// controlAddress rejects the connections to addresses which are not publicly routable.
+func controlAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrAddressNotAllowed, host)
+	}
+
+	return nil
+}
+
+// deniedNetworks are the special-purpose ranges which are not publicly routable
+// and are not covered by the checks of net.IP, e.g. the carrier-grade NAT range.
+var deniedNetworks = parseCIDRs(
+	"0.0.0.0/8",       // this network
+	"100.64.0.0/10",   // shared address space (carrier-grade NAT)
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation (TEST-NET-1)
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation (TEST-NET-2)
+	"203.0.113.0/24",  // documentation (TEST-NET-3)
+	"240.0.0.0/4",     // reserved, including the limited broadcast
+	"::/96",           // IPv4-compatible addresses
+	"64:ff9b:1::/48",  // local-use IPv4/IPv6 translation
+	"100::/64",        // discard-only
+	"2001::/23",       // IETF protocol assignments, including Teredo
+	"2001:db8::/32",   // documentation
+)
+
+// nat64Network is the well-known prefix of NAT64, the IPv4 address is embedded in the last 4 bytes.
+var nat64Network = parseCIDRs("64:ff9b::/96")[0]
+
+// sixToFourNetwork is the prefix of 6to4, the IPv4 address is embedded in the bytes 2 to 6.
+var sixToFourNetwork = parseCIDRs("2002::/16")[0]
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// isPublicIP reports whether the address is publicly routable. The IPv4 addresses which are embedded
+// in the IPv4-mapped, NAT64 and 6to4 addresses are checked as well, since they reach the same hosts.
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if nat64Network.Contains(ip) {
+		return isPublicIP(ip[12:16])
+	} else if sixToFourNetwork.Contains(ip) {
+		return isPublicIP(ip[2:6])
+	}
+
+	for _, n := range deniedNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// Dispatch creates a delivery of the event for each active webhook of the user which
+// is subscribed to the event type, the deliveries are sent asynchronously through the queue.
+func (s *service) Dispatch(ctx context.Context, userID int64, eventType string, data interface{}) error {
+	return s.DispatchAll(ctx, userID, eventType, []interface{}{data})
+}
+
+// DispatchAll dispatches an event of the type for each of the data, the webhooks
+// of the user are looked up once, e.g. for the subscribers of an import batch.
+func (s *service) DispatchAll(ctx context.Context, userID int64, eventType string, data []interface{}) error {
+	hooks, err := s.store.GetActiveWebhooks(userID)
+	if err != nil {
+		return fmt.Errorf("webhooks: get active webhooks: %w", err)
+	}
+
+	var subscribed []entities.Webhook
+	for _, w := range hooks {
+		if w.IsSubscribed(eventType) {
+			subscribed = append(subscribed, w)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	for _, d := range data {
+		event := entities.WebhookEvent{
+			ID:        ksuid.New(),
+			Type:      eventType,
+			CreatedAt: time.Now().UTC(),
+			Data:      d,
+		}
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("webhooks: marshal event: %w", err)
+		}
+
+		for _, w := range subscribed {
+			err = s.enqueue(ctx, &entities.WebhookDelivery{
+				UserID:    userID,
+				WebhookID: w.ID,
+				EventID:   event.ID,
+				EventType: eventType,
+				Payload:   payload,
+				Status:    entities.WebhookDeliveryStatusPending,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Redeliver creates a new delivery with the event of the given delivery and enqueues it.
+func (s *service) Redeliver(ctx context.Context, d *entities.WebhookDelivery) (*entities.WebhookDelivery, error) {
+	redelivery := &entities.WebhookDelivery{
+		UserID:    d.UserID,
+		WebhookID: d.WebhookID,
+		EventID:   d.EventID,
+		EventType: d.EventType,
+		Payload:   d.Payload,
+		Status:    entities.WebhookDeliveryStatusPending,
+	}
+
+	err := s.enqueue(ctx, redelivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return redelivery, nil
+}
+
+func (s *service) enqueue(ctx context.Context, d *entities.WebhookDelivery) error {
+	err := s.store.CreateWebhookDelivery(d)
+	if err != nil {
+		return fmt.Errorf("webhooks: create delivery: %w", err)
+	}
+
+	msg, err := json.Marshal(entities.WebhookTopicParams{
+		DeliveryID: d.ID,
+		UserID:     d.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("webhooks: marshal webhook params: %w", err)
+	}
+
+	err = s.publisher.SendMessage(ctx, s.queueURL, msg)
+	if err != nil {
+		return fmt.Errorf("webhooks: publish delivery: %w", err)
+	}
+
+	return nil
+}
+
+// Deliver posts the payload to the webhook endpoint and records the attempt. ErrRetry is
+// returned when the endpoint doesn't respond with 2xx and the attempts are not exhausted,
+// the delivery is failed when the webhook is disabled or removed.
+func (s *service) Deliver(ctx context.Context, d *entities.WebhookDelivery) error {
+	if d.Webhook == nil || !d.Webhook.Active {
+		d.Status = entities.WebhookDeliveryStatusFailed
+		d.ErrorMessage = "The webhook is disabled."
+		d.NextAttemptAt = entities.NullTime{}
+		if err := s.store.UpdateWebhookDelivery(d); err != nil {
+			return fmt.Errorf("webhooks: update delivery: %w", err)
+		}
+		return nil
+	}
+
+	d.Attempts++
+	d.ResponseStatus, d.ErrorMessage = s.post(ctx, d)
+
+	switch {
+	case d.ResponseStatus >= 200 && d.ResponseStatus < 300:
+		d.Status = entities.WebhookDeliveryStatusSucceeded
+		d.NextAttemptAt = entities.NullTime{}
+	case d.Attempts >= MaxAttempts:
+		d.Status = entities.WebhookDeliveryStatusFailed
+		d.NextAttemptAt = entities.NullTime{}
+	default:
+		d.Status = entities.WebhookDeliveryStatusPending
+		d.NextAttemptAt.SetValid(time.Now().UTC().Add(Backoff(d.Attempts)))
+	}
+
+	err := s.store.UpdateWebhookDelivery(d)
+	if err != nil {
+		return fmt.Errorf("webhooks: update delivery: %w", err)
+	}
+
+	if d.Status == entities.WebhookDeliveryStatusPending {
+		return ErrRetry
+	}
+
+	return nil
+}
+
+// post sends the signed payload and returns the response status, or the error message when
+// the request fails. The response body is discarded, so it can't be read through the deliveries.
+func (s *service) post(ctx context.Context, d *entities.WebhookDelivery) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Webhook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mailbadger-Webhooks")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(d.Webhook.Secret, time.Now(), d.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+
+	// the body is drained so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, maxDrainBytes))
+
+	return res.StatusCode, ""
+}
+
+// Sign returns the signature header value of the payload, which is the HMAC-SHA256 of
+// the timestamp and the payload joined with a dot: "t=<unix timestamp>,v1=<hex signature>".
+// The timestamp is included so the receivers can reject replayed requests.
+func Sign(secret string, t time.Time, payload []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the next delivery attempt.
+func Backoff(attempts int) time.Duration {
+	d := baseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxDelay {
+			return maxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,116 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestControlAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.1:80", false},
+		{"172.16.5.4:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"[::]:80", false},
+		{"224.0.0.1:80", false},
+		{"100.64.0.1:80", false},
+		{"100.127.255.254:80", false},
+		{"100.128.0.1:80", true},
+		{"192.0.0.170:80", false},
+		{"192.0.2.1:80", false},
+		{"198.18.0.1:80", false},
+		{"198.19.255.255:80", false},
+		{"198.20.0.1:80", true},
+		{"240.0.0.1:80", false},
+		{"255.255.255.255:80", false},
+		{"0.1.2.3:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[::ffff:10.0.0.1]:80", false},
+		{"[::ffff:100.64.0.1]:80", false},
+		{"[::ffff:93.184.216.34]:443", true},
+		{"[::10.0.0.1]:80", false},
+		{"[64:ff9b::a9fe:a9fe]:80", false},
+		{"[64:ff9b::c0a8:101]:80", false},
+		{"[64:ff9b::5db8:d822]:443", true},
+		{"[64:ff9b:1::5db8:d822]:443", false},
+		{"[2002:a00:1::1]:80", false},
+		{"[2002:5db8:d822::1]:443", true},
+		{"[2001:db8::1]:80", false},
+		{"[2001::1]:80", false},
+	}
+
+	for _, tt := range tests {
+		err := controlAddress("tcp", tt.address, nil)
+		assert.Equal(t, tt.allowed, err == nil, tt.address)
+		if !tt.allowed {
+			assert.True(t, errors.Is(err, ErrAddressNotAllowed), tt.address)
+		}
+	}
+}
+
+func TestClientRejectsInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	_, err := newClient().Get(srv.URL)
+	assert.True(t, errors.Is(err, ErrAddressNotAllowed))
+
+	// the host is resolved before the address is checked.
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	assert.Nil(t, err)
+
+	_, err = newClient().Get("http://localhost:" + port)
+	assert.True(t, errors.Is(err, ErrAddressNotAllowed))
+}
+
+func TestPostHeaders(t *testing.T) {
+	var headers []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := &service{client: srv.Client()}
+	event := ksuid.New()
+	for _, id := range []int64{1, 2} {
+		d := &entities.WebhookDelivery{
+			Model:     entities.Model{ID: id},
+			Webhook:   &entities.Webhook{URL: srv.URL, Secret: "secret"},
+			EventID:   event,
+			EventType: entities.WebhookEventSubscriberCreated,
+			Payload:   []byte(`{}`),
+		}
+		status, msg := s.post(context.Background(), d)
+		assert.Equal(t, http.StatusNoContent, status)
+		assert.Empty(t, msg)
+	}
+
+	// each delivery of the same event has its own id.
+	assert.Len(t, headers, 2)
+	assert.Equal(t, "1", headers[0].Get(HeaderDelivery))
+	assert.Equal(t, "2", headers[1].Get(HeaderDelivery))
+	assert.Equal(t, entities.WebhookEventSubscriberCreated, headers[0].Get(HeaderEvent))
+	assert.NotEmpty(t, headers[0].Get(HeaderSignature))
+}
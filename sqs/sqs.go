@@ -15,6 +15,12 @@ const (
 	CampaignerTopic = "SendCampaign"
 	// SenderTopic is the topic used by the sender consumer.
 	SenderTopic = "SendEmail"
+	// WebhookTopic is the topic used by the webhooks consumer.
+	WebhookTopic = "DeliverWebhook"
+	// EventsTopic is the topic used by the events consumer.
+	EventsTopic = "ProcessEvents"
+	// JobsTopic is the topic used by the jobs consumer.
+	JobsTopic = "RunJob"
 )
 
 // QueueURL is a pointer to a URL string, used by the SQS client.
@@ -26,6 +32,15 @@ type CampaignerQueueURL QueueURL
 // CampaignerQueueURL represents the queue url of the SendEmail queue.
 type SendEmailQueueURL QueueURL
 
+// WebhookQueueURL represents the queue url of the DeliverWebhook queue.
+type WebhookQueueURL QueueURL
+
+// EventsQueueURL represents the queue url of the ProcessEvents queue.
+type EventsQueueURL QueueURL
+
+// JobsQueueURL represents the queue url of the RunJob queue.
+type JobsQueueURL QueueURL
+
 // SendReceiveMessageAPI defines the interface for the GetQueueUrl function.
 // We use this interface to test the function using a mocked service.
 type SendReceiveMessageAPI interface {
@@ -115,6 +130,7 @@ func (c Consumer) PollSQS(ctx context.Context) <-chan types.Message {
 					},
 					AttributeNames: []types.QueueAttributeName{
 						types.QueueAttributeName(types.MessageSystemAttributeNameSentTimestamp),
+						types.QueueAttributeName(types.MessageSystemAttributeNameApproximateReceiveCount),
 					},
 					QueueUrl:            c.queueURL,
 					MaxNumberOfMessages: c.maxNumOfMessages,
@@ -198,3 +214,42 @@ func GetSendEmailQueueURL(ctx context.Context, api SendReceiveMessageAPI) (SendE
 	}
 	return urlResult.QueueUrl, nil
 }
+
+func GetWebhookQueueURL(ctx context.Context, api SendReceiveMessageAPI) (WebhookQueueURL, error) {
+	queueStr := WebhookTopic
+	gQInput := &sqs.GetQueueUrlInput{
+		QueueName: &queueStr,
+	}
+	// Get URL of queue
+	urlResult, err := api.GetQueueUrl(ctx, gQInput)
+	if err != nil {
+		return nil, err
+	}
+	return urlResult.QueueUrl, nil
+}
+
+func GetEventsQueueURL(ctx context.Context, api SendReceiveMessageAPI) (EventsQueueURL, error) {
+	queueStr := EventsTopic
+	gQInput := &sqs.GetQueueUrlInput{
+		QueueName: &queueStr,
+	}
+	// Get URL of queue
+	urlResult, err := api.GetQueueUrl(ctx, gQInput)
+	if err != nil {
+		return nil, err
+	}
+	return urlResult.QueueUrl, nil
+}
+
+func GetJobsQueueURL(ctx context.Context, api SendReceiveMessageAPI) (JobsQueueURL, error) {
+	queueStr := JobsTopic
+	gQInput := &sqs.GetQueueUrlInput{
+		QueueName: &queueStr,
+	}
+	// Get URL of queue
+	urlResult, err := api.GetQueueUrl(ctx, gQInput)
+	if err != nil {
+		return nil, err
+	}
+	return urlResult.QueueUrl, nil
+}
@@ -1,17 +1,26 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/mailbadger/app/entities"
 )
 
 // GetAPIKeys fetches api keys by user id.
 func (db *store) GetAPIKeys(userID int64) ([]*entities.APIKey, error) {
 	var keys []*entities.APIKey
-	err := db.Where("user_id = ?", userID).Find(&keys).Error
+	err := db.Where("user_id = ?", userID).Order("created_at desc, id desc").Find(&keys).Error
 	return keys, err
 }
 
-// GetAPIKey fetches access keys by the given secret.
+// GetAPIKeyByID fetches the api key by the given id and user id.
+func (db *store) GetAPIKeyByID(id, userID int64) (*entities.APIKey, error) {
+	var key = new(entities.APIKey)
+	err := db.Where("user_id = ? and id = ?", userID, id).First(key).Error
+	return key, err
+}
+
+// GetAPIKey fetches access keys by the given secret hash.
 func (db *store) GetAPIKey(identifier string) (*entities.APIKey, error) {
 	var key = new(entities.APIKey)
 	err := db.
@@ -33,6 +42,11 @@ func (db *store) UpdateAPIKey(ak *entities.APIKey) error {
 	return db.Where("id = ? and user_id = ?", ak.ID, ak.UserID).Save(ak).Error
 }
 
+// UpdateAPIKeyLastUsed sets the time when the api key was last used.
+func (db *store) UpdateAPIKeyLastUsed(id int64, t time.Time) error {
+	return db.Model(&entities.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", t).Error
+}
+
 // DeleteAccessKey deletes an existing api key from the database.
 func (db *store) DeleteAPIKey(id, userID int64) error {
 	return db.Where("user_id = ?", userID).Delete(entities.APIKey{ID: id}).Error
@@ -3,6 +3,7 @@ package storage
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/mailbadger/app/entities"
 	"github.com/stretchr/testify/assert"
@@ -12,8 +13,14 @@ import (
 func TestAPIKeys(t *testing.T) {
 	db := openTestDb()
 
+	// the sqlite driver hashes the existing keys in the migrations as MySQL does.
+	var hash string
+	err := db.Raw("SELECT sha2(?, 256)", "foobar").Scan(&hash).Error
+	assert.Nil(t, err)
+	assert.Equal(t, entities.HashAPIKey("foobar"), hash)
+
 	store := From(db)
-	_, err := store.GetAPIKey("foobar")
+	_, err = store.GetAPIKey("foobar")
 	assert.NotNil(t, err)
 
 	keys, err := store.GetAPIKeys(1)
@@ -24,6 +31,7 @@ func TestAPIKeys(t *testing.T) {
 		UserID:    1,
 		Active:    true,
 		SecretKey: "foobar",
+		Scopes:    entities.StringList{"subscribers:read", "subscribers:write"},
 	}
 
 	err = store.CreateAPIKey(k)
@@ -33,10 +41,21 @@ func TestAPIKeys(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, k.SecretKey, "foobar")
 	assert.True(t, k.Active)
+	assert.Equal(t, entities.StringList{"subscribers:read", "subscribers:write"}, k.Scopes)
 	assert.Equal(t, k.User.Username, "admin")
 	assert.NotNil(t, k.User.Boundaries)
 	assert.Equal(t, k.User.Boundaries.Type, entities.BoundaryTypeNoLimit)
 
+	_, err = store.GetAPIKeyByID(k.ID, 2)
+	assert.NotNil(t, err)
+
+	err = store.UpdateAPIKeyLastUsed(k.ID, time.Now())
+	assert.Nil(t, err)
+
+	k, err = store.GetAPIKeyByID(k.ID, 1)
+	assert.Nil(t, err)
+	assert.True(t, k.LastUsedAt.Valid)
+
 	k.Active = false
 	err = store.UpdateAPIKey(k)
 	assert.Nil(t, err)
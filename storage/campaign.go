@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/jinzhu/now"
@@ -47,10 +49,41 @@ func CurrentMonth(db *gorm.DB) *gorm.DB {
 // GetCampaign returns the campaign by the given id and user id
 func (db *store) GetCampaign(id, userID int64) (*entities.Campaign, error) {
 	var campaign = new(entities.Campaign)
-	err := db.Where("user_id = ? and id = ?", userID, id).Preload("BaseTemplate").Preload("Schedule").First(&campaign).Error
+	err := db.Where("user_id = ? and id = ?", userID, id).
+		Preload("BaseTemplate").
+		Preload("Schedule").
+		Preload("SplitTest").
+		Preload("SplitTest.Variants", orderByID).
+		Preload("SplitTest.Variants.BaseTemplate").
+		First(&campaign).Error
 	return campaign, err
 }
 
+// GetCampaignStatus returns the status of the campaign by the given id and user id
+func (db *store) GetCampaignStatus(id, userID int64) (string, error) {
+	var campaign = new(entities.Campaign)
+	err := db.Select("status").Where("user_id = ? and id = ?", userID, id).First(campaign).Error
+	return campaign.Status, err
+}
+
+// SwapCampaignStatus sets the status of the campaign only if it has the old status,
+// it reports whether the status was changed.
+func (db *store) SwapCampaignStatus(id, userID int64, old, new string) (bool, error) {
+	res := db.Model(&entities.Campaign{}).
+		Where("user_id = ? and id = ? and status = ?", userID, id, old).
+		Update("status", new)
+	return res.RowsAffected > 0, res.Error
+}
+
+// CompleteCampaign sets the final status and the completion time of the campaign only if it
+// has the old status, it reports whether the campaign was completed.
+func (db *store) CompleteCampaign(id, userID int64, old, new string, completedAt time.Time) (bool, error) {
+	res := db.Model(&entities.Campaign{}).
+		Where("user_id = ? and id = ? and status = ?", userID, id, old).
+		Updates(map[string]interface{}{"status": new, "completed_at": completedAt})
+	return res.RowsAffected > 0, res.Error
+}
+
 // GetCampaignByName returns the campaign by the given name and user id
 func (db *store) GetCampaignByName(name string, userID int64) (*entities.Campaign, error) {
 	var campaign = new(entities.Campaign)
@@ -68,6 +101,15 @@ func (db *store) UpdateCampaign(c *entities.Campaign) error {
 	return db.Where("id = ? and user_id = ?", c.ID, c.UserID).Save(c).Error
 }
 
+// UpdateCampaignSettings edits the settings of an existing campaign, its status is
+// changed only by the conditional updates since the campaigner changes it concurrently.
+func (db *store) UpdateCampaignSettings(c *entities.Campaign) error {
+	return db.Model(c).
+		Where("user_id = ?", c.UserID).
+		Select("name", "template_id", "track_opens", "track_clicks", "excluded_links", "updated_at").
+		Updates(c).Error
+}
+
 // DeleteCampaign deletes an existing campaign from the database.
 func (db *store) DeleteCampaign(id, userID int64) error {
 	return db.Where("user_id = ?", userID).Delete(entities.Campaign{Model: entities.Model{ID: id}}).Error
@@ -58,6 +58,31 @@ func TestCampaign(t *testing.T) {
 	assert.True(t, campaign.CompletedAt.Valid)
 	assert.Equal(t, campaign.CompletedAt.Time, now)
 
+	// the settings are updated without the status
+	swapped, err := store.SwapCampaignStatus(campaign.ID, 1, entities.StatusDraft, entities.StatusSending)
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+	campaign.TrackOpens = true
+	campaign.Status = entities.StatusDraft
+	err = store.UpdateCampaignSettings(campaign)
+	assert.Nil(t, err)
+	campaign, err = store.GetCampaign(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.True(t, campaign.TrackOpens)
+	assert.Equal(t, entities.StatusSending, campaign.Status)
+
+	// the campaign is completed only when it has the old status
+	completed, err := store.CompleteCampaign(campaign.ID, 1, entities.StatusPaused, entities.StatusCancelled, now)
+	assert.Nil(t, err)
+	assert.False(t, completed)
+	completed, err = store.CompleteCampaign(campaign.ID, 1, entities.StatusSending, entities.StatusSent, now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.True(t, completed)
+	campaign, err = store.GetCampaign(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.StatusSent, campaign.Status)
+	assert.Equal(t, now.Add(time.Hour), campaign.CompletedAt.Time)
+
 	//Test get campaigns
 	p := NewPaginationCursor("/api/campaigns", 13)
 	for i := 0; i < 10; i++ {
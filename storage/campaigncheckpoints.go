@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"github.com/mailbadger/app/entities"
+)
+
+// GetCampaignCheckpoint returns the checkpoint by the given campaign id and user id.
+func (db *store) GetCampaignCheckpoint(campaignID, userID int64) (*entities.CampaignCheckpoint, error) {
+	var c = new(entities.CampaignCheckpoint)
+	err := db.Where("user_id = ? and campaign_id = ?", userID, campaignID).First(c).Error
+	return c, err
+}
+
+// SaveCampaignCheckpoint creates or updates the checkpoint in the database.
+func (db *store) SaveCampaignCheckpoint(c *entities.CampaignCheckpoint) error {
+	return db.Save(c).Error
+}
+
+// ClaimPausedCampaignCheckpoint clears the time at which the campaigner stopped on the paused campaign,
+// it reports whether the checkpoint was claimed, only one of the concurrent callers can claim it.
+func (db *store) ClaimPausedCampaignCheckpoint(campaignID, userID int64) (bool, error) {
+	res := db.Model(&entities.CampaignCheckpoint{}).
+		Where("user_id = ? and campaign_id = ? and paused_at IS NOT NULL", userID, campaignID).
+		Update("paused_at", nil)
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteCampaignCheckpoint deletes the checkpoint by the given campaign id and user id.
+func (db *store) DeleteCampaignCheckpoint(campaignID, userID int64) error {
+	return db.Where("user_id = ? and campaign_id = ?", userID, campaignID).Delete(&entities.CampaignCheckpoint{}).Error
+}
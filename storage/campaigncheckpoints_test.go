@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestCampaignCheckpoint(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	campaign := &entities.Campaign{
+		Name:   "foo",
+		UserID: 1,
+		Status: entities.StatusSending,
+	}
+	err := store.CreateCampaign(campaign)
+	assert.Nil(t, err)
+
+	status, err := store.GetCampaignStatus(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.StatusSending, status)
+
+	_, err = store.GetCampaignStatus(campaign.ID, 2)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	_, err = store.GetCampaignCheckpoint(campaign.ID, 1)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	eventID := ksuid.New()
+	checkpoint := &entities.CampaignCheckpoint{
+		UserID:     1,
+		CampaignID: campaign.ID,
+		EventID:    eventID,
+	}
+	err = checkpoint.SetParams(entities.CampaignerTopicParams{
+		EventID:    eventID,
+		CampaignID: campaign.ID,
+		SegmentIDs: []int64{1, 2},
+		UserID:     1,
+		SesKeys: entities.SesKeys{
+			AccessKey: "abcd",
+			SecretKey: "efgh",
+		},
+	})
+	assert.Nil(t, err)
+
+	err = store.SaveCampaignCheckpoint(checkpoint)
+	assert.Nil(t, err)
+
+	now := time.Now().UTC()
+	checkpoint.LastSubscriberID = 1000
+	checkpoint.LastCreatedAt.SetValid(now)
+	err = store.SaveCampaignCheckpoint(checkpoint)
+	assert.Nil(t, err)
+
+	checkpoint, err = store.GetCampaignCheckpoint(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, eventID, checkpoint.EventID)
+	assert.Equal(t, int64(1000), checkpoint.LastSubscriberID)
+	assert.True(t, checkpoint.LastCreatedAt.Valid)
+	assert.Equal(t, now, checkpoint.LastCreatedAt.Time)
+
+	p, err := checkpoint.GetParams()
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2}, p.SegmentIDs)
+	assert.Empty(t, p.SesKeys.SecretKey)
+
+	claimed, err := store.ClaimPausedCampaignCheckpoint(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+
+	checkpoint.PausedAt.SetValid(now)
+	err = store.SaveCampaignCheckpoint(checkpoint)
+	assert.Nil(t, err)
+
+	claimed, err = store.ClaimPausedCampaignCheckpoint(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = store.ClaimPausedCampaignCheckpoint(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+
+	swapped, err := store.SwapCampaignStatus(campaign.ID, 1, entities.StatusPaused, entities.StatusSending)
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = store.SwapCampaignStatus(campaign.ID, 1, entities.StatusSending, entities.StatusPaused)
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+
+	status, err = store.GetCampaignStatus(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.StatusPaused, status)
+
+	err = store.DeleteCampaignCheckpoint(campaign.ID, 1)
+	assert.Nil(t, err)
+
+	_, err = store.GetCampaignCheckpoint(campaign.ID, 1)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
@@ -42,7 +42,7 @@ func openDbConn(driver, dsn string) *gorm.DB {
 	if driver == "mysql" {
 		dialect = mysql.Open(dsn)
 	} else {
-		dialect = sqlite.Open(dsn)
+		dialect = &sqlite.Dialector{DriverName: sqliteDriverName, DSN: dsn}
 	}
 
 	conf := &gorm.Config{}
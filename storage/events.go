@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// eventsBatchSize is the number of rows inserted with a single statement.
+const eventsBatchSize = 100
+
+// GetProcessedEventKeys returns the given keys of the events which are already stored.
+func (db *store) GetProcessedEventKeys(keys []string) ([]string, error) {
+	var processed []string
+	if len(keys) == 0 {
+		return processed, nil
+	}
+
+	err := db.Model(&entities.ProcessedEvent{}).Where("event_key IN (?)", keys).Pluck("event_key", &processed).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: get processed event keys: %w", err)
+	}
+	return processed, nil
+}
+
+// CreateEvents stores the events of the batch along with their keys in a single transaction.
+// The keys are unique, so the transaction fails when any of the events is already stored.
+func (db *store) CreateEvents(b *entities.EventsBatch) error {
+	if b.IsEmpty() {
+		return nil
+	}
+
+	tx := db.Begin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	records := []struct {
+		name  string
+		value interface{}
+		n     int
+	}{
+		{"processed events", &b.Processed, len(b.Processed)},
+		{"bounces", &b.Bounces, len(b.Bounces)},
+		{"complaints", &b.Complaints, len(b.Complaints)},
+		{"deliveries", &b.Deliveries, len(b.Deliveries)},
+		{"sends", &b.Sends, len(b.Sends)},
+		{"clicks", &b.Clicks, len(b.Clicks)},
+		{"opens", &b.Opens, len(b.Opens)},
+	}
+
+	for _, r := range records {
+		if r.n == 0 {
+			continue
+		}
+
+		err := tx.CreateInBatches(r.value, eventsBatchSize).Error
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("store: create %s: %w", r.name, err)
+		}
+	}
+
+	for _, e := range b.Transactional {
+		err := trackTransactionalMessageEvent(tx, e.ID, e.UserID, e.Event)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteProcessedEventsBefore deletes the keys of the events which were processed before the given time,
+// the keys are needed only while the notifications can still be delivered again.
+func (db *store) DeleteProcessedEventsBefore(t time.Time) (int64, error) {
+	res := db.Where("created_at < ?", t).Delete(&entities.ProcessedEvent{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("store: delete processed events: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestEvents(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+	now := time.Now().UTC()
+
+	m := &entities.TransactionalMessage{
+		UserID:     1,
+		EventID:    ksuid.New(),
+		TemplateID: 1,
+		Recipient:  "jane@example.com",
+		Source:     "auth@example.com",
+		Status:     entities.TransactionalStatusSent,
+	}
+	assert.Nil(t, store.CreateTransactionalMessage(m))
+
+	// an empty batch is a no-op.
+	assert.Nil(t, store.CreateEvents(&entities.EventsBatch{}))
+
+	keys, err := store.GetProcessedEventKeys(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	bounceKey := entities.EventKey("msg-1", "Bounce", now)
+	openKey := entities.EventKey("msg-2", "Open", now)
+	b := &entities.EventsBatch{
+		Processed: []entities.ProcessedEvent{{Key: bounceKey}, {Key: openKey}},
+		Bounces: []entities.Bounce{
+			{UserID: 1, CampaignID: 1, Recipient: "jane@example.com", Type: "Permanent", CreatedAt: now},
+			{UserID: 1, CampaignID: 1, Recipient: "john@example.com", Type: "Permanent", CreatedAt: now},
+		},
+		Transactional: []entities.TransactionalEvent{
+			{ID: m.ID, UserID: 1, Event: entities.TransactionalEventOpen},
+		},
+	}
+	assert.Nil(t, store.CreateEvents(b))
+	assert.NotZero(t, b.Bounces[0].ID)
+
+	total, err := store.GetTotalBounces(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+
+	m, err = store.GetTransactionalMessage(m.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), m.Opens)
+
+	keys, err = store.GetProcessedEventKeys([]string{bounceKey, openKey, entities.EventKey("msg-3", "Click", now)})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{bounceKey, openKey}, keys)
+
+	// storing an already processed event rolls back the whole batch.
+	err = store.CreateEvents(&entities.EventsBatch{
+		Processed: []entities.ProcessedEvent{{Key: entities.EventKey("msg-4", "Complaint", now)}, {Key: bounceKey}},
+		Complaints: []entities.Complaint{
+			{UserID: 1, CampaignID: 1, Recipient: "jane@example.com", CreatedAt: now},
+		},
+	})
+	assert.NotNil(t, err)
+
+	total, err = store.GetTotalComplaints(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), total)
+
+	keys, err = store.GetProcessedEventKeys([]string{entities.EventKey("msg-4", "Complaint", now)})
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	// only the keys processed before the given time are deleted.
+	oldKey := entities.EventKey("msg-5", "Delivery", now)
+	err = db.Create(&entities.ProcessedEvent{Key: oldKey, CreatedAt: now.Add(-15 * 24 * time.Hour)}).Error
+	assert.Nil(t, err)
+
+	deleted, err := store.DeleteProcessedEventsBefore(now.Add(-14 * 24 * time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	keys, err = store.GetProcessedEventKeys([]string{bounceKey, openKey, oldKey})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{bounceKey, openKey}, keys)
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// SeekCampaignResults fetches the results of the campaign for each recipient, the results are
+// ordered by the send log id and only the send logs with id greater than nextID are fetched.
+func (db *store) SeekCampaignResults(campaignID, userID int64, nextID string, limit int64) ([]entities.CampaignRecipientResult, error) {
+	var logs []struct {
+		ID           string
+		SubscriberID int64
+		Email        string
+		Status       string
+		Description  string
+		CreatedAt    time.Time
+	}
+	err := db.Table("send_logs").
+		Select("send_logs.id, send_logs.subscriber_id, coalesce(subscribers.email, '') as email, "+
+			"send_logs.status, coalesce(send_logs.description, '') as description, send_logs.created_at").
+		Joins("left join subscribers on subscribers.id = send_logs.subscriber_id").
+		Where("send_logs.campaign_id = ? and send_logs.user_id = ? and send_logs.id > ?", campaignID, userID, nextID).
+		Order("send_logs.id").
+		Limit(int(limit)).
+		Scan(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: seek campaign send logs: %w", err)
+	}
+
+	results := make([]entities.CampaignRecipientResult, len(logs))
+	emails := make([]string, 0, len(logs))
+	idx := make(map[string][]int)
+	for i, l := range logs {
+		results[i] = entities.CampaignRecipientResult{
+			SendLogID:    l.ID,
+			SubscriberID: l.SubscriberID,
+			Email:        l.Email,
+			Status:       l.Status,
+			Description:  l.Description,
+			SentAt:       l.CreatedAt,
+			ClickedLinks: []string{},
+		}
+		if l.Email == "" {
+			continue
+		}
+		if _, ok := idx[l.Email]; !ok {
+			emails = append(emails, l.Email)
+		}
+		idx[l.Email] = append(idx[l.Email], i)
+	}
+
+	if len(emails) == 0 {
+		return results, nil
+	}
+
+	events := func(table string) *gorm.DB {
+		return db.Table(table).Where("campaign_id = ? and user_id = ? and recipient in (?)", campaignID, userID, emails)
+	}
+
+	type recipientCount struct {
+		Recipient string
+		Total     int64
+	}
+	counts := []struct {
+		table string
+		set   func(r *entities.CampaignRecipientResult, total int64)
+	}{
+		{"deliveries", func(r *entities.CampaignRecipientResult, total int64) { r.Delivered = total > 0 }},
+		{"opens", func(r *entities.CampaignRecipientResult, total int64) { r.Opens = total }},
+		{"complaints", func(r *entities.CampaignRecipientResult, total int64) { r.Complained = total > 0 }},
+	}
+	for _, c := range counts {
+		var rc []recipientCount
+		err := events(c.table).Select("recipient, count(*) as total").Group("recipient").Scan(&rc).Error
+		if err != nil {
+			return nil, fmt.Errorf("store: count campaign %s by recipient: %w", c.table, err)
+		}
+		for _, r := range rc {
+			for _, i := range idx[r.Recipient] {
+				c.set(&results[i], r.Total)
+			}
+		}
+	}
+
+	var clicks []struct {
+		Recipient string
+		Link      string
+	}
+	err = events("clicks").Distinct("recipient", "link").Order("link").Scan(&clicks).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: get campaign clicked links: %w", err)
+	}
+	for _, c := range clicks {
+		for _, i := range idx[c.Recipient] {
+			results[i].ClickedLinks = append(results[i].ClickedLinks, c.Link)
+		}
+	}
+
+	var bounces []struct {
+		Recipient string
+		Type      string
+	}
+	err = events("bounces").Select("recipient, type").Order("created_at").Scan(&bounces).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: get campaign bounces: %w", err)
+	}
+	for _, b := range bounces {
+		// the type of the latest bounce of the recipient is kept.
+		for _, i := range idx[b.Recipient] {
+			results[i].BounceType = b.Type
+		}
+	}
+
+	return results, nil
+}
+
+// SeekBounces fetches the bounces of the user with id greater than nextID, the bounces
+// of all campaigns are fetched when the campaign id is 0.
+func (db *store) SeekBounces(userID, campaignID, nextID, limit int64) ([]entities.Bounce, error) {
+	var bounces []entities.Bounce
+	err := db.Scopes(inCampaign(campaignID)).
+		Where("user_id = ? and id > ?", userID, nextID).
+		Order("id").
+		Limit(int(limit)).
+		Find(&bounces).Error
+	return bounces, err
+}
+
+// SeekComplaints fetches the complaints of the user with id greater than nextID, the complaints
+// of all campaigns are fetched when the campaign id is 0.
+func (db *store) SeekComplaints(userID, campaignID, nextID, limit int64) ([]entities.Complaint, error) {
+	var complaints []entities.Complaint
+	err := db.Scopes(inCampaign(campaignID)).
+		Where("user_id = ? and id > ?", userID, nextID).
+		Order("id").
+		Limit(int(limit)).
+		Find(&complaints).Error
+	return complaints, err
+}
+
+// SeekSendLogs fetches the send logs of the user with id greater than nextID, the send logs
+// of all campaigns are fetched when the campaign id is 0.
+func (db *store) SeekSendLogs(userID, campaignID int64, nextID string, limit int64) ([]entities.SendLog, error) {
+	var logs []entities.SendLog
+	err := db.Scopes(inCampaign(campaignID)).
+		Where("user_id = ? and id > ?", userID, nextID).
+		Order("id").
+		Limit(int(limit)).
+		Find(&logs).Error
+	return logs, err
+}
+
+// CountBounces returns the number of bounces of the user, the bounces of all
+// campaigns are counted when the campaign id is 0.
+func (db *store) CountBounces(userID, campaignID int64) (int64, error) {
+	var count int64
+	err := db.Model(&entities.Bounce{}).Scopes(inCampaign(campaignID)).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// CountComplaints returns the number of complaints of the user, the complaints of all
+// campaigns are counted when the campaign id is 0.
+func (db *store) CountComplaints(userID, campaignID int64) (int64, error) {
+	var count int64
+	err := db.Model(&entities.Complaint{}).Scopes(inCampaign(campaignID)).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// CountSendLogs returns the number of send logs of the user, the send logs of all
+// campaigns are counted when the campaign id is 0.
+func (db *store) CountSendLogs(userID, campaignID int64) (int64, error) {
+	var count int64
+	err := db.Model(&entities.SendLog{}).Scopes(inCampaign(campaignID)).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// inCampaign is a query scope which filters the records by the campaign id, when set.
+func inCampaign(campaignID int64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if campaignID == 0 {
+			return db
+		}
+		return db.Where("campaign_id = ?", campaignID)
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestSeekCampaignResults(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	results, err := store.SeekCampaignResults(1, 1, "", 10)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+
+	jane := &entities.Subscriber{UserID: 1, Name: "Jane", Email: "jane@example.com", Active: true}
+	assert.Nil(t, store.CreateSubscriber(jane))
+	john := &entities.Subscriber{UserID: 1, Name: "John", Email: "john@example.com", Active: true}
+	assert.Nil(t, store.CreateSubscriber(john))
+
+	ids := []ksuid.KSUID{ksuid.New(), ksuid.New(), ksuid.New()}
+	ksuid.Sort(ids)
+
+	eventID := ksuid.New()
+	logs := []entities.SendLog{
+		{ID: ids[0], EventID: eventID, UserID: 1, CampaignID: 1, SubscriberID: jane.ID, Status: entities.SendLogStatusSuccessful, Description: entities.SendLogDescriptionOnSuccessful},
+		{ID: ids[1], EventID: eventID, UserID: 1, CampaignID: 1, SubscriberID: john.ID, Status: entities.SendLogStatusFailed, Description: entities.SendLogDescriptionOnSendEmailError},
+		{ID: ids[2], EventID: eventID, UserID: 1, CampaignID: 2, SubscriberID: jane.ID, Status: entities.SendLogStatusSuccessful},
+	}
+	for i := range logs {
+		assert.Nil(t, store.CreateSendLog(&logs[i]))
+	}
+
+	assert.Nil(t, store.CreateDelivery(&entities.Delivery{UserID: 1, CampaignID: 1, Recipient: "jane@example.com"}))
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 1, Recipient: "jane@example.com"}))
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 1, Recipient: "jane@example.com"}))
+	assert.Nil(t, store.CreateClick(&entities.Click{UserID: 1, CampaignID: 1, Recipient: "jane@example.com", Link: "https://example.com/b"}))
+	assert.Nil(t, store.CreateClick(&entities.Click{UserID: 1, CampaignID: 1, Recipient: "jane@example.com", Link: "https://example.com/a"}))
+	assert.Nil(t, store.CreateClick(&entities.Click{UserID: 1, CampaignID: 1, Recipient: "jane@example.com", Link: "https://example.com/a"}))
+	assert.Nil(t, store.CreateComplaint(&entities.Complaint{UserID: 1, CampaignID: 1, Recipient: "jane@example.com"}))
+	assert.Nil(t, store.CreateBounce(&entities.Bounce{UserID: 1, CampaignID: 1, Recipient: "john@example.com", Type: "Permanent"}))
+	// the events of the other campaigns are not included.
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 2, Recipient: "john@example.com"}))
+
+	results, err = store.SeekCampaignResults(1, 1, "", 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+
+	assert.Equal(t, ids[0].String(), results[0].SendLogID)
+	assert.Equal(t, "jane@example.com", results[0].Email)
+	assert.Equal(t, entities.SendLogStatusSuccessful, results[0].Status)
+	assert.True(t, results[0].Delivered)
+	assert.Equal(t, int64(2), results[0].Opens)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, results[0].ClickedLinks)
+	assert.True(t, results[0].Complained)
+	assert.Empty(t, results[0].BounceType)
+
+	assert.Equal(t, "john@example.com", results[1].Email)
+	assert.Equal(t, entities.SendLogStatusFailed, results[1].Status)
+	assert.False(t, results[1].Delivered)
+	assert.Equal(t, int64(0), results[1].Opens)
+	assert.Empty(t, results[1].ClickedLinks)
+	assert.Equal(t, "Permanent", results[1].BounceType)
+
+	// seek the next page
+	results, err = store.SeekCampaignResults(1, 1, ids[0].String(), 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "john@example.com", results[0].Email)
+
+	sendLogs, err := store.SeekSendLogs(1, 0, "", 10)
+	assert.Nil(t, err)
+	assert.Len(t, sendLogs, 3)
+
+	sendLogs, err = store.SeekSendLogs(1, 2, "", 10)
+	assert.Nil(t, err)
+	assert.Len(t, sendLogs, 1)
+
+	bounces, err := store.SeekBounces(1, 0, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, bounces, 1)
+
+	bounces, err = store.SeekBounces(1, 2, 0, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, bounces)
+
+	complaints, err := store.SeekComplaints(1, 1, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, complaints, 1)
+
+	complaints, err = store.SeekComplaints(1, 1, complaints[0].ID, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, complaints)
+
+	count, err := store.CountSendLogs(1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+
+	count, err = store.CountSendLogs(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = store.CountBounces(1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	count, err = store.CountComplaints(1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// GetForms fetches forms by user id, and populates the pagination obj
+func (db *store) GetForms(userID int64, p *PaginationCursor) error {
+	p.SetCollection(new([]entities.Form))
+	p.SetResource("forms")
+
+	p.AddScope(BelongsToUser(userID))
+
+	query := db.Table(p.Resource).
+		Order("created_at desc, id desc").
+		Limit(p.PerPage)
+
+	p.SetQuery(query)
+
+	return db.Paginate(p, userID)
+}
+
+// GetForm returns the form by the given id and user id
+func (db *store) GetForm(id, userID int64) (*entities.Form, error) {
+	var form = new(entities.Form)
+	err := db.Where("user_id = ? and id = ?", userID, id).Preload("Segments").First(form).Error
+	return form, err
+}
+
+// GetFormByUUID returns the form by the given uuid
+func (db *store) GetFormByUUID(uuid string) (*entities.Form, error) {
+	var form = new(entities.Form)
+	err := db.Where("uuid = ?", uuid).Preload("Segments").First(form).Error
+	return form, err
+}
+
+// GetFormByName returns the form by the given name and user id
+func (db *store) GetFormByName(name string, userID int64) (*entities.Form, error) {
+	var form = new(entities.Form)
+	err := db.Where("user_id = ? and name = ?", userID, name).First(form).Error
+	return form, err
+}
+
+// CreateForm creates a new form in the database.
+func (db *store) CreateForm(f *entities.Form) error {
+	return db.Create(f).Error
+}
+
+// UpdateForm edits an existing form in the database along with its segments.
+func (db *store) UpdateForm(f *entities.Form) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Model(f).Association("Segments").Replace(f.Segments); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: update form segments: %w", err)
+	}
+
+	if err := tx.Where("id = ? and user_id = ?", f.ID, f.UserID).Omit("Segments").Save(f).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: update form: %w", err)
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteForm deletes an existing form from the database and also clears the segments association.
+func (db *store) DeleteForm(id, userID int64) error {
+	f := &entities.Form{Model: entities.Model{ID: id}, UserID: userID}
+	if err := db.Model(f).Association("Segments").Clear(); err != nil {
+		return fmt.Errorf("store: clear form segments: %w", err)
+	}
+
+	return db.Where("user_id = ?", userID).Delete(f).Error
+}
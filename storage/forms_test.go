@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestForms(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	seg1 := &entities.Segment{Name: "news", UserID: 1}
+	assert.Nil(t, store.CreateSegment(seg1))
+	seg2 := &entities.Segment{Name: "offers", UserID: 1}
+	assert.Nil(t, store.CreateSegment(seg2))
+
+	f := &entities.Form{
+		UserID:   1,
+		UUID:     "b3c1f5b4-0d63-4c5e-9d4a-6a1d2f0f1e7a",
+		Name:     "signup",
+		Segments: []entities.Segment{*seg1},
+	}
+	assert.Nil(t, f.SetMetadataFields([]string{"plan"}))
+	assert.Nil(t, store.CreateForm(f))
+
+	form, err := store.GetForm(f.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "signup", form.Name)
+	assert.Len(t, form.Segments, 1)
+	fields, err := form.GetMetadataFields()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"plan"}, fields)
+
+	_, err = store.GetForm(f.ID, 2)
+	assert.NotNil(t, err)
+
+	form, err = store.GetFormByUUID(f.UUID)
+	assert.Nil(t, err)
+	assert.Equal(t, f.ID, form.ID)
+
+	form, err = store.GetFormByName("signup", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, f.ID, form.ID)
+
+	form.DoubleOptIn = true
+	form.Segments = []entities.Segment{*seg2}
+	assert.Nil(t, store.UpdateForm(form))
+
+	form, err = store.GetForm(f.ID, 1)
+	assert.Nil(t, err)
+	assert.True(t, form.DoubleOptIn)
+	assert.Len(t, form.Segments, 1)
+	assert.Equal(t, seg2.ID, form.Segments[0].ID)
+
+	p := NewPaginationCursor("/api/forms", 10)
+	assert.Nil(t, store.GetForms(1, p))
+	col := p.Collection.(*[]entities.Form)
+	assert.Len(t, *col, 1)
+
+	assert.Nil(t, store.DeleteForm(f.ID, 1))
+	_, err = store.GetForm(f.ID, 1)
+	assert.NotNil(t, err)
+}
+
+func TestConfirmSubscriber(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	s := &entities.Subscriber{UserID: 1, Name: "john", Email: "john@example.com"}
+	assert.Nil(t, store.CreateSubscriber(s))
+
+	assert.Nil(t, store.ConfirmSubscriber(s.ID, 1))
+
+	s, err := store.GetSubscriber(s.ID, 1)
+	assert.Nil(t, err)
+	assert.True(t, s.Active)
+
+	var count int64
+	err = db.Model(&entities.SubscriberEvent{}).
+		Where("subscriber_id = ? AND event_type = ?", s.ID, entities.SubscriberEventTypeConfirmed).
+		Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
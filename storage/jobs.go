@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"github.com/mailbadger/app/entities"
+)
+
+// GetJobs fetches the jobs of the user, and populates the pagination obj
+func (db *store) GetJobs(userID int64, p *PaginationCursor) error {
+	p.SetCollection(new([]entities.Job))
+	p.SetResource("jobs")
+
+	p.AddScope(BelongsToUser(userID))
+
+	query := db.Table(p.Resource).
+		Order("created_at desc, id desc").
+		Limit(p.PerPage)
+
+	p.SetQuery(query)
+
+	return db.Paginate(p, userID)
+}
+
+// GetJob returns the job by the given id and user id
+func (db *store) GetJob(id, userID int64) (*entities.Job, error) {
+	var j = new(entities.Job)
+	err := db.Where("user_id = ? and id = ?", userID, id).First(j).Error
+	return j, err
+}
+
+// CreateJob creates a new job in the database.
+func (db *store) CreateJob(j *entities.Job) error {
+	return db.Create(j).Error
+}
+
+// UpdateJob saves the state of the job.
+func (db *store) UpdateJob(j *entities.Job) error {
+	return db.Where("id = ? and user_id = ?", j.ID, j.UserID).Save(j).Error
+}
+
+// UpdateJobProgress updates the number of processed and total items of the job.
+func (db *store) UpdateJobProgress(j *entities.Job) error {
+	return db.Model(j).
+		Where("user_id = ?", j.UserID).
+		UpdateColumns(map[string]interface{}{
+			"processed": j.Processed,
+			"total":     j.Total,
+		}).Error
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestJobs(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	j := &entities.Job{
+		UserID:      1,
+		Type:        entities.JobTypeBulkRemoveSubscribers,
+		Status:      entities.JobStatusPending,
+		Payload:     []byte(`{"filename":"remove.csv"}`),
+		MaxAttempts: entities.JobMaxAttempts,
+	}
+	err := store.CreateJob(j)
+	assert.Nil(t, err)
+
+	err = store.CreateJob(&entities.Job{
+		UserID:  2,
+		Type:    entities.JobTypeExportReport,
+		Status:  entities.JobStatusPending,
+		Payload: []byte(`{"report_id":1}`),
+	})
+	assert.Nil(t, err)
+
+	job, err := store.GetJob(j.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.JobTypeBulkRemoveSubscribers, job.Type)
+	assert.JSONEq(t, `{"filename":"remove.csv"}`, string(job.Payload))
+
+	_, err = store.GetJob(j.ID, 2)
+	assert.NotNil(t, err)
+
+	job.Total = 10
+	job.Processed = 4
+	err = store.UpdateJobProgress(job)
+	assert.Nil(t, err)
+
+	job, err = store.GetJob(j.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), job.Total)
+	assert.Equal(t, int64(4), job.Processed)
+	assert.Equal(t, entities.JobStatusPending, job.Status)
+
+	job.Status = entities.JobStatusFailed
+	job.Attempts = 1
+	job.Error = "file not found"
+	err = store.UpdateJob(job)
+	assert.Nil(t, err)
+
+	job, err = store.GetJob(j.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.JobStatusFailed, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "file not found", job.Error)
+	assert.True(t, job.IsFinished())
+
+	p := NewPaginationCursor("/api/jobs", 10)
+	err = store.GetJobs(1, p)
+	assert.Nil(t, err)
+	col := p.Collection.(*[]entities.Job)
+	assert.Len(t, *col, 1)
+	assert.Equal(t, int64(1), p.Total)
+}
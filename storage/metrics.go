@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// GetSubscriberMetrics fetches the hourly subscriber metrics of the user in the date range,
+// from is inclusive and to is exclusive.
+func (db *store) GetSubscriberMetrics(userID int64, from, to time.Time) ([]entities.SubscriberMetrics, error) {
+	var metrics []entities.SubscriberMetrics
+	err := db.Where("user_id = ? and datetime >= ? and datetime < ?", userID, from, to).
+		Order("datetime").
+		Find(&metrics).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: get subscriber metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// GetCampaignsEngagement fetches the sends, deliveries, unique opens and unique clicks of
+// the campaigns of the user which were sent in the date range, from is inclusive and to is exclusive.
+func (db *store) GetCampaignsEngagement(userID int64, from, to time.Time) ([]entities.CampaignEngagement, error) {
+	var campaigns []entities.Campaign
+	err := db.Select("id", "name", "completed_at").
+		Where("user_id = ? and status = ? and completed_at >= ? and completed_at < ?", userID, entities.StatusSent, from, to).
+		Order("completed_at").
+		Find(&campaigns).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: get sent campaigns: %w", err)
+	}
+
+	engagement := make([]entities.CampaignEngagement, len(campaigns))
+	if len(campaigns) == 0 {
+		return engagement, nil
+	}
+
+	ids := make([]int64, len(campaigns))
+	idx := make(map[int64]*entities.CampaignEngagement, len(campaigns))
+	for i, c := range campaigns {
+		ids[i] = c.ID
+		engagement[i] = entities.CampaignEngagement{
+			CampaignID:  c.ID,
+			Name:        c.Name,
+			CompletedAt: c.CompletedAt.Time,
+		}
+		idx[c.ID] = &engagement[i]
+	}
+
+	events := []struct {
+		table string
+		count string
+		set   func(e *entities.CampaignEngagement, total int64)
+	}{
+		{"sends", "count(*)", func(e *entities.CampaignEngagement, total int64) { e.TotalSent = total }},
+		{"deliveries", "count(*)", func(e *entities.CampaignEngagement, total int64) { e.Delivered = total }},
+		{"opens", "count(distinct(recipient))", func(e *entities.CampaignEngagement, total int64) { e.UniqueOpens = total }},
+		{"clicks", "count(distinct(recipient))", func(e *entities.CampaignEngagement, total int64) { e.UniqueClicks = total }},
+	}
+
+	for _, e := range events {
+		var counts []struct {
+			CampaignID int64
+			Total      int64
+		}
+		err := db.Table(e.table).
+			Select("campaign_id, "+e.count+" as total").
+			Where("user_id = ? and campaign_id in (?)", userID, ids).
+			Group("campaign_id").
+			Scan(&counts).Error
+		if err != nil {
+			return nil, fmt.Errorf("store: count campaigns %s: %w", e.table, err)
+		}
+
+		for _, c := range counts {
+			if ce, ok := idx[c.CampaignID]; ok {
+				e.set(ce, c.Total)
+			}
+		}
+	}
+
+	return engagement, nil
+}
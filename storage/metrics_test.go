@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestMetrics(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	from := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	metrics, err := store.GetSubscriberMetrics(1, from, to)
+	assert.Nil(t, err)
+	assert.Empty(t, metrics)
+
+	for _, m := range []entities.SubscriberMetrics{
+		{UserID: 1, Created: 3, Unsubscribed: 1, Datetime: from.Add(-time.Hour)},
+		{UserID: 1, Created: 5, Unsubscribed: 2, Datetime: from.Add(10 * time.Hour)},
+		{UserID: 1, Created: 1, Datetime: from.AddDate(0, 0, 3)},
+		{UserID: 1, Created: 7, Datetime: to},
+	} {
+		assert.Nil(t, db.Create(&m).Error)
+	}
+
+	metrics, err = store.GetSubscriberMetrics(1, from, to)
+	assert.Nil(t, err)
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, int64(5), metrics[0].Created)
+	assert.Equal(t, int64(2), metrics[0].Unsubscribed)
+	assert.Equal(t, int64(1), metrics[1].Created)
+
+	engagement, err := store.GetCampaignsEngagement(1, from, to)
+	assert.Nil(t, err)
+	assert.Empty(t, engagement)
+
+	sent := &entities.Campaign{Name: "may", UserID: 1, Status: entities.StatusSent}
+	sent.CompletedAt.SetValid(from.AddDate(0, 0, 2))
+	assert.Nil(t, store.CreateCampaign(sent))
+
+	draft := &entities.Campaign{Name: "draft", UserID: 1, Status: entities.StatusDraft}
+	assert.Nil(t, store.CreateCampaign(draft))
+
+	old := &entities.Campaign{Name: "april", UserID: 1, Status: entities.StatusSent}
+	old.CompletedAt.SetValid(from.AddDate(0, 0, -2))
+	assert.Nil(t, store.CreateCampaign(old))
+
+	for _, r := range []string{"jane@example.com", "john@example.com"} {
+		assert.Nil(t, store.CreateSend(&entities.Send{UserID: 1, CampaignID: sent.ID, MessageID: r, Destination: r}))
+		assert.Nil(t, store.CreateDelivery(&entities.Delivery{UserID: 1, CampaignID: sent.ID, Recipient: r}))
+	}
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: sent.ID, Recipient: "jane@example.com"}))
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: sent.ID, Recipient: "jane@example.com"}))
+	assert.Nil(t, store.CreateClick(&entities.Click{UserID: 1, CampaignID: sent.ID, Recipient: "jane@example.com"}))
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: old.ID, Recipient: "jane@example.com"}))
+
+	engagement, err = store.GetCampaignsEngagement(1, from, to)
+	assert.Nil(t, err)
+	assert.Len(t, engagement, 1)
+	assert.Equal(t, sent.ID, engagement[0].CampaignID)
+	assert.Equal(t, "may", engagement[0].Name)
+	assert.True(t, engagement[0].CompletedAt.Equal(from.AddDate(0, 0, 2)))
+	assert.Equal(t, int64(2), engagement[0].TotalSent)
+	assert.Equal(t, int64(2), engagement[0].Delivered)
+	assert.Equal(t, int64(1), engagement[0].UniqueOpens)
+	assert.Equal(t, int64(1), engagement[0].UniqueClicks)
+
+	// the campaigns of the other users are not included.
+	engagement, err = store.GetCampaignsEngagement(2, from, to)
+	assert.Nil(t, err)
+	assert.Empty(t, engagement)
+}
@@ -0,0 +1,19 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `campaign_checkpoints` (
+    `id`                 integer unsigned primary key AUTO_INCREMENT NOT NULL,
+    `user_id`            integer unsigned                            NOT NULL,
+    `campaign_id`        integer unsigned                            NOT NULL UNIQUE,
+    `event_id`           varbinary(27)                               NOT NULL,
+    `params`             json                                        NOT NULL,
+    `last_subscriber_id` integer unsigned                            NOT NULL,
+    `last_created_at`    datetime(6),
+    `created_at`         datetime(6)                                 NOT NULL,
+    `updated_at`         datetime(6)                                 NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users (`id`),
+    FOREIGN KEY (`campaign_id`) REFERENCES campaigns (`id`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- +migrate Down
+
+DROP TABLE `campaign_checkpoints`;
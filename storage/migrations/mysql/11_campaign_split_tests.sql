@@ -0,0 +1,52 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `campaign_split_tests` (
+    `id`                integer unsigned primary key AUTO_INCREMENT NOT NULL,
+    `user_id`           integer unsigned                            NOT NULL,
+    `campaign_id`       integer unsigned                            NOT NULL UNIQUE,
+    `test_percentage`   integer unsigned                            NOT NULL,
+    `wait_minutes`      integer unsigned                            NOT NULL,
+    `winner_criteria`   varchar(191)                                NOT NULL,
+    `status`            varchar(191)                                NOT NULL,
+    `winner_variant_id` integer unsigned                            NOT NULL DEFAULT 0,
+    `params`            json,
+    `test_completed_at` datetime(6),
+    `created_at`        datetime(6)                                 NOT NULL,
+    `updated_at`        datetime(6)                                 NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users (`id`),
+    FOREIGN KEY (`campaign_id`) REFERENCES campaigns (`id`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `campaign_variants` (
+    `id`            integer unsigned primary key AUTO_INCREMENT NOT NULL,
+    `user_id`       integer unsigned                            NOT NULL,
+    `split_test_id` integer unsigned                            NOT NULL,
+    `campaign_id`   integer unsigned                            NOT NULL,
+    `template_id`   integer unsigned                            NOT NULL,
+    `name`          varchar(191)                                NOT NULL,
+    `created_at`    datetime(6)                                 NOT NULL,
+    `updated_at`    datetime(6)                                 NOT NULL,
+    INDEX idx_variant_campaign (`campaign_id`),
+    FOREIGN KEY (`user_id`) REFERENCES users (`id`),
+    FOREIGN KEY (`split_test_id`) REFERENCES campaign_split_tests (`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`template_id`) REFERENCES templates (`id`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+ALTER TABLE `sends` ADD COLUMN `variant_id` integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE `deliveries` ADD COLUMN `variant_id` integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE `opens` ADD COLUMN `variant_id` integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE `clicks` ADD COLUMN `variant_id` integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE `bounces` ADD COLUMN `variant_id` integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE `complaints` ADD COLUMN `variant_id` integer unsigned NOT NULL DEFAULT 0;
+
+-- +migrate Down
+
+ALTER TABLE `sends` DROP COLUMN `variant_id`;
+ALTER TABLE `deliveries` DROP COLUMN `variant_id`;
+ALTER TABLE `opens` DROP COLUMN `variant_id`;
+ALTER TABLE `clicks` DROP COLUMN `variant_id`;
+ALTER TABLE `bounces` DROP COLUMN `variant_id`;
+ALTER TABLE `complaints` DROP COLUMN `variant_id`;
+
+DROP TABLE `campaign_variants`;
+DROP TABLE `campaign_split_tests`;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+ALTER TABLE `segments` ADD COLUMN `type` varchar(191) NOT NULL DEFAULT 'static';
+ALTER TABLE `segments` ADD COLUMN `rules` json;
+
+-- +migrate Down
+
+ALTER TABLE `segments` DROP COLUMN `rules`;
+ALTER TABLE `segments` DROP COLUMN `type`;
@@ -0,0 +1,33 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `forms` (
+    `id`                       integer unsigned primary key AUTO_INCREMENT NOT NULL,
+    `user_id`                  integer unsigned                            NOT NULL,
+    `uuid`                     varchar(36)                                 NOT NULL UNIQUE,
+    `name`                     varchar(191)                                NOT NULL,
+    `metadata_fields`          json,
+    `redirect_url`             varchar(191)                                NOT NULL DEFAULT '',
+    `confirm_redirect_url`     varchar(191)                                NOT NULL DEFAULT '',
+    `double_opt_in`            TINYINT(1)                                  NOT NULL DEFAULT 0,
+    `confirmation_template_id` integer unsigned                            NOT NULL DEFAULT 0,
+    `source`                   varchar(191)                                NOT NULL DEFAULT '',
+    `from_name`                varchar(191)                                NOT NULL DEFAULT '',
+    `created_at`               datetime(6)                                 NOT NULL,
+    `updated_at`               datetime(6)                                 NOT NULL,
+    UNIQUE KEY `unique_user_form_name` (`user_id`, `name`),
+    INDEX id_created_at (`id`, `created_at`),
+    FOREIGN KEY (`user_id`) REFERENCES users (`id`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `forms_segments` (
+    `form_id`    integer unsigned NOT NULL,
+    `segment_id` integer unsigned NOT NULL,
+    PRIMARY KEY (`form_id`, `segment_id`),
+    FOREIGN KEY (`form_id`) REFERENCES forms (`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`segment_id`) REFERENCES segments (`id`) ON DELETE CASCADE
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- +migrate Down
+
+DROP TABLE `forms_segments`;
+DROP TABLE `forms`;
@@ -0,0 +1,21 @@
+-- +migrate Up
+
+ALTER TABLE `segments` ADD COLUMN `public` TINYINT(1) NOT NULL DEFAULT 0;
+ALTER TABLE `subscribers` ADD COLUMN `paused_until` DATETIME(6) DEFAULT NULL;
+ALTER TABLE `subscriber_events` ADD COLUMN `segment_id` INTEGER UNSIGNED DEFAULT NULL;
+ALTER TABLE `subscriber_metrics`
+    ADD COLUMN `updated` INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN `opted_in` INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN `opted_out` INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN `paused` INTEGER UNSIGNED NOT NULL DEFAULT 0;
+
+-- +migrate Down
+
+ALTER TABLE `subscriber_metrics`
+    DROP COLUMN `paused`,
+    DROP COLUMN `opted_out`,
+    DROP COLUMN `opted_in`,
+    DROP COLUMN `updated`;
+ALTER TABLE `subscriber_events` DROP COLUMN `segment_id`;
+ALTER TABLE `subscribers` DROP COLUMN `paused_until`;
+ALTER TABLE `segments` DROP COLUMN `public`;
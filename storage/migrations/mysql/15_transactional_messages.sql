@@ -0,0 +1,25 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `transactional_messages` (
+    `id`          BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `user_id`     INTEGER UNSIGNED NOT NULL,
+    `event_id`    VARBINARY(27) NOT NULL,
+    `template_id` INTEGER UNSIGNED NOT NULL,
+    `recipient`   VARCHAR(191) NOT NULL,
+    `source`      VARCHAR(191) NOT NULL,
+    `tags`        JSON,
+    `status`      VARCHAR(50) NOT NULL,
+    `description` VARCHAR(191) NOT NULL DEFAULT '',
+    `message_id`  VARCHAR(191),
+    `opens`       INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    `clicks`      INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    `created_at`  DATETIME(6) NOT NULL,
+    `updated_at`  DATETIME(6) NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`),
+    INDEX idx_user_id_created_at (`user_id`, `created_at`),
+    INDEX idx_message_id (`message_id`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- +migrate Down
+
+DROP TABLE `transactional_messages`;
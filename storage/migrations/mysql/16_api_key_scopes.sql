@@ -0,0 +1,21 @@
+-- +migrate Up
+
+ALTER TABLE `api_keys`
+    ADD COLUMN `name` VARCHAR(191) NOT NULL DEFAULT '' AFTER `user_id`,
+    ADD COLUMN `key_prefix` VARCHAR(20) NOT NULL DEFAULT '' AFTER `name`,
+    ADD COLUMN `scopes` VARCHAR(1000) NOT NULL DEFAULT '' AFTER `secret_key`,
+    ADD COLUMN `last_used_at` DATETIME(6) DEFAULT NULL AFTER `active`;
+
+-- the existing keys are hashed and keep the full access they had.
+UPDATE `api_keys` SET
+    `key_prefix` = LEFT(`secret_key`, 11),
+    `secret_key` = SHA2(`secret_key`, 256),
+    `scopes` = '*';
+
+-- +migrate Down
+
+ALTER TABLE `api_keys`
+    DROP COLUMN `last_used_at`,
+    DROP COLUMN `scopes`,
+    DROP COLUMN `key_prefix`,
+    DROP COLUMN `name`;
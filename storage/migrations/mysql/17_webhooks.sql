@@ -0,0 +1,38 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `webhooks` (
+    `id`         INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `user_id`    INTEGER UNSIGNED NOT NULL,
+    `url`        VARCHAR(191) NOT NULL,
+    `secret`     VARCHAR(191) NOT NULL,
+    `events`     VARCHAR(1000) NOT NULL,
+    `active`     TINYINT(1) NOT NULL DEFAULT 1,
+    `created_at` DATETIME(6) NOT NULL,
+    `updated_at` DATETIME(6) NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`),
+    INDEX idx_user_id_created_at (`user_id`, `created_at`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+    `id`              BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `user_id`         INTEGER UNSIGNED NOT NULL,
+    `webhook_id`      INTEGER UNSIGNED NOT NULL,
+    `event_id`        VARBINARY(27) NOT NULL,
+    `event_type`      VARCHAR(50) NOT NULL,
+    `payload`         JSON NOT NULL,
+    `status`          VARCHAR(20) NOT NULL,
+    `attempts`        INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    `response_status` INTEGER NOT NULL DEFAULT 0,
+    `error_message`   TEXT,
+    `next_attempt_at` DATETIME(6) DEFAULT NULL,
+    `created_at`      DATETIME(6) NOT NULL,
+    `updated_at`      DATETIME(6) NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`),
+    FOREIGN KEY (`webhook_id`) REFERENCES webhooks(`id`) ON DELETE CASCADE,
+    INDEX idx_webhook_id_created_at (`webhook_id`, `created_at`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- +migrate Down
+
+DROP TABLE `webhook_deliveries`;
+DROP TABLE `webhooks`;
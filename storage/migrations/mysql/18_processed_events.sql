@@ -0,0 +1,12 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `processed_events` (
+    `id`         BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `event_key`  VARCHAR(191) NOT NULL UNIQUE,
+    `created_at` DATETIME(6) NOT NULL,
+    INDEX idx_created_at (`created_at`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- +migrate Down
+
+DROP TABLE `processed_events`;
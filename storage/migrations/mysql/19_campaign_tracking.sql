@@ -0,0 +1,13 @@
+-- +migrate Up
+
+ALTER TABLE `campaigns`
+    ADD COLUMN `track_opens` TINYINT(1) NOT NULL DEFAULT 0 AFTER `status`,
+    ADD COLUMN `track_clicks` TINYINT(1) NOT NULL DEFAULT 0 AFTER `track_opens`,
+    ADD COLUMN `excluded_links` JSON DEFAULT NULL AFTER `track_clicks`;
+
+-- +migrate Down
+
+ALTER TABLE `campaigns`
+    DROP COLUMN `excluded_links`,
+    DROP COLUMN `track_clicks`,
+    DROP COLUMN `track_opens`;
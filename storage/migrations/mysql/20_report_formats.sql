@@ -0,0 +1,11 @@
+-- +migrate Up
+
+ALTER TABLE `reports`
+    ADD COLUMN `format` VARCHAR(10) NOT NULL DEFAULT 'csv' AFTER `resource`,
+    ADD COLUMN `campaign_id` INTEGER UNSIGNED DEFAULT NULL AFTER `format`;
+
+-- +migrate Down
+
+ALTER TABLE `reports`
+    DROP COLUMN `campaign_id`,
+    DROP COLUMN `format`;
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+ALTER TABLE `reports`
+    ADD COLUMN `gzip` TINYINT(1) NOT NULL DEFAULT 0 AFTER `format`,
+    ADD COLUMN `rows_exported` BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER `note`,
+    ADD COLUMN `total_rows` BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER `rows_exported`;
+
+-- +migrate Down
+
+ALTER TABLE `reports`
+    DROP COLUMN `total_rows`,
+    DROP COLUMN `rows_exported`,
+    DROP COLUMN `gzip`;
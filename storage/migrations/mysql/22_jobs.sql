@@ -0,0 +1,24 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `jobs` (
+    `id`           BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `user_id`      INTEGER UNSIGNED NOT NULL,
+    `type`         VARCHAR(50) NOT NULL,
+    `status`       VARCHAR(20) NOT NULL,
+    `payload`      JSON NOT NULL,
+    `processed`    BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    `total`        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    `attempts`     INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    `max_attempts` INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    `error`        TEXT,
+    `started_at`   DATETIME(6) DEFAULT NULL,
+    `completed_at` DATETIME(6) DEFAULT NULL,
+    `created_at`   DATETIME(6) NOT NULL,
+    `updated_at`   DATETIME(6) NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`),
+    INDEX idx_user_id_created_at (`user_id`, `created_at`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- +migrate Down
+
+DROP TABLE `jobs`;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+ALTER TABLE `jobs`
+    ADD COLUMN `result` JSON DEFAULT NULL AFTER `error`;
+
+-- +migrate Down
+
+ALTER TABLE `jobs`
+    DROP COLUMN `result`;
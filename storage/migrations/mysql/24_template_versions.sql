@@ -0,0 +1,32 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `template_versions` (
+    `id`           BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `user_id`      INTEGER UNSIGNED NOT NULL,
+    `template_id`  INTEGER UNSIGNED NOT NULL,
+    `version`      INTEGER UNSIGNED NOT NULL,
+    `name`         VARCHAR(191) NOT NULL,
+    `subject_part` VARCHAR(191) NOT NULL,
+    `text_part`    TEXT,
+    `created_at`   DATETIME(6) NOT NULL,
+    `updated_at`   DATETIME(6) NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`),
+    FOREIGN KEY (`template_id`) REFERENCES templates(`id`) ON DELETE CASCADE,
+    UNIQUE INDEX idx_template_id_version (`template_id`, `version`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+ALTER TABLE `templates` ADD COLUMN `version` INTEGER UNSIGNED NOT NULL DEFAULT 0 AFTER `text_part`;
+ALTER TABLE `campaigns` ADD COLUMN `template_version` INTEGER UNSIGNED NOT NULL DEFAULT 0 AFTER `template_id`;
+ALTER TABLE `campaign_variants` ADD COLUMN `template_version` INTEGER UNSIGNED NOT NULL DEFAULT 0 AFTER `template_id`;
+
+-- the existing templates are kept as version 0, their html part is stored under the unversioned key.
+INSERT INTO `template_versions` (`user_id`, `template_id`, `version`, `name`, `subject_part`, `text_part`, `created_at`, `updated_at`)
+    SELECT `user_id`, `id`, 0, `name`, `subject_part`, `text_part`, `updated_at`, `updated_at` FROM `templates`;
+
+-- +migrate Down
+
+ALTER TABLE `campaign_variants` DROP COLUMN `template_version`;
+ALTER TABLE `campaigns` DROP COLUMN `template_version`;
+ALTER TABLE `templates` DROP COLUMN `version`;
+
+DROP TABLE `template_versions`;
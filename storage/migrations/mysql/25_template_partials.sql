@@ -0,0 +1,23 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `template_partials` (
+    `id`         BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `user_id`    INTEGER UNSIGNED NOT NULL,
+    `name`       VARCHAR(191) NOT NULL,
+    `kind`       VARCHAR(20) NOT NULL,
+    `content`    MEDIUMTEXT NOT NULL,
+    `created_at` DATETIME(6) NOT NULL,
+    `updated_at` DATETIME(6) NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`),
+    UNIQUE INDEX idx_template_partials_user_id_name (`user_id`, `name`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+ALTER TABLE `templates` ADD COLUMN `layout_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER `version`;
+ALTER TABLE `template_versions` ADD COLUMN `layout_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER `text_part`;
+
+-- +migrate Down
+
+ALTER TABLE `template_versions` DROP COLUMN `layout_id`;
+ALTER TABLE `templates` DROP COLUMN `layout_id`;
+
+DROP TABLE `template_partials`;
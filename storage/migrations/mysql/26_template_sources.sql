@@ -0,0 +1,23 @@
+-- +migrate Up
+
+ALTER TABLE `templates`
+    ADD COLUMN `text_source` TEXT AFTER `text_part`,
+    ADD COLUMN `inline_css` TINYINT(1) NOT NULL DEFAULT 0 AFTER `layout_id`;
+
+ALTER TABLE `template_versions`
+    ADD COLUMN `text_source` TEXT AFTER `text_part`,
+    ADD COLUMN `inline_css` TINYINT(1) NOT NULL DEFAULT 0 AFTER `layout_id`;
+
+-- the text parts of the existing templates were written by their authors.
+UPDATE `templates` SET `text_source` = `text_part`;
+UPDATE `template_versions` SET `text_source` = `text_part`;
+
+-- +migrate Down
+
+ALTER TABLE `template_versions`
+    DROP COLUMN `inline_css`,
+    DROP COLUMN `text_source`;
+
+ALTER TABLE `templates`
+    DROP COLUMN `inline_css`,
+    DROP COLUMN `text_source`;
@@ -0,0 +1,11 @@
+-- +migrate Up
+
+ALTER TABLE `campaign_checkpoints` ADD COLUMN `paused_at` datetime(6) AFTER `last_created_at`;
+
+-- the existing checkpoints were saved by campaigners which stopped on a pause.
+UPDATE `campaign_checkpoints` SET `paused_at` = `updated_at`
+WHERE `campaign_id` IN (SELECT `id` FROM `campaigns` WHERE `status` = 'paused');
+
+-- +migrate Down
+
+ALTER TABLE `campaign_checkpoints` DROP COLUMN `paused_at`;
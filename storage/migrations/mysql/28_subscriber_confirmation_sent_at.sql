@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE `subscribers` ADD COLUMN `confirmation_sent_at` datetime(6) AFTER `paused_until`;
+
+-- +migrate Down
+
+ALTER TABLE `subscribers` DROP COLUMN `confirmation_sent_at`;
@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE `subscriber_metrics` ADD COLUMN `resumed` INTEGER UNSIGNED NOT NULL DEFAULT 0 AFTER `paused`;
+
+-- +migrate Down
+
+ALTER TABLE `subscriber_metrics` DROP COLUMN `resumed`;
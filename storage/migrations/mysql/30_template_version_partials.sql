@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- the content of the partials and the layout which the version includes, the versions are
+-- rendered with them so the later changes of the partials don't change the sent versions.
+ALTER TABLE `template_versions`
+    ADD COLUMN `partials` MEDIUMTEXT AFTER `inline_css`,
+    ADD COLUMN `layout` MEDIUMTEXT AFTER `partials`;
+
+-- +migrate Down
+
+ALTER TABLE `template_versions`
+    DROP COLUMN `layout`,
+    DROP COLUMN `partials`;
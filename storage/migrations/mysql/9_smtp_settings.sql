@@ -0,0 +1,19 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS `smtp_settings` (
+    `id`          integer unsigned primary key AUTO_INCREMENT NOT NULL,
+    `user_id`     integer unsigned                            NOT NULL UNIQUE,
+    `host`        varchar(191)                                NOT NULL,
+    `port`        integer unsigned                            NOT NULL,
+    `username`    varchar(191),
+    `password`    varchar(191),
+    `encryption`  varchar(20)                                 NOT NULL,
+    `auth_method` varchar(20),
+    `created_at`  datetime(6)                                 NOT NULL,
+    `updated_at`  datetime(6)                                 NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES users (`id`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- +migrate Down
+
+DROP TABLE `smtp_settings`;
@@ -0,0 +1,47 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "campaign_split_tests" (
+    "id"                integer primary key autoincrement,
+    "user_id"           integer NOT NULL,
+    "campaign_id"       integer NOT NULL,
+    "test_percentage"   integer NOT NULL,
+    "wait_minutes"      integer NOT NULL,
+    "winner_criteria"   varchar(191) NOT NULL,
+    "status"            varchar(191) NOT NULL,
+    "winner_variant_id" integer NOT NULL DEFAULT 0,
+    "params"            varchar,
+    "test_completed_at" datetime,
+    "created_at"        datetime,
+    "updated_at"        datetime,
+    UNIQUE("campaign_id"),
+    foreign key ("user_id") references users("id"),
+    foreign key ("campaign_id") references campaigns("id")
+);
+
+CREATE TABLE IF NOT EXISTS "campaign_variants" (
+    "id"            integer primary key autoincrement,
+    "user_id"       integer NOT NULL,
+    "split_test_id" integer NOT NULL,
+    "campaign_id"   integer NOT NULL,
+    "template_id"   integer NOT NULL,
+    "name"          varchar(191) NOT NULL,
+    "created_at"    datetime,
+    "updated_at"    datetime,
+    foreign key ("user_id") references users("id"),
+    foreign key ("split_test_id") references campaign_split_tests("id") ON DELETE CASCADE,
+    foreign key ("template_id") references templates("id")
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_campaign ON "campaign_variants" (campaign_id);
+
+ALTER TABLE "sends" ADD COLUMN "variant_id" integer NOT NULL DEFAULT 0;
+ALTER TABLE "deliveries" ADD COLUMN "variant_id" integer NOT NULL DEFAULT 0;
+ALTER TABLE "opens" ADD COLUMN "variant_id" integer NOT NULL DEFAULT 0;
+ALTER TABLE "clicks" ADD COLUMN "variant_id" integer NOT NULL DEFAULT 0;
+ALTER TABLE "bounces" ADD COLUMN "variant_id" integer NOT NULL DEFAULT 0;
+ALTER TABLE "complaints" ADD COLUMN "variant_id" integer NOT NULL DEFAULT 0;
+
+-- +migrate Down
+
+DROP TABLE "campaign_variants";
+DROP TABLE "campaign_split_tests";
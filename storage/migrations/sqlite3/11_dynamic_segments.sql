@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE "segments" ADD COLUMN "type" varchar(191) NOT NULL DEFAULT 'static';
+ALTER TABLE "segments" ADD COLUMN "rules" varchar;
+
+-- +migrate Down
+
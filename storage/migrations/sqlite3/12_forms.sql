@@ -0,0 +1,33 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "forms" (
+    "id"                       integer primary key autoincrement,
+    "user_id"                  integer NOT NULL,
+    "uuid"                     varchar(36) NOT NULL,
+    "name"                     varchar(191) NOT NULL,
+    "metadata_fields"          varchar,
+    "redirect_url"             varchar(191) NOT NULL DEFAULT '',
+    "confirm_redirect_url"     varchar(191) NOT NULL DEFAULT '',
+    "double_opt_in"            integer NOT NULL DEFAULT 0,
+    "confirmation_template_id" integer NOT NULL DEFAULT 0,
+    "source"                   varchar(191) NOT NULL DEFAULT '',
+    "from_name"                varchar(191) NOT NULL DEFAULT '',
+    "created_at"               datetime,
+    "updated_at"               datetime,
+    UNIQUE("uuid"),
+    UNIQUE("user_id", "name"),
+    foreign key ("user_id") references users("id")
+);
+
+CREATE TABLE IF NOT EXISTS "forms_segments" (
+    "form_id"    integer,
+    "segment_id" integer,
+    PRIMARY KEY ("form_id", "segment_id"),
+    FOREIGN KEY ("form_id") REFERENCES forms("id") ON DELETE CASCADE,
+    FOREIGN KEY ("segment_id") REFERENCES segments("id") ON DELETE CASCADE
+);
+
+-- +migrate Down
+
+DROP TABLE "forms_segments";
+DROP TABLE "forms";
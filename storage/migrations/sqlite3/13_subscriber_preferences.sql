@@ -0,0 +1,11 @@
+-- +migrate Up
+
+ALTER TABLE "segments" ADD COLUMN "public" integer NOT NULL DEFAULT 0;
+ALTER TABLE "subscribers" ADD COLUMN "paused_until" datetime;
+ALTER TABLE "subscriber_events" ADD COLUMN "segment_id" integer;
+ALTER TABLE "subscriber_metrics" ADD COLUMN "updated" integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE "subscriber_metrics" ADD COLUMN "opted_in" integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE "subscriber_metrics" ADD COLUMN "opted_out" integer unsigned NOT NULL DEFAULT 0;
+ALTER TABLE "subscriber_metrics" ADD COLUMN "paused" integer unsigned NOT NULL DEFAULT 0;
+
+-- +migrate Down
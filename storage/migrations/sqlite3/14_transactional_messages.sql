@@ -0,0 +1,25 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "transactional_messages" (
+    "id"          integer primary key autoincrement,
+    "user_id"     integer NOT NULL,
+    "event_id"    varchar(27) NOT NULL,
+    "template_id" integer NOT NULL,
+    "recipient"   varchar(191) NOT NULL,
+    "source"      varchar(191) NOT NULL,
+    "tags"        varchar,
+    "status"      varchar(50) NOT NULL,
+    "description" varchar(191) NOT NULL DEFAULT '',
+    "message_id"  varchar(191),
+    "opens"       integer NOT NULL DEFAULT 0,
+    "clicks"      integer NOT NULL DEFAULT 0,
+    "created_at"  datetime,
+    "updated_at"  datetime,
+    foreign key ("user_id") references users("id")
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactional_messages_user_id ON "transactional_messages" (user_id, created_at);
+
+-- +migrate Down
+
+DROP TABLE "transactional_messages";
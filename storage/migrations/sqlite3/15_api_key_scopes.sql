@@ -0,0 +1,15 @@
+-- +migrate Up
+
+ALTER TABLE "api_keys" ADD COLUMN "name" varchar(191) NOT NULL DEFAULT '';
+ALTER TABLE "api_keys" ADD COLUMN "key_prefix" varchar(20) NOT NULL DEFAULT '';
+ALTER TABLE "api_keys" ADD COLUMN "scopes" varchar(1000) NOT NULL DEFAULT '';
+ALTER TABLE "api_keys" ADD COLUMN "last_used_at" datetime;
+
+-- the existing keys are hashed and keep the full access they had,
+-- the sha2 function is provided by the sqlite driver.
+UPDATE "api_keys" SET
+    "key_prefix" = substr("secret_key", 1, 11),
+    "secret_key" = sha2("secret_key", 256),
+    "scopes" = '*';
+
+-- +migrate Down
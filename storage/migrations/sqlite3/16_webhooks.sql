@@ -0,0 +1,40 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id"         integer primary key autoincrement,
+    "user_id"    integer NOT NULL,
+    "url"        varchar(191) NOT NULL,
+    "secret"     varchar(191) NOT NULL,
+    "events"     varchar(1000) NOT NULL,
+    "active"     integer NOT NULL DEFAULT 1,
+    "created_at" datetime,
+    "updated_at" datetime,
+    foreign key ("user_id") references users("id")
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON "webhooks" (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id"              integer primary key autoincrement,
+    "user_id"         integer NOT NULL,
+    "webhook_id"      integer NOT NULL,
+    "event_id"        varchar(27) NOT NULL,
+    "event_type"      varchar(50) NOT NULL,
+    "payload"         varchar NOT NULL,
+    "status"          varchar(20) NOT NULL,
+    "attempts"        integer NOT NULL DEFAULT 0,
+    "response_status" integer NOT NULL DEFAULT 0,
+    "error_message"   text,
+    "next_attempt_at" datetime,
+    "created_at"      datetime,
+    "updated_at"      datetime,
+    foreign key ("user_id") references users("id"),
+    foreign key ("webhook_id") references webhooks("id") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON "webhook_deliveries" (webhook_id, created_at);
+
+-- +migrate Down
+
+DROP TABLE "webhook_deliveries";
+DROP TABLE "webhooks";
@@ -0,0 +1,14 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "processed_events" (
+    "id"         integer primary key autoincrement,
+    "event_key"  varchar(191) NOT NULL,
+    "created_at" datetime,
+    UNIQUE("event_key")
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_events_created_at ON "processed_events" (created_at);
+
+-- +migrate Down
+
+DROP TABLE "processed_events";
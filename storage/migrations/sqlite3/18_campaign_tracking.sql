@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE "campaigns" ADD COLUMN "track_opens" integer NOT NULL DEFAULT 0;
+ALTER TABLE "campaigns" ADD COLUMN "track_clicks" integer NOT NULL DEFAULT 0;
+ALTER TABLE "campaigns" ADD COLUMN "excluded_links" text;
+
+-- +migrate Down
@@ -0,0 +1,6 @@
+-- +migrate Up
+
+ALTER TABLE "reports" ADD COLUMN "format" varchar(10) NOT NULL DEFAULT 'csv';
+ALTER TABLE "reports" ADD COLUMN "campaign_id" integer;
+
+-- +migrate Down
@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE "reports" ADD COLUMN "gzip" integer NOT NULL DEFAULT 0;
+ALTER TABLE "reports" ADD COLUMN "rows_exported" integer NOT NULL DEFAULT 0;
+ALTER TABLE "reports" ADD COLUMN "total_rows" integer NOT NULL DEFAULT 0;
+
+-- +migrate Down
@@ -0,0 +1,25 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "jobs" (
+    "id"           integer primary key autoincrement,
+    "user_id"      integer NOT NULL,
+    "type"         varchar(50) NOT NULL,
+    "status"       varchar(20) NOT NULL,
+    "payload"      varchar NOT NULL,
+    "processed"    integer NOT NULL DEFAULT 0,
+    "total"        integer NOT NULL DEFAULT 0,
+    "attempts"     integer NOT NULL DEFAULT 0,
+    "max_attempts" integer NOT NULL DEFAULT 0,
+    "error"        text,
+    "started_at"   datetime,
+    "completed_at" datetime,
+    "created_at"   datetime,
+    "updated_at"   datetime,
+    foreign key ("user_id") references users("id")
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON "jobs" (user_id, created_at);
+
+-- +migrate Down
+
+DROP TABLE "jobs";
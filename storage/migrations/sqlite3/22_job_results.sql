@@ -0,0 +1,5 @@
+-- +migrate Up
+
+ALTER TABLE "jobs" ADD COLUMN "result" varchar;
+
+-- +migrate Down
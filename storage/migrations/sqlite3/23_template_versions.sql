@@ -0,0 +1,89 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "template_versions" (
+    "id"           integer PRIMARY KEY autoincrement,
+    "user_id"      integer NOT NULL,
+    "template_id"  integer NOT NULL,
+    "version"      integer NOT NULL,
+    "name"         varchar(191) NOT NULL,
+    "subject_part" varchar(191) NOT NULL,
+    "text_part"    text,
+    "created_at"   datetime,
+    "updated_at"   datetime,
+    FOREIGN KEY ("user_id") REFERENCES users("id"),
+    FOREIGN KEY ("template_id") REFERENCES templates("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_template_id_version ON "template_versions" (template_id, version);
+
+ALTER TABLE "templates" ADD COLUMN "version" integer NOT NULL DEFAULT 0;
+ALTER TABLE "campaigns" ADD COLUMN "template_version" integer NOT NULL DEFAULT 0;
+ALTER TABLE "campaign_variants" ADD COLUMN "template_version" integer NOT NULL DEFAULT 0;
+
+INSERT INTO "template_versions" ("user_id", "template_id", "version", "name", "subject_part", "text_part", "created_at", "updated_at")
+    SELECT "user_id", "id", 0, "name", "subject_part", "text_part", "updated_at", "updated_at" FROM "templates";
+
+-- +migrate Down
+
+DROP TABLE "template_versions";
+
+-- sqlite can't drop columns, the tables are rebuilt without the version columns.
+CREATE TABLE "templates_old" (
+    "id" integer PRIMARY KEY autoincrement,
+    "user_id" integer UNSIGNED NOT NULL,
+    "name" varchar(191) NOT NULL,
+    "subject_part" varchar(191) NOT NULL,
+    "text_part" text,
+    "created_at" datetime,
+    "updated_at" datetime,
+    FOREIGN KEY ("user_id") REFERENCES users("id")
+);
+INSERT INTO "templates_old" ("id", "user_id", "name", "subject_part", "text_part", "created_at", "updated_at")
+    SELECT "id", "user_id", "name", "subject_part", "text_part", "created_at", "updated_at" FROM "templates";
+DROP TABLE "templates";
+ALTER TABLE "templates_old" RENAME TO "templates";
+
+CREATE TABLE "campaigns_old" (
+    "id" integer PRIMARY KEY autoincrement,
+    "user_id" integer,
+    "name" varchar(191) NOT NULL,
+    "template_id" integer,
+    "event_id" varchar(27),
+    "status" varchar(191),
+    "created_at" datetime,
+    "updated_at" datetime,
+    "completed_at" datetime DEFAULT NULL,
+    "deleted_at" datetime DEFAULT NULL,
+    "started_at" datetime DEFAULT NULL,
+    "track_opens" integer NOT NULL DEFAULT 0,
+    "track_clicks" integer NOT NULL DEFAULT 0,
+    "excluded_links" text,
+    FOREIGN KEY ("user_id") REFERENCES users("id"),
+    FOREIGN KEY ("template_id") REFERENCES templates("id")
+);
+INSERT INTO "campaigns_old" ("id", "user_id", "name", "template_id", "event_id", "status", "created_at", "updated_at",
+    "completed_at", "deleted_at", "started_at", "track_opens", "track_clicks", "excluded_links")
+    SELECT "id", "user_id", "name", "template_id", "event_id", "status", "created_at", "updated_at",
+    "completed_at", "deleted_at", "started_at", "track_opens", "track_clicks", "excluded_links" FROM "campaigns";
+DROP TABLE "campaigns";
+ALTER TABLE "campaigns_old" RENAME TO "campaigns";
+CREATE INDEX IF NOT EXISTS idx_id_created_at ON "campaigns" (id, created_at);
+
+CREATE TABLE "campaign_variants_old" (
+    "id"            integer primary key autoincrement,
+    "user_id"       integer NOT NULL,
+    "split_test_id" integer NOT NULL,
+    "campaign_id"   integer NOT NULL,
+    "template_id"   integer NOT NULL,
+    "name"          varchar(191) NOT NULL,
+    "created_at"    datetime,
+    "updated_at"    datetime,
+    foreign key ("user_id") references users("id"),
+    foreign key ("split_test_id") references campaign_split_tests("id") ON DELETE CASCADE,
+    foreign key ("template_id") references templates("id")
+);
+INSERT INTO "campaign_variants_old" ("id", "user_id", "split_test_id", "campaign_id", "template_id", "name", "created_at", "updated_at")
+    SELECT "id", "user_id", "split_test_id", "campaign_id", "template_id", "name", "created_at", "updated_at" FROM "campaign_variants";
+DROP TABLE "campaign_variants";
+ALTER TABLE "campaign_variants_old" RENAME TO "campaign_variants";
+CREATE INDEX IF NOT EXISTS idx_variant_campaign ON "campaign_variants" (campaign_id);
@@ -0,0 +1,21 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "template_partials" (
+    "id"         integer PRIMARY KEY autoincrement,
+    "user_id"    integer NOT NULL,
+    "name"       varchar(191) NOT NULL,
+    "kind"       varchar(20) NOT NULL,
+    "content"    text NOT NULL,
+    "created_at" datetime,
+    "updated_at" datetime,
+    FOREIGN KEY ("user_id") REFERENCES users("id")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_template_partials_user_id_name ON "template_partials" (user_id, name);
+
+ALTER TABLE "templates" ADD COLUMN "layout_id" integer NOT NULL DEFAULT 0;
+ALTER TABLE "template_versions" ADD COLUMN "layout_id" integer NOT NULL DEFAULT 0;
+
+-- +migrate Down
+
+DROP TABLE "template_partials";
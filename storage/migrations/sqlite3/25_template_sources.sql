@@ -0,0 +1,11 @@
+-- +migrate Up
+
+ALTER TABLE "templates" ADD COLUMN "text_source" text;
+ALTER TABLE "templates" ADD COLUMN "inline_css" integer NOT NULL DEFAULT 0;
+ALTER TABLE "template_versions" ADD COLUMN "text_source" text;
+ALTER TABLE "template_versions" ADD COLUMN "inline_css" integer NOT NULL DEFAULT 0;
+
+UPDATE "templates" SET "text_source" = "text_part";
+UPDATE "template_versions" SET "text_source" = "text_part";
+
+-- +migrate Down
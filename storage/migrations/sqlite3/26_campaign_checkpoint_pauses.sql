@@ -0,0 +1,8 @@
+-- +migrate Up
+
+ALTER TABLE "campaign_checkpoints" ADD COLUMN "paused_at" datetime;
+
+UPDATE "campaign_checkpoints" SET "paused_at" = "updated_at"
+WHERE "campaign_id" IN (SELECT "id" FROM "campaigns" WHERE "status" = 'paused');
+
+-- +migrate Down
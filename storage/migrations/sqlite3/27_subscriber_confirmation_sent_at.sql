@@ -0,0 +1,5 @@
+-- +migrate Up
+
+ALTER TABLE "subscribers" ADD COLUMN "confirmation_sent_at" datetime;
+
+-- +migrate Down
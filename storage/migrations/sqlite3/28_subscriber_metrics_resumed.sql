@@ -0,0 +1,5 @@
+-- +migrate Up
+
+ALTER TABLE "subscriber_metrics" ADD COLUMN "resumed" integer unsigned NOT NULL DEFAULT 0;
+
+-- +migrate Down
@@ -0,0 +1,31 @@
+-- +migrate Up
+
+ALTER TABLE "template_versions" ADD COLUMN "partials" text;
+ALTER TABLE "template_versions" ADD COLUMN "layout" text;
+
+-- +migrate Down
+
+-- sqlite can't drop columns, the table is rebuilt without the snapshot columns.
+CREATE TABLE "template_versions_old" (
+    "id"           integer PRIMARY KEY autoincrement,
+    "user_id"      integer NOT NULL,
+    "template_id"  integer NOT NULL,
+    "version"      integer NOT NULL,
+    "name"         varchar(191) NOT NULL,
+    "subject_part" varchar(191) NOT NULL,
+    "text_part"    text,
+    "created_at"   datetime,
+    "updated_at"   datetime,
+    "layout_id"    integer NOT NULL DEFAULT 0,
+    "text_source"  text,
+    "inline_css"   integer NOT NULL DEFAULT 0,
+    FOREIGN KEY ("user_id") REFERENCES users("id"),
+    FOREIGN KEY ("template_id") REFERENCES templates("id") ON DELETE CASCADE
+);
+INSERT INTO "template_versions_old" ("id", "user_id", "template_id", "version", "name", "subject_part", "text_part",
+    "created_at", "updated_at", "layout_id", "text_source", "inline_css")
+    SELECT "id", "user_id", "template_id", "version", "name", "subject_part", "text_part",
+    "created_at", "updated_at", "layout_id", "text_source", "inline_css" FROM "template_versions";
+DROP TABLE "template_versions";
+ALTER TABLE "template_versions_old" RENAME TO "template_versions";
+CREATE UNIQUE INDEX IF NOT EXISTS idx_template_id_version ON "template_versions" (template_id, version);
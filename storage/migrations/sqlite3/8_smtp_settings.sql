@@ -0,0 +1,20 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "smtp_settings" (
+    "id"          integer primary key autoincrement,
+    "user_id"     integer NOT NULL,
+    "host"        varchar(191) NOT NULL,
+    "port"        integer NOT NULL,
+    "username"    varchar(191),
+    "password"    varchar(191),
+    "encryption"  varchar(20) NOT NULL,
+    "auth_method" varchar(20),
+    "created_at"  datetime,
+    "updated_at"  datetime,
+    UNIQUE("user_id"),
+    foreign key ("user_id") references users("id")
+);
+
+-- +migrate Down
+
+DROP TABLE "smtp_settings";
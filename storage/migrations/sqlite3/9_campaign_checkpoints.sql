@@ -0,0 +1,20 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS "campaign_checkpoints" (
+    "id"                 integer primary key autoincrement,
+    "user_id"            integer NOT NULL,
+    "campaign_id"        integer NOT NULL,
+    "event_id"           varchar(27) NOT NULL,
+    "params"             varchar NOT NULL,
+    "last_subscriber_id" integer NOT NULL,
+    "last_created_at"    datetime,
+    "created_at"         datetime,
+    "updated_at"         datetime,
+    UNIQUE("campaign_id"),
+    foreign key ("user_id") references users("id"),
+    foreign key ("campaign_id") references campaigns("id")
+);
+
+-- +migrate Down
+
+DROP TABLE "campaign_checkpoints";
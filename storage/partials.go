@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// GetPartials fetches the partials and layouts by user id, and populates the pagination obj
+func (db *store) GetPartials(userID int64, p *PaginationCursor, scopeMap map[string]string) error {
+	p.SetCollection(new([]entities.Partial))
+	p.SetResource("template_partials")
+
+	p.AddScope(BelongsToUser(userID))
+	val, ok := scopeMap["kind"]
+	if ok {
+		p.AddScope(PartialKind(val))
+	}
+
+	query := db.Table(p.Resource).
+		Order("created_at desc, id desc").
+		Limit(p.PerPage)
+
+	p.SetQuery(query)
+
+	return db.Paginate(p, userID)
+}
+
+// GetAllPartials returns all of the partials and layouts of the user, they are
+// resolved by the templates when they are parsed.
+func (db *store) GetAllPartials(userID int64) ([]entities.Partial, error) {
+	var partials []entities.Partial
+	err := db.Where("user_id = ?", userID).Find(&partials).Error
+	return partials, err
+}
+
+// GetPartial returns the partial by the given id and user id
+func (db *store) GetPartial(id, userID int64) (*entities.Partial, error) {
+	var partial = new(entities.Partial)
+	err := db.Where("user_id = ? and id = ?", userID, id).First(partial).Error
+	return partial, err
+}
+
+// GetPartialByName returns the partial by the given name and user id
+func (db *store) GetPartialByName(name string, userID int64) (*entities.Partial, error) {
+	var partial = new(entities.Partial)
+	err := db.Where("user_id = ? and name = ?", userID, name).First(partial).Error
+	return partial, err
+}
+
+// CreatePartial creates a new partial in the database.
+func (db *store) CreatePartial(p *entities.Partial) error {
+	return db.Create(p).Error
+}
+
+// UpdatePartial edits an existing partial in the database.
+func (db *store) UpdatePartial(p *entities.Partial) error {
+	return db.Where("user_id = ? and id = ?", p.UserID, p.ID).Save(p).Error
+}
+
+// DeletePartial deletes the partial by the given id and user id.
+func (db *store) DeletePartial(id, userID int64) error {
+	return db.Where("user_id = ? and id = ?", userID, id).Delete(&entities.Partial{}).Error
+}
+
+// CountLayoutUsages returns the number of template versions which are wrapped by the layout,
+// the current version of every template is among its versions.
+func (db *store) CountLayoutUsages(layoutID, userID int64) (int64, error) {
+	var count int64
+	err := db.Model(&entities.TemplateVersion{}).
+		Where("user_id = ? and layout_id = ?", userID, layoutID).
+		Count(&count).Error
+	return count, err
+}
+
+// CountPartialUsages returns the number of template versions which include the partial, directly or
+// through other partials. The names are the keys of the json objects which hold the partials of the
+// versions, the quotes of the content are escaped so only the keys follow a brace or a comma.
+func (db *store) CountPartialUsages(name string, userID int64) (int64, error) {
+	key := `"` + likeEscaper.Replace(name) + `":`
+	var count int64
+	err := db.Model(&entities.TemplateVersion{}).
+		Where("user_id = ? and (partials LIKE ? ESCAPE '!' or partials LIKE ? ESCAPE '!')", userID, "{"+key+"%", "%,"+key+"%").
+		Count(&count).Error
+	return count, err
+}
+
+// PartialKind scopes the partials by their kind.
+func PartialKind(kind string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("kind = ?", kind)
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestPartials(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	header := &entities.Partial{UserID: 1, Name: "header", Kind: entities.PartialKindPartial, Content: "<h1>News</h1>"}
+	assert.Nil(t, store.CreatePartial(header))
+	layout := &entities.Partial{UserID: 1, Name: "main", Kind: entities.PartialKindLayout, Content: "<body>{{> content}}</body>"}
+	assert.Nil(t, store.CreatePartial(layout))
+
+	// the names are unique per user
+	assert.NotNil(t, store.CreatePartial(&entities.Partial{UserID: 1, Name: "header", Kind: entities.PartialKindPartial, Content: "x"}))
+	assert.Nil(t, store.CreatePartial(&entities.Partial{UserID: 2, Name: "header", Kind: entities.PartialKindPartial, Content: "x"}))
+
+	p, err := store.GetPartial(header.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "<h1>News</h1>", p.Content)
+
+	_, err = store.GetPartial(header.ID, 2)
+	assert.NotNil(t, err)
+
+	p, err = store.GetPartialByName("main", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, layout.ID, p.ID)
+
+	header.Content = "<h1>Weekly news</h1>"
+	assert.Nil(t, store.UpdatePartial(header))
+
+	all, err := store.GetAllPartials(1)
+	assert.Nil(t, err)
+	assert.Len(t, all, 2)
+
+	pc := NewPaginationCursor("/api/partials", 10)
+	assert.Nil(t, store.GetPartials(1, pc, map[string]string{"kind": entities.PartialKindLayout}))
+	col := pc.Collection.(*[]entities.Partial)
+	assert.Len(t, *col, 1)
+	assert.Equal(t, "main", (*col)[0].Name)
+
+	count, err := store.CountLayoutUsages(layout.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	count, err = store.CountPartialUsages("header", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	template := &entities.Template{
+		BaseTemplate: entities.BaseTemplate{
+			UserID:      1,
+			Name:        "with-layout",
+			SubjectPart: "subject",
+			Version:     1,
+			LayoutID:    layout.ID,
+		},
+		TextPart: "text",
+		Partials: entities.StringMap{"footer": `<p>{"nav":</p>`, "header": "<h1>News</h1>"},
+		Layout:   layout.Content,
+	}
+	assert.Nil(t, store.CreateTemplate(template))
+
+	count, err = store.CountLayoutUsages(layout.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// the partials are matched by the keys of the snapshot, not by their content.
+	for name, expected := range map[string]int64{"header": 1, "footer": 1, "head": 0, "h_ader": 0, "nav": 0} {
+		count, err = store.CountPartialUsages(name, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, count, name)
+	}
+	count, err = store.CountPartialUsages("header", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	v, err := store.GetTemplateVersion(template.ID, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, template.Partials, v.Partials)
+	assert.Equal(t, layout.Content, v.Layout)
+
+	assert.Nil(t, store.DeletePartial(header.ID, 1))
+	_, err = store.GetPartial(header.ID, 1)
+	assert.NotNil(t, err)
+}
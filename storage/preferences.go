@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/now"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// UpdateSubscriberPreferences saves the changes the subscriber made in the preference center,
+// adds a subscriber event for each change and increments the subscriber metrics.
+func (db *store) UpdateSubscriberPreferences(s *entities.Subscriber, changes *entities.PreferenceChanges) error {
+	if changes.IsEmpty() {
+		return nil
+	}
+
+	tx := db.Begin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Model(&entities.Subscriber{}).
+		Where("user_id = ? AND id = ?", s.UserID, s.ID).
+		Updates(map[string]interface{}{
+			"name":         s.Name,
+			"metadata":     s.MetaJSON,
+			"paused_until": s.PausedUntil,
+		}).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: update preferences: %w", err)
+	}
+
+	sub := *s
+	sub.Segments = nil
+
+	var events []entities.SubscriberEvent
+	for i := range changes.OptIn {
+		seg := changes.OptIn[i]
+		if err := tx.Model(&seg).Association("Subscribers").Append([]entities.Subscriber{sub}); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("subscription store: opt in segment: %w", err)
+		}
+		events = append(events, entities.SubscriberEvent{EventType: entities.SubscriberEventTypeOptedIn, SegmentID: &seg.ID})
+	}
+	for i := range changes.OptOut {
+		seg := changes.OptOut[i]
+		if err := tx.Model(&seg).Association("Subscribers").Delete([]entities.Subscriber{sub}); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("subscription store: opt out segment: %w", err)
+		}
+		events = append(events, entities.SubscriberEvent{EventType: entities.SubscriberEventTypeOptedOut, SegmentID: &seg.ID})
+	}
+
+	metrics := &entities.SubscriberMetrics{
+		UserID:   s.UserID,
+		OptedIn:  int64(len(changes.OptIn)),
+		OptedOut: int64(len(changes.OptOut)),
+		Datetime: now.BeginningOfHour(),
+	}
+	assignments := map[string]interface{}{
+		"opted_in":  gorm.Expr("opted_in + ?", metrics.OptedIn),
+		"opted_out": gorm.Expr("opted_out + ?", metrics.OptedOut),
+	}
+	if changes.Updated {
+		metrics.Updated = 1
+		assignments["updated"] = gorm.Expr("updated + 1")
+		events = append(events, entities.SubscriberEvent{EventType: entities.SubscriberEventTypeUpdated})
+	}
+	if changes.Paused {
+		metrics.Paused = 1
+		assignments["paused"] = gorm.Expr("paused + 1")
+		events = append(events, entities.SubscriberEvent{EventType: entities.SubscriberEventTypePaused})
+	}
+	if changes.Resumed {
+		metrics.Resumed = 1
+		assignments["resumed"] = gorm.Expr("resumed + 1")
+		events = append(events, entities.SubscriberEvent{EventType: entities.SubscriberEventTypeResumed})
+	}
+
+	for i := range events {
+		events[i].UserID = s.UserID
+		events[i].SubscriberID = s.ID
+	}
+	if err := tx.Create(&events).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: add subscriber events (preferences): %w", err)
+	}
+
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "datetime"}},
+		DoUpdates: clause.Assignments(assignments),
+	}).Create(metrics).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: add subscriber metric (preferences): %w", err)
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestUpdateSubscriberPreferences(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	public := &entities.Segment{Name: "news", UserID: 1, Public: true}
+	assert.Nil(t, store.CreateSegment(public))
+	private := &entities.Segment{Name: "internal", UserID: 1}
+	assert.Nil(t, store.CreateSegment(private))
+	dynamic := &entities.Segment{Name: "pro", UserID: 1, Type: entities.SegmentTypeDynamic, Public: true}
+	assert.Nil(t, store.CreateSegment(dynamic))
+
+	segs, err := store.GetPublicSegments(1)
+	assert.Nil(t, err)
+	assert.Len(t, segs, 1)
+	assert.Equal(t, public.ID, segs[0].ID)
+
+	s := &entities.Subscriber{UserID: 1, Name: "john", Email: "john@example.com", Active: true, Segments: []entities.Segment{*private}}
+	assert.Nil(t, store.CreateSubscriber(s))
+
+	// no changes, nothing is saved
+	assert.Nil(t, store.UpdateSubscriberPreferences(s, &entities.PreferenceChanges{}))
+
+	paused := time.Now().Add(24 * time.Hour)
+	s.PausedUntil = &paused
+	err = store.UpdateSubscriberPreferences(s, &entities.PreferenceChanges{
+		OptIn:  []entities.Segment{*public},
+		Paused: true,
+	})
+	assert.Nil(t, err)
+
+	s, err = store.GetSubscriber(s.ID, 1)
+	assert.Nil(t, err)
+	assert.True(t, s.IsPaused(time.Now()))
+	assert.Len(t, s.Segments, 2)
+
+	// paused subscribers are not sent to
+	var timestamp time.Time
+	subs, err := store.GetDistinctSubscribersBySegmentIDs([]int64{public.ID, private.ID}, 1, false, true, timestamp, 0, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, subs)
+
+	s.PausedUntil = nil
+	err = store.UpdateSubscriberPreferences(s, &entities.PreferenceChanges{
+		OptOut:  []entities.Segment{*public},
+		Resumed: true,
+	})
+	assert.Nil(t, err)
+
+	subs, err = store.GetDistinctSubscribersBySegmentIDs([]int64{public.ID, private.ID}, 1, false, true, timestamp, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, subs, 1)
+
+	total, err := store.GetTotalSubscribersBySegment(public.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), total)
+
+	var optedOut int64
+	err = db.Model(&entities.SubscriberEvent{}).
+		Where("subscriber_id = ? AND event_type = ? AND segment_id = ?", s.ID, entities.SubscriberEventTypeOptedOut, public.ID).
+		Count(&optedOut).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), optedOut)
+}
@@ -31,3 +31,18 @@ func (rs *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
 func (rs *RedisStore) Expire(ctx context.Context, key string, duration time.Duration) error {
 	return rs.client.WithContext(ctx).Expire(key, duration).Err()
 }
+
+func (rs *RedisStore) Eval(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	return script.script.Run(rs.client.WithContext(ctx), keys, args...).Result()
+}
+
+// Script is a lua script whose hash is computed once, it's run by its hash
+// and loaded into redis when it's missing from the script cache.
+type Script struct {
+	script *redis.Script
+}
+
+// NewScript returns the script of the given lua source.
+func NewScript(src string) *Script {
+	return &Script{script: redis.NewScript(src)}
+}
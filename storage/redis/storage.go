@@ -16,6 +16,8 @@ type Store interface {
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 	Expire(ctx context.Context, key string, seconds time.Duration) error
+	// Eval runs the lua script atomically with the given keys and arguments.
+	Eval(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error)
 }
 
 func NewStoreFrom(conf config.Config) (*RedisStore, error) {
@@ -55,6 +55,13 @@ func TestReport(t *testing.T) {
 	report, err = store.GetReportByFilename("subv1", 1)
 	assert.Nil(t, err)
 
+	byID, err := store.GetReport(report.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, report.FileName, byID.FileName)
+
+	_, err = store.GetReport(report.ID, 2)
+	assert.NotNil(t, err)
+
 	assert.Equal(t, reports[0].FileName, report.FileName)
 	assert.Equal(t, reports[0].Resource, report.Resource)
 
@@ -73,6 +80,21 @@ func TestReport(t *testing.T) {
 	assert.Equal(t, updatedReport.Status, upReport.Status)
 	assert.Equal(t, updatedReport.Note, upReport.Note)
 
+	// test update report progress
+	upReport.RowsExported = 500
+	upReport.TotalRows = 2000
+	upReport.Status = "done"
+	err = store.UpdateReportProgress(upReport)
+	assert.Nil(t, err)
+
+	upReport, err = store.GetReportByFilename("subv2", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(500), upReport.RowsExported)
+	assert.Equal(t, int64(2000), upReport.TotalRows)
+	assert.Equal(t, int64(25), upReport.Progress())
+	// only the progress is updated.
+	assert.Equal(t, "failed", upReport.Status)
+
 	numOfRep, err := store.GetNumberOfReportsForDate(1, now)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(2), numOfRep)
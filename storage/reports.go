@@ -16,6 +16,13 @@ func (db *store) UpdateReport(r *entities.Report) error {
 	return db.Where("user_id = ?", r.UserID).Save(r).Error
 }
 
+// GetReport returns the report by the given id and user id
+func (db *store) GetReport(id, userID int64) (*entities.Report, error) {
+	var report = new(entities.Report)
+	err := db.Where("user_id = ? and id = ?", userID, id).First(report).Error
+	return report, err
+}
+
 // GetReportByFilename returns the report by the given file name and user id
 func (db *store) GetReportByFilename(filename string, userID int64) (*entities.Report, error) {
 	var report = new(entities.Report)
@@ -35,3 +42,13 @@ func (db *store) GetNumberOfReportsForDate(userID int64, time time.Time) (int64,
 	err := db.Model(entities.Report{}).Where("user_id = ? and DATE(created_at) = DATE(?)", userID, time).Count(&count).Error
 	return count, err
 }
+
+// UpdateReportProgress updates the number of exported and total rows of the report.
+func (db *store) UpdateReportProgress(r *entities.Report) error {
+	return db.Model(r).
+		Where("user_id = ?", r.UserID).
+		UpdateColumns(map[string]interface{}{
+			"rows_exported": r.RowsExported,
+			"total_rows":    r.TotalRows,
+		}).Error
+}
@@ -29,6 +29,11 @@ func (m *MockS3Client) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput,
 func (m *MockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
 	args := m.Called(input)
 
+	// the body is not serializable, the output is returned as is.
+	if out, ok := args.Get(0).(*s3.GetObjectOutput); ok && out != nil && out.Body != nil {
+		return out, args.Error(1)
+	}
+
 	var obj s3.GetObjectOutput
 	objBytes, _ := json.Marshal(args.Get(0))
 
@@ -49,3 +54,63 @@ func (m *MockS3Client) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObje
 
 	return &obj, args.Error(1)
 }
+
+func (m *MockS3Client) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	args := m.Called(input)
+
+	var obj s3.DeleteObjectsOutput
+	objBytes, _ := json.Marshal(args.Get(0))
+
+	// nolint:errcheck
+	json.Unmarshal(objBytes, &obj)
+
+	return &obj, args.Error(1)
+}
+
+func (m *MockS3Client) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	args := m.Called(input)
+
+	var obj s3.CreateMultipartUploadOutput
+	objBytes, _ := json.Marshal(args.Get(0))
+
+	// nolint:errcheck
+	json.Unmarshal(objBytes, &obj)
+
+	return &obj, args.Error(1)
+}
+
+func (m *MockS3Client) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	args := m.Called(input)
+
+	var obj s3.UploadPartOutput
+	objBytes, _ := json.Marshal(args.Get(0))
+
+	// nolint:errcheck
+	json.Unmarshal(objBytes, &obj)
+
+	return &obj, args.Error(1)
+}
+
+func (m *MockS3Client) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	args := m.Called(input)
+
+	var obj s3.CompleteMultipartUploadOutput
+	objBytes, _ := json.Marshal(args.Get(0))
+
+	// nolint:errcheck
+	json.Unmarshal(objBytes, &obj)
+
+	return &obj, args.Error(1)
+}
+
+func (m *MockS3Client) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	args := m.Called(input)
+
+	var obj s3.AbortMultipartUploadOutput
+	objBytes, _ := json.Marshal(args.Get(0))
+
+	// nolint:errcheck
+	json.Unmarshal(objBytes, &obj)
+
+	return &obj, args.Error(1)
+}
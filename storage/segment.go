@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/mailbadger/app/entities"
 )
 
@@ -22,7 +25,75 @@ func (db *store) GetSegments(userID int64, p *PaginationCursor) error {
 
 	p.SetQuery(query)
 
-	return db.Paginate(p, userID)
+	err := db.Paginate(p, userID)
+	if err != nil {
+		return err
+	}
+
+	// the subquery counts only the subscribers of static segments.
+	segs, ok := p.Collection.(*[]entities.SegmentWithTotalSubs)
+	if !ok {
+		return nil
+	}
+
+	err = db.countDynamicSegments(*segs, userID)
+	if err != nil {
+		return fmt.Errorf("store: count dynamic segment subscribers: %w", err)
+	}
+
+	return nil
+}
+
+// countDynamicSegments counts the subscribers of the dynamic segments in a single query,
+// every segment's rule is compiled to a conditional sum over the subscribers of the user.
+func (db *store) countDynamicSegments(segs []entities.SegmentWithTotalSubs, userID int64) error {
+	var (
+		sums []string
+		args []interface{}
+		idx  []int
+	)
+	for i := range segs {
+		if !segs[i].IsDynamic() {
+			continue
+		}
+
+		rule, err := segs[i].GetRules()
+		if err != nil {
+			return fmt.Errorf("segment %d: unmarshal rules: %w", segs[i].ID, err)
+		}
+		cond, condArgs, err := compileRule(*rule, db.Dialector.Name())
+		if err != nil {
+			return fmt.Errorf("segment %d: %w", segs[i].ID, err)
+		}
+
+		sums = append(sums, "COALESCE(SUM(CASE WHEN "+cond+" THEN 1 ELSE 0 END), 0)")
+		args = append(args, condArgs...)
+		idx = append(idx, i)
+	}
+	if len(idx) == 0 {
+		return nil
+	}
+
+	counts := make([]int64, len(idx))
+	dest := make([]interface{}, len(idx))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+
+	err := db.Model(&entities.Subscriber{}).
+		Select(strings.Join(sums, ", "), args...).
+		Scopes(BelongsToUser(userID)).
+		Row().
+		Scan(dest...)
+	if err != nil {
+		return err
+	}
+
+	for i, n := range counts {
+		segs[idx[i]].SubscribersInSeg = n
+	}
+
+	return nil
 }
 
 // GetTotalSegments fetches the total count by user id
@@ -41,6 +112,18 @@ func (db *store) GetSegmentsByIDs(userID int64, ids []int64) ([]entities.Segment
 	return lists, err
 }
 
+// GetPublicSegments fetches the public segments by user id, the subscribers
+// can opt in or out of these segments from the preference center.
+func (db *store) GetPublicSegments(userID int64) ([]entities.Segment, error) {
+	var segs []entities.Segment
+
+	err := db.Where("user_id = ? AND public = ? AND type = ?", userID, true, entities.SegmentTypeStatic).
+		Order("name").
+		Find(&segs).Error
+
+	return segs, err
+}
+
 // GetSegment returns the list by the given id and user id
 func (db *store) GetSegment(id, userID int64) (*entities.Segment, error) {
 	var seg = new(entities.Segment)
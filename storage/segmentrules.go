@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// likeEscaper escapes the LIKE wildcards, '!' is used as the escape
+// character because backslashes are handled differently by each dialect.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// InSegments is a query scope that finds all subscribers in any of the given segments.
+// Static segments are matched through the subscribers_segments table, and the rules of
+// dynamic segments are compiled to conditions on the subscribers table.
+func InSegments(segs []entities.Segment) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		var (
+			conds     []string
+			args      []interface{}
+			staticIDs []int64
+		)
+
+		for _, seg := range segs {
+			if !seg.IsDynamic() {
+				staticIDs = append(staticIDs, seg.ID)
+				continue
+			}
+
+			rule, err := seg.GetRules()
+			if err != nil {
+				_ = db.AddError(fmt.Errorf("segment %d: unmarshal rules: %w", seg.ID, err))
+				return db
+			}
+
+			cond, condArgs, err := compileRule(*rule, db.Dialector.Name())
+			if err != nil {
+				_ = db.AddError(fmt.Errorf("segment %d: %w", seg.ID, err))
+				return db
+			}
+			conds = append(conds, cond)
+			args = append(args, condArgs...)
+		}
+
+		if len(staticIDs) > 0 {
+			conds = append(conds, "subscribers.id IN (SELECT subscriber_id FROM subscribers_segments WHERE segment_id IN (?))")
+			args = append(args, staticIDs)
+		}
+
+		if len(conds) == 0 {
+			return db.Where("1 = 0")
+		}
+
+		return db.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+}
+
+// compileRule compiles the rule tree to an SQL condition on the subscribers table.
+func compileRule(r entities.SegmentRule, dialect string) (string, []interface{}, error) {
+	if r.IsGroup() {
+		sep := " AND "
+		if r.Match == entities.RuleMatchAny {
+			sep = " OR "
+		}
+
+		var (
+			conds []string
+			args  []interface{}
+		)
+		for _, child := range r.Rules {
+			cond, condArgs, err := compileRule(child, dialect)
+			if err != nil {
+				return "", nil, err
+			}
+			conds = append(conds, cond)
+			args = append(args, condArgs...)
+		}
+		if len(conds) == 0 {
+			return "", nil, fmt.Errorf("%w: empty group", entities.ErrInvalidSegmentRule)
+		}
+
+		return "(" + strings.Join(conds, sep) + ")", args, nil
+	}
+
+	switch r.Field {
+	case entities.RuleFieldEmail, entities.RuleFieldName:
+		col := "subscribers." + r.Field
+		switch r.Operator {
+		case entities.RuleOpEquals:
+			return col + " = ?", []interface{}{r.Value}, nil
+		case entities.RuleOpNotEquals:
+			return col + " <> ?", []interface{}{r.Value}, nil
+		case entities.RuleOpContains:
+			return col + " LIKE ? ESCAPE '!'", []interface{}{"%" + likeEscaper.Replace(r.Value) + "%"}, nil
+		}
+	case entities.RuleFieldMetadata:
+		return compileMetadataRule(r, dialect)
+	case entities.RuleFieldCreatedAt:
+		from, err := entities.ParseRuleTime(r.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: invalid date '%s'", entities.ErrInvalidSegmentRule, r.Value)
+		}
+		switch r.Operator {
+		case entities.RuleOpBefore:
+			return "subscribers.created_at < ?", []interface{}{from}, nil
+		case entities.RuleOpAfter:
+			return "subscribers.created_at > ?", []interface{}{from}, nil
+		case entities.RuleOpBetween:
+			to, err := entities.ParseRuleTime(r.To)
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: invalid date '%s'", entities.ErrInvalidSegmentRule, r.To)
+			}
+			return "subscribers.created_at BETWEEN ? AND ?", []interface{}{from, to}, nil
+		}
+	case entities.RuleFieldActive, entities.RuleFieldBlacklisted:
+		v, err := strconv.ParseBool(r.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: invalid boolean '%s'", entities.ErrInvalidSegmentRule, r.Value)
+		}
+		return "subscribers." + r.Field + " = ?", []interface{}{v}, nil
+	case entities.RuleFieldOpenedCampaign:
+		id, err := strconv.ParseInt(r.Value, 10, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: invalid campaign id '%s'", entities.ErrInvalidSegmentRule, r.Value)
+		}
+		return eventExists(r.Operator, `SELECT 1 FROM opens WHERE opens.user_id = subscribers.user_id
+			AND opens.recipient = subscribers.email AND opens.campaign_id = ?`, id)
+	case entities.RuleFieldClickedLink:
+		return eventExists(r.Operator, `SELECT 1 FROM clicks WHERE clicks.user_id = subscribers.user_id
+			AND clicks.recipient = subscribers.email AND clicks.link = ?`, r.Value)
+	}
+
+	return "", nil, fmt.Errorf("%w: operator '%s' is not supported for field '%s'", entities.ErrInvalidSegmentRule, r.Operator, r.Field)
+}
+
+func eventExists(op, subquery string, arg interface{}) (string, []interface{}, error) {
+	switch op {
+	case entities.RuleOpExists:
+		return "EXISTS (" + subquery + ")", []interface{}{arg}, nil
+	case entities.RuleOpNotExists:
+		return "NOT EXISTS (" + subquery + ")", []interface{}{arg}, nil
+	}
+	return "", nil, fmt.Errorf("%w: unsupported operator '%s'", entities.ErrInvalidSegmentRule, op)
+}
+
+// compileMetadataRule compiles a condition on a metadata key. The sqlite driver provides
+// the mb_json_extract and mb_json_type functions, see sqliteDriverName.
+func compileMetadataRule(r entities.SegmentRule, dialect string) (string, []interface{}, error) {
+	path := `$."` + r.Key + `"`
+	val := "CAST(mb_json_extract(subscribers.metadata, ?) AS TEXT)"
+	exists := "mb_json_type(subscribers.metadata, ?)"
+	if dialect == "mysql" {
+		val = "JSON_UNQUOTE(JSON_EXTRACT(subscribers.metadata, ?))"
+		exists = "JSON_EXTRACT(subscribers.metadata, ?)"
+	}
+
+	switch r.Operator {
+	case entities.RuleOpEquals:
+		return val + " = ?", []interface{}{path, r.Value}, nil
+	case entities.RuleOpNotEquals:
+		return "(" + val + " IS NULL OR " + val + " <> ?)", []interface{}{path, path, r.Value}, nil
+	case entities.RuleOpContains:
+		return val + " LIKE ? ESCAPE '!'", []interface{}{path, "%" + likeEscaper.Replace(r.Value) + "%"}, nil
+	case entities.RuleOpExists:
+		return exists + " IS NOT NULL", []interface{}{path}, nil
+	case entities.RuleOpNotExists:
+		return exists + " IS NULL", []interface{}{path}, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: unsupported operator '%s'", entities.ErrInvalidSegmentRule, r.Operator)
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestDynamicSegments(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	static := &entities.Segment{Name: "static", UserID: 1}
+	err := store.CreateSegment(static)
+	assert.Nil(t, err)
+
+	subs := []*entities.Subscriber{
+		{UserID: 1, Name: "john", Email: "john@example.com", MetaJSON: []byte(`{"plan":"pro","city":"Skopje"}`), Active: true},
+		{UserID: 1, Name: "jane", Email: "jane@example.org", MetaJSON: []byte(`{"plan": "free"}`), Active: true},
+		{UserID: 1, Name: "bob", Email: "bob_1@example.com", Active: false},
+		// the values of the other keys and the nested keys are not matched.
+		{UserID: 1, Name: "ann", Email: "ann@example.net", MetaJSON: []byte(`{"city":"Ohrid","hometown":"Skopje","age":30,"extra":{"plan":"pro"}}`), Active: true},
+		{UserID: 2, Name: "other", Email: "other@example.com", MetaJSON: []byte(`{"plan":"pro"}`), Active: true},
+	}
+	for _, s := range subs {
+		err = store.CreateSubscriber(s)
+		assert.Nil(t, err)
+	}
+
+	err = store.AppendSubscribers(&entities.Segment{
+		Model:       static.Model,
+		UserID:      1,
+		Subscribers: []entities.Subscriber{*subs[2]},
+	})
+	assert.Nil(t, err)
+
+	err = store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 7, Recipient: "jane@example.org"})
+	assert.Nil(t, err)
+	err = store.CreateClick(&entities.Click{UserID: 1, CampaignID: 7, Recipient: "john@example.com", Link: "https://example.com"})
+	assert.Nil(t, err)
+
+	tomorrow := time.Now().Add(24 * time.Hour).Format("2006-01-02")
+
+	cases := []struct {
+		name   string
+		rule   entities.SegmentRule
+		emails []string
+	}{
+		{
+			name:   "metadata equals",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "plan", Operator: entities.RuleOpEquals, Value: "pro"},
+			emails: []string{"john@example.com"},
+		},
+		{
+			name:   "metadata equals with spaces",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "plan", Operator: entities.RuleOpEquals, Value: "free"},
+			emails: []string{"jane@example.org"},
+		},
+		{
+			name:   "metadata not equals",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "plan", Operator: entities.RuleOpNotEquals, Value: "pro"},
+			emails: []string{"jane@example.org", "bob_1@example.com", "ann@example.net"},
+		},
+		{
+			name:   "metadata contains",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "city", Operator: entities.RuleOpContains, Value: "kop"},
+			emails: []string{"john@example.com"},
+		},
+		{
+			name:   "metadata equals number",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "age", Operator: entities.RuleOpEquals, Value: "30"},
+			emails: []string{"ann@example.net"},
+		},
+		{
+			name:   "metadata exists",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "plan", Operator: entities.RuleOpExists},
+			emails: []string{"john@example.com", "jane@example.org"},
+		},
+		{
+			name:   "metadata not exists",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "city", Operator: entities.RuleOpNotExists},
+			emails: []string{"jane@example.org", "bob_1@example.com"},
+		},
+		{
+			name:   "email contains escapes wildcards",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldEmail, Operator: entities.RuleOpContains, Value: "_1"},
+			emails: []string{"bob_1@example.com"},
+		},
+		{
+			name:   "inactive",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldActive, Operator: entities.RuleOpEquals, Value: "false"},
+			emails: []string{"bob_1@example.com"},
+		},
+		{
+			name:   "created before",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldCreatedAt, Operator: entities.RuleOpBefore, Value: tomorrow},
+			emails: []string{"john@example.com", "jane@example.org", "bob_1@example.com", "ann@example.net"},
+		},
+		{
+			name:   "created after",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldCreatedAt, Operator: entities.RuleOpAfter, Value: tomorrow},
+			emails: nil,
+		},
+		{
+			name:   "opened campaign",
+			rule:   entities.SegmentRule{Field: entities.RuleFieldOpenedCampaign, Operator: entities.RuleOpExists, Value: "7"},
+			emails: []string{"jane@example.org"},
+		},
+		{
+			name: "clicked link or name equals",
+			rule: entities.SegmentRule{
+				Match: entities.RuleMatchAny,
+				Rules: []entities.SegmentRule{
+					{Field: entities.RuleFieldClickedLink, Operator: entities.RuleOpExists, Value: "https://example.com"},
+					{Field: entities.RuleFieldName, Operator: entities.RuleOpEquals, Value: "bob"},
+				},
+			},
+			emails: []string{"john@example.com", "bob_1@example.com"},
+		},
+		{
+			name: "not opened and active",
+			rule: entities.SegmentRule{
+				Match: entities.RuleMatchAll,
+				Rules: []entities.SegmentRule{
+					{Field: entities.RuleFieldOpenedCampaign, Operator: entities.RuleOpNotExists, Value: "7"},
+					{Field: entities.RuleFieldActive, Operator: entities.RuleOpEquals, Value: "true"},
+				},
+			},
+			emails: []string{"john@example.com", "ann@example.net"},
+		},
+	}
+
+	counts := make(map[string]int64)
+	for _, tc := range cases {
+		counts[tc.name] = int64(len(tc.emails))
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Nil(t, tc.rule.Validate())
+
+			seg := &entities.Segment{Name: tc.name, UserID: 1, Type: entities.SegmentTypeDynamic}
+			assert.Nil(t, seg.SetRules(tc.rule))
+			assert.Nil(t, store.CreateSegment(seg))
+
+			var found []entities.Subscriber
+			err := db.Table("subscribers").
+				Scopes(BelongsToUser(1), InSegments([]entities.Segment{*seg})).
+				Order("id").
+				Find(&found).Error
+			assert.Nil(t, err)
+
+			var emails []string
+			for _, s := range found {
+				emails = append(emails, s.Email)
+			}
+			assert.ElementsMatch(t, tc.emails, emails)
+
+			total, err := store.GetTotalSubscribersBySegment(seg.ID, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(len(tc.emails)), total)
+		})
+	}
+
+	// the subscribers of the dynamic segments are counted along with the segments
+	p := NewPaginationCursor("/api/segments", 50)
+	err = store.GetSegments(1, p)
+	assert.Nil(t, err)
+	segs, ok := p.Collection.(*[]entities.SegmentWithTotalSubs)
+	assert.True(t, ok)
+	assert.Len(t, *segs, len(cases)+1)
+	for _, seg := range *segs {
+		if seg.IsDynamic() {
+			assert.Equal(t, counts[seg.Name], seg.SubscribersInSeg, seg.Name)
+		} else {
+			assert.Equal(t, int64(1), seg.SubscribersInSeg, seg.Name)
+		}
+	}
+
+	// static and dynamic segments are combined without duplicates
+	pro := &entities.Segment{Name: "pro", UserID: 1, Type: entities.SegmentTypeDynamic}
+	assert.Nil(t, pro.SetRules(entities.SegmentRule{Field: entities.RuleFieldMetadata, Key: "plan", Operator: entities.RuleOpExists}))
+	assert.Nil(t, store.CreateSegment(pro))
+
+	var timestamp time.Time
+	found, err := store.GetDistinctSubscribersBySegmentIDs([]int64{static.ID, pro.ID}, 1, false, true, timestamp, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+
+	found, err = store.GetDistinctSubscribersBySegmentIDs([]int64{static.ID, pro.ID}, 1, false, false, timestamp, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "bob_1@example.com", found[0].Email)
+
+	found, err = store.GetDistinctSubscribersBySegmentIDs([]int64{pro.ID}, 2, false, true, timestamp, 0, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, found, "segments of other users are ignored")
+}
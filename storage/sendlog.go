@@ -26,3 +26,14 @@ func (db *store) GetSendLogByUUID(id string) (*entities.SendLog, error) {
 	err := db.Where("id = ?", id).First(log).Error
 	return log, err
 }
+
+// GetSentSubscriberIDs returns the IDs of the given subscribers who were successfully sent the campaign.
+func (db *store) GetSentSubscriberIDs(campaignID, userID int64, subscriberIDs []int64) ([]int64, error) {
+	var ids []int64
+	err := db.Model(&entities.SendLog{}).
+		Where("user_id = ? AND campaign_id = ? AND status = ?", userID, campaignID, entities.SendLogStatusSuccessful).
+		Where("subscriber_id IN (?)", subscriberIDs).
+		Distinct().
+		Pluck("subscriber_id", &ids).Error
+	return ids, err
+}
@@ -60,4 +60,23 @@ func TestSendLogs(t *testing.T) {
 	n, err := store.CountLogsByStatus(entities.SendLogStatusFailed)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(2), n)
+
+	err = store.CreateSendLog(&entities.SendLog{
+		ID:           id,
+		UserID:       1,
+		EventID:      ksuid.New(),
+		SubscriberID: 3,
+		CampaignID:   1,
+		Status:       entities.SendLogStatusSuccessful,
+		CreatedAt:    now,
+	})
+	assert.Nil(t, err)
+
+	ids, err := store.GetSentSubscriberIDs(1, 1, []int64{1, 2, 3, 4})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{3}, ids)
+
+	ids, err = store.GetSentSubscriberIDs(2, 1, []int64{1, 2, 3, 4})
+	assert.Nil(t, err)
+	assert.Empty(t, ids)
 }
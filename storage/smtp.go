@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// GetSMTPSettings returns the SMTP settings by the given user id
+func (db *store) GetSMTPSettings(userID int64) (*entities.SMTPSettings, error) {
+	var s = new(entities.SMTPSettings)
+	err := db.Where("user_id = ?", userID).First(s).Error
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// HasSMTPSettings reports whether the user delivers through SMTP, the errors
+// other than the missing settings are returned.
+func (db *store) HasSMTPSettings(userID int64) (bool, error) {
+	_, err := db.GetSMTPSettings(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateSMTPSettings adds new SMTP settings in the database.
+func (db *store) CreateSMTPSettings(s *entities.SMTPSettings) error {
+	return db.Create(s).Error
+}
+
+// DeleteSMTPSettings deletes the SMTP settings by the given user id.
+func (db *store) DeleteSMTPSettings(userID int64) error {
+	return db.Where("user_id = ?", userID).Delete(&entities.SMTPSettings{}).Error
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestSMTPSettings(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	_, err := store.GetSMTPSettings(1)
+	assert.NotNil(t, err)
+
+	settings := &entities.SMTPSettings{
+		UserID:     1,
+		Host:       "smtp.example.com",
+		Port:       587,
+		Username:   "john",
+		Password:   "hunter1",
+		Encryption: "starttls",
+		AuthMethod: "login",
+	}
+
+	err = store.CreateSMTPSettings(settings)
+	assert.Nil(t, err)
+
+	settings, err = store.GetSMTPSettings(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "smtp.example.com", settings.Host)
+	assert.Equal(t, 587, settings.Port)
+	assert.Equal(t, "john", settings.Username)
+	assert.Equal(t, "hunter1", settings.Password)
+	assert.Equal(t, "starttls", settings.Encryption)
+	assert.Equal(t, "login", settings.AuthMethod)
+
+	err = store.DeleteSMTPSettings(1)
+	assert.Nil(t, err)
+
+	settings, err = store.GetSMTPSettings(1)
+	assert.NotNil(t, err)
+	assert.Nil(t, settings)
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// GetCampaignSplitTest returns the split test with its variants by the given campaign id and user id.
+func (db *store) GetCampaignSplitTest(campaignID, userID int64) (*entities.CampaignSplitTest, error) {
+	var st = new(entities.CampaignSplitTest)
+	err := db.Where("user_id = ? and campaign_id = ?", userID, campaignID).
+		Preload("Variants", orderByID).
+		Preload("Variants.BaseTemplate").
+		First(st).Error
+	return st, err
+}
+
+// GetWaitingSplitTests returns the split tests of the sending campaigns which
+// have finished the test phase and are waiting for a winner to be chosen.
+func (db *store) GetWaitingSplitTests() ([]entities.CampaignSplitTest, error) {
+	var tests []entities.CampaignSplitTest
+	err := db.Joins("JOIN campaigns ON campaigns.id = campaign_split_tests.campaign_id").
+		Where("campaign_split_tests.status = ? AND campaigns.status = ?", entities.SplitTestStatusWaiting, entities.StatusSending).
+		Preload("Variants", orderByID).
+		Find(&tests).Error
+	return tests, err
+}
+
+// SaveCampaignSplitTest replaces the split test of the campaign and its variants.
+func (db *store) SaveCampaignSplitTest(st *entities.CampaignSplitTest) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := deleteSplitTest(tx, st.CampaignID, st.UserID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Create(st).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: create split test: %w", err)
+	}
+
+	return tx.Commit().Error
+}
+
+// UpdateCampaignSplitTest updates the state of the split test and the template versions
+// pinned to its variants, the rest of the variants are left intact.
+func (db *store) UpdateCampaignSplitTest(st *entities.CampaignSplitTest) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Model(st).
+		Where("user_id = ?", st.UserID).
+		Select("status", "winner_variant_id", "params", "test_completed_at", "updated_at").
+		Updates(st).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: update split test: %w", err)
+	}
+
+	for i := range st.Variants {
+		err = tx.Model(&st.Variants[i]).
+			Where("user_id = ?", st.UserID).
+			UpdateColumn("template_version", st.Variants[i].TemplateVersion).Error
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("store: update variant template version: %w", err)
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteCampaignSplitTest deletes the split test and its variants by the given campaign id and user id.
+func (db *store) DeleteCampaignSplitTest(campaignID, userID int64) error {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := deleteSplitTest(tx, campaignID, userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// GetCampaignVariantStats fetches the sending stats of a single split test variant.
+func (db *store) GetCampaignVariantStats(campaignID, variantID, userID int64) (*entities.VariantStats, error) {
+	stats := &entities.VariantStats{
+		VariantID: variantID,
+		Opens:     &entities.OpensStats{},
+		Clicks:    &entities.ClicksStats{},
+	}
+
+	events := func(table string) *gorm.DB {
+		return db.Table(table).Where("campaign_id = ? and variant_id = ? and user_id = ?", campaignID, variantID, userID)
+	}
+
+	err := events("sends").Count(&stats.TotalSent).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: count variant sends: %w", err)
+	}
+	err = events("deliveries").Count(&stats.Delivered).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: count variant deliveries: %w", err)
+	}
+	err = events("opens").
+		Select("count(distinct(recipient))").Count(&stats.Opens.Unique).
+		Select("count(recipient)").Count(&stats.Opens.Total).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: count variant opens: %w", err)
+	}
+	err = events("clicks").
+		Select("count(distinct(recipient))").Count(&stats.Clicks.UniqueClicks).
+		Select("count(recipient)").Count(&stats.Clicks.TotalClicks).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: count variant clicks: %w", err)
+	}
+	err = events("bounces").Count(&stats.Bounces).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: count variant bounces: %w", err)
+	}
+	err = events("complaints").Count(&stats.Complaints).Error
+	if err != nil {
+		return nil, fmt.Errorf("store: count variant complaints: %w", err)
+	}
+
+	return stats, nil
+}
+
+func deleteSplitTest(tx *gorm.DB, campaignID, userID int64) error {
+	err := tx.Where("campaign_id = ? and user_id = ?", campaignID, userID).Delete(&entities.CampaignVariant{}).Error
+	if err != nil {
+		return fmt.Errorf("store: delete split test variants: %w", err)
+	}
+
+	err = tx.Where("campaign_id = ? and user_id = ?", campaignID, userID).Delete(&entities.CampaignSplitTest{}).Error
+	if err != nil {
+		return fmt.Errorf("store: delete split test: %w", err)
+	}
+
+	return nil
+}
+
+func orderByID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestCampaignSplitTest(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	var templateIDs []int64
+	for _, name := range []string{"variant-a", "variant-b"} {
+		tmpl := &entities.Template{
+			BaseTemplate: entities.BaseTemplate{
+				UserID:      1,
+				Name:        name,
+				SubjectPart: "subject",
+			},
+			TextPart: "text",
+		}
+		err := store.CreateTemplate(tmpl)
+		assert.Nil(t, err)
+		templateIDs = append(templateIDs, tmpl.ID)
+	}
+
+	campaign := &entities.Campaign{
+		Name:       "foo",
+		UserID:     1,
+		TemplateID: templateIDs[0],
+		Status:     entities.StatusDraft,
+	}
+	err := store.CreateCampaign(campaign)
+	assert.Nil(t, err)
+
+	_, err = store.GetCampaignSplitTest(campaign.ID, 1)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	newSplitTest := func(pct int) *entities.CampaignSplitTest {
+		st := &entities.CampaignSplitTest{
+			UserID:         1,
+			CampaignID:     campaign.ID,
+			TestPercentage: pct,
+			WaitMinutes:    60,
+			WinnerCriteria: entities.WinnerCriteriaOpens,
+			Status:         entities.SplitTestStatusDraft,
+		}
+		for i, id := range templateIDs {
+			st.Variants = append(st.Variants, entities.CampaignVariant{
+				UserID:     1,
+				CampaignID: campaign.ID,
+				TemplateID: id,
+				Name:       entities.VariantName(i),
+			})
+		}
+		return st
+	}
+
+	err = store.SaveCampaignSplitTest(newSplitTest(10))
+	assert.Nil(t, err)
+
+	// saving again replaces the previous split test
+	err = store.SaveCampaignSplitTest(newSplitTest(20))
+	assert.Nil(t, err)
+
+	st, err := store.GetCampaignSplitTest(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, st.TestPercentage)
+	assert.Len(t, st.Variants, 2)
+	assert.Equal(t, "A", st.Variants[0].Name)
+	assert.Equal(t, "variant-a", st.Variants[0].BaseTemplate.Name)
+	assert.Equal(t, "B", st.Variants[1].Name)
+
+	var count int64
+	db.Model(&entities.CampaignVariant{}).Where("campaign_id = ?", campaign.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	c, err := store.GetCampaign(campaign.ID, 1)
+	assert.Nil(t, err)
+	assert.NotNil(t, c.SplitTest)
+	assert.Len(t, c.SplitTest.Variants, 2)
+
+	// the split test is waiting only for sending campaigns
+	st.Status = entities.SplitTestStatusWaiting
+	st.TestCompletedAt.SetValid(time.Now().UTC())
+	err = st.SetParams(entities.CampaignerTopicParams{CampaignID: campaign.ID, SegmentIDs: []int64{1}})
+	assert.Nil(t, err)
+	err = store.UpdateCampaignSplitTest(st)
+	assert.Nil(t, err)
+
+	waiting, err := store.GetWaitingSplitTests()
+	assert.Nil(t, err)
+	assert.Empty(t, waiting)
+
+	campaign.Status = entities.StatusSending
+	err = store.UpdateCampaign(campaign)
+	assert.Nil(t, err)
+
+	waiting, err = store.GetWaitingSplitTests()
+	assert.Nil(t, err)
+	assert.Len(t, waiting, 1)
+	assert.Len(t, waiting[0].Variants, 2)
+	assert.True(t, waiting[0].TestCompletedAt.Valid)
+	p, err := waiting[0].GetParams()
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1}, p.SegmentIDs)
+
+	variantA, variantB := st.Variants[0].ID, st.Variants[1].ID
+	for i, recipient := range []string{"foo@example.com", "foo@example.com", "bar@example.com"} {
+		err = store.CreateOpen(&entities.Open{
+			UserID:     1,
+			CampaignID: campaign.ID,
+			VariantID:  variantA,
+			Recipient:  recipient,
+		})
+		assert.Nil(t, err)
+		err = store.CreateSend(&entities.Send{
+			UserID:     1,
+			CampaignID: campaign.ID,
+			VariantID:  []int64{variantA, variantB, variantB}[i],
+			MessageID:  recipient,
+		})
+		assert.Nil(t, err)
+	}
+
+	stats, err := store.GetCampaignVariantStats(campaign.ID, variantA, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), stats.TotalSent)
+	assert.Equal(t, int64(2), stats.Opens.Unique)
+	assert.Equal(t, int64(3), stats.Opens.Total)
+
+	stats, err = store.GetCampaignVariantStats(campaign.ID, variantB, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), stats.TotalSent)
+	assert.Equal(t, int64(0), stats.Opens.Unique)
+
+	err = store.DeleteCampaignSplitTest(campaign.ID, 1)
+	assert.Nil(t, err)
+
+	_, err = store.GetCampaignSplitTest(campaign.ID, 1)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	db.Model(&entities.CampaignVariant{}).Where("campaign_id = ?", campaign.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
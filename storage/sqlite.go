@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriverName is the sqlite driver which provides the mb_json_extract and mb_json_type
+// functions for the segment rules, the sqlite library is not always compiled with the JSON1
+// extension. The functions have their own names, so the built-in JSON functions are not
+// replaced when they are available. It also provides the sha2 function of MySQL, which is
+// used by the migrations.
+const sqliteDriverName = "sqlite3_json"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			err := conn.RegisterFunc("mb_json_extract", jsonExtract, true)
+			if err != nil {
+				return err
+			}
+			err = conn.RegisterFunc("mb_json_type", jsonType, true)
+			if err != nil {
+				return err
+			}
+			return conn.RegisterFunc("sha2", sha2, true)
+		},
+	})
+}
+
+// jsonExtract returns the value of the top level key of the JSON object, the strings are unquoted
+// and the other values are in their JSON form. The driver returns the empty values as NULL.
+func jsonExtract(doc interface{}, path string) ([]byte, error) {
+	raw, err := jsonValue(doc, path)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []byte(s), nil
+	}
+
+	return raw, nil
+}
+
+// jsonType returns the type of the value of the top level key of the JSON object, or NULL if it's missing.
+func jsonType(doc interface{}, path string) ([]byte, error) {
+	raw, err := jsonValue(doc, path)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+
+	switch raw[0] {
+	case '"':
+		return []byte("text"), nil
+	case '{':
+		return []byte("object"), nil
+	case '[':
+		return []byte("array"), nil
+	case 't':
+		return []byte("true"), nil
+	case 'f':
+		return []byte("false"), nil
+	case 'n':
+		return []byte("null"), nil
+	}
+
+	if bytes.ContainsAny(raw, ".eE") {
+		return []byte("real"), nil
+	}
+	return []byte("integer"), nil
+}
+
+// sha2 returns the hex encoded SHA-256 hash of the string, as the SHA2 function of MySQL.
+// Only the 256 bit length is supported.
+func sha2(s string, length int) (string, error) {
+	if length != 256 {
+		return "", fmt.Errorf("sha2: unsupported hash length %d", length)
+	}
+
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:]), nil
+}
+
+// jsonValue returns the raw JSON value of the key in the $."key" or $.key path.
+func jsonValue(doc interface{}, path string) (json.RawMessage, error) {
+	var b []byte
+	switch d := doc.(type) {
+	case string:
+		b = []byte(d)
+	case []byte:
+		b = d
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(path, "$.") {
+		return nil, fmt.Errorf("json: unsupported path %s", path)
+	}
+	key := strings.TrimPrefix(path, "$.")
+	if len(key) >= 2 && strings.HasPrefix(key, `"`) && strings.HasSuffix(key, `"`) {
+		key = key[1 : len(key)-1]
+	}
+
+	var obj map[string]json.RawMessage
+	err := json.Unmarshal(b, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("json: malformed JSON: %w", err)
+	}
+
+	raw, ok := obj[key]
+	if !ok {
+		return nil, nil
+	}
+
+	return bytes.TrimSpace(raw), nil
+}
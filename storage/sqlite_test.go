@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteJSONFunctions(t *testing.T) {
+	db := openTestDb()
+
+	tests := []struct {
+		doc   string
+		path  string
+		value sql.NullString
+		typ   sql.NullString
+	}{
+		{doc: `{"city":"Skopje"}`, path: `$."city"`, value: sql.NullString{String: "Skopje", Valid: true}, typ: sql.NullString{String: "text", Valid: true}},
+		{doc: `{"age": 30}`, path: `$.age`, value: sql.NullString{String: "30", Valid: true}, typ: sql.NullString{String: "integer", Valid: true}},
+		{doc: `{"score":1.5}`, path: `$."score"`, value: sql.NullString{String: "1.5", Valid: true}, typ: sql.NullString{String: "real", Valid: true}},
+		{doc: `{"tags":["a"]}`, path: `$."tags"`, value: sql.NullString{String: `["a"]`, Valid: true}, typ: sql.NullString{String: "array", Valid: true}},
+		{doc: `{"city":"Skopje"}`, path: `$."country"`},
+		{doc: ``, path: `$."city"`},
+	}
+
+	for _, tt := range tests {
+		var value, typ sql.NullString
+		err := db.Raw("SELECT CAST(mb_json_extract(?, ?) AS TEXT), mb_json_type(?, ?)", tt.doc, tt.path, tt.doc, tt.path).
+			Row().Scan(&value, &typ)
+		assert.Nil(t, err, tt.doc)
+		assert.Equal(t, tt.value, value, tt.doc)
+		assert.Equal(t, tt.typ, typ, tt.doc)
+	}
+}
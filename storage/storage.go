@@ -29,7 +29,11 @@ type Storage interface {
 	GetCampaignByName(name string, userID int64) (*entities.Campaign, error)
 	CreateCampaign(*entities.Campaign) error
 	UpdateCampaign(*entities.Campaign) error
+	UpdateCampaignSettings(c *entities.Campaign) error
 	DeleteCampaign(int64, int64) error
+	GetCampaignStatus(id, userID int64) (string, error)
+	SwapCampaignStatus(id, userID int64, old, new string) (bool, error)
+	CompleteCampaign(id, userID int64, old, new string, completedAt time.Time) (bool, error)
 	GetMonthlyTotalCampaigns(userID int64) (int64, error)
 	GetCampaignOpens(campaignID, userID int64, p *PaginationCursor) error
 	GetClicksStats(campaignID, userID int64) (*entities.ClicksStats, error)
@@ -38,6 +42,9 @@ type Storage interface {
 	GetTotalDelivered(campaignID, userID int64) (int64, error)
 	GetTotalBounces(campaignID, userID int64) (int64, error)
 	GetTotalComplaints(campaignID, userID int64) (int64, error)
+	GetCampaignStatsTimeSeries(campaignID, userID int64, interval string) (*entities.CampaignStatsTimeSeries, error)
+	GetSubscriberMetrics(userID int64, from, to time.Time) ([]entities.SubscriberMetrics, error)
+	GetCampaignsEngagement(userID int64, from, to time.Time) ([]entities.CampaignEngagement, error)
 	GetCampaignClicksStats(int64, int64) ([]entities.ClicksStats, error)
 	GetCampaignComplaints(campaignID, userID int64, p *PaginationCursor) error
 	GetCampaignBounces(campaignID, userID int64, p *PaginationCursor) error
@@ -47,8 +54,21 @@ type Storage interface {
 	DeleteCampaignSchedule(campaignID int64) error
 	GetScheduledCampaigns(time time.Time) ([]entities.CampaignSchedule, error)
 
+	GetCampaignCheckpoint(campaignID, userID int64) (*entities.CampaignCheckpoint, error)
+	SaveCampaignCheckpoint(c *entities.CampaignCheckpoint) error
+	ClaimPausedCampaignCheckpoint(campaignID, userID int64) (bool, error)
+	DeleteCampaignCheckpoint(campaignID, userID int64) error
+
+	GetCampaignSplitTest(campaignID, userID int64) (*entities.CampaignSplitTest, error)
+	GetWaitingSplitTests() ([]entities.CampaignSplitTest, error)
+	SaveCampaignSplitTest(st *entities.CampaignSplitTest) error
+	UpdateCampaignSplitTest(st *entities.CampaignSplitTest) error
+	DeleteCampaignSplitTest(campaignID, userID int64) error
+	GetCampaignVariantStats(campaignID, variantID, userID int64) (*entities.VariantStats, error)
+
 	GetSegments(int64, *PaginationCursor) error
 	GetSegmentsByIDs(userID int64, ids []int64) ([]entities.Segment, error)
+	GetPublicSegments(userID int64) ([]entities.Segment, error)
 	GetSegment(int64, int64) (*entities.Segment, error)
 	GetSegmentByName(name string, userID int64) (*entities.Segment, error)
 	GetTotalSegments(userID int64) (int64, error)
@@ -63,6 +83,7 @@ type Storage interface {
 	GetSubscriber(int64, int64) (*entities.Subscriber, error)
 	GetSubscribersByIDs([]int64, int64) ([]entities.Subscriber, error)
 	GetSubscriberByEmail(string, int64) (*entities.Subscriber, error)
+	GetSubscribersByEmails(emails []string, userID int64) ([]entities.Subscriber, error)
 	GetDistinctSubscribersBySegmentIDs(
 		listIDs []int64,
 		userID int64,
@@ -71,24 +92,75 @@ type Storage interface {
 		nextID, limit int64,
 	) ([]entities.Subscriber, error)
 	CreateSubscriber(*entities.Subscriber) error
+	CreateSubscribers(subs []*entities.Subscriber) error
 	UpdateSubscriber(*entities.Subscriber) error
 	DeactivateSubscriber(userID int64, email string) error
+	ConfirmSubscriber(id, userID int64) error
+	ClaimConfirmationEmail(id, userID int64, sentAt, since time.Time) (bool, error)
+	ReleaseConfirmationEmail(id, userID int64) error
+	UpdateSubscriberPreferences(s *entities.Subscriber, changes *entities.PreferenceChanges) error
 	DeleteSubscriber(int64, int64) error
 	DeleteSubscriberByEmail(string, int64) error
 	GetTotalSubscribers(int64) (int64, error)
+	GetSubscriberMetadataKeys(userID int64) ([]string, error)
 	GetTotalSubscribersBySegment(segmentID, userID int64) (int64, error)
 	SeekSubscribersByUserID(userID int64, nextID int64, limit int64) ([]entities.Subscriber, error)
+	SeekCampaignResults(campaignID, userID int64, nextID string, limit int64) ([]entities.CampaignRecipientResult, error)
+	SeekBounces(userID, campaignID, nextID, limit int64) ([]entities.Bounce, error)
+	SeekComplaints(userID, campaignID, nextID, limit int64) ([]entities.Complaint, error)
+	SeekSendLogs(userID, campaignID int64, nextID string, limit int64) ([]entities.SendLog, error)
+	CountBounces(userID, campaignID int64) (int64, error)
+	CountComplaints(userID, campaignID int64) (int64, error)
+	CountSendLogs(userID, campaignID int64) (int64, error)
+
+	GetForms(userID int64, p *PaginationCursor) error
+	GetForm(id, userID int64) (*entities.Form, error)
+	GetFormByUUID(uuid string) (*entities.Form, error)
+	GetFormByName(name string, userID int64) (*entities.Form, error)
+	CreateForm(f *entities.Form) error
+	UpdateForm(f *entities.Form) error
+	DeleteForm(id, userID int64) error
+
+	GetWebhooks(userID int64, p *PaginationCursor) error
+	GetWebhook(id, userID int64) (*entities.Webhook, error)
+	GetActiveWebhooks(userID int64) ([]entities.Webhook, error)
+	CreateWebhook(w *entities.Webhook) error
+	UpdateWebhook(w *entities.Webhook) error
+	DeleteWebhook(id, userID int64) error
+	GetWebhookDeliveries(webhookID, userID int64, p *PaginationCursor) error
+	GetWebhookDelivery(id, userID int64) (*entities.WebhookDelivery, error)
+	CreateWebhookDelivery(d *entities.WebhookDelivery) error
+	UpdateWebhookDelivery(d *entities.WebhookDelivery) error
+
+	GetJobs(userID int64, p *PaginationCursor) error
+	GetJob(id, userID int64) (*entities.Job, error)
+	CreateJob(j *entities.Job) error
+	UpdateJob(j *entities.Job) error
+	UpdateJobProgress(j *entities.Job) error
+
+	GetTransactionalMessages(userID int64, p *PaginationCursor) error
+	GetTransactionalMessage(id, userID int64) (*entities.TransactionalMessage, error)
+	CreateTransactionalMessage(m *entities.TransactionalMessage) error
+	UpdateTransactionalMessageStatus(id, userID int64, status, description string, messageID *string) error
+	TrackTransactionalMessageEvent(id, userID int64, event string) error
 
 	GetAPIKeys(userID int64) ([]*entities.APIKey, error)
+	GetAPIKeyByID(id, userID int64) (*entities.APIKey, error)
 	GetAPIKey(identifier string) (*entities.APIKey, error)
 	CreateAPIKey(ak *entities.APIKey) error
 	UpdateAPIKey(ak *entities.APIKey) error
+	UpdateAPIKeyLastUsed(id int64, t time.Time) error
 	DeleteAPIKey(id, userID int64) error
 
 	GetSesKeys(userID int64) (*entities.SesKeys, error)
 	CreateSesKeys(s *entities.SesKeys) error
 	DeleteSesKeys(userID int64) error
 
+	GetSMTPSettings(userID int64) (*entities.SMTPSettings, error)
+	HasSMTPSettings(userID int64) (bool, error)
+	CreateSMTPSettings(s *entities.SMTPSettings) error
+	DeleteSMTPSettings(userID int64) error
+
 	GetToken(token string) (*entities.Token, error)
 	CreateToken(s *entities.Token) error
 	DeleteToken(token string) error
@@ -97,6 +169,7 @@ type Storage interface {
 	CountLogsByUUID(id string) (int64, error)
 	CountLogsByStatus(status string) (int64, error)
 	GetSendLogByUUID(id string) (*entities.SendLog, error)
+	GetSentSubscriberIDs(campaignID, userID int64, subscriberIDs []int64) ([]int64, error)
 
 	CreateBounce(b *entities.Bounce) error
 	CreateComplaint(c *entities.Complaint) error
@@ -105,8 +178,14 @@ type Storage interface {
 	CreateOpen(o *entities.Open) error
 	CreateDelivery(d *entities.Delivery) error
 
+	GetProcessedEventKeys(keys []string) ([]string, error)
+	CreateEvents(b *entities.EventsBatch) error
+	DeleteProcessedEventsBefore(t time.Time) (int64, error)
+
 	CreateReport(r *entities.Report) error
 	UpdateReport(r *entities.Report) error
+	UpdateReportProgress(r *entities.Report) error
+	GetReport(id, userID int64) (*entities.Report, error)
 	GetReportByFilename(filename string, userID int64) (*entities.Report, error)
 	GetRunningReportForUser(userID int64) (*entities.Report, error)
 	GetNumberOfReportsForDate(userID int64, time time.Time) (int64, error)
@@ -117,4 +196,17 @@ type Storage interface {
 	GetTemplate(templateID int64, userID int64) (*entities.Template, error)
 	GetTemplates(userID int64, p *PaginationCursor, scopeMap map[string]string) error
 	DeleteTemplate(templateID int64, userID int64) error
+	SaveTemplateVersion(t *entities.Template, upload func() error) (bool, error)
+	GetTemplateVersion(templateID, version, userID int64) (*entities.TemplateVersion, error)
+	GetTemplateVersions(templateID, userID int64, p *PaginationCursor) error
+
+	GetPartials(userID int64, p *PaginationCursor, scopeMap map[string]string) error
+	GetAllPartials(userID int64) ([]entities.Partial, error)
+	GetPartial(id, userID int64) (*entities.Partial, error)
+	GetPartialByName(name string, userID int64) (*entities.Partial, error)
+	CreatePartial(p *entities.Partial) error
+	UpdatePartial(p *entities.Partial) error
+	DeletePartial(id, userID int64) error
+	CountLayoutUsages(layoutID, userID int64) (int64, error)
+	CountPartialUsages(name string, userID int64) (int64, error)
 }
@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,6 +14,9 @@ import (
 	"github.com/mailbadger/app/entities"
 )
 
+// subscribersBatchSize is the number of rows inserted with a single statement.
+const subscribersBatchSize = 100
+
 // GetSubscribers fetches subscribers by user id, and populates the pagination obj
 func (db *store) GetSubscribers(userID int64, p *PaginationCursor, scopeMap map[string]string) error {
 	p.SetCollection(new([]entities.Subscriber))
@@ -41,9 +47,14 @@ func EmailLike(email string) func(*gorm.DB) *gorm.DB {
 
 // GetSubscribersBySegmentID fetches subscribers by user id and list id, and populates the pagination obj
 func (db *store) GetSubscribersBySegmentID(segmentID, userID int64, p *PaginationCursor) error {
+	seg, err := db.GetSegment(segmentID, userID)
+	if err != nil {
+		return err
+	}
+
 	p.SetCollection(&[]entities.Subscriber{})
 	p.SetResource("subscribers")
-	p.SetScopes(BelongsToUser(userID), BelongsToSegment(segmentID))
+	p.SetScopes(BelongsToUser(userID), InSegments([]entities.Segment{*seg}))
 
 	query := db.Table(p.Resource).
 		Order("created_at desc, id desc").
@@ -54,14 +65,6 @@ func (db *store) GetSubscribersBySegmentID(segmentID, userID int64, p *Paginatio
 	return db.Paginate(p, userID)
 }
 
-// BelongsToSegment is a query scope that finds all subscribers under a segment id.
-func BelongsToSegment(segID int64) func(*gorm.DB) *gorm.DB {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Joins("INNER JOIN subscribers_segments ON subscribers_segments.subscriber_id = subscribers.id").
-			Where("subscribers_segments.segment_id = ?", segID)
-	}
-}
-
 // GetTotalSubscribers fetches the total count by user id.
 func (db *store) GetTotalSubscribers(userID int64) (int64, error) {
 	var count int64
@@ -69,12 +72,68 @@ func (db *store) GetTotalSubscribers(userID int64) (int64, error) {
 	return count, err
 }
 
+// GetSubscriberMetadataKeys returns the distinct keys of the metadata of the user's subscribers. MySQL
+// returns the distinct key sets with JSON_KEYS, while sqlite returns the distinct metadata objects. Both
+// scan the subscribers of the user, so the callers cache the keys.
+func (db *store) GetSubscriberMetadataKeys(userID int64) ([]string, error) {
+	col := "metadata"
+	if db.Dialector.Name() == "mysql" {
+		col = "JSON_KEYS(metadata)"
+	}
+
+	var rows []sql.NullString
+	err := db.Model(entities.Subscriber{}).
+		Where("user_id = ? AND metadata IS NOT NULL", userID).
+		Distinct().
+		Pluck(col, &rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, r := range rows {
+		if !r.Valid || r.String == "" {
+			continue
+		}
+
+		var rowKeys []string
+		if col == "metadata" {
+			var m map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(r.String), &m); err != nil {
+				return nil, fmt.Errorf("unmarshal metadata: %w", err)
+			}
+			for k := range m {
+				rowKeys = append(rowKeys, k)
+			}
+		} else if err := json.Unmarshal([]byte(r.String), &rowKeys); err != nil {
+			return nil, fmt.Errorf("unmarshal metadata keys: %w", err)
+		}
+
+		for _, k := range rowKeys {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
 // GetTotalSubscribersBySegment fetches the total count by user and segment id.
 func (db *store) GetTotalSubscribersBySegment(segmentID, userID int64) (int64, error) {
-	var seg = entities.Segment{Model: entities.Model{ID: segmentID}}
+	seg, err := db.GetSegment(segmentID, userID)
+	if err != nil {
+		return 0, err
+	}
 
-	assoc := db.Model(&seg).Where("user_id = ?", userID).Association("Subscribers")
-	return int64(assoc.Count()), assoc.Error
+	var count int64
+	err = db.Model(entities.Subscriber{}).
+		Scopes(BelongsToUser(userID), InSegments([]entities.Segment{*seg})).
+		Count(&count).Error
+	return count, err
 }
 
 // GetSubscriber returns the subscriber by the given id and user id
@@ -98,6 +157,19 @@ func (db *store) GetSubscriberByEmail(email string, userID int64) (*entities.Sub
 	return s, err
 }
 
+// GetSubscribersByEmails returns the subscribers with their segments by the given emails and user id
+func (db *store) GetSubscribersByEmails(emails []string, userID int64) ([]entities.Subscriber, error) {
+	// the emails are compared case insensitively on sqlite as well, the same as with the MySQL collation.
+	cond := "user_id = ? and email in (?)"
+	if db.Dialector.Name() != "mysql" {
+		cond = "user_id = ? and email COLLATE NOCASE in (?)"
+	}
+
+	var s []entities.Subscriber
+	err := db.Preload("Segments").Where(cond, userID, emails).Find(&s).Error
+	return s, err
+}
+
 // GetDistinctSubscribersBySegmentIDs fetches all distinct subscribers by user id and list ids
 func (db *store) GetDistinctSubscribersBySegmentIDs(
 	listIDs []int64,
@@ -111,22 +183,27 @@ func (db *store) GetDistinctSubscribersBySegmentIDs(
 		limit = 1000
 	}
 
+	segs, err := db.GetSegmentsByIDs(userID, listIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	var subs []entities.Subscriber
 
-	err := db.Table("subscribers").
-		Select("DISTINCT(id), name, email, created_at, metadata").
-		Joins("INNER JOIN subscribers_segments ON subscribers_segments.subscriber_id = subscribers.id").
+	err = db.Table("subscribers").
+		Select("id, name, email, created_at, metadata").
+		Scopes(InSegments(segs)).
 		Where(`
-			subscribers.user_id = ? 
-			AND subscribers_segments.segment_id IN (?)
-			AND subscribers.blacklisted = ? 
+			subscribers.user_id = ?
+			AND subscribers.blacklisted = ?
 			AND subscribers.active = ?
+			AND (subscribers.paused_until IS NULL OR subscribers.paused_until < ?)
 			AND (created_at > ? OR (created_at = ? AND id > ?))
 			AND created_at < ?`,
-			listIDs,
 			userID,
 			blacklisted,
 			active,
+			time.Now(),
 			timestamp.Format(time.RFC3339),
 			timestamp.Format(time.RFC3339),
 			nextID,
@@ -181,6 +258,58 @@ func (db *store) CreateSubscriber(s *entities.Subscriber) error {
 	return tx.Commit().Error
 }
 
+// CreateSubscribers creates the subscribers in batches with their created events,
+// and increments the created subscribers metric.
+func (db *store) CreateSubscribers(subs []*entities.Subscriber) error {
+	if len(subs) == 0 {
+		return nil
+	}
+
+	tx := db.Begin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.CreateInBatches(subs, subscribersBatchSize).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: create subscribers: %w", err)
+	}
+
+	events := make([]entities.SubscriberEvent, len(subs))
+	for i, s := range subs {
+		events[i] = entities.SubscriberEvent{
+			UserID:       s.UserID,
+			SubscriberID: s.ID,
+			EventType:    entities.SubscriberEventTypeCreated,
+		}
+	}
+
+	err = tx.CreateInBatches(events, subscribersBatchSize).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: add subscriber events (created): %w", err)
+	}
+
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "datetime"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"created": gorm.Expr("created + ?", len(subs))}),
+	}).Create(&entities.SubscriberMetrics{
+		UserID:   subs[0].UserID,
+		Created:  int64(len(subs)),
+		Datetime: now.BeginningOfHour(),
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: add subscriber metric: %w", err)
+	}
+
+	return tx.Commit().Error
+}
+
 // UpdateSubscriber edits an existing subscriber in the database.
 func (db *store) UpdateSubscriber(s *entities.Subscriber) error {
 	tx := db.Begin()
@@ -253,6 +382,55 @@ func (db *store) DeactivateSubscriber(userID int64, email string) error {
 	return tx.Commit().Error
 }
 
+// ConfirmSubscriber activates a pending subscriber by the given id and user
+// and adds confirmed subscriber event.
+func (db *store) ConfirmSubscriber(id, userID int64) error {
+	tx := db.Begin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Model(&entities.Subscriber{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		Update("active", true).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: confirm subscriber: %w", err)
+	}
+
+	err = tx.Create(&entities.SubscriberEvent{
+		UserID:       userID,
+		SubscriberID: id,
+		EventType:    entities.SubscriberEventTypeConfirmed,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("subscription store: add subscriber event (confirmed): %w", err)
+	}
+
+	return tx.Commit().Error
+}
+
+// ClaimConfirmationEmail records the time at which the confirmation email is sent to the subscriber, unless
+// one was already sent after the given time. It reports whether the confirmation email can be sent.
+func (db *store) ClaimConfirmationEmail(id, userID int64, sentAt, since time.Time) (bool, error) {
+	res := db.Table("subscribers").
+		Where("user_id = ? AND id = ?", userID, id).
+		Where("confirmation_sent_at IS NULL OR confirmation_sent_at < ?", since).
+		UpdateColumn("confirmation_sent_at", sentAt)
+	return res.RowsAffected > 0, res.Error
+}
+
+// ReleaseConfirmationEmail clears the time of the confirmation email which couldn't be sent.
+func (db *store) ReleaseConfirmationEmail(id, userID int64) error {
+	return db.Table("subscribers").
+		Where("user_id = ? AND id = ?", userID, id).
+		UpdateColumn("confirmation_sent_at", nil).Error
+}
+
 // DeleteSubscriber deletes an existing subscriber from the database along with
 // all his metadata and adds deleted subscriber event.
 func (db *store) DeleteSubscriber(id, userID int64) error {
@@ -322,6 +500,11 @@ func (db *store) DeleteSubscriberByEmail(email string, userID int64) error {
 // SeekSubscribersByUserID fetches chunk of subscribers with id greater than nextID
 func (db *store) SeekSubscribersByUserID(userID, nextID, limit int64) ([]entities.Subscriber, error) {
 	var s []entities.Subscriber
-	err := db.Where("user_id = ? and id > ?", userID, nextID).Limit(int(limit)).Find(&s).Error
+	// the segments of the whole page are preloaded with a single query.
+	err := db.Preload("Segments").
+		Where("user_id = ? and id > ?", userID, nextID).
+		Order("id").
+		Limit(int(limit)).
+		Find(&s).Error
 	return s, err
 }
@@ -61,6 +61,45 @@ func TestSubscriber(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, m["foo"], "bar")
 
+	//Test get metadata keys
+	s3 := &entities.Subscriber{
+		Name:     "foo 3",
+		Email:    "john+2@example.com",
+		UserID:   1,
+		MetaJSON: []byte(`{"foo":"baz","city":"Skopje"}`),
+		Active:   true,
+	}
+	err = store.CreateSubscriber(s3)
+	assert.Nil(t, err)
+
+	keys, err := store.GetSubscriberMetadataKeys(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"city", "foo"}, keys)
+
+	err = store.DeleteSubscriber(s3.ID, 1)
+	assert.Nil(t, err)
+
+	//Test claim confirmation email
+	now := time.Now().UTC()
+	claimed, err := store.ClaimConfirmationEmail(s.ID, 1, now, now.Add(-10*time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = store.ClaimConfirmationEmail(s.ID, 1, now.Add(time.Minute), now.Add(-9*time.Minute))
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+
+	claimed, err = store.ClaimConfirmationEmail(s.ID, 1, now.Add(11*time.Minute), now.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	err = store.ReleaseConfirmationEmail(s.ID, 1)
+	assert.Nil(t, err)
+
+	claimed, err = store.ClaimConfirmationEmail(s.ID, 1, now.Add(12*time.Minute), now.Add(2*time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
 	//Test get subs by list id
 	p := NewPaginationCursor(fmt.Sprintf("/api/segments/%d/subscribers", l.ID), 10)
 	err = store.GetSubscribersBySegmentID(l.ID, 1, p)
@@ -109,6 +148,25 @@ func TestSubscriber(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, total, int64(2))
 
+	//Test create subs in batches
+	batch := []*entities.Subscriber{
+		{UserID: 1, Name: "a", Email: "a@example.com", Active: true},
+		{UserID: 1, Name: "b", Email: "b@example.com", Active: true},
+	}
+	err = store.CreateSubscribers(batch)
+	assert.Nil(t, err)
+	assert.NotZero(t, batch[0].ID)
+	assert.NotZero(t, batch[1].ID)
+
+	//Test get subs by emails
+	subs, err = store.GetSubscribersByEmails([]string{"a@example.com", "B@Example.com", "missing@example.com"}, 1)
+	assert.Nil(t, err)
+	assert.Len(t, subs, 2)
+
+	total, err = store.GetTotalSubscribers(1)
+	assert.Nil(t, err)
+	assert.Equal(t, total, int64(4))
+
 	//Test delete subscriber
 	err = store.DeleteSubscriberByEmail(s2.Email, 1)
 	assert.Nil(t, err)
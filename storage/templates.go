@@ -1,15 +1,38 @@
 package storage
 
 import (
+	"fmt"
+
+	"gorm.io/gorm"
+
 	"github.com/mailbadger/app/entities"
 )
 
-// CreateSubscriber creates a new subscriber in the database.
+// CreateTemplate creates a new template along with its first version in the database.
 func (db *store) CreateTemplate(t *entities.Template) error {
-	return db.Create(t).Error
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Create(t).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: create template: %w", err)
+	}
+
+	err = tx.Create(t.Snapshot()).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: create template version: %w", err)
+	}
+
+	return tx.Commit().Error
 }
 
-// UpdateReport edits an existing template in the database.
+// UpdateTemplate edits an existing template in the database.
 func (db *store) UpdateTemplate(t *entities.Template) error {
 	return db.Where("user_id = ? and id = ?", t.UserID, t.ID).Save(t).Error
 }
@@ -48,7 +71,96 @@ func (db *store) GetTemplates(userID int64, p *PaginationCursor, scopeMap map[st
 	return db.Paginate(p, userID)
 }
 
-// DeleteTemplate deletes the template with given template id and user id from db
+// DeleteTemplate deletes the template with given template id and user id from db along with its versions.
 func (db *store) DeleteTemplate(templateID int64, userID int64) error {
-	return db.Where("user_id = ? and id = ?", userID, templateID).Delete(&entities.Template{}).Error
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Where("user_id = ? and template_id = ?", userID, templateID).Delete(&entities.TemplateVersion{}).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: delete template versions: %w", err)
+	}
+
+	err = tx.Where("user_id = ? and id = ?", userID, templateID).Delete(&entities.Template{}).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: delete template: %w", err)
+	}
+
+	return tx.Commit().Error
+}
+
+// SaveTemplateVersion updates the template to its new version and creates the version in a single
+// transaction, the upload is run before the transaction is committed. The template is updated only if
+// it is still at the previous version, false is returned when it was saved by another request meanwhile.
+func (db *store) SaveTemplateVersion(t *entities.Template, upload func() error) (bool, error) {
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	res := tx.Model(t).
+		Where("user_id = ? and version = ?", t.UserID, t.Version-1).
+		Select("*").
+		Updates(t)
+	if res.Error != nil {
+		tx.Rollback()
+		return false, fmt.Errorf("store: update template: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	err := tx.Create(t.Snapshot()).Error
+	if err != nil {
+		tx.Rollback()
+		return false, fmt.Errorf("store: create template version: %w", err)
+	}
+
+	err = upload()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit().Error
+}
+
+// GetTemplateVersion returns the version of the template by the given template id and user id.
+func (db *store) GetTemplateVersion(templateID, version, userID int64) (*entities.TemplateVersion, error) {
+	var v = new(entities.TemplateVersion)
+	err := db.Where("user_id = ? and template_id = ? and version = ?", userID, templateID, version).First(v).Error
+	return v, err
+}
+
+// GetTemplateVersions fetches the versions of the template, and populates the pagination obj
+func (db *store) GetTemplateVersions(templateID, userID int64, p *PaginationCursor) error {
+	p.SetCollection(new([]entities.TemplateVersion))
+	p.SetResource("template_versions")
+
+	p.AddScope(BelongsToUser(userID))
+	p.AddScope(BelongsToTemplate(templateID))
+
+	query := db.Table(p.Resource).
+		Order("created_at desc, id desc").
+		Limit(p.PerPage)
+
+	p.SetQuery(query)
+
+	return db.Paginate(p, userID)
+}
+
+// BelongsToTemplate scopes the versions by the template id.
+func BelongsToTemplate(templateID int64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("template_id = ?", templateID)
+	}
 }
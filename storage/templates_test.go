@@ -128,3 +128,85 @@ func TestTemplate(t *testing.T) {
 	err = store.DeleteTemplate(templateByID.ID, 1)
 	assert.Nil(t, err)
 }
+
+func TestTemplateVersions(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	template := &entities.Template{
+		BaseTemplate: entities.BaseTemplate{
+			UserID:      1,
+			Name:        "versioned",
+			SubjectPart: "subject v1",
+			Version:     1,
+		},
+		TextPart: "text v1",
+	}
+
+	// the first version is created along with the template
+	err := store.CreateTemplate(template)
+	assert.Nil(t, err)
+
+	v1, err := store.GetTemplateVersion(template.ID, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "subject v1", v1.SubjectPart)
+	assert.Equal(t, "text v1", v1.TextPart)
+
+	// the template is updated to the new version along with the upload
+	uploads := 0
+	template.SubjectPart = "subject v2"
+	template.Version = 2
+	saved, err := store.SaveTemplateVersion(template, func() error {
+		uploads++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, saved)
+	assert.Equal(t, 1, uploads)
+
+	v2, err := store.GetTemplateVersion(template.ID, 2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "subject v2", v2.SubjectPart)
+
+	// the template which was saved by another request in the meantime isn't updated
+	stale := *template
+	stale.SubjectPart = "stale"
+	saved, err = store.SaveTemplateVersion(&stale, func() error {
+		uploads++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.False(t, saved)
+	assert.Equal(t, 1, uploads)
+
+	// the version is rolled back when the upload fails
+	template.SubjectPart = "subject v3"
+	template.Version = 3
+	saved, err = store.SaveTemplateVersion(template, func() error {
+		return errors.New("upload failed")
+	})
+	assert.NotNil(t, err)
+	assert.False(t, saved)
+
+	_, err = store.GetTemplateVersion(template.ID, 3, 1)
+	assert.Equal(t, errors.New("record not found"), err)
+
+	current, err := store.GetTemplate(template.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), current.Version)
+	assert.Equal(t, "subject v2", current.SubjectPart)
+
+	p := NewPaginationCursor("/api/templates/1/versions", 10)
+	err = store.GetTemplateVersions(template.ID, 1, p)
+	assert.Nil(t, err)
+	col := p.Collection.(*[]entities.TemplateVersion)
+	assert.Len(t, *col, 2)
+	assert.Equal(t, int64(2), p.Total)
+
+	// the versions are deleted along with the template
+	err = store.DeleteTemplate(template.ID, 1)
+	assert.Nil(t, err)
+
+	_, err = store.GetTemplateVersion(template.ID, 1, 1)
+	assert.Equal(t, errors.New("record not found"), err)
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// bucketLayout is the layout of the bucket column of the time series queries.
+const bucketLayout = "2006-01-02 15:04:05"
+
+type bucketCount struct {
+	Bucket string
+	Total  int64
+	Uniq   int64
+}
+
+// GetCampaignStatsTimeSeries fetches the totals of the campaign along with the
+// sends, deliveries, opens, clicks, bounces and complaints bucketed by the interval.
+func (db *store) GetCampaignStatsTimeSeries(campaignID, userID int64, interval string) (*entities.CampaignStatsTimeSeries, error) {
+	expr, err := bucketExpr(interval, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &entities.CampaignStatsTimeSeries{Interval: interval}
+	buckets := make(map[time.Time]*entities.StatsBucket)
+
+	events := []struct {
+		table  string
+		unique bool
+		set    func(b *entities.StatsBucket, c bucketCount)
+	}{
+		{"sends", false, func(b *entities.StatsBucket, c bucketCount) { b.Sent = c.Total }},
+		{"deliveries", false, func(b *entities.StatsBucket, c bucketCount) { b.Delivered = c.Total }},
+		{"opens", true, func(b *entities.StatsBucket, c bucketCount) {
+			b.Opens = entities.OpensStats{Unique: c.Uniq, Total: c.Total}
+		}},
+		{"clicks", true, func(b *entities.StatsBucket, c bucketCount) {
+			b.Clicks = entities.ClicksStats{UniqueClicks: c.Uniq, TotalClicks: c.Total}
+		}},
+		{"bounces", false, func(b *entities.StatsBucket, c bucketCount) { b.Bounces = c.Total }},
+		{"complaints", false, func(b *entities.StatsBucket, c bucketCount) { b.Complaints = c.Total }},
+	}
+
+	for _, e := range events {
+		sel := expr + " as bucket, count(*) as total"
+		if e.unique {
+			sel += ", count(distinct(recipient)) as uniq"
+		}
+
+		var counts []bucketCount
+		err := db.Table(e.table).
+			Select(sel).
+			Where("campaign_id = ? and user_id = ? and created_at is not null", campaignID, userID).
+			Group("bucket").
+			Scan(&counts).Error
+		if err != nil {
+			return nil, fmt.Errorf("store: count %s by %s: %w", e.table, interval, err)
+		}
+
+		for _, c := range counts {
+			t, err := time.ParseInLocation(bucketLayout, c.Bucket, time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("store: parse %s bucket '%s': %w", e.table, c.Bucket, err)
+			}
+
+			b, ok := buckets[t]
+			if !ok {
+				b = &entities.StatsBucket{Time: t}
+				buckets[t] = b
+			}
+			e.set(b, c)
+		}
+	}
+
+	ts.TotalSent, err = db.GetTotalSends(campaignID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: get total sends: %w", err)
+	}
+	ts.Delivered, err = db.GetTotalDelivered(campaignID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: get total deliveries: %w", err)
+	}
+	opens, err := db.GetOpensStats(campaignID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: get opens stats: %w", err)
+	}
+	ts.Opens = *opens
+	clicks, err := db.GetClicksStats(campaignID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: get clicks stats: %w", err)
+	}
+	ts.Clicks = *clicks
+	ts.Bounces, err = db.GetTotalBounces(campaignID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: get total bounces: %w", err)
+	}
+	ts.Complaints, err = db.GetTotalComplaints(campaignID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: get total complaints: %w", err)
+	}
+
+	ts.Fill(buckets)
+
+	return ts, nil
+}
+
+// bucketExpr returns the expression which truncates the created_at column to the interval.
+func bucketExpr(interval, dialect string) (string, error) {
+	var format string
+	switch interval {
+	case entities.StatsIntervalHour:
+		format = "%Y-%m-%d %H:00:00"
+	case entities.StatsIntervalDay:
+		format = "%Y-%m-%d 00:00:00"
+	default:
+		return "", fmt.Errorf("store: unsupported stats interval '%s'", interval)
+	}
+
+	if dialect == "mysql" {
+		return "DATE_FORMAT(created_at, '" + format + "')", nil
+	}
+
+	return "strftime('" + format + "', created_at)", nil
+}
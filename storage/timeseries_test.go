@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestCampaignStatsTimeSeries(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	start := time.Date(2021, 3, 10, 9, 15, 0, 0, time.UTC)
+	later := start.Add(2 * time.Hour)
+
+	ts, err := store.GetCampaignStatsTimeSeries(1, 1, entities.StatsIntervalHour)
+	assert.Nil(t, err)
+	assert.Empty(t, ts.Buckets)
+	assert.Equal(t, float64(0), ts.OpenRate)
+
+	for i, r := range []string{"jane@example.com", "john@example.com", "joe@example.com", "jim@example.com"} {
+		assert.Nil(t, store.CreateSend(&entities.Send{UserID: 1, CampaignID: 1, MessageID: r, Destination: r, CreatedAt: start}))
+		if i < 3 {
+			assert.Nil(t, store.CreateDelivery(&entities.Delivery{UserID: 1, CampaignID: 1, Recipient: r, CreatedAt: start.Add(time.Minute)}))
+		}
+	}
+	assert.Nil(t, store.CreateBounce(&entities.Bounce{UserID: 1, CampaignID: 1, Recipient: "jim@example.com", CreatedAt: start}))
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 1, Recipient: "jane@example.com", CreatedAt: start.Add(10 * time.Minute)}))
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 1, Recipient: "jane@example.com", CreatedAt: later}))
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 1, Recipient: "john@example.com", CreatedAt: later}))
+	assert.Nil(t, store.CreateClick(&entities.Click{UserID: 1, CampaignID: 1, Recipient: "john@example.com", Link: "https://example.com", CreatedAt: later}))
+	assert.Nil(t, store.CreateComplaint(&entities.Complaint{UserID: 1, CampaignID: 1, Recipient: "joe@example.com", CreatedAt: later}))
+
+	// events of other campaigns are not counted.
+	assert.Nil(t, store.CreateOpen(&entities.Open{UserID: 1, CampaignID: 2, Recipient: "jane@example.com", CreatedAt: later}))
+
+	ts, err = store.GetCampaignStatsTimeSeries(1, 1, entities.StatsIntervalHour)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.StatsIntervalHour, ts.Interval)
+	assert.Equal(t, int64(4), ts.TotalSent)
+	assert.Equal(t, int64(3), ts.Delivered)
+	assert.Equal(t, entities.OpensStats{Unique: 2, Total: 3}, ts.Opens)
+	assert.Equal(t, 0.6667, ts.OpenRate)
+	assert.Equal(t, 0.3333, ts.ClickRate)
+	assert.Equal(t, 0.25, ts.BounceRate)
+
+	// the empty hour between the buckets is filled.
+	assert.Len(t, ts.Buckets, 3)
+
+	first := ts.Buckets[0]
+	assert.True(t, first.Time.Equal(time.Date(2021, 3, 10, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(4), first.Sent)
+	assert.Equal(t, int64(3), first.Delivered)
+	assert.Equal(t, int64(1), first.Bounces)
+	assert.Equal(t, entities.OpensStats{Unique: 1, Total: 1}, first.Opens)
+	assert.Equal(t, 0.3333, first.OpenRate)
+
+	assert.True(t, ts.Buckets[1].Time.Equal(time.Date(2021, 3, 10, 10, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(0), ts.Buckets[1].Sent)
+
+	last := ts.Buckets[2]
+	assert.True(t, last.Time.Equal(time.Date(2021, 3, 10, 11, 0, 0, 0, time.UTC)))
+	assert.Equal(t, entities.OpensStats{Unique: 2, Total: 2}, last.Opens)
+	assert.Equal(t, int64(1), last.Clicks.UniqueClicks)
+	assert.Equal(t, int64(1), last.Complaints)
+
+	ts, err = store.GetCampaignStatsTimeSeries(1, 1, entities.StatsIntervalDay)
+	assert.Nil(t, err)
+	assert.Len(t, ts.Buckets, 1)
+	assert.True(t, ts.Buckets[0].Time.Equal(time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, entities.OpensStats{Unique: 2, Total: 3}, ts.Buckets[0].Opens)
+
+	_, err = store.GetCampaignStatsTimeSeries(1, 1, "week")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// GetTransactionalMessages fetches the transactional messages by user id, and populates the pagination obj
+func (db *store) GetTransactionalMessages(userID int64, p *PaginationCursor) error {
+	p.SetCollection(new([]entities.TransactionalMessage))
+	p.SetResource("transactional_messages")
+
+	p.AddScope(BelongsToUser(userID))
+
+	query := db.Table(p.Resource).
+		Order("created_at desc, id desc").
+		Limit(p.PerPage)
+
+	p.SetQuery(query)
+
+	return db.Paginate(p, userID)
+}
+
+// GetTransactionalMessage returns the transactional message by the given id and user id
+func (db *store) GetTransactionalMessage(id, userID int64) (*entities.TransactionalMessage, error) {
+	var m = new(entities.TransactionalMessage)
+	err := db.Where("user_id = ? and id = ?", userID, id).First(m).Error
+	return m, err
+}
+
+// CreateTransactionalMessage creates a new transactional message in the database.
+func (db *store) CreateTransactionalMessage(m *entities.TransactionalMessage) error {
+	return db.Create(m).Error
+}
+
+// UpdateTransactionalMessageStatus sets the send result of the transactional message.
+func (db *store) UpdateTransactionalMessageStatus(id, userID int64, status, description string, messageID *string) error {
+	err := db.Model(&entities.TransactionalMessage{}).
+		Where("user_id = ? and id = ?", userID, id).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"description": description,
+			"message_id":  messageID,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("store: update transactional message status: %w", err)
+	}
+	return nil
+}
+
+// TrackTransactionalMessageEvent records the SES event of the transactional message,
+// deliveries, bounces and complaints set the status while opens and clicks are counted.
+func (db *store) TrackTransactionalMessageEvent(id, userID int64, event string) error {
+	return trackTransactionalMessageEvent(db.DB, id, userID, event)
+}
+
+func trackTransactionalMessageEvent(db *gorm.DB, id, userID int64, event string) error {
+	var updates map[string]interface{}
+	switch event {
+	case entities.TransactionalEventOpen:
+		updates = map[string]interface{}{"opens": gorm.Expr("opens + 1")}
+	case entities.TransactionalEventClick:
+		updates = map[string]interface{}{"clicks": gorm.Expr("clicks + 1")}
+	case entities.TransactionalStatusDelivered, entities.TransactionalStatusBounced, entities.TransactionalStatusComplained:
+		updates = map[string]interface{}{"status": event}
+	default:
+		return fmt.Errorf("store: track transactional message event: unknown event %q", event)
+	}
+
+	err := db.Model(&entities.TransactionalMessage{}).
+		Where("user_id = ? and id = ?", userID, id).
+		Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("store: track transactional message event: %w", err)
+	}
+	return nil
+}
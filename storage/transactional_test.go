@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestTransactionalMessages(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	m := &entities.TransactionalMessage{
+		UserID:     1,
+		EventID:    ksuid.New(),
+		TemplateID: 1,
+		Recipient:  "jane@example.com",
+		Source:     "auth@example.com",
+		Status:     entities.TransactionalStatusQueued,
+	}
+	assert.Nil(t, m.SetTags(map[string]string{"flow": "login"}))
+	assert.Nil(t, store.CreateTransactionalMessage(m))
+
+	messageID := "abc"
+	err := store.UpdateTransactionalMessageStatus(m.ID, 1, entities.TransactionalStatusSent, "Email sent", &messageID)
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.TrackTransactionalMessageEvent(m.ID, 1, entities.TransactionalStatusDelivered))
+	assert.Nil(t, store.TrackTransactionalMessageEvent(m.ID, 1, entities.TransactionalEventOpen))
+	assert.Nil(t, store.TrackTransactionalMessageEvent(m.ID, 1, entities.TransactionalEventOpen))
+	assert.Nil(t, store.TrackTransactionalMessageEvent(m.ID, 1, entities.TransactionalEventClick))
+	assert.NotNil(t, store.TrackTransactionalMessageEvent(m.ID, 1, "unknown"))
+
+	// other users' messages are not updated.
+	assert.Nil(t, store.TrackTransactionalMessageEvent(m.ID, 2, entities.TransactionalStatusBounced))
+
+	m, err = store.GetTransactionalMessage(m.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionalStatusDelivered, m.Status)
+	assert.Equal(t, "Email sent", m.Description)
+	assert.Equal(t, &messageID, m.MessageID)
+	assert.Equal(t, int64(2), m.Opens)
+	assert.Equal(t, int64(1), m.Clicks)
+
+	tags, err := m.GetTags()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"flow": "login"}, tags)
+
+	_, err = store.GetTransactionalMessage(m.ID, 2)
+	assert.NotNil(t, err)
+
+	p := NewPaginationCursor("/api/transactional", 10)
+	assert.Nil(t, store.GetTransactionalMessages(1, p))
+	col := p.Collection.(*[]entities.TransactionalMessage)
+	assert.Len(t, *col, 1)
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/mailbadger/app/entities"
+)
+
+// GetWebhooks fetches webhooks by user id, and populates the pagination obj
+func (db *store) GetWebhooks(userID int64, p *PaginationCursor) error {
+	p.SetCollection(new([]entities.Webhook))
+	p.SetResource("webhooks")
+
+	p.AddScope(BelongsToUser(userID))
+
+	query := db.Table(p.Resource).
+		Order("created_at desc, id desc").
+		Limit(p.PerPage)
+
+	p.SetQuery(query)
+
+	return db.Paginate(p, userID)
+}
+
+// GetWebhook returns the webhook by the given id and user id
+func (db *store) GetWebhook(id, userID int64) (*entities.Webhook, error) {
+	var w = new(entities.Webhook)
+	err := db.Where("user_id = ? and id = ?", userID, id).First(w).Error
+	return w, err
+}
+
+// GetActiveWebhooks returns the active webhooks of the user
+func (db *store) GetActiveWebhooks(userID int64) ([]entities.Webhook, error) {
+	var hooks []entities.Webhook
+	err := db.Where("user_id = ? and active = ?", userID, true).Find(&hooks).Error
+	return hooks, err
+}
+
+// CreateWebhook creates a new webhook in the database.
+func (db *store) CreateWebhook(w *entities.Webhook) error {
+	return db.Create(w).Error
+}
+
+// UpdateWebhook edits an existing webhook in the database.
+func (db *store) UpdateWebhook(w *entities.Webhook) error {
+	return db.Where("id = ? and user_id = ?", w.ID, w.UserID).Save(w).Error
+}
+
+// DeleteWebhook deletes the webhook along with its delivery log.
+func (db *store) DeleteWebhook(id, userID int64) error {
+	tx := db.Begin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Where("user_id = ? and webhook_id = ?", userID, id).Delete(&entities.WebhookDelivery{}).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: delete webhook deliveries: %w", err)
+	}
+
+	err = tx.Where("user_id = ? and id = ?", userID, id).Delete(&entities.Webhook{}).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: delete webhook: %w", err)
+	}
+
+	return tx.Commit().Error
+}
+
+// GetWebhookDeliveries fetches the deliveries of the webhook, and populates the pagination obj
+func (db *store) GetWebhookDeliveries(webhookID, userID int64, p *PaginationCursor) error {
+	p.SetCollection(new([]entities.WebhookDelivery))
+	p.SetResource("webhook_deliveries")
+
+	p.AddScope(BelongsToUser(userID))
+	p.AddScope(BelongsToWebhook(webhookID))
+
+	query := db.Table(p.Resource).
+		Order("created_at desc, id desc").
+		Limit(p.PerPage)
+
+	p.SetQuery(query)
+
+	return db.Paginate(p, userID)
+}
+
+// BelongsToWebhook scopes the deliveries by the webhook id.
+func BelongsToWebhook(webhookID int64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("webhook_id = ?", webhookID)
+	}
+}
+
+// GetWebhookDelivery returns the delivery by the given id and user id, along with the webhook.
+func (db *store) GetWebhookDelivery(id, userID int64) (*entities.WebhookDelivery, error) {
+	var d = new(entities.WebhookDelivery)
+	err := db.Where("user_id = ? and id = ?", userID, id).Preload("Webhook").First(d).Error
+	return d, err
+}
+
+// CreateWebhookDelivery creates a new webhook delivery in the database.
+func (db *store) CreateWebhookDelivery(d *entities.WebhookDelivery) error {
+	return db.Omit("Webhook").Create(d).Error
+}
+
+// UpdateWebhookDelivery saves the result of the delivery attempt.
+func (db *store) UpdateWebhookDelivery(d *entities.WebhookDelivery) error {
+	return db.Omit("Webhook").Where("id = ? and user_id = ?", d.ID, d.UserID).Save(d).Error
+}
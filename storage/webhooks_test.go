@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mailbadger/app/entities"
+)
+
+func TestWebhooks(t *testing.T) {
+	db := openTestDb()
+	store := From(db)
+
+	w := &entities.Webhook{
+		UserID: 1,
+		URL:    "https://example.com/hooks",
+		Secret: "secret",
+		Events: entities.StringList{entities.WebhookEventSubscriberCreated, entities.WebhookEventEmailBounced},
+		Active: true,
+	}
+	err := store.CreateWebhook(w)
+	assert.Nil(t, err)
+
+	inactive := &entities.Webhook{
+		UserID: 1,
+		URL:    "https://example.com/inactive",
+		Secret: "secret",
+		Events: entities.StringList{entities.WebhookEventSubscriberCreated},
+	}
+	err = store.CreateWebhook(inactive)
+	assert.Nil(t, err)
+
+	w, err = store.GetWebhook(w.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/hooks", w.URL)
+	assert.True(t, w.IsSubscribed(entities.WebhookEventEmailBounced))
+	assert.False(t, w.IsSubscribed(entities.WebhookEventCampaignSent))
+
+	_, err = store.GetWebhook(w.ID, 2)
+	assert.NotNil(t, err)
+
+	hooks, err := store.GetActiveWebhooks(1)
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+	assert.Equal(t, w.ID, hooks[0].ID)
+
+	p := NewPaginationCursor("/api/webhooks", 10)
+	err = store.GetWebhooks(1, p)
+	assert.Nil(t, err)
+	col := p.Collection.(*[]entities.Webhook)
+	assert.Len(t, *col, 2)
+	assert.Equal(t, int64(2), p.Total)
+
+	w.Events = entities.StringList{entities.WebhookEventCampaignSent}
+	err = store.UpdateWebhook(w)
+	assert.Nil(t, err)
+
+	w, err = store.GetWebhook(w.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.StringList{entities.WebhookEventCampaignSent}, w.Events)
+
+	d := &entities.WebhookDelivery{
+		UserID:    1,
+		WebhookID: w.ID,
+		EventID:   ksuid.New(),
+		EventType: entities.WebhookEventCampaignSent,
+		Payload:   []byte(`{"foo":"bar"}`),
+		Status:    entities.WebhookDeliveryStatusPending,
+	}
+	err = store.CreateWebhookDelivery(d)
+	assert.Nil(t, err)
+
+	d, err = store.GetWebhookDelivery(d.ID, 1)
+	assert.Nil(t, err)
+	assert.NotNil(t, d.Webhook)
+	assert.Equal(t, w.URL, d.Webhook.URL)
+
+	_, err = store.GetWebhookDelivery(d.ID, 2)
+	assert.NotNil(t, err)
+
+	d.Attempts = 1
+	d.ResponseStatus = 500
+	d.NextAttemptAt.SetValid(time.Now().UTC())
+	err = store.UpdateWebhookDelivery(d)
+	assert.Nil(t, err)
+
+	p = NewPaginationCursor("/api/webhooks/{id}/deliveries", 10)
+	err = store.GetWebhookDeliveries(w.ID, 1, p)
+	assert.Nil(t, err)
+	deliveries := p.Collection.(*[]entities.WebhookDelivery)
+	assert.Len(t, *deliveries, 1)
+	assert.Equal(t, 1, (*deliveries)[0].Attempts)
+	assert.Equal(t, 500, (*deliveries)[0].ResponseStatus)
+
+	p = NewPaginationCursor("/api/webhooks/{id}/deliveries", 10)
+	err = store.GetWebhookDeliveries(inactive.ID, 1, p)
+	assert.Nil(t, err)
+	assert.Empty(t, *p.Collection.(*[]entities.WebhookDelivery))
+
+	err = store.DeleteWebhook(w.ID, 1)
+	assert.Nil(t, err)
+
+	_, err = store.GetWebhook(w.ID, 1)
+	assert.NotNil(t, err)
+
+	_, err = store.GetWebhookDelivery(d.ID, 1)
+	assert.NotNil(t, err)
+}
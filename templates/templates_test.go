@@ -17,12 +17,17 @@ func TestInit(t *testing.T) {
 
 	rec := httptest.NewRecorder()
 
-	// render unsubscribe.html
-	err = r.HTMLRender.Instance("unsubscribe.html", gin.H{
-		"email":  "foo@bar.com",
-		"t":      "foo",
-		"uuid":   "abcdefgh",
-		"failed": false,
+	// render preferences.html
+	err = r.HTMLRender.Instance("preferences.html", gin.H{
+		"email":        "foo@bar.com",
+		"t":            "foo",
+		"uuid":         "abcdefgh",
+		"failed":       false,
+		"name":         "foo",
+		"metadata":     map[string]string{"city": "Skopje"},
+		"topics":       []gin.H{{"ID": 1, "Name": "news", "Subscribed": true}},
+		"active":       true,
+		"paused_until": "January 2, 2006",
 	}).Render(rec)
 
 	assert.Nil(t, err)
@@ -38,3 +38,23 @@ func TestSignData(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "bd209680297c13ce4d5eaf0c8dea68691de725cfb7ae116b8e8845a9606b22d4", hash)
 }
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := "supersecret"
+
+	enc, err := Encrypt("hunter1", key)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "hunter1", enc)
+
+	dec, err := Decrypt(enc, key)
+	assert.Nil(t, err)
+	assert.Equal(t, "hunter1", dec)
+
+	_, err = Decrypt(enc, "othersecret")
+	assert.NotNil(t, err)
+
+	// values stored before the encryption are returned as they are.
+	dec, err = Decrypt("hunter1", key)
+	assert.Nil(t, err)
+	assert.Equal(t, "hunter1", dec)
+}